@@ -26,6 +26,13 @@ Action semantics:
                                                      Postgres only. destructive. Terminates the listed transaction_id if it still matches server state.
   kill <database> <branch> <connection-id>           destructive. Terminates the listed connection_id.
 
+Review a trace captured with top --capture offline:
+  analyze <trace>    Longest queries, top blockers, lock waits, connection counts, and idle transactions.
+  trim <trace>       Write the snapshots between --from and --to to a smaller trace file.
+
+Alert on rule violations with watch <database> <branch>. Its --remediate action is
+a dry run unless --apply is set. destructive with --apply.
+
 Use --format json when an agent or script needs to inspect query_id,
 transaction_id, and connection_id fields. Human output uses vertical records so
 query text and action IDs are not truncated.`,
@@ -35,6 +42,9 @@ query text and action IDs are not truncated.`,
 	cmd.AddCommand(ConnectionsKillCmd(ch))
 	cmd.AddCommand(ConnectionsKillTransactionCmd(ch))
 	cmd.AddCommand(connections.TopCmd(ch))
+	cmd.AddCommand(connections.AnalyzeCmd(ch))
+	cmd.AddCommand(connections.TrimCmd(ch))
+	cmd.AddCommand(connections.WatchCmd(ch))
 
 	return cmd
 }
@@ -0,0 +1,453 @@
+package connections
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/planetscale/cli/internal/cmdutil"
+	live "github.com/planetscale/cli/internal/connections"
+	"github.com/planetscale/cli/internal/connections/history"
+	"github.com/planetscale/cli/internal/printer"
+	"github.com/spf13/cobra"
+)
+
+// analyzeQueryWidth bounds the query text of a human output row.
+const analyzeQueryWidth = 60
+
+// AnalyzeCmd summarizes a trace written by connections top --capture.
+func AnalyzeCmd(ch *cmdutil.Helper) *cobra.Command {
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "analyze <trace>",
+		Short: "Summarize a connections trace captured with connections top --capture",
+		Long: `Summarize a connections trace captured with connections top --capture.
+
+The report covers the longest-running queries, the sessions that blocked others
+by total blocked time, lock-wait chains over time, connection counts per user,
+application, and client address, and sessions left idle in a transaction.
+
+Blocked time is summed over the samples for every session waiting on a blocker,
+directly or through a chain, so it grows with both the wait and the number of
+sessions held up. A rotated or compressed capture is read as one trace.
+Analysis reads the trace file only and does not contact PlanetScale.`,
+		Example: `  pscale branch connections top my-db main --capture incident.ndjson --duration 10m
+  pscale branch connections analyze incident.ndjson
+  pscale branch connections analyze incident.ndjson --limit 25 --format json`,
+		Args: cmdutil.RequiredArgs("trace"),
+		// A trace is a local file: analyzing it needs neither
+		// authentication nor an organization.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return nil },
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if limit < 0 {
+				return errors.New("--limit must not be negative")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runAnalyze(ch, args[0], limit)
+		},
+	}
+
+	// Shadows the required --org flag of pscale branch.
+	cmd.PersistentFlags().StringVar(&ch.Config.Organization, "org", ch.Config.Organization, "The organization for the current user")
+	cmd.Flags().IntVar(&limit, "limit", 10, "Number of queries, blockers, idle transactions, and connection counts per dimension to report. 0 reports all.")
+
+	return cmd
+}
+
+func runAnalyze(ch *cmdutil.Helper, path string, limit int) error {
+	file, err := history.OpenTrace(path)
+	if err != nil {
+		return fmt.Errorf("open trace: %w", err)
+	}
+	defer file.Close()
+
+	reader := history.NewCaptureReader(file)
+	captures, err := reader.ReadAll()
+	if err != nil {
+		return fmt.Errorf("read trace %s: %w", path, err)
+	}
+	if len(captures) == 0 {
+		return fmt.Errorf("trace %s contains no snapshots", path)
+	}
+
+	start, _ := reader.CaptureStart()
+	analysis := history.Analyze(captures, history.AnalysisOptions{Limit: limit})
+	report := toAnalysisReport(path, start, analysis)
+
+	if ch.Printer.Format() == printer.Human {
+		var out strings.Builder
+		printHumanAnalysis(&out, report)
+		ch.Printer.Print(out.String())
+		return nil
+	}
+	return ch.Printer.PrintResource(report)
+}
+
+type analysisReport struct {
+	Trace             string                    `json:"trace"`
+	Organization      string                    `json:"org,omitempty"`
+	Database          string                    `json:"database,omitempty"`
+	Branch            string                    `json:"branch,omitempty"`
+	DatabaseKind      live.DatabaseKind         `json:"database_kind,omitempty"`
+	From              time.Time                 `json:"from"`
+	To                time.Time                 `json:"to"`
+	Samples           int                       `json:"samples"`
+	LongestQueries    []analyzedQuery           `json:"longest_queries"`
+	Blockers          []analyzedBlocker         `json:"blockers"`
+	LockWaits         []analyzedLockWait        `json:"lock_waits"`
+	ConnectionCounts  []analyzedConnectionCount `json:"connection_counts"`
+	IdleInTransaction []analyzedIdleTransaction `json:"idle_in_transaction"`
+}
+
+type analyzedQuery struct {
+	PID             int       `json:"pid"`
+	Instance        string    `json:"instance"`
+	Username        string    `json:"username"`
+	ApplicationName string    `json:"application_name"`
+	ClientAddr      string    `json:"client_addr"`
+	State           string    `json:"state"`
+	DurationMS      int64     `json:"duration_ms"`
+	FirstSeen       time.Time `json:"first_seen"`
+	LastSeen        time.Time `json:"last_seen"`
+	QueryText       string    `json:"query_text"`
+}
+
+type analyzedBlocker struct {
+	PID             int       `json:"pid"`
+	Instance        string    `json:"instance"`
+	Username        string    `json:"username"`
+	ApplicationName string    `json:"application_name"`
+	BlockedTimeMS   int64     `json:"blocked_time_ms"`
+	MaxBlocked      int       `json:"max_blocked"`
+	FirstSeen       time.Time `json:"first_seen"`
+	LastSeen        time.Time `json:"last_seen"`
+	QueryText       string    `json:"query_text"`
+}
+
+type analyzedLockWait struct {
+	At      time.Time           `json:"at"`
+	Waiting int                 `json:"waiting"`
+	Chains  []analyzedLockChain `json:"chains"`
+}
+
+type analyzedLockChain struct {
+	Instance string `json:"instance"`
+	PIDs     []int  `json:"pids"`
+	WaitMS   int64  `json:"wait_ms"`
+}
+
+type analyzedConnectionCount struct {
+	Dimension string    `json:"dimension"`
+	Value     string    `json:"value"`
+	Peak      int       `json:"peak"`
+	PeakAt    time.Time `json:"peak_at"`
+	Average   float64   `json:"average"`
+}
+
+type analyzedIdleTransaction struct {
+	PID              int       `json:"pid"`
+	Instance         string    `json:"instance"`
+	Username         string    `json:"username"`
+	ApplicationName  string    `json:"application_name"`
+	ClientAddr       string    `json:"client_addr"`
+	TransactionAgeMS int64     `json:"transaction_age_ms"`
+	IdleTimeMS       int64     `json:"idle_time_ms"`
+	Samples          int       `json:"samples"`
+	FirstSeen        time.Time `json:"first_seen"`
+	LastSeen         time.Time `json:"last_seen"`
+	QueryText        string    `json:"query_text"`
+}
+
+func toAnalysisReport(path string, start history.CaptureStart, a history.Analysis) analysisReport {
+	report := analysisReport{
+		Trace:             path,
+		Organization:      start.Organization,
+		Database:          start.Database,
+		Branch:            start.Branch,
+		DatabaseKind:      a.DatabaseKind,
+		From:              a.From,
+		To:                a.To,
+		Samples:           a.Samples,
+		LongestQueries:    make([]analyzedQuery, 0, len(a.LongestQueries)),
+		Blockers:          make([]analyzedBlocker, 0, len(a.Blockers)),
+		LockWaits:         make([]analyzedLockWait, 0, len(a.LockWaits)),
+		ConnectionCounts:  make([]analyzedConnectionCount, 0, len(a.ConnectionCounts)),
+		IdleInTransaction: make([]analyzedIdleTransaction, 0, len(a.IdleInTransaction)),
+	}
+	for _, q := range a.LongestQueries {
+		report.LongestQueries = append(report.LongestQueries, analyzedQuery{
+			PID:             q.PID,
+			Instance:        q.Instance,
+			Username:        q.Username,
+			ApplicationName: q.ApplicationName,
+			ClientAddr:      q.ClientAddr,
+			State:           q.State,
+			DurationMS:      q.Duration.Milliseconds(),
+			FirstSeen:       q.FirstSeen,
+			LastSeen:        q.LastSeen,
+			QueryText:       q.QueryText,
+		})
+	}
+	for _, b := range a.Blockers {
+		report.Blockers = append(report.Blockers, analyzedBlocker{
+			PID:             b.PID,
+			Instance:        b.Instance,
+			Username:        b.Username,
+			ApplicationName: b.ApplicationName,
+			BlockedTimeMS:   b.BlockedTime.Milliseconds(),
+			MaxBlocked:      b.MaxBlocked,
+			FirstSeen:       b.FirstSeen,
+			LastSeen:        b.LastSeen,
+			QueryText:       b.QueryText,
+		})
+	}
+	for _, sample := range a.LockWaits {
+		wait := analyzedLockWait{At: sample.At, Waiting: sample.Waiting, Chains: make([]analyzedLockChain, 0, len(sample.Chains))}
+		for _, chain := range sample.Chains {
+			wait.Chains = append(wait.Chains, analyzedLockChain{
+				Instance: chain.Instance,
+				PIDs:     chain.PIDs,
+				WaitMS:   chain.Wait.Milliseconds(),
+			})
+		}
+		report.LockWaits = append(report.LockWaits, wait)
+	}
+	for _, count := range a.ConnectionCounts {
+		report.ConnectionCounts = append(report.ConnectionCounts, analyzedConnectionCount{
+			Dimension: count.Dimension,
+			Value:     count.Value,
+			Peak:      count.Peak,
+			PeakAt:    count.PeakAt,
+			Average:   count.Average,
+		})
+	}
+	for _, t := range a.IdleInTransaction {
+		report.IdleInTransaction = append(report.IdleInTransaction, analyzedIdleTransaction{
+			PID:              t.PID,
+			Instance:         t.Instance,
+			Username:         t.Username,
+			ApplicationName:  t.ApplicationName,
+			ClientAddr:       t.ClientAddr,
+			TransactionAgeMS: t.TransactionAge.Milliseconds(),
+			IdleTimeMS:       t.IdleTime.Milliseconds(),
+			Samples:          t.Samples,
+			FirstSeen:        t.FirstSeen,
+			LastSeen:         t.LastSeen,
+			QueryText:        t.QueryText,
+		})
+	}
+	return report
+}
+
+// analysisRow is one finding of the report in CSV output. Section names the
+// finding, and each section fills the columns that apply to it.
+type analysisRow struct {
+	Section         string `csv:"section"`
+	At              string `csv:"at"`
+	Instance        string `csv:"instance"`
+	PID             string `csv:"pid"`
+	Username        string `csv:"username"`
+	ApplicationName string `csv:"application_name"`
+	ClientAddr      string `csv:"client_addr"`
+	DurationMS      string `csv:"duration_ms"`
+	Count           string `csv:"count"`
+	Average         string `csv:"average"`
+	Chain           string `csv:"chain"`
+	QueryText       string `csv:"query_text"`
+}
+
+func (r analysisReport) MarshalCSVValue() interface{} {
+	rows := make([]analysisRow, 0)
+	for _, q := range r.LongestQueries {
+		rows = append(rows, analysisRow{
+			Section:         "longest_query",
+			At:              formatAnalysisTime(q.FirstSeen),
+			Instance:        q.Instance,
+			PID:             strconv.Itoa(q.PID),
+			Username:        q.Username,
+			ApplicationName: q.ApplicationName,
+			ClientAddr:      q.ClientAddr,
+			DurationMS:      strconv.FormatInt(q.DurationMS, 10),
+			QueryText:       q.QueryText,
+		})
+	}
+	for _, b := range r.Blockers {
+		rows = append(rows, analysisRow{
+			Section:         "blocker",
+			At:              formatAnalysisTime(b.FirstSeen),
+			Instance:        b.Instance,
+			PID:             strconv.Itoa(b.PID),
+			Username:        b.Username,
+			ApplicationName: b.ApplicationName,
+			DurationMS:      strconv.FormatInt(b.BlockedTimeMS, 10),
+			Count:           strconv.Itoa(b.MaxBlocked),
+			QueryText:       b.QueryText,
+		})
+	}
+	for _, sample := range r.LockWaits {
+		for _, chain := range sample.Chains {
+			rows = append(rows, analysisRow{
+				Section:    "lock_wait",
+				At:         formatAnalysisTime(sample.At),
+				Instance:   chain.Instance,
+				PID:        strconv.Itoa(chain.PIDs[0]),
+				DurationMS: strconv.FormatInt(chain.WaitMS, 10),
+				Count:      strconv.Itoa(sample.Waiting),
+				Chain:      formatLockChain(chain.PIDs, ">"),
+			})
+		}
+	}
+	for _, count := range r.ConnectionCounts {
+		row := analysisRow{
+			Section: "connection_count",
+			At:      formatAnalysisTime(count.PeakAt),
+			Count:   strconv.Itoa(count.Peak),
+			Average: strconv.FormatFloat(count.Average, 'f', 2, 64),
+		}
+		switch count.Dimension {
+		case history.DimensionUser:
+			row.Username = count.Value
+		case history.DimensionApplication:
+			row.ApplicationName = count.Value
+		case history.DimensionClientAddr:
+			row.ClientAddr = count.Value
+		}
+		rows = append(rows, row)
+	}
+	for _, t := range r.IdleInTransaction {
+		rows = append(rows, analysisRow{
+			Section:         "idle_in_transaction",
+			At:              formatAnalysisTime(t.FirstSeen),
+			Instance:        t.Instance,
+			PID:             strconv.Itoa(t.PID),
+			Username:        t.Username,
+			ApplicationName: t.ApplicationName,
+			ClientAddr:      t.ClientAddr,
+			DurationMS:      strconv.FormatInt(t.TransactionAgeMS, 10),
+			Count:           strconv.Itoa(t.Samples),
+			QueryText:       t.QueryText,
+		})
+	}
+	return rows
+}
+
+func printHumanAnalysis(out io.Writer, r analysisReport) {
+	name := r.Trace
+	if r.Database != "" {
+		name = r.Database + "/" + r.Branch
+		if r.Organization != "" {
+			name = r.Organization + "/" + name
+		}
+	}
+	fmt.Fprintf(out, "trace:    %s\n", name)
+	fmt.Fprintf(out, "samples:  %d from %s to %s (%s)\n", r.Samples, r.From.Format(time.RFC3339), r.To.Format(time.RFC3339), formatAnalysisDuration(r.To.Sub(r.From)))
+
+	section := func(title string, empty bool, header string, rows func(w io.Writer)) {
+		fmt.Fprintf(out, "\n%s\n", printer.Bold(title))
+		if empty {
+			fmt.Fprintln(out, "  None found.")
+			return
+		}
+		w := tabwriter.NewWriter(out, 2, 2, 2, ' ', 0)
+		fmt.Fprintln(w, "  "+header)
+		rows(w)
+		w.Flush()
+	}
+
+	section("Longest-running queries", len(r.LongestQueries) == 0,
+		"PID\tINSTANCE\tUSER\tAPPLICATION\tDURATION\tFIRST SEEN\tQUERY",
+		func(w io.Writer) {
+			for _, q := range r.LongestQueries {
+				fmt.Fprintf(w, "  %d\t%s\t%s\t%s\t%s\t%s\t%s\n", q.PID, humanValue(q.Instance), humanValue(q.Username), humanValue(q.ApplicationName),
+					formatAnalysisDuration(time.Duration(q.DurationMS)*time.Millisecond), q.FirstSeen.Format(time.TimeOnly), truncateQuery(q.QueryText))
+			}
+		})
+
+	section("Top blockers", len(r.Blockers) == 0,
+		"PID\tINSTANCE\tUSER\tAPPLICATION\tBLOCKED TIME\tMAX BLOCKED\tQUERY",
+		func(w io.Writer) {
+			for _, b := range r.Blockers {
+				fmt.Fprintf(w, "  %d\t%s\t%s\t%s\t%s\t%d\t%s\n", b.PID, humanValue(b.Instance), humanValue(b.Username), humanValue(b.ApplicationName),
+					formatAnalysisDuration(time.Duration(b.BlockedTimeMS)*time.Millisecond), b.MaxBlocked, truncateQuery(b.QueryText))
+			}
+		})
+
+	section("Lock waits", len(r.LockWaits) == 0,
+		"TIME\tWAITING\tINSTANCE\tCHAIN\tWAIT",
+		func(w io.Writer) {
+			for _, sample := range r.LockWaits {
+				for i, chain := range sample.Chains {
+					at, waiting := "", ""
+					if i == 0 {
+						at, waiting = sample.At.Format(time.TimeOnly), strconv.Itoa(sample.Waiting)
+					}
+					fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", at, waiting, humanValue(chain.Instance),
+						formatLockChain(chain.PIDs, " → "), formatAnalysisDuration(time.Duration(chain.WaitMS)*time.Millisecond))
+				}
+			}
+		})
+
+	section("Connections", len(r.ConnectionCounts) == 0,
+		"BY\tVALUE\tPEAK\tAVERAGE\tPEAK AT",
+		func(w io.Writer) {
+			for _, count := range r.ConnectionCounts {
+				fmt.Fprintf(w, "  %s\t%s\t%d\t%.1f\t%s\n", count.Dimension, humanValue(count.Value), count.Peak, count.Average, count.PeakAt.Format(time.TimeOnly))
+			}
+		})
+
+	section("Idle in transaction", len(r.IdleInTransaction) == 0,
+		"PID\tINSTANCE\tUSER\tAPPLICATION\tCLIENT\tTRANSACTION AGE\tIDLE\tLAST QUERY",
+		func(w io.Writer) {
+			for _, t := range r.IdleInTransaction {
+				fmt.Fprintf(w, "  %d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", t.PID, humanValue(t.Instance), humanValue(t.Username), humanValue(t.ApplicationName), humanValue(t.ClientAddr),
+					formatAnalysisDuration(time.Duration(t.TransactionAgeMS)*time.Millisecond), formatAnalysisDuration(time.Duration(t.IdleTimeMS)*time.Millisecond), truncateQuery(t.QueryText))
+			}
+		})
+}
+
+func formatLockChain(pids []int, sep string) string {
+	parts := make([]string, 0, len(pids))
+	for _, pid := range pids {
+		parts = append(parts, strconv.Itoa(pid))
+	}
+	return strings.Join(parts, sep)
+}
+
+func formatAnalysisTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func formatAnalysisDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
+
+func humanValue(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return live.SanitizeDisplayText(value)
+}
+
+func truncateQuery(query string) string {
+	query = live.SanitizeDisplayText(strings.Join(strings.Fields(query), " "))
+	if query == "" {
+		return "-"
+	}
+	if runes := []rune(query); len(runes) > analyzeQueryWidth {
+		return string(runes[:analyzeQueryWidth-1]) + "…"
+	}
+	return query
+}
@@ -0,0 +1,126 @@
+package connections
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	live "github.com/planetscale/cli/internal/connections"
+	"github.com/planetscale/cli/internal/connections/history"
+	"github.com/planetscale/cli/internal/printer"
+)
+
+func TestAnalyzeCmdReportsTrace(t *testing.T) {
+	path := writeAnalyzeTrace(t)
+
+	t.Run("json", func(t *testing.T) {
+		c := qt.New(t)
+		var out bytes.Buffer
+		cmd := AnalyzeCmd(connectionsTestHelper("", printer.JSON, &out))
+		cmd.SetArgs([]string{path})
+		c.Assert(cmd.Execute(), qt.IsNil)
+
+		var report analysisReport
+		c.Assert(json.Unmarshal(out.Bytes(), &report), qt.IsNil)
+		c.Assert(report.Database, qt.Equals, "prod")
+		c.Assert(report.Samples, qt.Equals, 2)
+		c.Assert(report.Blockers, qt.HasLen, 1)
+		c.Assert(report.Blockers[0].PID, qt.Equals, 101)
+		c.Assert(report.Blockers[0].BlockedTimeMS, qt.Equals, int64(10000))
+		c.Assert(report.LockWaits, qt.HasLen, 2)
+		c.Assert(report.LockWaits[0].Chains[0].PIDs, qt.DeepEquals, []int{101, 102})
+		c.Assert(report.IdleInTransaction, qt.HasLen, 1)
+		c.Assert(report.IdleInTransaction[0].PID, qt.Equals, 101)
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		c := qt.New(t)
+		var out bytes.Buffer
+		cmd := AnalyzeCmd(connectionsTestHelper("", printer.CSV, &out))
+		cmd.SetArgs([]string{path})
+		c.Assert(cmd.Execute(), qt.IsNil)
+
+		records, err := csv.NewReader(&out).ReadAll()
+		c.Assert(err, qt.IsNil)
+		c.Assert(records[0][0], qt.Equals, "section")
+		sections := make(map[string]int)
+		for _, record := range records[1:] {
+			sections[record[0]]++
+		}
+		c.Assert(sections["longest_query"], qt.Equals, 1)
+		c.Assert(sections["blocker"], qt.Equals, 1)
+		c.Assert(sections["lock_wait"], qt.Equals, 2)
+		c.Assert(sections["idle_in_transaction"], qt.Equals, 1)
+		c.Assert(sections["connection_count"], qt.Equals, 5)
+	})
+
+	t.Run("human", func(t *testing.T) {
+		c := qt.New(t)
+		var out bytes.Buffer
+		cmd := AnalyzeCmd(connectionsTestHelper("", printer.Human, &out))
+		cmd.SetArgs([]string{path})
+		c.Assert(cmd.Execute(), qt.IsNil)
+
+		got := out.String()
+		c.Assert(got, qt.Contains, "trace:    acme/prod/main")
+		c.Assert(got, qt.Contains, "Top blockers")
+		c.Assert(got, qt.Contains, "101 → 102")
+		c.Assert(got, qt.Contains, "UPDATE widgets SET n = 2 WHERE id = 1")
+		c.Assert(got, qt.Contains, "Idle in transaction")
+	})
+}
+
+func TestAnalyzeCmdRejectsEmptyTrace(t *testing.T) {
+	c := qt.New(t)
+	path := filepath.Join(t.TempDir(), "empty.ndjson")
+	c.Assert(os.WriteFile(path, nil, 0o600), qt.IsNil)
+
+	cmd := AnalyzeCmd(connectionsTestHelper("", printer.JSON, &bytes.Buffer{}))
+	cmd.SetArgs([]string{path})
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
+	c.Assert(cmd.Execute(), qt.ErrorMatches, `trace .* contains no snapshots`)
+}
+
+func TestTruncateQuery(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(truncateQuery("SELECT *\n  FROM widgets"), qt.Equals, "SELECT * FROM widgets")
+	c.Assert(truncateQuery(""), qt.Equals, "-")
+	long := truncateQuery(strings.Repeat("x", 100))
+	c.Assert([]rune(long), qt.HasLen, analyzeQueryWidth)
+	c.Assert(strings.HasSuffix(long, "…"), qt.IsTrue)
+}
+
+func writeAnalyzeTrace(t *testing.T) string {
+	t.Helper()
+	c := qt.New(t)
+	path := filepath.Join(t.TempDir(), "trace.ndjson")
+	file, err := os.Create(path)
+	c.Assert(err, qt.IsNil)
+
+	base := time.Date(2026, 4, 28, 15, 0, 0, 0, time.UTC)
+	xactStart := base.Add(-time.Minute)
+	writer := history.NewCaptureWriter(file)
+	c.Assert(writer.WriteCaptureStart(history.CaptureStart{At: base, Organization: "acme", Database: "prod", Branch: "main"}), qt.IsNil)
+	for i := range 2 {
+		at := base.Add(time.Duration(i) * 5 * time.Second)
+		c.Assert(writer.Write(history.NewCapture(live.ConnectionList{
+			CapturedAt:   at,
+			DatabaseKind: live.DatabaseKindPostgreSQL,
+			Connections: []live.Connection{
+				{PID: 101, Instance: "primary", Username: "app", ApplicationName: "web", ClientAddr: "10.0.0.1", State: "idle in transaction", XactStart: &xactStart, Duration: 30 * time.Second, QueryText: "UPDATE widgets SET n = 1 WHERE id = 1"},
+				{PID: 102, Instance: "primary", Username: "app", ApplicationName: "worker", ClientAddr: "10.0.0.2", State: "active", QueryStart: &base, Duration: at.Sub(base), BlockedBy: []int{101}, QueryText: "UPDATE widgets SET n = 2 WHERE id = 1"},
+			},
+		})), qt.IsNil)
+	}
+	c.Assert(writer.Close(), qt.IsNil)
+	return path
+}
@@ -0,0 +1,164 @@
+package connections
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/planetscale/cli/internal/connections/history"
+	"github.com/planetscale/cli/internal/connections/metrics"
+)
+
+// metricsExportTimeout bounds a single OTLP export, so a stuck collector
+// can't stall the capture.
+const metricsExportTimeout = 10 * time.Second
+
+// metricsExporter receives the metrics derived from each capture sample.
+type metricsExporter interface {
+	Export(context.Context, metrics.Sample) error
+}
+
+// exportingWriter derives metrics from each capture before passing it on to
+// the trace writer. Export failures are reported as warnings: a collector
+// outage must not stop the capture.
+type exportingWriter struct {
+	captureWriter
+	exporters []metricsExporter
+	labels    []metrics.Label
+	errOut    io.Writer
+}
+
+func (w *exportingWriter) Write(capture history.Capture) error {
+	sample := metrics.FromList(capture.List, w.labels...)
+	for _, exporter := range w.exporters {
+		if err := exporter.Export(context.Background(), sample); err != nil {
+			fmt.Fprintf(w.errOut, "warning: %s\n", err)
+		}
+	}
+	return w.captureWriter.Write(capture)
+}
+
+// nopCaptureWriter stands in for the trace writer when the headless capture
+// only exports metrics.
+type nopCaptureWriter struct{}
+
+func (nopCaptureWriter) Write(history.Capture) error { return nil }
+func (nopCaptureWriter) Close() error                { return nil }
+
+// prometheusEndpoint serves the metrics of the latest sample on /metrics.
+type prometheusEndpoint struct {
+	listener net.Listener
+	server   *http.Server
+
+	mu     sync.Mutex
+	sample *metrics.Sample
+}
+
+// listenPrometheus starts serving /metrics on addr. The listener is opened
+// before the first sample so a bad or busy address fails at startup.
+func listenPrometheus(addr string) (*prometheusEndpoint, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("--metrics-listen: %w", err)
+	}
+	p := &prometheusEndpoint{listener: listener}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", p)
+	p.server = &http.Server{Handler: mux, ReadHeaderTimeout: metricsExportTimeout}
+	go p.server.Serve(listener) // nolint:errcheck
+	return p, nil
+}
+
+// URL is the address Prometheus scrapes.
+func (p *prometheusEndpoint) URL() string {
+	return "http://" + p.listener.Addr().String() + "/metrics"
+}
+
+func (p *prometheusEndpoint) Export(_ context.Context, sample metrics.Sample) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sample = &sample
+	return nil
+}
+
+func (p *prometheusEndpoint) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	p.mu.Lock()
+	sample := p.sample
+	p.mu.Unlock()
+	if sample == nil {
+		http.Error(w, "no connections sample yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", metrics.PrometheusContentType)
+	metrics.WritePrometheus(w, *sample) // nolint:errcheck
+}
+
+func (p *prometheusEndpoint) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), metricsExportTimeout)
+	defer cancel()
+	return p.server.Shutdown(ctx)
+}
+
+// otlpExporter POSTs each sample to an OpenTelemetry collector with the
+// OTLP/HTTP JSON encoding.
+type otlpExporter struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newOTLPExporter(endpoint string, headers map[string]string) *otlpExporter {
+	return &otlpExporter{url: otlpMetricsURL(endpoint), headers: headers, client: &http.Client{Timeout: metricsExportTimeout}}
+}
+
+// otlpMetricsURL appends the OTLP metrics path to a collector base URL, the
+// way OTEL_EXPORTER_OTLP_ENDPOINT is resolved. A URL that already names the
+// path is used as is.
+func otlpMetricsURL(endpoint string) string {
+	if strings.HasSuffix(endpoint, "/v1/metrics") {
+		return endpoint
+	}
+	return strings.TrimSuffix(endpoint, "/") + "/v1/metrics"
+}
+
+func (e *otlpExporter) Export(ctx context.Context, sample metrics.Sample) error {
+	body, err := metrics.MarshalOTLP(sample, metrics.Label{Name: "service.name", Value: "pscale"})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("otlp: %w", err)
+	}
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("otlp: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body) // nolint:errcheck
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("otlp: %s returned %s", e.url, resp.Status)
+	}
+	return nil
+}
+
+func validateOTLPEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("--otlp-endpoint must be an http or https URL")
+	}
+	return nil
+}
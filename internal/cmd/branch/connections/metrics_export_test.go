@@ -0,0 +1,182 @@
+package connections
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	live "github.com/planetscale/cli/internal/connections"
+	"github.com/planetscale/cli/internal/connections/history"
+	"github.com/planetscale/cli/internal/connections/metrics"
+)
+
+func TestTopCmdRunEHeadlessExportsOTLPWithoutCapture(t *testing.T) {
+	c := qt.New(t)
+	restoreTTY := setPrinterTTY(t, false)
+	defer restoreTTY()
+	server := liveConnectionsServer(t, sampleTopResponse())
+	exports := make(chan *http.Request, 10)
+	bodies := make(chan []byte, 10)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		exports <- r
+		bodies <- body
+	}))
+	defer collector.Close()
+	var stderr bytes.Buffer
+	cmd := topCmdForServer(server.URL, &bytes.Buffer{})
+	cmd.SetErr(&stderr)
+	cmd.SetArgs([]string{"pgload", "main", "--otlp-endpoint", collector.URL, "--otlp-header", "authorization=Bearer token", "--duration", "200ms", "--interval", "1s"})
+
+	err := cmd.Execute()
+
+	c.Assert(err, qt.IsNil)
+	c.Assert(stderr.String(), qt.Contains, "Exporting metrics to "+collector.URL+"/v1/metrics")
+	c.Assert(exports, qt.HasLen, 1)
+	req := <-exports
+	c.Assert(req.URL.Path, qt.Equals, "/v1/metrics")
+	c.Assert(req.Header.Get("Authorization"), qt.Equals, "Bearer token")
+	c.Assert(req.Header.Get("Content-Type"), qt.Equals, "application/json")
+	var body struct {
+		ResourceMetrics []struct {
+			ScopeMetrics []struct {
+				Metrics []struct {
+					Name string `json:"name"`
+				} `json:"metrics"`
+			} `json:"scopeMetrics"`
+		} `json:"resourceMetrics"`
+	}
+	c.Assert(json.Unmarshal(<-bodies, &body), qt.IsNil)
+	c.Assert(body.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].Name, qt.Equals, metrics.NameConnections)
+}
+
+func TestTopCmdMetricsValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		tty     bool
+		args    []string
+		wantErr string
+	}{
+		{
+			name:    "otlp endpoint without scheme",
+			args:    []string{"pgload", "main", "--otlp-endpoint", "localhost:4318"},
+			wantErr: "--otlp-endpoint must be an http or https URL",
+		},
+		{
+			name:    "otlp header without endpoint",
+			args:    []string{"pgload", "main", "--capture", "trace.jsonl", "--otlp-header", "a=b"},
+			wantErr: "--otlp-header requires --otlp-endpoint",
+		},
+		{
+			name:    "metrics with replay",
+			args:    []string{"--replay", "trace.jsonl", "--metrics-listen", ":0"},
+			wantErr: "--replay cannot be combined with --metrics-listen or --otlp-endpoint",
+		},
+		{
+			name:    "metrics in the TUI",
+			tty:     true,
+			args:    []string{"pgload", "main", "--metrics-listen", "127.0.0.1:0"},
+			wantErr: "--metrics-listen and --otlp-endpoint require running without a TTY",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			restoreTTY := setPrinterTTY(t, tt.tty)
+			defer restoreTTY()
+			server := liveConnectionsServer(t, sampleTopResponse())
+			cmd := topCmdForServer(server.URL, &bytes.Buffer{})
+			cmd.SetArgs(tt.args)
+
+			err := cmd.Execute()
+
+			c.Assert(err, qt.ErrorMatches, tt.wantErr)
+		})
+	}
+}
+
+func TestPrometheusEndpointServesLatestSample(t *testing.T) {
+	c := qt.New(t)
+	endpoint, err := listenPrometheus("127.0.0.1:0")
+	c.Assert(err, qt.IsNil)
+	defer endpoint.Close()
+
+	resp, err := http.Get(endpoint.URL())
+	c.Assert(err, qt.IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, qt.Equals, http.StatusServiceUnavailable)
+
+	for _, n := range []int{1, 2} {
+		list := live.NewConnectionList(time.Unix(int64(n), 0), make([]live.Connection, n), live.SortByTransactionStart)
+		c.Assert(endpoint.Export(context.Background(), metrics.FromList(list)), qt.IsNil)
+	}
+	resp, err = http.Get(endpoint.URL())
+	c.Assert(err, qt.IsNil)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	c.Assert(err, qt.IsNil)
+
+	c.Assert(resp.StatusCode, qt.Equals, http.StatusOK)
+	c.Assert(resp.Header.Get("Content-Type"), qt.Equals, metrics.PrometheusContentType)
+	c.Assert(string(body), qt.Contains, `pscale_connections{state="unknown",role="unknown"} 2`+"\n")
+}
+
+func TestListenPrometheusRejectsBusyAddress(t *testing.T) {
+	c := qt.New(t)
+	endpoint, err := listenPrometheus("127.0.0.1:0")
+	c.Assert(err, qt.IsNil)
+	defer endpoint.Close()
+
+	_, err = listenPrometheus(endpoint.listener.Addr().String())
+
+	c.Assert(err, qt.ErrorMatches, "--metrics-listen: .*address already in use")
+}
+
+func TestExportingWriterWarnsOnExportFailure(t *testing.T) {
+	c := qt.New(t)
+	var stderr bytes.Buffer
+	var written []history.Capture
+	writer := &exportingWriter{
+		captureWriter: recordingCaptureWriter{captures: &written},
+		exporters:     []metricsExporter{failingExporter{}},
+		errOut:        &stderr,
+	}
+
+	err := writer.Write(history.NewCapture(live.ConnectionList{CapturedAt: time.Unix(1, 0)}))
+
+	c.Assert(err, qt.IsNil)
+	c.Assert(written, qt.HasLen, 1)
+	c.Assert(stderr.String(), qt.Equals, "warning: otlp: collector unavailable\n")
+}
+
+func TestOTLPMetricsURL(t *testing.T) {
+	c := qt.New(t)
+	c.Assert(otlpMetricsURL("http://collector:4318"), qt.Equals, "http://collector:4318/v1/metrics")
+	c.Assert(otlpMetricsURL("http://collector:4318/"), qt.Equals, "http://collector:4318/v1/metrics")
+	c.Assert(otlpMetricsURL("https://otlp.example.com/v1/metrics"), qt.Equals, "https://otlp.example.com/v1/metrics")
+}
+
+type recordingCaptureWriter struct {
+	captures *[]history.Capture
+}
+
+func (w recordingCaptureWriter) Write(capture history.Capture) error {
+	*w.captures = append(*w.captures, capture)
+	return nil
+}
+
+func (recordingCaptureWriter) Close() error { return nil }
+
+type failingExporter struct{}
+
+func (failingExporter) Export(context.Context, metrics.Sample) error {
+	return errors.New("otlp: collector unavailable")
+}
@@ -144,6 +144,53 @@ func TestTopCmdReplayPreloadsAllCapturesAndStartsPaused(t *testing.T) {
 	c.Assert(updated.(tui.Model).View(), qt.Contains, "step 1/3")
 }
 
+func TestTopCmdReplayTrimsToFromAndTo(t *testing.T) {
+	c := qt.New(t)
+	restoreTTY := setPrinterTTY(t, true)
+	defer restoreTTY()
+
+	var capturedModel tea.Model
+	restoreProgram := setRunTeaProgram(t, func(model tea.Model, options ...tea.ProgramOption) error {
+		capturedModel = model
+		return nil
+	})
+	defer restoreProgram()
+
+	tmp := writeMultiSampleReplayFixture(t, 5)
+	cmd := testTopCmd(&cmdutil.Helper{Config: &config.Config{}})
+	cmd.SetArgs([]string{"--replay", tmp, "--from", "+1s", "--to", "2026-05-27T12:00:03Z"})
+
+	c.Assert(cmd.Execute(), qt.IsNil)
+
+	m := capturedModel.(tui.Model)
+	sized, _ := m.Update(tea.WindowSizeMsg{Width: 180, Height: 24})
+	c.Assert(sized.(tui.Model).View(), qt.Contains, "103")
+	updated, _ := sized.(tui.Model).Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("{")})
+	view := updated.(tui.Model).View()
+	c.Assert(view, qt.Contains, "step 1/3")
+	c.Assert(view, qt.Contains, "101")
+}
+
+func TestTopCmdReplayRejectsRangeOutsideTrace(t *testing.T) {
+	c := qt.New(t)
+	restoreTTY := setPrinterTTY(t, true)
+	defer restoreTTY()
+	tmp := writeMultiSampleReplayFixture(t, 2)
+
+	cmd := testTopCmd(&cmdutil.Helper{Config: &config.Config{}})
+	cmd.SetArgs([]string{"--replay", tmp, "--from", "+1h"})
+
+	c.Assert(cmd.Execute(), qt.ErrorMatches, "--replay: capture file contains no snapshots in the requested time range")
+}
+
+func TestTopCmdRejectsFromWithoutReplay(t *testing.T) {
+	c := qt.New(t)
+	cmd := testTopCmd(&cmdutil.Helper{Config: &config.Config{}})
+	cmd.SetArgs([]string{"db", "main", "--from", "+1m"})
+
+	c.Assert(cmd.Execute(), qt.ErrorMatches, "--from and --to require --replay")
+}
+
 func TestTopCmdReplayUsesPostgresViewForPostgreSQLTrace(t *testing.T) {
 	c := qt.New(t)
 	restoreTTY := setPrinterTTY(t, true)
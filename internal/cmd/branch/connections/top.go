@@ -11,9 +11,11 @@ import (
 
 	"github.com/AlecAivazis/survey/v2"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dustin/go-humanize"
 	"github.com/planetscale/cli/internal/cmdutil"
 	live "github.com/planetscale/cli/internal/connections"
 	"github.com/planetscale/cli/internal/connections/history"
+	"github.com/planetscale/cli/internal/connections/metrics"
 	"github.com/planetscale/cli/internal/connections/tui"
 	ps "github.com/planetscale/cli/internal/planetscale"
 	"github.com/planetscale/cli/internal/printer"
@@ -33,6 +35,21 @@ Run interactively in a terminal to launch the TUI. Pipe or redirect output to
 run headlessly with --capture; --duration bounds either mode and without
 --duration the command runs until interrupted. Pass --replay FILE to render a
 previously captured trace in the TUI — actions are rejected in replay mode.
+With --replay, --from and --to limit the replay to part of the trace; in the
+TUI, / filters rows, g seeks to a time, and d diffs against the previous
+capture.
+
+--capture writes gzip or zstd when the file name ends in .gz or .zst, or as
+set by --capture-compression. For an always-on capture, --capture-rotate-size
+and --capture-rotate-interval rotate the file to FILE.1, FILE.2, and so on, and
+--capture-retain bounds how many rotated files are kept. --replay reads a
+rotated, compressed set as one trace.
+
+Without a TTY, --metrics-listen serves Prometheus metrics derived from each
+sample on /metrics, and --otlp-endpoint pushes them to an OpenTelemetry
+collector over OTLP/HTTP. Metrics cover connections by state, wait event, and
+instance role, blocked connections, and the age of the oldest transaction.
+--capture is optional when either is set.
 
 For Postgres, connections top shows session activity across instances. For
 Vitess, pass --keyspace and --shard or run interactively to select them when
@@ -56,6 +73,19 @@ the server reports available targets.`,
 			if flags.replay != "" && flags.duration > 0 {
 				return errors.New("--duration cannot be combined with --replay")
 			}
+			if flags.replay == "" && (flags.from != "" || flags.to != "") {
+				return errors.New("--from and --to require --replay")
+			}
+			if flags.replay != "" && flags.exportsMetrics() {
+				return errors.New("--replay cannot be combined with --metrics-listen or --otlp-endpoint")
+			}
+			if flags.otlpEndpoint != "" {
+				if err := validateOTLPEndpoint(flags.otlpEndpoint); err != nil {
+					return err
+				}
+			} else if len(flags.otlpHeaders) > 0 {
+				return errors.New("--otlp-header requires --otlp-endpoint")
+			}
 			if err := validateConnectionFilter(flags.instance, flags.role); err != nil {
 				return err
 			}
@@ -72,6 +102,11 @@ the server reports available targets.`,
 					return err
 				}
 			}
+			opts, err := flags.captureOptions(cmd)
+			if err != nil {
+				return err
+			}
+			flags.captureFile = opts
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -81,7 +116,16 @@ the server reports available targets.`,
 
 	cmd.Flags().DurationVar(&flags.interval, "interval", 1*time.Second, "Refresh interval.")
 	cmd.Flags().StringVar(&flags.capture, "capture", "", "Write captured samples to a trace file. Required in headless mode.")
+	cmd.Flags().StringVar(&flags.captureCompression, "capture-compression", "", "Compress the trace file: none, gzip, or zstd. Defaults to the --capture file extension (.gz, .zst).")
+	cmd.Flags().StringVar(&flags.captureRotateSize, "capture-rotate-size", "", "Rotate the trace file once it reaches this size, such as 100MB.")
+	cmd.Flags().DurationVar(&flags.captureRotateInterval, "capture-rotate-interval", 0, "Rotate the trace file after writing to it for this long.")
+	cmd.Flags().IntVar(&flags.captureRetain, "capture-retain", 0, "Number of rotated trace files to keep. Default is to keep all of them.")
+	cmd.Flags().StringVar(&flags.metricsListen, "metrics-listen", "", "Serve Prometheus metrics on /metrics at this address, such as :9187. Headless mode only.")
+	cmd.Flags().StringVar(&flags.otlpEndpoint, "otlp-endpoint", "", "Push metrics to this OpenTelemetry collector over OTLP/HTTP, such as http://localhost:4318. Headless mode only.")
+	cmd.Flags().StringToStringVar(&flags.otlpHeaders, "otlp-header", nil, "Header to send with each OTLP export, as key=value. Can be repeated.")
 	cmd.Flags().StringVar(&flags.replay, "replay", "", "Replay a previously captured trace file in the TUI. Mutually exclusive with --capture.")
+	cmd.Flags().StringVar(&flags.from, "from", "", "With --replay, start the replay at the first snapshot captured at or after this time.")
+	cmd.Flags().StringVar(&flags.to, "to", "", "With --replay, end the replay at the last snapshot captured at or before this time.")
 	cmd.Flags().DurationVar(&flags.duration, "duration", 0, "Run for this duration. Default is to run until interrupted.")
 	cmd.Flags().StringVar(&flags.instance, "instance", "", "Filter the live view to a single instance (by id from the list response).")
 	cmd.Flags().StringVar(&flags.role, "role", "", "Filter the live view to rows whose instance role is primary or replica.")
@@ -95,11 +139,76 @@ type topFlags struct {
 	interval time.Duration
 	capture  string
 	replay   string
+	from     string
+	to       string
 	duration time.Duration
 	instance string
 	role     string
 	keyspace string
 	shard    string
+
+	captureCompression    string
+	captureRotateSize     string
+	captureRotateInterval time.Duration
+	captureRetain         int
+	// captureFile holds the --capture-* flags parsed by PreRunE.
+	captureFile history.CaptureFileOptions
+
+	metricsListen string
+	otlpEndpoint  string
+	otlpHeaders   map[string]string
+}
+
+func (f topFlags) exportsMetrics() bool {
+	return f.metricsListen != "" || f.otlpEndpoint != ""
+}
+
+var captureFileFlags = []string{"capture-compression", "capture-rotate-size", "capture-rotate-interval", "capture-retain"}
+
+// captureOptions parses the --capture-* flags. The compression defaults to the
+// one implied by the --capture file extension.
+func (f topFlags) captureOptions(cmd *cobra.Command) (history.CaptureFileOptions, error) {
+	if f.capture == "" {
+		for _, name := range captureFileFlags {
+			if cmd.Flags().Changed(name) {
+				return history.CaptureFileOptions{}, fmt.Errorf("--%s requires --capture", name)
+			}
+		}
+		return history.CaptureFileOptions{}, nil
+	}
+
+	opts := history.CaptureFileOptions{
+		Compression:    history.CompressionForPath(f.capture),
+		RotateInterval: f.captureRotateInterval,
+		Retain:         f.captureRetain,
+	}
+	if f.captureCompression != "" {
+		compression, err := history.ParseCompression(f.captureCompression)
+		if err != nil {
+			return history.CaptureFileOptions{}, fmt.Errorf("--capture-compression: %w", err)
+		}
+		opts.Compression = compression
+	}
+	if f.captureRotateSize != "" {
+		size, err := humanize.ParseBytes(f.captureRotateSize)
+		if err != nil {
+			return history.CaptureFileOptions{}, fmt.Errorf("--capture-rotate-size: %w", err)
+		}
+		if size == 0 {
+			return history.CaptureFileOptions{}, errors.New("--capture-rotate-size must be greater than 0")
+		}
+		opts.RotateSize = int64(size)
+	}
+	if f.captureRotateInterval < 0 {
+		return history.CaptureFileOptions{}, errors.New("--capture-rotate-interval must not be negative")
+	}
+	if f.captureRetain < 0 {
+		return history.CaptureFileOptions{}, errors.New("--capture-retain must not be negative")
+	}
+	if f.captureRetain > 0 && !opts.Rotates() {
+		return history.CaptureFileOptions{}, errors.New("--capture-retain requires --capture-rotate-size or --capture-rotate-interval")
+	}
+	return opts, nil
 }
 
 func (f topFlags) filter() connectionFilter {
@@ -121,7 +230,7 @@ type topRequest struct {
 
 func runTop(ctx context.Context, cmd *cobra.Command, ch *cmdutil.Helper, args []string, flags topFlags) (err error) {
 	if flags.replay != "" {
-		return runReplay(ctx, flags.replay, flags.duration, flags.interval)
+		return runReplay(ctx, flags.replay, flags.from, flags.to, flags.duration, flags.interval)
 	}
 
 	request, err := newTopRequest(ctx, ch, args, flags)
@@ -156,9 +265,12 @@ func newTopRequest(ctx context.Context, ch *cmdutil.Helper, args []string, flags
 	}
 
 	interactive := isHumanMode(ch)
-	if !interactive && flags.capture == "" {
+	if !interactive && flags.capture == "" && !flags.exportsMetrics() {
 		return topRequest{}, errors.New("--capture is required when running without a TTY")
 	}
+	if interactive && flags.exportsMetrics() {
+		return topRequest{}, errors.New("--metrics-listen and --otlp-endpoint require running without a TTY")
+	}
 
 	return topRequest{
 		Database:    database,
@@ -182,7 +294,7 @@ func runTopWithSource(ctx context.Context, cmd *cobra.Command, ch *cmdutil.Helpe
 }
 
 func runTopInteractive(ctx context.Context, ch *cmdutil.Helper, request topRequest, source topSource, flags topFlags) error {
-	control := newCaptureControl(flags.capture, ch.Config.Organization, request.Database, request.Branch, request.Filter, source.Target)
+	control := newCaptureControl(flags.capture, flags.captureFile, ch.Config.Organization, request.Database, request.Branch, request.Filter, source.Target)
 	if flags.capture != "" {
 		writer, path, err := control.Open()
 		if err != nil {
@@ -200,18 +312,50 @@ func runTopInteractive(ctx context.Context, ch *cmdutil.Helper, request topReque
 	return runInteractive(ctx, source.Client, flags.duration, flags.interval, control, target, request.Filter.chip(), source.View)
 }
 
-func runTopHeadless(ctx context.Context, cmd *cobra.Command, ch *cmdutil.Helper, request topRequest, source topSource, flags topFlags) error {
-	writer, err := openCaptureWriter(flags.capture, ch.Config.Organization, request.Database, request.Branch, request.Filter, source.Target)
-	if err != nil {
-		return err
+func runTopHeadless(ctx context.Context, cmd *cobra.Command, ch *cmdutil.Helper, request topRequest, source topSource, flags topFlags) (err error) {
+	exporting := &exportingWriter{
+		captureWriter: nopCaptureWriter{},
+		labels: []metrics.Label{
+			{Name: "database", Value: request.Database},
+			{Name: "branch", Value: request.Branch},
+		},
+		errOut: cmd.ErrOrStderr(),
+	}
+	if flags.metricsListen != "" {
+		endpoint, listenErr := listenPrometheus(flags.metricsListen)
+		if listenErr != nil {
+			return listenErr
+		}
+		defer func() {
+			err = errors.Join(err, endpoint.Close())
+		}()
+		exporting.exporters = append(exporting.exporters, endpoint)
+		fmt.Fprintf(cmd.ErrOrStderr(), "Serving metrics on %s\n", endpoint.URL())
+	}
+	if flags.otlpEndpoint != "" {
+		exporter := newOTLPExporter(flags.otlpEndpoint, flags.otlpHeaders)
+		exporting.exporters = append(exporting.exporters, exporter)
+		fmt.Fprintf(cmd.ErrOrStderr(), "Exporting metrics to %s\n", exporter.url)
 	}
 
-	if flags.duration > 0 {
-		fmt.Fprintf(cmd.ErrOrStderr(), "Capturing for %s to %s\n", flags.duration, flags.capture)
-	} else {
-		fmt.Fprintf(cmd.ErrOrStderr(), "Capturing to %s (Ctrl-C to stop)\n", flags.capture)
+	if flags.capture != "" {
+		writer, err := openCaptureWriter(flags.capture, flags.captureFile, ch.Config.Organization, request.Database, request.Branch, request.Filter, source.Target)
+		if err != nil {
+			return err
+		}
+		exporting.captureWriter = writer
+
+		if flags.duration > 0 {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Capturing for %s to %s\n", flags.duration, flags.capture)
+		} else {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Capturing to %s (Ctrl-C to stop)\n", flags.capture)
+		}
 	}
 
+	var writer captureWriter = exporting
+	if len(exporting.exporters) == 0 {
+		writer = exporting.captureWriter
+	}
 	return runHeadlessCapture(ctx, sortedTopLister{client: source.Client, sort: source.View.DefaultSort()}, writer, flags.duration, flags.interval)
 }
 
@@ -227,8 +371,8 @@ func validateTopFlagsForEngine(engine ps.DatabaseEngine, filter connectionFilter
 	}
 }
 
-func runReplay(ctx context.Context, path string, duration, interval time.Duration) error {
-	file, err := os.Open(path)
+func runReplay(ctx context.Context, path, fromValue, toValue string, duration, interval time.Duration) error {
+	file, err := history.OpenTrace(path)
 	if err != nil {
 		return fmt.Errorf("--replay: %w", err)
 	}
@@ -238,6 +382,15 @@ func runReplay(ctx context.Context, path string, duration, interval time.Duratio
 	if err != nil {
 		return fmt.Errorf("--replay: %w", err)
 	}
+	if fromValue != "" || toValue != "" {
+		from, to, err := traceRange(source.Captures(), fromValue, toValue)
+		if err != nil {
+			return err
+		}
+		if err := source.Trim(from, to); err != nil {
+			return fmt.Errorf("--replay: %w", err)
+		}
+	}
 
 	captures := source.Captures()
 	samples := history.NewCaptureHistory(len(captures))
@@ -455,14 +608,14 @@ func (l sortedTopLister) List(ctx context.Context, _ live.SortMode) (live.Connec
 	return l.client.List(ctx, l.sort)
 }
 
-func newCaptureControl(path, org, database, branch string, filter connectionFilter, target ConnectionTarget) *tui.CaptureControl {
+func newCaptureControl(path string, opts history.CaptureFileOptions, org, database, branch string, filter connectionFilter, target ConnectionTarget) *tui.CaptureControl {
 	return &tui.CaptureControl{
 		Open: func() (*history.CaptureWriter, string, error) {
 			capturePath := path
 			if capturePath == "" {
 				capturePath = defaultInteractiveCapturePath(time.Now())
 			}
-			writer, err := openCaptureWriter(capturePath, org, database, branch, filter, target)
+			writer, err := openCaptureWriter(capturePath, opts, org, database, branch, filter, target)
 			return writer, capturePath, err
 		},
 	}
@@ -586,8 +739,8 @@ func (f filteredLister) TerminateConnection(ctx context.Context, target live.Act
 	return f.client.TerminateConnection(ctx, target)
 }
 
-func openCaptureWriter(path, org, database, branch string, filter connectionFilter, target ConnectionTarget) (*history.CaptureWriter, error) {
-	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+func openCaptureWriter(path string, opts history.CaptureFileOptions, org, database, branch string, filter connectionFilter, target ConnectionTarget) (*history.CaptureWriter, error) {
+	file, err := history.OpenCaptureFile(path, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -18,6 +18,7 @@ import (
 	"github.com/planetscale/cli/internal/cmdutil"
 	"github.com/planetscale/cli/internal/config"
 	live "github.com/planetscale/cli/internal/connections"
+	"github.com/planetscale/cli/internal/connections/history"
 	"github.com/planetscale/cli/internal/mock"
 	ps "github.com/planetscale/cli/internal/planetscale"
 	"github.com/planetscale/cli/internal/printer"
@@ -168,6 +169,30 @@ func TestTopCmdValidation(t *testing.T) {
 			args:    []string{"--role", "writer", "pgload", "main"},
 			wantErr: "--role must be primary or replica",
 		},
+		{
+			name:    "capture options without capture",
+			engine:  ps.DatabaseEnginePostgres,
+			args:    []string{"pgload", "main", "--capture-rotate-size", "10MB"},
+			wantErr: "--capture-rotate-size requires --capture",
+		},
+		{
+			name:    "unknown capture compression",
+			engine:  ps.DatabaseEnginePostgres,
+			args:    []string{"pgload", "main", "--capture", "trace.jsonl", "--capture-compression", "lz4"},
+			wantErr: `--capture-compression: invalid compression "lz4": must be none, gzip, or zstd`,
+		},
+		{
+			name:    "invalid capture rotate size",
+			engine:  ps.DatabaseEnginePostgres,
+			args:    []string{"pgload", "main", "--capture", "trace.jsonl", "--capture-rotate-size", "lots"},
+			wantErr: "--capture-rotate-size: .*",
+		},
+		{
+			name:    "capture retain without rotation",
+			engine:  ps.DatabaseEnginePostgres,
+			args:    []string{"pgload", "main", "--capture", "trace.jsonl", "--capture-retain", "3"},
+			wantErr: "--capture-retain requires --capture-rotate-size or --capture-rotate-interval",
+		},
 		{
 			name:    "primary flag removed",
 			engine:  ps.DatabaseEnginePostgres,
@@ -209,7 +234,7 @@ func TestTopCmdRunEHeadlessHappyPathWritesCaptureHeaderAndCapture(t *testing.T)
 	c.Assert(header["org"], qt.Equals, "acme")
 	c.Assert(header["database"], qt.Equals, "pgload")
 	c.Assert(header["branch"], qt.Equals, "main")
-	c.Assert(header["schema_version"], qt.Equals, float64(1))
+	c.Assert(header["schema_version"], qt.Equals, float64(2))
 	captureRecord := capturedConnectionList(c, records[1])
 	c.Assert(captureRecord["database_kind"], qt.Equals, "postgresql")
 	c.Assert(captureRecord["instances"], qt.DeepEquals, []any{map[string]any{"id": "primary", "role": "primary"}})
@@ -218,6 +243,34 @@ func TestTopCmdRunEHeadlessHappyPathWritesCaptureHeaderAndCapture(t *testing.T)
 	c.Assert(info.Mode().Perm(), qt.Equals, os.FileMode(0o600))
 }
 
+func TestTopCmdRunEHeadlessWritesCompressedCapture(t *testing.T) {
+	c := qt.New(t)
+	restoreTTY := setPrinterTTY(t, false)
+	defer restoreTTY()
+	server := liveConnectionsServer(t, sampleTopResponse())
+	capture := filepath.Join(t.TempDir(), "trace.jsonl.zst")
+	var out bytes.Buffer
+	cmd := topCmdForServer(server.URL, &out)
+	cmd.SetArgs([]string{"pgload", "main", "--capture", capture, "--duration", "200ms", "--interval", "1s"})
+
+	err := cmd.Execute()
+
+	c.Assert(err, qt.IsNil)
+	raw, err := os.ReadFile(capture)
+	c.Assert(err, qt.IsNil)
+	c.Assert(raw[:4], qt.DeepEquals, []byte{0x28, 0xb5, 0x2f, 0xfd})
+	trace, err := history.OpenTrace(capture)
+	c.Assert(err, qt.IsNil)
+	defer trace.Close()
+	reader := history.NewCaptureReader(trace)
+	captures, err := reader.ReadAll()
+	c.Assert(err, qt.IsNil)
+	c.Assert(captures, qt.HasLen, 1)
+	start, ok := reader.CaptureStart()
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(start.Database, qt.Equals, "pgload")
+}
+
 func TestTopCmdRunERecordsRoleFilterInCaptureHeader(t *testing.T) {
 	c := qt.New(t)
 	restoreTTY := setPrinterTTY(t, false)
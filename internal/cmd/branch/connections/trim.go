@@ -0,0 +1,165 @@
+package connections
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/planetscale/cli/internal/cmdutil"
+	"github.com/planetscale/cli/internal/connections/history"
+	"github.com/spf13/cobra"
+)
+
+// TrimCmd writes the part of a trace between two times to a new trace file.
+func TrimCmd(ch *cmdutil.Helper) *cobra.Command {
+	var from, to, output string
+
+	cmd := &cobra.Command{
+		Use:   "trim <trace>",
+		Short: "Write a time slice of a connections trace to a new file",
+		Long: `Write a time slice of a connections trace to a new file.
+
+Keeps the snapshots captured between --from and --to, inclusive, along with the
+trace header, so a long capture can be cut down to the minutes around an
+incident before it is shared or replayed. Either bound may be omitted.
+
+--from and --to accept RFC 3339, a local date and time ("2006-01-02 15:04:05"),
+a local time of day ("15:04:05") on the date the trace starts, or an offset
+from the first snapshot ("+5m"). A rotated or compressed trace is read as one
+trace, and --output is compressed when its name ends in .gz or .zst. Trimming
+reads the trace file only and does not contact PlanetScale.`,
+		Example: `  pscale branch connections trim incident.ndjson --from 14:02 --to 14:10 --output slice.ndjson
+  pscale branch connections trim incident.ndjson --from +5m --output tail.ndjson
+  pscale branch connections top --replay slice.ndjson`,
+		Args: cmdutil.RequiredArgs("trace"),
+		// A trace is a local file: trimming it needs neither
+		// authentication nor an organization.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return nil },
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if from == "" && to == "" {
+				return errors.New("at least one of --from or --to is required")
+			}
+			return validateCapturePath(output)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTrim(ch, args[0], output, from, to)
+		},
+	}
+
+	// Shadows the required --org flag of pscale branch.
+	cmd.PersistentFlags().StringVar(&ch.Config.Organization, "org", ch.Config.Organization, "The organization for the current user")
+	cmd.Flags().StringVar(&from, "from", "", "Keep snapshots captured at or after this time.")
+	cmd.Flags().StringVar(&to, "to", "", "Keep snapshots captured at or before this time.")
+	cmd.Flags().StringVar(&output, "output", "", "Trace file to write. Must not be the input trace.")
+	cmd.MarkFlagRequired("output") // nolint:errcheck
+
+	return cmd
+}
+
+func runTrim(ch *cmdutil.Helper, path, output, fromValue, toValue string) error {
+	if same, err := sameFile(path, output); err != nil {
+		return err
+	} else if same {
+		return errors.New("--output must not be the input trace")
+	}
+
+	file, err := history.OpenTrace(path)
+	if err != nil {
+		return fmt.Errorf("open trace: %w", err)
+	}
+	defer file.Close()
+
+	reader := history.NewCaptureReader(file)
+	captures, err := reader.ReadAll()
+	if err != nil {
+		return fmt.Errorf("read trace %s: %w", path, err)
+	}
+	if len(captures) == 0 {
+		return fmt.Errorf("trace %s contains no snapshots", path)
+	}
+
+	from, to, err := traceRange(captures, fromValue, toValue)
+	if err != nil {
+		return err
+	}
+	kept := history.TrimCaptures(captures, from, to)
+	if len(kept) == 0 {
+		return fmt.Errorf("trace %s contains no snapshots between --from and --to", path)
+	}
+
+	if err := os.Remove(output); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("--output: %w", err)
+	}
+	out, err := history.OpenCaptureFile(output, history.CaptureFileOptions{Compression: history.CompressionForPath(output)})
+	if err != nil {
+		return fmt.Errorf("--output: %w", err)
+	}
+	writer := history.NewCaptureWriter(out)
+	if err := writeTrace(writer, reader, kept); err != nil {
+		_ = writer.Close()
+		return fmt.Errorf("write %s: %w", output, err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", output, err)
+	}
+
+	ch.Printer.Printf("Wrote %d of %d snapshots to %s\n", len(kept), len(captures), output)
+	return nil
+}
+
+func writeTrace(writer *history.CaptureWriter, reader *history.CaptureReader, captures []history.Capture) error {
+	if start, ok := reader.CaptureStart(); ok {
+		// The slice is a new single-file trace in the current format.
+		start.SchemaVersion = 0
+		start.Segment = 0
+		if err := writer.WriteCaptureStart(start); err != nil {
+			return err
+		}
+	}
+	for _, capture := range captures {
+		if err := writer.Write(capture); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// traceRange parses --from and --to against the first snapshot of a trace,
+// which anchors offsets and times of day. An empty value leaves that end of
+// the range open.
+func traceRange(captures []history.Capture, fromValue, toValue string) (from, to time.Time, err error) {
+	start := captures[0].At
+	if start.IsZero() {
+		start = captures[0].List.CapturedAt
+	}
+	if fromValue != "" {
+		if from, err = history.ParseTime(fromValue, start); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("--from: %w", err)
+		}
+	}
+	if toValue != "" {
+		if to, err = history.ParseTime(toValue, start); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("--to: %w", err)
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && from.After(to) {
+		return time.Time{}, time.Time{}, errors.New("--from must not be after --to")
+	}
+	return from, to, nil
+}
+
+func sameFile(a, b string) (bool, error) {
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false, fmt.Errorf("open trace: %w", err)
+	}
+	bInfo, err := os.Stat(b)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("--output: %w", err)
+	}
+	return os.SameFile(aInfo, bInfo), nil
+}
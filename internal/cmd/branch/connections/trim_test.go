@@ -0,0 +1,121 @@
+package connections
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/planetscale/cli/internal/printer"
+)
+
+func TestTrimCmdWritesSlice(t *testing.T) {
+	c := qt.New(t)
+	path := writeMultiSampleReplayFixture(t, 5)
+	output := filepath.Join(t.TempDir(), "slice.jsonl")
+
+	var out bytes.Buffer
+	cmd := TrimCmd(connectionsTestHelper("", printer.Human, &out))
+	cmd.SetArgs([]string{path, "--from", "2026-05-27T12:00:01Z", "--to", "+3s", "--output", output})
+	c.Assert(cmd.Execute(), qt.IsNil)
+
+	c.Assert(out.String(), qt.Equals, "Wrote 3 of 5 snapshots to "+output+"\n")
+	records := readJSONLines(t, output)
+	c.Assert(records, qt.HasLen, 3)
+	c.Assert(records[0]["at"], qt.Equals, "2026-05-27T12:00:01Z")
+	c.Assert(records[2]["at"], qt.Equals, "2026-05-27T12:00:03Z")
+}
+
+func TestTrimCmdKeepsCaptureStart(t *testing.T) {
+	c := qt.New(t)
+	path := writeAnalyzeTrace(t)
+	output := filepath.Join(t.TempDir(), "slice.jsonl")
+
+	cmd := TrimCmd(connectionsTestHelper("", printer.Human, &bytes.Buffer{}))
+	cmd.SetArgs([]string{path, "--to", "+1s", "--output", output})
+	c.Assert(cmd.Execute(), qt.IsNil)
+
+	records := readJSONLines(t, output)
+	c.Assert(records, qt.HasLen, 2)
+	c.Assert(records[0]["type"], qt.Equals, "capture_start")
+	c.Assert(records[0]["database"], qt.Equals, "prod")
+}
+
+func TestTrimCmdReadsAndWritesCompressedTraces(t *testing.T) {
+	c := qt.New(t)
+	path := writeMultiSampleReplayFixture(t, 5)
+	dir := t.TempDir()
+	compressed := filepath.Join(dir, "slice.jsonl.gz")
+	output := filepath.Join(dir, "slice.jsonl")
+
+	cmd := TrimCmd(connectionsTestHelper("", printer.Human, &bytes.Buffer{}))
+	cmd.SetArgs([]string{path, "--from", "+1s", "--output", compressed})
+	c.Assert(cmd.Execute(), qt.IsNil)
+	raw, err := os.ReadFile(compressed)
+	c.Assert(err, qt.IsNil)
+	c.Assert(raw[:2], qt.DeepEquals, []byte{0x1f, 0x8b})
+
+	cmd = TrimCmd(connectionsTestHelper("", printer.Human, &bytes.Buffer{}))
+	cmd.SetArgs([]string{compressed, "--to", "+1s", "--output", output})
+	c.Assert(cmd.Execute(), qt.IsNil)
+
+	records := readJSONLines(t, output)
+	c.Assert(records, qt.HasLen, 2)
+	c.Assert(records[0]["at"], qt.Equals, "2026-05-27T12:00:01Z")
+}
+
+func TestTrimCmdRejectsInvalidInput(t *testing.T) {
+	path := writeMultiSampleReplayFixture(t, 2)
+	output := filepath.Join(t.TempDir(), "slice.jsonl")
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{
+			name:    "no bounds",
+			args:    []string{path, "--output", output},
+			wantErr: "at least one of --from or --to is required",
+		},
+		{
+			name:    "no output",
+			args:    []string{path, "--from", "+1s"},
+			wantErr: `required flag\(s\) "output" not set`,
+		},
+		{
+			name:    "invalid time",
+			args:    []string{path, "--from", "noon", "--output", output},
+			wantErr: `--from: invalid time "noon".*`,
+		},
+		{
+			name:    "reversed range",
+			args:    []string{path, "--from", "+1s", "--to", "-1s", "--output", output},
+			wantErr: "--from must not be after --to",
+		},
+		{
+			name:    "empty range",
+			args:    []string{path, "--from", "+1h", "--output", output},
+			wantErr: "trace .* contains no snapshots between --from and --to",
+		},
+		{
+			name:    "output is input",
+			args:    []string{path, "--from", "+1s", "--output", path},
+			wantErr: "--output must not be the input trace",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			cmd := TrimCmd(connectionsTestHelper("", printer.Human, &bytes.Buffer{}))
+			cmd.SetArgs(tt.args)
+			cmd.SetOut(io.Discard)
+			cmd.SetErr(io.Discard)
+
+			c.Assert(cmd.Execute(), qt.ErrorMatches, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,371 @@
+package connections
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/planetscale/cli/internal/cmdutil"
+	live "github.com/planetscale/cli/internal/connections"
+	"github.com/planetscale/cli/internal/connections/watch"
+	"github.com/planetscale/cli/internal/printer"
+	"github.com/spf13/cobra"
+)
+
+// Values of --remediate.
+const (
+	remediateCancelQuery         = "cancel-query"
+	remediateTerminateConnection = "terminate-connection"
+)
+
+// WatchCmd alerts on, and optionally remediates, connections that break rules.
+func WatchCmd(ch *cmdutil.Helper) *cobra.Command {
+	var flags watchFlags
+
+	cmd := &cobra.Command{
+		Use:   "watch <database> [branch]",
+		Short: "Alert on branch connections that break rules",
+		Long: `Alert on branch connections that break rules.
+
+Watch polls the branch connections like connections top --capture and evaluates
+the rules given by flags on each sample. An event is emitted once when a rule
+starts to match and once when it is resolved:
+
+  --max-transaction-age   a transaction open longer than this
+  --max-query-duration    a query running longer than this
+  --max-chain-depth       a session blocking a lock-wait chain deeper than this
+  --max-app-connections   an application holding more connections than this
+
+Events are JSON objects with type alert, resolved, or remediation. They are
+written to stdout (one per line with --format json), POSTed to --webhook, and
+passed on stdin to the --execute command.
+
+Remediation is off unless --remediate is set, and a dry run unless --apply is
+also set: --remediate cancel-query cancels the query of a matching session,
+--remediate terminate-connection terminates its connection. destructive with
+--apply. Every remediation, including dry runs, is emitted as an event and
+appended to --audit-log.`,
+		Example: `  pscale branch connections watch my-db main --max-transaction-age 5m --format json
+  pscale branch connections watch my-db main --max-chain-depth 3 --webhook https://hooks.example.com/pscale
+  pscale branch connections watch my-db main --max-query-duration 10m --remediate cancel-query --audit-log audit.ndjson
+  pscale branch connections watch my-db main --max-query-duration 10m --remediate cancel-query --apply --audit-log audit.ndjson`,
+		Args: cmdutil.RequiredArgs("database"),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return flags.validate(ch)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWatchCmd(cmd.Context(), cmd, ch, args, flags)
+		},
+	}
+
+	cmd.Flags().DurationVar(&flags.interval, "interval", 5*time.Second, "Polling interval.")
+	cmd.Flags().DurationVar(&flags.duration, "duration", 0, "Run for this duration. Default is to run until interrupted.")
+	cmd.Flags().DurationVar(&flags.rules.MaxTransactionAge, "max-transaction-age", 0, "Alert on transactions open longer than this.")
+	cmd.Flags().DurationVar(&flags.rules.MaxQueryDuration, "max-query-duration", 0, "Alert on queries running longer than this.")
+	cmd.Flags().IntVar(&flags.rules.MaxChainDepth, "max-chain-depth", 0, "Alert on sessions blocking a lock-wait chain with more than this many waiting sessions.")
+	cmd.Flags().IntVar(&flags.rules.MaxAppConnections, "max-app-connections", 0, "Alert on applications holding more than this many connections.")
+	cmd.Flags().StringVar(&flags.webhook, "webhook", "", "POST each event as JSON to this URL.")
+	cmd.Flags().StringVar(&flags.execute, "execute", "", "Run this command for each event, with the event as JSON on stdin.")
+	cmd.Flags().StringVar(&flags.remediate, "remediate", "", "Act on the session of each alert, allowed values are: cancel-query, terminate-connection. A dry run unless --apply is set.")
+	cmd.Flags().StringSliceVar(&flags.remediateRules, "remediate-rule", nil, "Only remediate alerts of these rules: transaction_age, query_duration, chain_depth. Defaults to all of them.")
+	cmd.Flags().BoolVar(&flags.apply, "apply", false, "Run the --remediate action instead of only reporting it. destructive.")
+	cmd.Flags().StringVar(&flags.auditLog, "audit-log", "", "Append every remediation event, including dry runs, to this file.")
+	cmd.Flags().StringVar(&flags.instance, "instance", "", "Only watch a single instance (by id from the list response).")
+	cmd.Flags().StringVar(&flags.role, "role", "", "Only watch instances of this role, primary or replica.")
+	cmd.Flags().StringVar(&flags.keyspace, "keyspace", "", "Vitess keyspace to target.")
+	cmd.Flags().StringVar(&flags.shard, "shard", "", "Vitess shard to target.")
+
+	return cmd
+}
+
+type watchFlags struct {
+	interval       time.Duration
+	duration       time.Duration
+	rules          watch.Rules
+	webhook        string
+	execute        string
+	remediate      string
+	remediateRules []string
+	apply          bool
+	auditLog       string
+	instance       string
+	role           string
+	keyspace       string
+	shard          string
+}
+
+func (f watchFlags) validate(ch *cmdutil.Helper) error {
+	if f.interval <= 0 {
+		return errors.New("--interval must be greater than 0")
+	}
+	if f.duration < 0 {
+		return errors.New("--duration must not be negative")
+	}
+	if f.rules.MaxTransactionAge < 0 || f.rules.MaxQueryDuration < 0 || f.rules.MaxChainDepth < 0 || f.rules.MaxAppConnections < 0 {
+		return errors.New("rule thresholds must not be negative")
+	}
+	if f.rules.Empty() {
+		return errors.New("at least one rule is required: --max-transaction-age, --max-query-duration, --max-chain-depth, or --max-app-connections")
+	}
+	if ch.Printer.Format() == printer.CSV {
+		return errors.New("watch does not support --format csv, use --format json")
+	}
+	switch f.remediate {
+	case "", remediateCancelQuery, remediateTerminateConnection:
+	default:
+		return fmt.Errorf("invalid --remediate %q, allowed values are: %s, %s", f.remediate, remediateCancelQuery, remediateTerminateConnection)
+	}
+	if f.remediate == "" && (f.apply || len(f.remediateRules) > 0) {
+		return errors.New("--apply and --remediate-rule require --remediate")
+	}
+	for _, rule := range f.remediateRules {
+		if !slices.Contains(watch.SessionRules, rule) {
+			return fmt.Errorf("invalid --remediate-rule %q, allowed values are: %s", rule, strings.Join(watch.SessionRules, ", "))
+		}
+	}
+	return validateConnectionFilter(f.instance, f.role)
+}
+
+func (f watchFlags) remediation() remediationPolicy {
+	policy := remediationPolicy{rules: f.remediateRules, apply: f.apply}
+	switch f.remediate {
+	case remediateCancelQuery:
+		policy.action = watch.ActionCancelQuery
+	case remediateTerminateConnection:
+		policy.action = watch.ActionTerminateConnection
+	}
+	if len(policy.rules) == 0 {
+		policy.rules = watch.SessionRules
+	}
+	return policy
+}
+
+func runWatchCmd(ctx context.Context, cmd *cobra.Command, ch *cmdutil.Helper, args []string, flags watchFlags) (err error) {
+	database := args[0]
+	engine, err := DatabaseEngine(ctx, ch, database)
+	if err != nil {
+		return err
+	}
+	filter := connectionFilter{instance: flags.instance, role: flags.role}
+	target := ConnectionTarget{Keyspace: flags.keyspace, Shard: flags.shard}
+	if err := validateEngineFlags(engine, filter, target); err != nil {
+		return err
+	}
+	branch, err := resolveBranch(ctx, ch, database, args)
+	if err != nil {
+		return err
+	}
+
+	source, err := newTopSource(ctx, ch, topRequest{
+		Database: database,
+		Branch:   branch,
+		Engine:   engine,
+		Filter:   filter,
+		Target:   target,
+	})
+	if err != nil {
+		return err
+	}
+
+	sinks := []eventSink{newPrinterSink(ch)}
+	if flags.webhook != "" {
+		sinks = append(sinks, newWebhookSink(flags.webhook))
+	}
+	if flags.execute != "" {
+		sink, err := newCommandSink(flags.execute, database, branch, cmd.ErrOrStderr())
+		if err != nil {
+			return err
+		}
+		sinks = append(sinks, sink)
+	}
+
+	w := &watcher{
+		client:       source.Client,
+		rules:        flags.rules,
+		remediation:  flags.remediation(),
+		sinks:        sinks,
+		errOut:       cmd.ErrOrStderr(),
+		organization: ch.Config.Organization,
+		database:     database,
+		branch:       branch,
+	}
+	if flags.auditLog != "" {
+		audit, err := openAuditLog(flags.auditLog)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			err = errors.Join(err, audit.Close())
+		}()
+		w.audit = audit
+	}
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "Watching %s/%s every %s (Ctrl-C to stop)\n", database, branch, flags.interval)
+	if w.remediation.action != "" && !w.remediation.apply {
+		fmt.Fprintln(cmd.ErrOrStderr(), "Remediation is a dry run: pass --apply to act on matching sessions.")
+	}
+	return w.run(ctx, flags.duration, flags.interval)
+}
+
+// watchClient lists connections and runs the remediation actions.
+type watchClient interface {
+	List(context.Context, live.SortMode) (live.ConnectionList, error)
+	CancelQuery(context.Context, live.ActionTarget) error
+	TerminateConnection(context.Context, live.ActionTarget) error
+}
+
+// remediationPolicy is the action taken on the session of an alert. An empty
+// action disables remediation.
+type remediationPolicy struct {
+	action string
+	rules  []string
+	apply  bool
+}
+
+type watcher struct {
+	client      watchClient
+	rules       watch.Rules
+	remediation remediationPolicy
+	sinks       []eventSink
+	// audit receives every remediation event. Unlike the sinks, failing to
+	// write to it stops the watch.
+	audit  eventSink
+	errOut io.Writer
+
+	organization string
+	database     string
+	branch       string
+
+	tracker *watch.Tracker
+}
+
+// run evaluates the rules on a connection list every interval until duration
+// elapses or ctx is canceled. A non-positive duration runs until ctx fires.
+func (w *watcher) run(ctx context.Context, duration, interval time.Duration) error {
+	if duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, duration)
+		defer cancel()
+	}
+	w.tracker = watch.NewTracker()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		list, err := w.client.List(ctx, live.SortByTransactionStart)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return live.UserFacingError(err, "view")
+		}
+		if err := w.evaluate(ctx, list); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *watcher) evaluate(ctx context.Context, list live.ConnectionList) error {
+	at := list.CapturedAt
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+
+	started, resolved := w.tracker.Update(w.rules.Evaluate(list))
+	for _, m := range started {
+		w.emit(ctx, watch.NewEvent(watch.EventAlert, at, m))
+		if !w.remediates(m) {
+			continue
+		}
+		event := watch.NewEvent(watch.EventRemediation, at, m)
+		event.Remediation = w.remediate(ctx, m)
+		w.emit(ctx, event)
+		if w.audit != nil {
+			if err := w.audit.Send(ctx, w.withTarget(event)); err != nil {
+				return fmt.Errorf("write audit log: %w", err)
+			}
+		}
+	}
+	for _, m := range resolved {
+		w.emit(ctx, watch.NewEvent(watch.EventResolved, at, m))
+	}
+	return nil
+}
+
+func (w *watcher) remediates(m watch.Match) bool {
+	return w.remediation.action != "" && m.Session != nil && slices.Contains(w.remediation.rules, m.Rule)
+}
+
+// remediate runs the remediation action on the session of m, or in a dry run
+// reports whether it could have.
+func (w *watcher) remediate(ctx context.Context, m watch.Match) *watch.Remediation {
+	result := &watch.Remediation{Action: w.remediation.action}
+	conn := m.Session
+	target := live.ActionTarget{
+		Instance:      conn.Instance,
+		PID:           conn.PID,
+		ConnectionID:  conn.ConnectionID,
+		TransactionID: conn.TransactionID,
+		QueryID:       conn.QueryID,
+	}
+
+	var run func(context.Context, live.ActionTarget) error
+	switch w.remediation.action {
+	case watch.ActionCancelQuery:
+		switch {
+		case !conn.HasWork():
+			result.Status, result.Error = watch.StatusSkipped, "the session is not running a query"
+			return result
+		case live.DerefString(conn.QueryID) == "":
+			result.Status, result.Error = watch.StatusSkipped, "the session has no query_id"
+			return result
+		}
+		run = w.client.CancelQuery
+	case watch.ActionTerminateConnection:
+		if live.DerefString(conn.ConnectionID) == "" {
+			result.Status, result.Error = watch.StatusSkipped, "the session has no connection_id"
+			return result
+		}
+		run = w.client.TerminateConnection
+	}
+
+	if !w.remediation.apply {
+		result.Status = watch.StatusDryRun
+		return result
+	}
+	if err := run(ctx, target); err != nil {
+		result.Status, result.Error = watch.StatusFailed, err.Error()
+		return result
+	}
+	result.Status = watch.StatusSucceeded
+	return result
+}
+
+// emit sends event to every sink. A failing sink is reported and does not
+// stop the watch.
+func (w *watcher) emit(ctx context.Context, event watch.Event) {
+	event = w.withTarget(event)
+	for _, sink := range w.sinks {
+		if err := sink.Send(ctx, event); err != nil {
+			fmt.Fprintf(w.errOut, "warning: %s\n", err)
+		}
+	}
+}
+
+func (w *watcher) withTarget(event watch.Event) watch.Event {
+	event.Organization = w.organization
+	event.Database = w.database
+	event.Branch = w.branch
+	return event
+}
@@ -0,0 +1,194 @@
+package connections
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-shellwords"
+	"github.com/planetscale/cli/internal/cmdutil"
+	live "github.com/planetscale/cli/internal/connections"
+	"github.com/planetscale/cli/internal/connections/watch"
+	"github.com/planetscale/cli/internal/printer"
+)
+
+// eventSinkTimeout bounds a single webhook request or command run, so a stuck
+// receiver can't stall the watch.
+const eventSinkTimeout = 30 * time.Second
+
+// eventSink receives the events of connections watch.
+type eventSink interface {
+	Send(context.Context, watch.Event) error
+}
+
+// printerSink writes events to stdout: one JSON object per line with
+// --format json, and one line of text in human output.
+type printerSink struct {
+	ch *cmdutil.Helper
+}
+
+func newPrinterSink(ch *cmdutil.Helper) *printerSink {
+	return &printerSink{ch: ch}
+}
+
+func (s *printerSink) Send(_ context.Context, event watch.Event) error {
+	if s.ch.Printer.Format() != printer.Human {
+		return writeEventLine(s.ch.Printer.ResourceOutput(), event)
+	}
+	s.ch.Printer.Println(humanEvent(event))
+	return nil
+}
+
+func humanEvent(event watch.Event) string {
+	var subject string
+	switch {
+	case event.Session != nil:
+		subject = fmt.Sprintf("pid %d", event.Session.PID)
+		if event.Session.Instance != "" {
+			subject += " on " + event.Session.Instance
+		}
+		if event.Session.Username != "" || event.Session.ApplicationName != "" {
+			subject += fmt.Sprintf(" (%s/%s)", event.Session.Username, event.Session.ApplicationName)
+		}
+	case event.Application != nil:
+		subject = "application " + *event.Application
+	}
+
+	message := event.Message
+	if r := event.Remediation; r != nil {
+		action := strings.ReplaceAll(r.Action, "_", " ")
+		switch r.Status {
+		case watch.StatusDryRun:
+			message = "dry run, would " + action
+		case watch.StatusSucceeded:
+			message = action + " succeeded"
+		default:
+			message = fmt.Sprintf("%s %s: %s", action, r.Status, r.Error)
+		}
+	}
+
+	label := strings.ToUpper(event.Type)
+	if event.Type == watch.EventAlert {
+		label = printer.BoldRed(label)
+	}
+	return fmt.Sprintf("%s  %s  %s  %s: %s", event.At.Format(time.RFC3339), label, event.Rule, live.SanitizeDisplayText(subject), live.SanitizeDisplayText(message))
+}
+
+// webhookSink POSTs each event as JSON.
+type webhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func newWebhookSink(url string) *webhookSink {
+	return &webhookSink{url: url, client: &http.Client{Timeout: eventSinkTimeout}}
+}
+
+func (s *webhookSink) Send(ctx context.Context, event watch.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body) // nolint:errcheck
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook: %s returned %s", s.url, resp.Status)
+	}
+	return nil
+}
+
+// commandSink runs a command for each event, with the event as JSON on
+// stdin. The command's output goes to stderr so stdout keeps only events.
+type commandSink struct {
+	args     []string
+	database string
+	branch   string
+	out      io.Writer
+}
+
+func newCommandSink(command, database, branch string, out io.Writer) (*commandSink, error) {
+	args, err := shellwords.Parse(command)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse --execute command: %w", err)
+	}
+	if len(args) == 0 {
+		return nil, errors.New("--execute command is empty")
+	}
+	return &commandSink{args: args, database: database, branch: branch, out: out}, nil
+}
+
+func (s *commandSink) Send(ctx context.Context, event watch.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, eventSinkTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, s.args[0], s.args[1:]...)
+	cmd.Stdin = bytes.NewReader(append(body, '\n'))
+	cmd.Stdout = s.out
+	cmd.Stderr = s.out
+	cmd.Env = append(os.Environ(),
+		"PLANETSCALE_DATABASE_NAME="+s.database,
+		"PLANETSCALE_BRANCH_NAME="+s.branch,
+		"PLANETSCALE_EVENT_TYPE="+event.Type,
+		"PLANETSCALE_EVENT_RULE="+event.Rule,
+	)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("--execute command: %w", err)
+	}
+	return nil
+}
+
+// auditLog appends events to a file, one JSON object per line.
+type auditLog struct {
+	file *os.File
+}
+
+func openAuditLog(path string) (*auditLog, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("--audit-log: %w", err)
+	}
+	return &auditLog{file: file}, nil
+}
+
+func (a *auditLog) Send(_ context.Context, event watch.Event) error {
+	if err := writeEventLine(a.file, event); err != nil {
+		return err
+	}
+	return a.file.Sync()
+}
+
+func (a *auditLog) Close() error {
+	return a.file.Close()
+}
+
+func writeEventLine(w io.Writer, event watch.Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
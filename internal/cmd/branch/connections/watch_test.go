@@ -0,0 +1,275 @@
+package connections
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	live "github.com/planetscale/cli/internal/connections"
+	"github.com/planetscale/cli/internal/connections/watch"
+	"github.com/planetscale/cli/internal/printer"
+)
+
+type stubWatchClient struct {
+	stubClientInterface
+	canceled   []live.ActionTarget
+	terminated []live.ActionTarget
+}
+
+func (s *stubWatchClient) CancelQuery(_ context.Context, target live.ActionTarget) error {
+	s.canceled = append(s.canceled, target)
+	return nil
+}
+
+func (s *stubWatchClient) TerminateConnection(_ context.Context, target live.ActionTarget) error {
+	s.terminated = append(s.terminated, target)
+	return nil
+}
+
+type recordingSink struct {
+	events []watch.Event
+}
+
+func (r *recordingSink) Send(_ context.Context, event watch.Event) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func watchTestLists() []live.ConnectionList {
+	at := time.Date(2026, 4, 28, 15, 0, 0, 0, time.UTC)
+	xactStart := at.Add(-10 * time.Minute)
+	connectionID := "conn-101"
+	queryID := "query-101"
+	long := live.Connection{PID: 101, Instance: "primary", Username: "app", ApplicationName: "web", State: "idle in transaction", XactStart: &xactStart, ConnectionID: &connectionID, QueryID: &queryID}
+	return []live.ConnectionList{
+		{CapturedAt: at, Connections: []live.Connection{long}},
+		{CapturedAt: at.Add(time.Second), Connections: []live.Connection{long}},
+		{CapturedAt: at.Add(2 * time.Second)},
+	}
+}
+
+func runTestWatcher(t *testing.T, w *watcher) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := w.run(ctx, 0, time.Millisecond); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+}
+
+func TestWatcherDryRunReportsWithoutActing(t *testing.T) {
+	c := qt.New(t)
+	client := &stubWatchClient{stubClientInterface: stubClientInterface{lists: watchTestLists()}}
+	sink := &recordingSink{}
+	audit := &recordingSink{}
+	w := &watcher{
+		client:      client,
+		rules:       watch.Rules{MaxTransactionAge: 5 * time.Minute},
+		remediation: remediationPolicy{action: watch.ActionTerminateConnection, rules: watch.SessionRules},
+		sinks:       []eventSink{sink},
+		audit:       audit,
+		errOut:      io.Discard,
+		database:    "prod",
+		branch:      "main",
+	}
+
+	runTestWatcher(t, w)
+
+	c.Assert(client.terminated, qt.HasLen, 0)
+	c.Assert(sink.events, qt.HasLen, 3)
+	c.Assert(sink.events[0].Type, qt.Equals, watch.EventAlert)
+	c.Assert(sink.events[0].Database, qt.Equals, "prod")
+	c.Assert(sink.events[0].Session.PID, qt.Equals, 101)
+	c.Assert(sink.events[1].Type, qt.Equals, watch.EventRemediation)
+	c.Assert(*sink.events[1].Remediation, qt.Equals, watch.Remediation{Action: watch.ActionTerminateConnection, Status: watch.StatusDryRun})
+	c.Assert(sink.events[2].Type, qt.Equals, watch.EventResolved)
+	c.Assert(audit.events, qt.HasLen, 1)
+	c.Assert(audit.events[0].Remediation.Status, qt.Equals, watch.StatusDryRun)
+	c.Assert(audit.events[0].Branch, qt.Equals, "main")
+}
+
+func TestWatcherApplyRunsActionOncePerAlert(t *testing.T) {
+	c := qt.New(t)
+	client := &stubWatchClient{stubClientInterface: stubClientInterface{lists: watchTestLists()}}
+	sink := &recordingSink{}
+	w := &watcher{
+		client:      client,
+		rules:       watch.Rules{MaxTransactionAge: 5 * time.Minute},
+		remediation: remediationPolicy{action: watch.ActionTerminateConnection, rules: watch.SessionRules, apply: true},
+		sinks:       []eventSink{sink},
+		errOut:      io.Discard,
+	}
+
+	runTestWatcher(t, w)
+
+	c.Assert(client.terminated, qt.HasLen, 1)
+	c.Assert(*client.terminated[0].ConnectionID, qt.Equals, "conn-101")
+	c.Assert(sink.events[1].Remediation.Status, qt.Equals, watch.StatusSucceeded)
+}
+
+func TestWatcherSkipsCancelingIdleSessions(t *testing.T) {
+	c := qt.New(t)
+	client := &stubWatchClient{stubClientInterface: stubClientInterface{lists: watchTestLists()}}
+	sink := &recordingSink{}
+	w := &watcher{
+		client:      client,
+		rules:       watch.Rules{MaxTransactionAge: 5 * time.Minute},
+		remediation: remediationPolicy{action: watch.ActionCancelQuery, rules: watch.SessionRules, apply: true},
+		sinks:       []eventSink{sink},
+		errOut:      io.Discard,
+	}
+
+	runTestWatcher(t, w)
+
+	c.Assert(client.canceled, qt.HasLen, 0)
+	c.Assert(*sink.events[1].Remediation, qt.Equals, watch.Remediation{
+		Action: watch.ActionCancelQuery,
+		Status: watch.StatusSkipped,
+		Error:  "the session is not running a query",
+	})
+}
+
+func TestWatcherOnlyRemediatesSelectedRules(t *testing.T) {
+	c := qt.New(t)
+	client := &stubWatchClient{stubClientInterface: stubClientInterface{lists: watchTestLists()}}
+	sink := &recordingSink{}
+	w := &watcher{
+		client:      client,
+		rules:       watch.Rules{MaxTransactionAge: 5 * time.Minute},
+		remediation: remediationPolicy{action: watch.ActionTerminateConnection, rules: []string{watch.RuleChainDepth}, apply: true},
+		sinks:       []eventSink{sink},
+		errOut:      io.Discard,
+	}
+
+	runTestWatcher(t, w)
+
+	c.Assert(client.terminated, qt.HasLen, 0)
+	c.Assert(sink.events, qt.HasLen, 2)
+}
+
+func TestWatchEventSinks(t *testing.T) {
+	event := watch.Event{Type: watch.EventAlert, Rule: watch.RuleTransactionAge, Message: "transaction open for 10m0s (threshold 5m0s)", Session: &watch.Session{PID: 101, Instance: "primary"}}
+
+	t.Run("webhook", func(t *testing.T) {
+		c := qt.New(t)
+		var got watch.Event
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c.Check(r.Method, qt.Equals, http.MethodPost)
+			c.Check(r.Header.Get("Content-Type"), qt.Equals, "application/json")
+			c.Check(json.NewDecoder(r.Body).Decode(&got), qt.IsNil)
+		}))
+		defer server.Close()
+
+		c.Assert(newWebhookSink(server.URL).Send(context.Background(), event), qt.IsNil)
+		c.Assert(got.Session.PID, qt.Equals, 101)
+	})
+
+	t.Run("webhook error status", func(t *testing.T) {
+		c := qt.New(t)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		err := newWebhookSink(server.URL).Send(context.Background(), event)
+		c.Assert(err, qt.ErrorMatches, `webhook: .* returned 502 Bad Gateway`)
+	})
+
+	t.Run("audit log", func(t *testing.T) {
+		c := qt.New(t)
+		path := filepath.Join(t.TempDir(), "audit.ndjson")
+		for range 2 {
+			audit, err := openAuditLog(path)
+			c.Assert(err, qt.IsNil)
+			c.Assert(audit.Send(context.Background(), event), qt.IsNil)
+			c.Assert(audit.Close(), qt.IsNil)
+		}
+
+		data, err := os.ReadFile(path)
+		c.Assert(err, qt.IsNil)
+		c.Assert(strings.Count(string(data), "\n"), qt.Equals, 2)
+	})
+
+	t.Run("json stdout", func(t *testing.T) {
+		c := qt.New(t)
+		var out bytes.Buffer
+		sink := newPrinterSink(connectionsTestHelper("", printer.JSON, &out))
+		c.Assert(sink.Send(context.Background(), event), qt.IsNil)
+		c.Assert(sink.Send(context.Background(), event), qt.IsNil)
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		c.Assert(lines, qt.HasLen, 2)
+		var got watch.Event
+		c.Assert(json.Unmarshal([]byte(lines[0]), &got), qt.IsNil)
+		c.Assert(got.Rule, qt.Equals, watch.RuleTransactionAge)
+	})
+
+	t.Run("human stdout", func(t *testing.T) {
+		c := qt.New(t)
+		var out bytes.Buffer
+		sink := newPrinterSink(connectionsTestHelper("", printer.Human, &out))
+		c.Assert(sink.Send(context.Background(), event), qt.IsNil)
+		c.Assert(out.String(), qt.Contains, "transaction_age  pid 101 on primary: transaction open for 10m0s")
+	})
+}
+
+func TestWatchCmdValidatesFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		csv     bool
+		wantErr string
+	}{
+		{
+			name:    "no rules",
+			args:    []string{"prod", "main"},
+			wantErr: "at least one rule is required.*",
+		},
+		{
+			name:    "invalid remediation",
+			args:    []string{"prod", "main", "--max-query-duration", "1m", "--remediate", "kill"},
+			wantErr: `invalid --remediate "kill".*`,
+		},
+		{
+			name:    "apply without remediation",
+			args:    []string{"prod", "main", "--max-query-duration", "1m", "--apply"},
+			wantErr: "--apply and --remediate-rule require --remediate",
+		},
+		{
+			name:    "app rule cannot be remediated",
+			args:    []string{"prod", "main", "--max-app-connections", "10", "--remediate", "terminate-connection", "--remediate-rule", "app_connections"},
+			wantErr: `invalid --remediate-rule "app_connections".*`,
+		},
+		{
+			name:    "csv",
+			args:    []string{"prod", "main", "--max-query-duration", "1m"},
+			csv:     true,
+			wantErr: "watch does not support --format csv, use --format json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			format := printer.JSON
+			if tt.csv {
+				format = printer.CSV
+			}
+			cmd := WatchCmd(connectionsTestHelper("", format, &bytes.Buffer{}))
+			cmd.SetArgs(tt.args)
+			cmd.SetOut(io.Discard)
+			cmd.SetErr(io.Discard)
+
+			c.Assert(cmd.Execute(), qt.ErrorMatches, tt.wantErr)
+		})
+	}
+}
@@ -31,7 +31,7 @@ func TestConnectionsCmdConstruction(t *testing.T) {
 	c.Assert(cmd.Use, qt.Equals, "connections <command>")
 	c.Assert(cmd.Aliases, qt.HasLen, 0)
 	names := commandNames(cmd)
-	for _, name := range []string{"kill", "kill-transaction", "show", "top"} {
+	for _, name := range []string{"analyze", "kill", "kill-transaction", "show", "top", "trim", "watch"} {
 		c.Assert(slices.Contains(names, name), qt.IsTrue)
 	}
 }
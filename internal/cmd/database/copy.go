@@ -0,0 +1,258 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/planetscale/cli/internal/cmdutil"
+	"github.com/planetscale/cli/internal/dumper"
+	"github.com/planetscale/cli/internal/passwordutil"
+	ps "github.com/planetscale/cli/internal/planetscale"
+	"github.com/planetscale/cli/internal/printer"
+	"github.com/planetscale/cli/internal/proxyutil"
+	"github.com/spf13/cobra"
+
+	"vitess.io/vitess/go/mysql"
+)
+
+type copyFlags struct {
+	tables     string
+	wheres     string
+	threads    int
+	schemaOnly bool
+	dataOnly   bool
+	overwrite  bool
+}
+
+// CopyCmd copies the tables of a branch into another branch.
+func CopyCmd(ch *cmdutil.Helper) *cobra.Command {
+	f := &copyFlags{}
+	cmd := &cobra.Command{
+		Use:   "copy <source-database> <source-branch> <target-database> <target-branch> [options]",
+		Short: "Copy the schema and data of a branch into another branch",
+		Long: "Copy the schema and data of a branch into another branch.\n\n" +
+			"Rows are streamed from the source branch straight into the target branch, without writing a dump to disk. " +
+			"Only Vitess databases are supported.",
+		Args: cmdutil.RequiredArgs("source-database", "source-branch", "target-database", "target-branch"),
+		RunE: func(cmd *cobra.Command, args []string) error { return copyBranch(ch, cmd, f, args) },
+	}
+
+	cmd.PersistentFlags().StringVar(&f.tables, "tables", "",
+		"Comma separated string of tables to copy. By default all tables are copied.")
+	cmd.PersistentFlags().StringVar(&f.wheres, "wheres", "",
+		"Comma separated string of WHERE clauses to filter the tables to copy. Only used when you specify tables to copy.")
+	cmd.PersistentFlags().IntVar(&f.threads, "threads", 4, "Number of tables to copy concurrently.")
+	cmd.PersistentFlags().BoolVar(&f.schemaOnly, "schema-only", false, "Only copy the schema, skip table data.")
+	cmd.PersistentFlags().BoolVar(&f.dataOnly, "data-only", false, "Only copy table data into the existing tables of the target branch.")
+	cmd.PersistentFlags().BoolVar(&f.overwrite, "overwrite-tables", false, "If true, will DROP the tables of the target branch before creating them.")
+	return cmd
+}
+
+func copyBranch(ch *cmdutil.Helper, cmd *cobra.Command, flags *copyFlags, args []string) error {
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+
+	srcDatabase, srcBranch := args[0], args[1]
+	dstDatabase, dstBranch := args[2], args[3]
+
+	if srcDatabase == dstDatabase && srcBranch == dstBranch {
+		return errors.New("the source and target branch must be different")
+	}
+	if flags.schemaOnly && flags.dataOnly {
+		return errors.New("--schema-only cannot be combined with --data-only")
+	}
+	if flags.wheres != "" && flags.tables == "" {
+		return errors.New("--wheres requires --tables")
+	}
+	if flags.threads < 1 {
+		return errors.New("--threads must be at least 1")
+	}
+
+	var wheres map[string]string
+	if flags.wheres != "" {
+		tables := strings.Split(flags.tables, ",")
+		clauses := strings.Split(flags.wheres, ",")
+		if len(clauses) > len(tables) {
+			return fmt.Errorf("--wheres has %d clauses for %d tables", len(clauses), len(tables))
+		}
+		wheres = make(map[string]string, len(clauses))
+		for i, where := range clauses {
+			wheres[tables[i]] = where
+		}
+	}
+
+	client, err := ch.Client()
+	if err != nil {
+		return err
+	}
+
+	srcAddr, srcCleanup, err := branchProxy(ctx, ch, client, srcDatabase, srcBranch, cmdutil.ReaderRole)
+	if err != nil {
+		return err
+	}
+	defer srcCleanup()
+
+	dstAddr, dstCleanup, err := branchProxy(ctx, ch, client, dstDatabase, dstBranch, cmdutil.AdministratorRole)
+	if err != nil {
+		return err
+	}
+	defer dstCleanup()
+
+	srcName, err := getDatabaseName(srcDatabase, srcAddr)
+	if err != nil {
+		return err
+	}
+	dstName, err := getDatabaseName(dstDatabase, dstAddr)
+	if err != nil {
+		return err
+	}
+
+	cfg := dumper.NewDefaultConfig()
+	// NOTE(mattrobenolt): credentials are needed even though they aren't used,
+	// otherwise, dumper will complain.
+	cfg.User = "nobody"
+	cfg.Password = "nobody"
+	cfg.Address = srcAddr
+	cfg.Database = srcName
+	cfg.SessionVars = []string{"set workload=olap;"}
+	cfg.ToUser = "nobody"
+	cfg.ToPassword = "nobody"
+	cfg.ToAddress = dstAddr
+	cfg.ToDatabase = dstName
+	cfg.Threads = flags.threads
+	cfg.StmtSize = 1000000
+	cfg.Table = flags.tables
+	cfg.Wheres = wheres
+	cfg.SchemaOnly = flags.schemaOnly
+	cfg.DataOnly = flags.dataOnly
+	cfg.OverwriteTables = flags.overwrite
+	cfg.IntervalMs = 10 * 1000
+	cfg.Debug = ch.Debug()
+	cfg.Printer = ch.Printer
+
+	copier, err := dumper.NewCopier(cfg)
+	if err != nil {
+		return err
+	}
+
+	ch.Printer.Printf("Starting to copy branch %s of database %s into branch %s of database %s\n",
+		printer.BoldBlue(srcBranch), printer.BoldBlue(srcDatabase), printer.BoldBlue(dstBranch), printer.BoldBlue(dstDatabase))
+
+	// The copier reports the progress of every table, so there is no spinner.
+	ch.Printer.Println("Copying database ...")
+
+	start := time.Now()
+	if err := copier.Run(ctx); err != nil {
+		return fmt.Errorf("failed to copy database: %s", err)
+	}
+
+	ch.Printer.Printf("Copy is finished! %s rows copied (elapsed time: %s)\n",
+		humanize.Comma(int64(cfg.Allrows)), time.Since(start))
+	return nil
+}
+
+// branchProxy starts a local proxy to a Vitess branch with credentials of
+// the given role. The returned cleanup stops the proxy and deletes the
+// credentials.
+func branchProxy(ctx context.Context, ch *cmdutil.Helper, client *ps.Client, database, branch string, role cmdutil.PasswordRole) (string, func(), error) {
+	db, err := client.Databases.Get(ctx, &ps.GetDatabaseRequest{
+		Organization: ch.Config.Organization,
+		Database:     database,
+	})
+	if err != nil {
+		switch cmdutil.ErrCode(err) {
+		case ps.ErrNotFound:
+			return "", nil, fmt.Errorf("database %s does not exist in organization: %s",
+				printer.BoldBlue(database), printer.BoldBlue(ch.Config.Organization))
+		default:
+			return "", nil, cmdutil.HandleError(err)
+		}
+	}
+
+	if db.Kind != ps.DatabaseEngineMySQL {
+		return "", nil, fmt.Errorf("database %s is not a Vitess database, copy is only supported for Vitess databases", printer.BoldBlue(database))
+	}
+	if db.State == ps.DatabaseSleeping {
+		return "", nil, fmt.Errorf("database %s is sleeping, please wake the database and retry this command", printer.BoldBlue(database))
+	}
+
+	dbBranch, err := client.DatabaseBranches.Get(ctx, &ps.GetDatabaseBranchRequest{
+		Organization: ch.Config.Organization,
+		Database:     database,
+		Branch:       branch,
+	})
+	if err != nil {
+		switch cmdutil.ErrCode(err) {
+		case ps.ErrNotFound:
+			return "", nil, fmt.Errorf("branch %s does not exist in database %s (organization: %s)",
+				printer.BoldBlue(branch), printer.BoldBlue(database), printer.BoldBlue(ch.Config.Organization))
+		default:
+			return "", nil, cmdutil.HandleError(err)
+		}
+	}
+
+	if !dbBranch.Ready {
+		return "", nil, fmt.Errorf("branch %s of database %s is not ready yet, please try again in a few minutes",
+			printer.BoldBlue(branch), printer.BoldBlue(database))
+	}
+
+	pw, err := passwordutil.New(ctx, client, passwordutil.Options{
+		Organization: ch.Config.Organization,
+		Database:     database,
+		Branch:       branch,
+		Role:         role,
+		Name:         passwordutil.GenerateName("pscale-cli-copy"),
+		TTL:          5 * time.Minute,
+	})
+	if err != nil {
+		return "", nil, cmdutil.HandleError(err)
+	}
+	deletePassword := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := pw.Cleanup(ctx); err != nil {
+			ch.Printer.Println("failed to delete credentials: ", err)
+		}
+	}
+
+	proxy := proxyutil.New(proxyutil.Config{
+		Logger:       cmdutil.NewZapLogger(ch.Debug()),
+		UpstreamAddr: pw.Password.Hostname,
+		Username:     pw.Password.Username,
+		Password:     pw.Password.PlainText,
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		proxy.Close()
+		deletePassword()
+		return "", nil, cmdutil.HandleError(err)
+	}
+
+	go func() {
+		// The dumper uses go-mysqlstack, which does not support
+		// caching_sha2_password.
+		if err := proxy.Serve(l, mysql.MysqlNativePassword); err != nil {
+			ch.Printer.Println("proxy error: ", err)
+		}
+	}()
+
+	go func() {
+		if err := pw.Renew(ctx); err != nil {
+			ch.Printer.Println("proxy error: ", err)
+		}
+	}()
+
+	cleanup := func() {
+		l.Close()
+		proxy.Close()
+		deletePassword()
+	}
+	return l.Addr().String(), cleanup, nil
+}
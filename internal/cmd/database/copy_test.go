@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/planetscale/cli/internal/cmdutil"
+	"github.com/planetscale/cli/internal/config"
+	"github.com/planetscale/cli/internal/mock"
+	ps "github.com/planetscale/cli/internal/planetscale"
+	"github.com/planetscale/cli/internal/printer"
+)
+
+func TestCopy_FlagValidation(t *testing.T) {
+	c := qt.New(t)
+
+	format := printer.Human
+	p := printer.NewPrinter(&format)
+	ch := &cmdutil.Helper{
+		Printer: p,
+		Config: &config.Config{
+			Organization: "planetscale",
+		},
+		Client: func() (*ps.Client, error) {
+			return &ps.Client{}, nil
+		},
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"db", "main", "db", "main"}, "the source and target branch must be different"},
+		{[]string{"db", "main", "db", "dev", "--schema-only", "--data-only"}, "--schema-only cannot be combined with --data-only"},
+		{[]string{"db", "main", "db", "dev", "--wheres", "id > 10"}, "--wheres requires --tables"},
+		{[]string{"db", "main", "db", "dev", "--tables", "users", "--wheres", "id > 10,id < 5"}, "--wheres has 2 clauses for 1 tables"},
+		{[]string{"db", "main", "db", "dev", "--threads", "0"}, "--threads must be at least 1"},
+	}
+	for _, tt := range tests {
+		cmd := CopyCmd(ch)
+		cmd.SetArgs(tt.args)
+		err := cmd.Execute()
+		c.Assert(err, qt.ErrorMatches, tt.want, qt.Commentf("args: %v", tt.args))
+	}
+}
+
+func TestCopy_RejectsPostgres(t *testing.T) {
+	c := qt.New(t)
+
+	format := printer.Human
+	p := printer.NewPrinter(&format)
+	ch := &cmdutil.Helper{
+		Printer: p,
+		Config: &config.Config{
+			Organization: "planetscale",
+		},
+		Client: func() (*ps.Client, error) {
+			return &ps.Client{
+				Databases: &mock.DatabaseService{
+					GetFn: func(ctx context.Context, req *ps.GetDatabaseRequest) (*ps.Database, error) {
+						return &ps.Database{Name: req.Database, Kind: ps.DatabaseEnginePostgres}, nil
+					},
+				},
+			}, nil
+		},
+	}
+
+	cmd := CopyCmd(ch)
+	cmd.SetArgs([]string{"db", "main", "db", "dev"})
+	err := cmd.Execute()
+	c.Assert(err, qt.ErrorMatches, "database .*db.* is not a Vitess database, copy is only supported for Vitess databases")
+}
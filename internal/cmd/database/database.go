@@ -35,6 +35,8 @@ func DatabaseCmd(ch *cmdutil.Helper) *cobra.Command {
 	cmd.AddCommand(IPRestrictionCmd(ch))
 	cmd.AddCommand(DumpCmd(ch))
 	cmd.AddCommand(RestoreCmd(ch))
+	cmd.AddCommand(VerifyDumpCmd(ch))
+	cmd.AddCommand(CopyCmd(ch))
 
 	return cmd
 }
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"bufio"
 	"context"
 	"database/sql"
 	"errors"
@@ -36,10 +37,15 @@ type dumpFlags struct {
 	tables         string
 	wheres         string
 	columns        []string
+	maskConfig     string
 	output         string
 	threads        int
 	schemaOnly     bool
 	outputFormat   string
+	resume         bool
+	compress       string
+	consistent     bool
+	dbName         string
 }
 
 // DumpCmd encapsulates the commands for dumping a database
@@ -47,10 +53,13 @@ func DumpCmd(ch *cmdutil.Helper) *cobra.Command {
 	f := &dumpFlags{}
 	cmd := &cobra.Command{
 		Use:   "dump <database> <branch> [options]",
-		Short: "Backup and dump your database (Vitess databases only)",
-		Long:  "Backup and dump your database.\n\nThis command is only supported for Vitess databases. For Postgres databases, use standard PostgreSQL tools like pg_dump. See: https://planetscale.com/docs/postgres/imports/postgres-migrate-dumprestore",
-		Args:  cmdutil.RequiredArgs("database", "branch"),
-		RunE:  func(cmd *cobra.Command, args []string) error { return dump(ch, cmd, f, args) },
+		Short: "Backup and dump your database",
+		Long: "Backup and dump your database.\n\n" +
+			"For Postgres databases, the dump holds a schema file and COPY data files per table, " +
+			"read from a single snapshot. Indexes, constraints and sequence values are restored after the data by restore-dump. " +
+			"Partitioned tables are not supported, use pg_dump for them. See: https://planetscale.com/docs/postgres/imports/postgres-migrate-dumprestore",
+		Args: cmdutil.RequiredArgs("database", "branch"),
+		RunE: func(cmd *cobra.Command, args []string) error { return dump(ch, cmd, f, args) },
 	}
 
 	cmd.PersistentFlags().StringVar(&f.keyspace, "keyspace",
@@ -69,13 +78,23 @@ func DumpCmd(ch *cmdutil.Helper) *cobra.Command {
 	cmd.PersistentFlags().StringVar(&f.wheres, "wheres", "",
 		"Comma separated string of WHERE clauses to filter the tables to dump. Only used when you specify tables to dump. Default is not to filter dumped tables.")
 	cmd.PersistentFlags().StringVar(&f.output, "output", "",
-		"Output directory of the dump. By default the dump is saved to a folder in the current directory.")
-	cmd.PersistentFlags().IntVar(&f.threads, "threads", 16, "Number of concurrent threads to use to dump the database.")
+		"Output directory of the dump, or - to stream the dump as a tar archive to stdout. By default the dump is saved to a folder in the current directory.")
+	cmd.PersistentFlags().IntVar(&f.threads, "threads", 16, "Number of concurrent threads to use to dump the database. Tables larger than a chunk with a single integer primary key are split into ranges that are dumped concurrently.")
 	cmd.PersistentFlags().BoolVar(&f.schemaOnly, "schema-only", false, "Only dump schema, skip table data.")
 	cmd.PersistentFlags().StringVar(&f.outputFormat, "output-format", "sql",
-		"Output format for data: sql (for MySQL, default), json, or csv.")
+		"Output format for data: sql (for MySQL, default), json, csv, or parquet.")
 	cmd.PersistentFlags().StringArrayVar(&f.columns, "columns", nil,
 		"Columns to include for specific tables (format: 'table:col1,col2'). Can be specified multiple times for different tables.")
+	cmd.PersistentFlags().StringVar(&f.maskConfig, "mask-config", "",
+		"Path to a YAML file mapping table.column to a masking transform (hash, email, null, fixed, truncate or digits) applied to the dumped values. "+
+			"Transforms are deterministic for a given salt, so foreign keys still match after masking.")
+	cmd.PersistentFlags().StringVar(&f.compress, "compress", "",
+		"Compress data files as they are written: gzip or zstd. Compressed dumps can be restored directly.")
+	cmd.PersistentFlags().BoolVar(&f.consistent, "consistent", false,
+		"Dump all tables from a single consistent snapshot across threads. Fails for sharded keyspaces unless --shard is used.")
+	cmd.PersistentFlags().StringVar(&f.dbName, "dbname", "postgres", "Database to dump (Postgres databases only).")
+	cmd.PersistentFlags().BoolVar(&f.resume, "resume", false,
+		"Resume an interrupted dump in the directory given by --output, skipping finished tables and continuing partially dumped tables from their last completed chunk. A dump taken with --consistent cannot be resumed.")
 
 	return cmd
 }
@@ -100,9 +119,32 @@ func dump(ch *cmdutil.Helper, cmd *cobra.Command, flags *dumpFlags, args []strin
 		return fmt.Errorf("--read-only-region cannot be combined with --rdonly or --replica")
 	}
 
-	validFormats := map[string]bool{"sql": true, "json": true, "csv": true}
+	streaming := flags.output == "-"
+
+	if flags.resume && (flags.output == "" || streaming) {
+		return fmt.Errorf("--resume requires --output to point at the directory of the interrupted dump")
+	}
+
+	if flags.resume && flags.consistent {
+		return fmt.Errorf("--consistent cannot be combined with --resume, the snapshot of the interrupted dump is gone")
+	}
+
+	if !dumper.ValidCompression(flags.compress) {
+		return fmt.Errorf("invalid compression: %s. Valid options are: gzip, zstd", flags.compress)
+	}
+
+	if flags.compress != "" && flags.outputFormat == "parquet" {
+		return fmt.Errorf("--compress cannot be used with the parquet output format, which is already compressed")
+	}
+
+	if streaming {
+		// Keep stdout free for the archive.
+		ch.Printer.SetHumanOutput(cmd.ErrOrStderr())
+	}
+
+	validFormats := map[string]bool{"sql": true, "json": true, "csv": true, "parquet": true}
 	if !validFormats[flags.outputFormat] {
-		return fmt.Errorf("invalid output format: %s. Valid options are: sql, json, csv", flags.outputFormat)
+		return fmt.Errorf("invalid output format: %s. Valid options are: sql, json, csv, parquet", flags.outputFormat)
 	}
 
 	client, err := ch.Client()
@@ -151,6 +193,10 @@ func dump(ch *cmdutil.Helper, cmd *cobra.Command, flags *dumpFlags, args []strin
 		return errors.New("database branch is not ready yet, please try again in a few minutes")
 	}
 
+	if db.Kind != ps.DatabaseEngineMySQL {
+		return dumpPostgres(ctx, ch, cmd, flags, client, database, branch)
+	}
+
 	role := cmdutil.AdministratorRole
 	var readOnlyRegionID string
 	if flags.readOnlyRegion != "" {
@@ -205,8 +251,13 @@ func dump(ch *cmdutil.Helper, cmd *cobra.Command, flags *dumpFlags, args []strin
 		remoteAddr = pw.Password.Hostname
 	}
 
+	logger := cmdutil.NewZapLogger(ch.Debug())
+	if streaming {
+		logger = cmdutil.NewStderrZapLogger(ch.Debug())
+	}
+
 	proxy := proxyutil.New(proxyutil.Config{
-		Logger:       cmdutil.NewZapLogger(ch.Debug()),
+		Logger:       logger,
 		UpstreamAddr: remoteAddr,
 		Username:     pw.Password.Username,
 		Password:     pw.Password.PlainText,
@@ -241,6 +292,47 @@ func dump(ch *cmdutil.Helper, cmd *cobra.Command, flags *dumpFlags, args []strin
 		return err
 	}
 
+	cfg := dumper.NewDefaultConfig()
+	// NOTE(mattrobenolt): credentials are needed even though they aren't used,
+	// otherwise, dumper will complain.
+	cfg.User = "nobody"
+	cfg.Password = "nobody"
+	cfg.Address = addr.String()
+	cfg.Shard = flags.shard
+	cfg.StmtSize = 1000000
+	cfg.SessionVars = []string{"set workload=olap;"}
+
+	if flags.shard != "" {
+		useCmd := shardUseCommand(dbName, flags.shard, flags.replica, flags.rdonly)
+		cfg.SessionVars = append([]string{useCmd}, cfg.SessionVars...)
+	}
+
+	if flags.replica && flags.shard == "" {
+		useCmd := "USE @replica;"
+		cfg.SessionVars = append([]string{useCmd}, cfg.SessionVars...)
+	}
+
+	if flags.rdonly && flags.shard == "" {
+		useCmd := "USE @rdonly;"
+		cfg.SessionVars = append([]string{useCmd}, cfg.SessionVars...)
+	}
+
+	return runDump(ctx, ch, cmd, flags, database, branch, dbName, cfg)
+}
+
+// runDump dumps the database into the output directory (or archive) once
+// the connection settings of cfg are set up for the database engine.
+func runDump(ctx context.Context, ch *cmdutil.Helper, cmd *cobra.Command, flags *dumpFlags, database, branch, dbName string, cfg *dumper.Config) error {
+	streaming := flags.output == "-"
+
+	var mask *dumper.MaskConfig
+	if flags.maskConfig != "" {
+		var err error
+		if mask, err = dumper.ReadMaskConfig(flags.maskConfig); err != nil {
+			return fmt.Errorf("invalid --mask-config: %w", err)
+		}
+	}
+
 	dir, err := os.Getwd()
 	if err != nil {
 		return err
@@ -257,45 +349,37 @@ func dump(ch *cmdutil.Helper, cmd *cobra.Command, flags *dumpFlags, args []strin
 		dir = flags.output
 	}
 
-	if _, err := os.Stat(dir); err == nil {
-		return fmt.Errorf("backup directory already exists: %s", dir)
-	}
+	var archive *bufio.Writer
+	if streaming {
+		dir = ""
+		archive = bufio.NewWriterSize(cmd.OutOrStdout(), 1<<20)
+	} else if flags.resume {
+		if _, err := os.Stat(dir); err != nil {
+			return fmt.Errorf("cannot resume dump: %w", err)
+		}
+	} else {
+		if _, err := os.Stat(dir); err == nil {
+			return fmt.Errorf("backup directory already exists: %s", dir)
+		}
 
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return err
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
 	}
 
-	cfg := dumper.NewDefaultConfig()
 	cfg.Threads = flags.threads
-	// NOTE(mattrobenolt): credentials are needed even though they aren't used,
-	// otherwise, dumper will complain.
-	cfg.User = "nobody"
-	cfg.Password = "nobody"
-	cfg.Address = addr.String()
 	cfg.Database = dbName
-	cfg.Shard = flags.shard
 	cfg.Debug = ch.Debug()
-	cfg.StmtSize = 1000000
 	cfg.IntervalMs = 10 * 1000
 	cfg.ChunksizeInMB = 128
-	cfg.SessionVars = []string{"set workload=olap;"}
 	cfg.Outdir = dir
 	cfg.SchemaOnly = flags.schemaOnly
 	cfg.OutputFormat = flags.outputFormat
-
-	if flags.shard != "" {
-		useCmd := shardUseCommand(dbName, flags.shard, flags.replica, flags.rdonly)
-		cfg.SessionVars = append([]string{useCmd}, cfg.SessionVars...)
-	}
-
-	if flags.replica && flags.shard == "" {
-		useCmd := "USE @replica;"
-		cfg.SessionVars = append([]string{useCmd}, cfg.SessionVars...)
-	}
-
-	if flags.rdonly && flags.shard == "" {
-		useCmd := "USE @rdonly;"
-		cfg.SessionVars = append([]string{useCmd}, cfg.SessionVars...)
+	cfg.Resume = flags.resume
+	cfg.Compression = flags.compress
+	cfg.Consistent = flags.consistent
+	if archive != nil {
+		cfg.Archive = archive
 	}
 
 	if flags.tables != "" {
@@ -318,13 +402,20 @@ func dump(ch *cmdutil.Helper, cmd *cobra.Command, flags *dumpFlags, args []strin
 		}
 		cfg.ColumnIncludes = includes
 	}
+	cfg.Mask = mask
 
 	d, err := dumper.NewDumper(cfg)
 	if err != nil {
 		return err
 	}
 
-	if flags.tables == "" {
+	if streaming {
+		ch.Printer.Printf("Starting to stream a dump of database %s to stdout\n",
+			printer.BoldBlue(database))
+	} else if flags.resume {
+		ch.Printer.Printf("Resuming dump of database %s in folder %s\n",
+			printer.BoldBlue(database), printer.Bold(dir))
+	} else if flags.tables == "" {
 		ch.Printer.Printf("Starting to dump all tables from database %s to folder %s\n",
 			printer.BoldBlue(database), printer.Bold(dir))
 	} else {
@@ -341,6 +432,12 @@ func dump(ch *cmdutil.Helper, cmd *cobra.Command, flags *dumpFlags, args []strin
 		return fmt.Errorf("failed to dump database: %s", err)
 	}
 
+	if archive != nil {
+		if err := archive.Flush(); err != nil {
+			return fmt.Errorf("failed to write dump archive: %s", err)
+		}
+	}
+
 	end()
 	ch.Printer.Printf("Dumping is finished! (elapsed time: %s)\n", time.Since(start))
 	return nil
@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/planetscale/cli/internal/cmdutil"
+	"github.com/planetscale/cli/internal/dumper"
+	"github.com/planetscale/cli/internal/passwordutil"
+	ps "github.com/planetscale/cli/internal/planetscale"
+	"github.com/planetscale/cli/internal/roleutil"
+	"github.com/spf13/cobra"
+)
+
+// postgresDumpConfig returns a dumper config connecting to the Postgres
+// branch with a temporary role, renewed until the returned cleanup deletes
+// it.
+func postgresDumpConfig(ctx context.Context, ch *cmdutil.Helper, client *ps.Client, database, branch, name, remoteAddr string, role cmdutil.PasswordRole, replica bool) (*dumper.Config, func(), error) {
+	inheritedRoles, successor := cmdutil.PostgresInheritedRoles(role)
+
+	pgRole, err := roleutil.New(ctx, client, roleutil.Options{
+		Organization:   ch.Config.Organization,
+		Database:       database,
+		Branch:         branch,
+		Name:           passwordutil.GenerateName(name),
+		TTL:            5 * time.Minute,
+		InheritedRoles: inheritedRoles,
+	})
+	if err != nil {
+		return nil, nil, cmdutil.HandleError(err)
+	}
+
+	renewCtx, stopRenew := context.WithCancel(ctx)
+	go func() {
+		if err := pgRole.Renew(renewCtx); err != nil {
+			ch.Printer.Println("role error: ", err)
+		}
+	}()
+
+	cleanup := func() {
+		stopRenew()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := pgRole.Cleanup(ctx, successor); err != nil {
+			ch.Printer.Println("failed to delete role: ", err)
+		}
+	}
+
+	cfg := dumper.NewDefaultConfig()
+	cfg.Engine = dumper.EnginePostgres
+	cfg.User = pgRole.Role.Username
+	if replica {
+		cfg.User += "|replica"
+	}
+	cfg.Password = pgRole.Role.Password
+	cfg.Address = pgRole.Role.AccessHostURL
+	if remoteAddr != "" {
+		cfg.Address = remoteAddr
+	}
+	return cfg, cleanup, nil
+}
+
+// dumpPostgres dumps a Postgres branch. Postgres dumps hold per-table schema
+// files and COPY data files, read from a single exported snapshot.
+func dumpPostgres(ctx context.Context, ch *cmdutil.Helper, cmd *cobra.Command, flags *dumpFlags, client *ps.Client, database, branch string) error {
+	switch {
+	case flags.keyspace != "" || flags.shard != "":
+		return fmt.Errorf("--keyspace and --shard are only supported for Vitess databases, use --dbname to pick the Postgres database")
+	case flags.rdonly || flags.readOnlyRegion != "":
+		return fmt.Errorf("--rdonly and --read-only-region are only supported for Vitess databases")
+	case flags.resume:
+		return fmt.Errorf("--resume is only supported for Vitess databases")
+	case flags.outputFormat != "sql":
+		return fmt.Errorf("only the sql output format is supported for Postgres databases")
+	case flags.maskConfig != "":
+		return fmt.Errorf("--mask-config is only supported for Vitess databases")
+	}
+
+	cfg, cleanup, err := postgresDumpConfig(ctx, ch, client, database, branch, "pscale-cli-dump", flags.remoteAddr, cmdutil.ReaderRole, flags.replica)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	return runDump(ctx, ch, cmd, flags, database, branch, flags.dbName, cfg)
+}
+
+// postgresRestoreConfig returns the loader config restoring a Postgres dump
+// into the branch.
+func postgresRestoreConfig(ctx context.Context, ch *cmdutil.Helper, flags *restoreFlags, client *ps.Client, database, branch string) (*dumper.Config, func(), error) {
+	if flags.allowDifferentDestination {
+		return nil, nil, fmt.Errorf("--allow-different-destination is only supported for Vitess databases, Postgres dumps are restored into --dbname")
+	}
+	if flags.verifyChecksum {
+		return nil, nil, fmt.Errorf("--verify-checksum is only supported for Vitess databases, use --verify to compare row counts")
+	}
+
+	cfg, cleanup, err := postgresDumpConfig(ctx, ch, client, database, branch, "pscale-cli-restore", flags.remoteAddr, cmdutil.AdministratorRole, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg.Database = flags.dbName
+	return cfg, cleanup, nil
+}
@@ -1,11 +1,13 @@
 package database
 
 import (
+	"context"
 	"testing"
 
 	qt "github.com/frankban/quicktest"
 	"github.com/planetscale/cli/internal/cmdutil"
 	"github.com/planetscale/cli/internal/config"
+	"github.com/planetscale/cli/internal/mock"
 	ps "github.com/planetscale/cli/internal/planetscale"
 	"github.com/planetscale/cli/internal/printer"
 )
@@ -141,3 +143,103 @@ func TestShardUseCommand(t *testing.T) {
 	c.Assert(shardUseCommand("commerce", "-80", false, true), qt.Equals, "USE `commerce/-80@rdonly`;")
 	c.Assert(shardUseCommand("key`space", "sh`ard", false, false), qt.Equals, "USE `key``space/sh``ard`;")
 }
+
+func TestDump_ResumeRequiresOutput(t *testing.T) {
+	c := qt.New(t)
+
+	format := printer.Human
+	p := printer.NewPrinter(&format)
+	ch := &cmdutil.Helper{
+		Printer: p,
+		Config: &config.Config{
+			Organization: "planetscale",
+		},
+		Client: func() (*ps.Client, error) {
+			return &ps.Client{}, nil
+		},
+	}
+
+	cmd := DumpCmd(ch)
+	cmd.SetArgs([]string{"db", "main", "--resume"})
+	err := cmd.Execute()
+	c.Assert(err, qt.IsNotNil)
+	c.Assert(err.Error(), qt.Contains, "--resume requires --output")
+}
+
+func TestDump_CompressFlagValidation(t *testing.T) {
+	c := qt.New(t)
+
+	format := printer.Human
+	p := printer.NewPrinter(&format)
+	ch := &cmdutil.Helper{
+		Printer: p,
+		Config: &config.Config{
+			Organization: "planetscale",
+		},
+		Client: func() (*ps.Client, error) {
+			return &ps.Client{}, nil
+		},
+	}
+
+	cmd := DumpCmd(ch)
+	cmd.SetArgs([]string{"db", "main", "--compress", "lz4"})
+	err := cmd.Execute()
+	c.Assert(err, qt.IsNotNil)
+	c.Assert(err.Error(), qt.Contains, "invalid compression")
+
+	cmd = DumpCmd(ch)
+	cmd.SetArgs([]string{"db", "main", "--compress", "gzip", "--output-format", "parquet"})
+	err = cmd.Execute()
+	c.Assert(err, qt.IsNotNil)
+	c.Assert(err.Error(), qt.Contains, "cannot be used with the parquet output format")
+
+	cmd = DumpCmd(ch)
+	cmd.SetArgs([]string{"db", "main", "--output", "-", "--resume"})
+	err = cmd.Execute()
+	c.Assert(err, qt.IsNotNil)
+	c.Assert(err.Error(), qt.Contains, "--resume requires --output")
+}
+
+func TestDump_PostgresRejectsVitessFlags(t *testing.T) {
+	c := qt.New(t)
+
+	format := printer.Human
+	p := printer.NewPrinter(&format)
+	ch := &cmdutil.Helper{
+		Printer: p,
+		Config: &config.Config{
+			Organization: "planetscale",
+		},
+		Client: func() (*ps.Client, error) {
+			return &ps.Client{
+				Databases: &mock.DatabaseService{
+					GetFn: func(ctx context.Context, req *ps.GetDatabaseRequest) (*ps.Database, error) {
+						return &ps.Database{Name: req.Database, Kind: ps.DatabaseEnginePostgres}, nil
+					},
+				},
+				DatabaseBranches: &mock.DatabaseBranchesService{
+					GetFn: func(ctx context.Context, req *ps.GetDatabaseBranchRequest) (*ps.DatabaseBranch, error) {
+						return &ps.DatabaseBranch{Name: req.Branch, Ready: true}, nil
+					},
+				},
+			}, nil
+		},
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--keyspace", "db"}, "--keyspace and --shard are only supported for Vitess databases"},
+		{[]string{"--rdonly"}, "--rdonly and --read-only-region are only supported for Vitess databases"},
+		{[]string{"--output", c.TempDir(), "--resume"}, "--resume is only supported for Vitess databases"},
+		{[]string{"--output-format", "csv"}, "only the sql output format is supported for Postgres databases"},
+		{[]string{"--mask-config", "mask.yml"}, "--mask-config is only supported for Vitess databases"},
+	}
+	for _, tt := range tests {
+		cmd := DumpCmd(ch)
+		cmd.SetArgs(append([]string{"db", "main"}, tt.args...))
+		err := cmd.Execute()
+		c.Assert(err, qt.ErrorMatches, tt.want+".*", qt.Commentf("args: %v", tt.args))
+	}
+}
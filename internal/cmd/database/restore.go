@@ -2,9 +2,12 @@ package database
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/planetscale/cli/internal/cmdutil"
@@ -32,6 +35,9 @@ type restoreFlags struct {
 	allowDifferentDestination bool
 	maxQuerySize              int
 	threads                   int
+	dbName                    string
+	verify                    bool
+	verifyChecksum            bool
 }
 
 // RestoreCmd encapsulates the commands for restore a database
@@ -39,10 +45,12 @@ func RestoreCmd(ch *cmdutil.Helper) *cobra.Command {
 	f := &restoreFlags{}
 	cmd := &cobra.Command{
 		Use:   "restore-dump <database> <branch> [options]",
-		Short: "Restore your database from a local dump directory (Vitess databases only)",
-		Long:  "Restore your database from a local dump directory.\n\nThis command is only supported for Vitess databases. For Postgres databases, use standard PostgreSQL tools like pg_restore. See: https://planetscale.com/docs/postgres/imports/postgres-migrate-dumprestore",
-		Args:  cmdutil.RequiredArgs("database", "branch"),
-		RunE:  func(cmd *cobra.Command, args []string) error { return restore(ch, cmd, f, args) },
+		Short: "Restore your database from a local dump directory",
+		Long: "Restore your database from a local dump directory.\n\n" +
+			"Postgres dumps taken with the dump command are restored into --dbname: tables are created, their data is loaded with COPY, " +
+			"and indexes, constraints and sequence values are restored afterwards. See: https://planetscale.com/docs/postgres/imports/postgres-migrate-dumprestore",
+		Args: cmdutil.RequiredArgs("database", "branch"),
+		RunE: func(cmd *cobra.Command, args []string) error { return restore(ch, cmd, f, args) },
 	}
 
 	cmd.PersistentFlags().StringVar(&f.localAddr, "local-addr",
@@ -62,6 +70,9 @@ func RestoreCmd(ch *cmdutil.Helper) *cobra.Command {
 	cmd.PersistentFlags().BoolVar(&f.allowDifferentDestination, "allow-different-destination", false, "If true, will allow you to restore the files to a database with a different name without needing to rename the existing dump's files.")
 	cmd.PersistentFlags().IntVar(&f.maxQuerySize, "max-query-size", 16777216, "The maximum size allowed for each individual query processed by the command. Defaults to 16777216 bytes (16 MiB).")
 	cmd.PersistentFlags().IntVar(&f.threads, "threads", 1, "Number of concurrent threads to use to restore the database.")
+	cmd.PersistentFlags().StringVar(&f.dbName, "dbname", "postgres", "Database to restore into (Postgres databases only).")
+	cmd.PersistentFlags().BoolVar(&f.verify, "verify", false, "If true, will compare the row counts of the restored tables with the row counts recorded in the dump.")
+	cmd.PersistentFlags().BoolVar(&f.verifyChecksum, "verify-checksum", false, "If true, will also compare a checksum of the rows in every primary key range of the restored tables with the dump (MySQL databases only). Implies --verify.")
 	return cmd
 }
 
@@ -76,6 +87,13 @@ func restore(ch *cmdutil.Helper, cmd *cobra.Command, flags *restoreFlags, args [
 		return errors.New("--dir flag is missing, it's needed to restore the database")
 	}
 
+	if flags.verifyChecksum {
+		flags.verify = true
+	}
+	if flags.verify && flags.schemaOnly {
+		return errors.New("--verify cannot be combined with --schema-only, no data is restored to verify")
+	}
+
 	if flags.endingTable != "" && flags.startingTable != "" && (flags.endingTable < flags.startingTable) {
 		return fmt.Errorf("provided ending table %s must come alphabetically after your provided starting table %s for the restore to continue",
 			printer.BoldBlue(flags.endingTable), printer.BoldBlue(flags.startingTable))
@@ -86,6 +104,20 @@ func restore(ch *cmdutil.Helper, cmd *cobra.Command, flags *restoreFlags, args [
 		return err
 	}
 
+	db, err := client.Databases.Get(ctx, &ps.GetDatabaseRequest{
+		Organization: ch.Config.Organization,
+		Database:     database,
+	})
+	if err != nil {
+		switch cmdutil.ErrCode(err) {
+		case ps.ErrNotFound:
+			return fmt.Errorf("database %s does not exist in organization: %s",
+				printer.BoldBlue(database), printer.BoldBlue(ch.Config.Organization))
+		default:
+			return cmdutil.HandleError(err)
+		}
+	}
+
 	dbBranch, err := client.DatabaseBranches.Get(ctx, &ps.GetDatabaseBranchRequest{
 		Organization: ch.Config.Organization,
 		Database:     database,
@@ -105,6 +137,16 @@ func restore(ch *cmdutil.Helper, cmd *cobra.Command, flags *restoreFlags, args [
 		return errors.New("database branch is not ready yet, please try again in a few minutes")
 	}
 
+	if db.Kind != ps.DatabaseEngineMySQL {
+		cfg, cleanup, err := postgresRestoreConfig(ctx, ch, flags, client, database, branch)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		return runRestore(ctx, ch, flags, database, cfg)
+	}
+
 	pw, err := passwordutil.New(ctx, client, passwordutil.Options{
 		Organization: ch.Config.Organization,
 		Database:     database,
@@ -164,12 +206,21 @@ func restore(ch *cmdutil.Helper, cmd *cobra.Command, flags *restoreFlags, args [
 	addr := l.Addr()
 
 	cfg := dumper.NewDefaultConfig()
-	cfg.Threads = flags.threads
 	// NOTE(mattrobenolt): credentials are needed even though they aren't used,
 	// otherwise, dumper will complain.
 	cfg.User = "nobody"
 	cfg.Password = "nobody"
 	cfg.Address = addr.String()
+	cfg.AllowDifferentDestination = flags.allowDifferentDestination
+	cfg.Database = database // Needs to be passed in to allow for allowDifferentDestination flag to work
+
+	return runRestore(ctx, ch, flags, database, cfg)
+}
+
+// runRestore restores the dump directory once the connection settings of
+// cfg are set up for the database engine.
+func runRestore(ctx context.Context, ch *cmdutil.Helper, flags *restoreFlags, database string, cfg *dumper.Config) error {
+	cfg.Threads = flags.threads
 	cfg.Debug = ch.Debug()
 	cfg.Printer = ch.Printer
 	cfg.IntervalMs = 10 * 1000
@@ -178,8 +229,6 @@ func restore(ch *cmdutil.Helper, cmd *cobra.Command, flags *restoreFlags, args [
 	cfg.SchemaOnly = flags.schemaOnly
 	cfg.DataOnly = flags.dataOnly
 	cfg.ShowDetails = flags.showDetails
-	cfg.AllowDifferentDestination = flags.allowDifferentDestination
-	cfg.Database = database // Needs to be passed in to allow for allowDifferentDestination flag to work
 	cfg.StartingTable = flags.startingTable
 	cfg.EndingTable = flags.endingTable
 	cfg.MaxQuerySize = flags.maxQuerySize
@@ -209,5 +258,68 @@ func restore(ch *cmdutil.Helper, cmd *cobra.Command, flags *restoreFlags, args [
 
 	end()
 	ch.Printer.Printf("Restore is finished! (elapsed time: %s)\n", time.Since(start))
+
+	if flags.verify {
+		return verifyRestore(ctx, ch, loader, flags.verifyChecksum)
+	}
+	return nil
+}
+
+type restoreVerification struct {
+	Database     string `header:"database" json:"database"`
+	Table        string `header:"table" json:"table"`
+	DumpRows     uint64 `header:"dump_rows" json:"dump_rows"`
+	RestoredRows uint64 `header:"restored_rows" json:"restored_rows"`
+	Checksum     string `header:"checksum" json:"checksum,omitempty"`
+	Match        bool   `header:"match" json:"match"`
+
+	orig *dumper.TableVerification
+}
+
+func (v *restoreVerification) MarshalJSON() ([]byte, error) {
+	return json.MarshalIndent(v.orig, "", "  ")
+}
+
+// verifyRestore compares the restored tables with the dump and prints the
+// result, failing if any table does not match.
+func verifyRestore(ctx context.Context, ch *cmdutil.Helper, loader *dumper.Loader, checksums bool) error {
+	end := ch.Printer.PrintProgress("Verifying restored tables ...")
+	results, err := loader.Verify(ctx, checksums)
+	end()
+	if err != nil {
+		return err
+	}
+
+	rows := make([]*restoreVerification, 0, len(results))
+	mismatched := 0
+	for _, r := range results {
+		checksum := r.Checksum
+		if len(r.MismatchedChunks) > 0 {
+			chunks := make([]string, 0, len(r.MismatchedChunks))
+			for _, c := range r.MismatchedChunks {
+				chunks = append(chunks, strconv.Itoa(c))
+			}
+			checksum = fmt.Sprintf("%s (chunks %s)", checksum, strings.Join(chunks, ", "))
+		}
+		if !r.Match {
+			mismatched++
+		}
+		rows = append(rows, &restoreVerification{
+			Database:     r.Database,
+			Table:        r.Table,
+			DumpRows:     r.DumpRows,
+			RestoredRows: r.RestoredRows,
+			Checksum:     checksum,
+			Match:        r.Match,
+			orig:         r,
+		})
+	}
+
+	if err := ch.Printer.PrintResource(rows); err != nil {
+		return err
+	}
+	if mismatched > 0 {
+		return fmt.Errorf("restore verification failed: %d of %d tables do not match the dump", mismatched, len(rows))
+	}
 	return nil
 }
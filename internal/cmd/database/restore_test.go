@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/planetscale/cli/internal/cmdutil"
+	"github.com/planetscale/cli/internal/config"
+	"github.com/planetscale/cli/internal/mock"
+	ps "github.com/planetscale/cli/internal/planetscale"
+	"github.com/planetscale/cli/internal/printer"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestRestore_VerifyFlagValidation(t *testing.T) {
+	c := qt.New(t)
+
+	format := printer.Human
+	ch := &cmdutil.Helper{
+		Printer: printer.NewPrinter(&format),
+		Config: &config.Config{
+			Organization: "planetscale",
+		},
+		Client: func() (*ps.Client, error) {
+			return &ps.Client{
+				Databases: &mock.DatabaseService{
+					GetFn: func(ctx context.Context, req *ps.GetDatabaseRequest) (*ps.Database, error) {
+						return &ps.Database{Name: req.Database, Kind: ps.DatabaseEnginePostgres}, nil
+					},
+				},
+				DatabaseBranches: &mock.DatabaseBranchesService{
+					GetFn: func(ctx context.Context, req *ps.GetDatabaseBranchRequest) (*ps.DatabaseBranch, error) {
+						return &ps.DatabaseBranch{Name: req.Branch, Ready: true}, nil
+					},
+				},
+			}, nil
+		},
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--verify", "--schema-only"}, "--verify cannot be combined with --schema-only.*"},
+		{[]string{"--verify-checksum", "--schema-only"}, "--verify cannot be combined with --schema-only.*"},
+		{[]string{"--verify-checksum"}, "--verify-checksum is only supported for Vitess databases.*"},
+	}
+	for _, tt := range tests {
+		cmd := RestoreCmd(ch)
+		cmd.SetArgs(append([]string{"db", "main", "--dir", c.TempDir()}, tt.args...))
+		c.Assert(cmd.Execute(), qt.ErrorMatches, tt.want, qt.Commentf("args: %v", tt.args))
+	}
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dustin/go-humanize"
+	"github.com/planetscale/cli/internal/cmdutil"
+	"github.com/planetscale/cli/internal/dumper"
+	"github.com/planetscale/cli/internal/printer"
+	"github.com/spf13/cobra"
+)
+
+type dumpVerification struct {
+	Dir      string         `json:"dir"`
+	OK       bool           `json:"ok"`
+	Files    int            `json:"files"`
+	Bytes    int64          `json:"bytes"`
+	Rows     uint64         `json:"rows"`
+	Problems []*dumpProblem `json:"problems"`
+	Warnings []*dumpProblem `json:"warnings"`
+}
+
+func (v *dumpVerification) MarshalCSVValue() interface{} {
+	return v.Problems
+}
+
+type dumpProblem struct {
+	File    string `header:"file" json:"file"`
+	Problem string `header:"problem" json:"problem"`
+}
+
+// VerifyDumpCmd checks the files of a local dump directory against the
+// manifest written when the dump was taken.
+func VerifyDumpCmd(ch *cmdutil.Helper) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify-dump <dir>",
+		Short: "Verify the files of a local dump directory against its manifest",
+		Long: "Verify the files of a local dump directory against its manifest.\n\n" +
+			"Dumps record the size and SHA-256 checksum of every file they write in manifest.json. " +
+			"This command reports files that are missing, truncated or modified, " +
+			"so a damaged dump is caught before it is restored. Files not listed in the manifest are reported as warnings.",
+		Args: cmdutil.RequiredArgs("dir"),
+		// Verifying a local directory does not talk to the API.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return nil },
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := args[0]
+
+			result, err := dumper.VerifyDump(dir)
+			if err != nil {
+				if errors.Is(err, dumper.ErrNoManifest) {
+					return fmt.Errorf("%s has no manifest.json, it was not taken by a version of the CLI that records checksums", printer.BoldBlue(dir))
+				}
+				return err
+			}
+
+			v := &dumpVerification{
+				Dir:      dir,
+				OK:       len(result.Problems) == 0,
+				Files:    result.Files,
+				Bytes:    result.Bytes,
+				Rows:     result.Rows,
+				Problems: make([]*dumpProblem, 0, len(result.Problems)),
+				Warnings: make([]*dumpProblem, 0, len(result.Warnings)),
+			}
+			for _, p := range result.Problems {
+				v.Problems = append(v.Problems, &dumpProblem{File: p.File, Problem: p.Problem})
+			}
+			for _, w := range result.Warnings {
+				v.Warnings = append(v.Warnings, &dumpProblem{File: w.File, Problem: w.Problem})
+			}
+
+			if ch.Printer.Format() == printer.Human {
+				for _, w := range v.Warnings {
+					ch.Printer.Printf("Warning: %s is %s.\n", printer.BoldBlue(w.File), w.Problem)
+				}
+				if v.OK {
+					ch.Printer.Printf("Dump in %s is intact: %d files, %s, %s rows.\n",
+						printer.BoldBlue(dir), v.Files, humanize.IBytes(uint64(v.Bytes)), humanize.Comma(int64(v.Rows)))
+					return nil
+				}
+				if err := ch.Printer.PrintResource(v.Problems); err != nil {
+					return err
+				}
+			} else if err := ch.Printer.PrintResource(v); err != nil {
+				return err
+			}
+
+			if !v.OK {
+				return fmt.Errorf("dump in %s does not match its manifest: %d problems found", dir, len(v.Problems))
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
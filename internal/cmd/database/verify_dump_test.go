@@ -0,0 +1,102 @@
+package database
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/planetscale/cli/internal/cmdutil"
+	"github.com/planetscale/cli/internal/config"
+	"github.com/planetscale/cli/internal/printer"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestDatabase_VerifyDumpCmd(t *testing.T) {
+	c := qt.New(t)
+
+	dir := c.TempDir()
+	c.Assert(os.WriteFile(filepath.Join(dir, "test.t1.00001.sql"), []byte("INSERT INTO `t1` VALUES (1);\n"), 0o644), qt.IsNil)
+	c.Assert(os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("restore on Monday"), 0o644), qt.IsNil)
+	c.Assert(os.WriteFile(filepath.Join(dir, "manifest.json"), []byte(`{
+  "version": 1,
+  "files": [
+    {"name": "test.t1.00001.sql", "size": 29, "sha256": "0000", "rows": 1},
+    {"name": "test.t2.00001.sql", "size": 10, "sha256": "0000", "rows": 2}
+  ]
+}`), 0o644), qt.IsNil)
+
+	var buf bytes.Buffer
+	format := printer.JSON
+	p := printer.NewPrinter(&format)
+	p.SetResourceOutput(&buf)
+
+	ch := &cmdutil.Helper{
+		Printer: p,
+		Config:  &config.Config{},
+	}
+
+	cmd := VerifyDumpCmd(ch)
+	cmd.SetArgs([]string{dir})
+	err := cmd.Execute()
+	c.Assert(err, qt.ErrorMatches, "dump in .* does not match its manifest: 2 problems found")
+
+	c.Assert(buf.String(), qt.JSONEquals, map[string]any{
+		"dir":   dir,
+		"ok":    false,
+		"files": 2,
+		"bytes": 39,
+		"rows":  3,
+		"problems": []map[string]string{
+			{"file": "test.t1.00001.sql", "problem": "checksum mismatch"},
+			{"file": "test.t2.00001.sql", "problem": "missing"},
+		},
+		"warnings": []map[string]string{
+			{"file": "notes.txt", "problem": "not listed in manifest.json"},
+		},
+	})
+}
+
+func TestDatabase_VerifyDumpCmd_UnlistedFilesAreWarnings(t *testing.T) {
+	c := qt.New(t)
+
+	dir := c.TempDir()
+	c.Assert(os.WriteFile(filepath.Join(dir, "test.t1.00001.sql"), []byte("INSERT INTO `t1` VALUES (1);\n"), 0o644), qt.IsNil)
+	c.Assert(os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("restore on Monday"), 0o644), qt.IsNil)
+	c.Assert(os.WriteFile(filepath.Join(dir, "manifest.json"), []byte(`{
+  "version": 1,
+  "files": [
+    {"name": "test.t1.00001.sql", "size": 29, "sha256": "9fc3d4b251d8e593788e6bda88e0fa9866a5ca7e689f991191c9ce816fdb7dc9", "rows": 1}
+  ]
+}`), 0o644), qt.IsNil)
+
+	var buf bytes.Buffer
+	format := printer.Human
+	p := printer.NewPrinter(&format)
+	p.SetHumanOutput(&buf)
+
+	ch := &cmdutil.Helper{
+		Printer: p,
+		Config:  &config.Config{},
+	}
+
+	cmd := VerifyDumpCmd(ch)
+	cmd.SetArgs([]string{dir})
+	c.Assert(cmd.Execute(), qt.IsNil)
+	c.Assert(buf.String(), qt.Matches, `Warning: .*notes.txt.* is not listed in manifest.json.\nDump in .* is intact: 1 files, 29 B, 1 rows.\n`)
+}
+
+func TestDatabase_VerifyDumpCmd_NoManifest(t *testing.T) {
+	c := qt.New(t)
+
+	format := printer.Human
+	ch := &cmdutil.Helper{
+		Printer: printer.NewPrinter(&format),
+		Config:  &config.Config{},
+	}
+
+	cmd := VerifyDumpCmd(ch)
+	cmd.SetArgs([]string{c.TempDir()})
+	c.Assert(cmd.Execute(), qt.ErrorMatches, ".* has no manifest.json, .*")
+}
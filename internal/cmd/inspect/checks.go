@@ -29,6 +29,9 @@ type check struct {
 	// after results and in JSON output so agents know to cross-reference.
 	// Occurrences of <database> and <branch> are replaced with the real args.
 	NextSteps []string
+	// ShardTotals aggregates the rows of all shards when the check runs with
+	// --all-shards. Checks without it only report the rows of each shard.
+	ShardTotals *shardTotals
 }
 
 // mysqlSystemSchemas excludes MySQL system schemas and Vitess internal
@@ -40,6 +43,7 @@ var checks = []check{
 		Name:         "table-sizes",
 		Short:        "Tables by total size, largest first",
 		EmptyMessage: "No user tables found.",
+		ShardTotals:  &shardTotals{Keys: []string{"schema", "name"}, Sums: []string{"size_mb", "approx_rows"}},
 		MySQL: &engineSQL{
 			SQL: `
 				SELECT
@@ -91,6 +95,7 @@ var checks = []check{
 		Name:         "index-sizes",
 		Short:        "Indexes by size, largest first",
 		EmptyMessage: "No indexes found.",
+		ShardTotals:  &shardTotals{Keys: []string{"schema", "table", "index_name"}, Sums: []string{"size_mb"}},
 		MySQL: &engineSQL{
 			SQL: `
 				SELECT
@@ -54,6 +54,14 @@ func TestCheckCatalog(t *testing.T) {
 			}
 		}
 
+		if chk.ShardTotals != nil {
+			c.Assert(chk.MySQL, qt.IsNotNil, qt.Commentf("%s: shard totals need a MySQL implementation", chk.Name))
+			for _, col := range append(chk.ShardTotals.Keys, chk.ShardTotals.Sums...) {
+				c.Assert(strings.Contains(chk.MySQL.SQL, col), qt.IsTrue,
+					qt.Commentf("%s: shard totals column %q is not selected", chk.Name, col))
+			}
+		}
+
 		for _, step := range chk.NextSteps {
 			c.Assert(strings.HasPrefix(step, "pscale "), qt.IsTrue,
 				qt.Commentf("%s: next step %q must be a pscale command", chk.Name, step))
@@ -21,10 +21,13 @@ import (
 const checkTimeout = 30 * time.Second
 
 type inspectFlags struct {
-	keyspace   string
-	postgresDB string
-	role       string
-	replica    bool
+	keyspace    string
+	postgresDB  string
+	role        string
+	replica     bool
+	policy      string
+	allShards   bool
+	concurrency int
 }
 
 // InspectCmd runs read-only diagnostic checks against a database branch.
@@ -47,11 +50,19 @@ On sharded Vitess databases, statistics reflect one shard's MySQL instance
 per run. Pass --keyspace to pick the keyspace, or target an exact shard with
 --keyspace 'mykeyspace/-80' (enumerate shards with SHOW VITESS_SHARDS via
 pscale sql). Databases can have hundreds of shards, so no check fans out
-across shards automatically.
+across shards automatically: pass --all-shards to run the checks on every
+shard of --keyspace (or of every keyspace), at most --concurrency shards at
+a time. Rows are tagged with their shard in the _shard column; table-sizes
+and index-sizes also report totals across shards. Shards a check fails on
+are reported without failing the other shards.
 
 On PostgreSQL, statistics are scoped to one database. Pass --dbname to target
 the database your application uses (defaults to postgres).
 
+Checks are subject to the same SQL policy as pscale sql (the sql-policy key of
+the project .pscale.yml, and --policy on top of it). A check the policy denies
+fails with the denial reason.
+
 For server-side, traffic-aware analysis (slow queries, schema recommendations,
 anomalies), see pscale insights.`,
 		PersistentPreRunE: cmdutil.CheckAuthentication(ch.Config),
@@ -68,6 +79,12 @@ anomalies), see pscale insights.`,
 		"Access role for the ephemeral credentials: reader, writer, readwriter, or admin. Defaults to reader. On PostgreSQL, the reader role may lack CONNECT on non-default databases; use --role admin if connecting with --dbname fails.")
 	cmd.PersistentFlags().BoolVar(&flags.replica, "replica", false,
 		"Run checks against a replica instead of the primary")
+	cmd.PersistentFlags().BoolVar(&flags.allShards, "all-shards", false,
+		"Run the checks on every shard of --keyspace (or of every keyspace) of a MySQL (Vitess) database")
+	cmd.PersistentFlags().IntVar(&flags.concurrency, "concurrency", sqlquery.DefaultShardConcurrency,
+		"Maximum number of shards inspected at once with --all-shards")
+	cmd.PersistentFlags().StringVar(&flags.policy, "policy", "",
+		"Path to a SQL policy file, enforced in addition to the sql-policy key of the project .pscale.yml.")
 
 	for _, c := range checks {
 		cmd.AddCommand(checkCmd(ch, c, flags))
@@ -86,6 +103,12 @@ type CheckResult struct {
 	Rows     []map[string]any `json:"rows"`
 	RowCount int              `json:"row_count"`
 	Skipped  string           `json:"skipped,omitempty"`
+	// Shards reports the outcome on each shard when the check ran with
+	// --all-shards. Totals aggregates the rows of all shards for checks that
+	// support it, in TotalsColumns order.
+	Shards        []sqlquery.ShardResult `json:"shards,omitempty"`
+	TotalsColumns []string               `json:"totals_columns,omitempty"`
+	Totals        []map[string]any       `json:"totals,omitempty"`
 	// NextSteps points at the pscale insights commands that analyze the same
 	// problem from server-side production traffic data.
 	NextSteps []string `json:"next_steps,omitempty"`
@@ -144,7 +167,12 @@ func checkCmd(ch *cmdutil.Helper, c check, flags *inspectFlags) *cobra.Command {
 			}
 			defer sess.Close()
 
-			result, err := runCheck(ctx, sess, c, ch.Config.Organization, database, branch)
+			shards, err := listShards(ctx, sess, flags)
+			if err != nil {
+				return err
+			}
+
+			result, err := runCheck(ctx, sess, c, ch.Config.Organization, database, branch, shards, flags.concurrency)
 			if err != nil {
 				return cmdutil.HandleError(err)
 			}
@@ -160,8 +188,15 @@ func checkCmd(ch *cmdutil.Helper, c check, flags *inspectFlags) *cobra.Command {
 				return err
 			}
 			if ch.Printer.Format() == printer.Human {
+				printShardFailures(ch, result)
 				printNextSteps(ch, result.NextSteps)
 			}
+			if failed := failedShards(result); failed > 0 {
+				if ch.Printer.Format() == printer.JSON {
+					return cmdutil.JSONReportedError(cmdutil.FatalErrExitCode)
+				}
+				return fmt.Errorf("%s check failed on %d of %d shard(s)", c.Name, failed, len(result.Shards))
+			}
 			return nil
 		},
 	}
@@ -185,11 +220,16 @@ func allCmd(ch *cmdutil.Helper, flags *inspectFlags) *cobra.Command {
 				return cmdutil.HandleError(err)
 			}
 			defer sess.Close()
+
+			shards, err := listShards(ctx, sess, flags)
+			if err != nil {
+				return err
+			}
 			end()
 
 			var results []*CheckResult
 			for _, c := range checks {
-				result, err := runCheck(ctx, sess, c, ch.Config.Organization, database, branch)
+				result, err := runCheck(ctx, sess, c, ch.Config.Organization, database, branch, shards, flags.concurrency)
 				if err != nil {
 					// One failing check shouldn't abort the report. Keep the
 					// catalog next steps so consumers still get the
@@ -212,6 +252,7 @@ func allCmd(ch *cmdutil.Helper, flags *inspectFlags) *cobra.Command {
 						continue
 					}
 					printHumanTable(ch, c, result)
+					printShardFailures(ch, result)
 					// Only surface the targeted follow-up when the check
 					// actually found something; the combined report already
 					// ends with the full insights pointer.
@@ -266,6 +307,10 @@ func printCompactNextSteps(ch *cmdutil.Helper, steps []string) {
 }
 
 func newSession(ctx context.Context, ch *cmdutil.Helper, database, branch string, flags *inspectFlags) (*sqlquery.Session, error) {
+	policy, err := sqlquery.ResolvePolicy(flags.policy)
+	if err != nil {
+		return nil, err
+	}
 	sess, err := sqlquery.NewSession(ctx, ch, sqlquery.Options{
 		Organization:            ch.Config.Organization,
 		Database:                database,
@@ -275,6 +320,7 @@ func newSession(ctx context.Context, ch *cmdutil.Helper, database, branch string
 		PostgresAdditionalRoles: []string{"pg_read_all_stats"},
 		Role:                    flags.role,
 		Replica:                 flags.replica,
+		Policy:                  policy,
 	})
 	if err != nil && strings.Contains(err.Error(), "permission denied for database") {
 		return nil, fmt.Errorf("%w (the reader role may not have CONNECT on database %q; retry with --role admin)", err, flags.postgresDB)
@@ -282,7 +328,8 @@ func newSession(ctx context.Context, ch *cmdutil.Helper, database, branch string
 	return sess, err
 }
 
-func runCheck(ctx context.Context, sess *sqlquery.Session, c check, organization, database, branch string) (*CheckResult, error) {
+// runCheck runs a check on the session, or on each of shards when given.
+func runCheck(ctx context.Context, sess *sqlquery.Session, c check, organization, database, branch string, shards []string, concurrency int) (*CheckResult, error) {
 	result := &CheckResult{Check: c.Name, Database: database, Branch: branch}
 
 	var impl *engineSQL
@@ -325,6 +372,11 @@ func runCheck(ctx context.Context, sess *sqlquery.Session, c check, organization
 		}
 	}
 
+	if len(shards) > 0 {
+		result.NextSteps = formatNextSteps(c.NextSteps, organization, database, branch)
+		return runCheckOnShards(ctx, sess, c, impl, result, shards, concurrency)
+	}
+
 	columns, rows, err := sess.Query(ctx, impl.SQL)
 	if err != nil {
 		return nil, fmt.Errorf("%s check failed: %w", c.Name, err)
@@ -355,12 +407,20 @@ func printHumanTable(ch *cmdutil.Helper, c check, result *CheckResult) {
 		return
 	}
 
+	// Totals across shards are more useful to read than the rows of every
+	// shard, which remain available in JSON and CSV output.
+	columns, rows := result.Columns, result.Rows
+	if result.Totals != nil {
+		ch.Printer.Printf("  Totals across %d shard(s):\n", len(result.Shards))
+		columns, rows = result.TotalsColumns, result.Totals
+	}
+
 	var sb strings.Builder
 	w := tabwriter.NewWriter(&sb, 2, 2, 2, ' ', 0)
-	fmt.Fprintln(w, "  "+strings.Join(result.Columns, "\t"))
-	for _, row := range result.Rows {
-		values := make([]string, 0, len(result.Columns))
-		for _, col := range result.Columns {
+	fmt.Fprintln(w, "  "+strings.Join(columns, "\t"))
+	for _, row := range rows {
+		values := make([]string, 0, len(columns))
+		for _, col := range columns {
 			values = append(values, formatValue(row[col]))
 		}
 		fmt.Fprintln(w, "  "+strings.Join(values, "\t"))
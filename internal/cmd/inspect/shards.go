@@ -0,0 +1,165 @@
+package inspect
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/planetscale/cli/internal/cmdutil"
+	"github.com/planetscale/cli/internal/sqlquery"
+)
+
+// shardTotals aggregates the rows of a check run on all shards: rows with the
+// same Keys are merged into one and their Sums added up. Each shard still
+// only reports its own top rows, so totals cover the rows that made the LIMIT
+// on at least one shard.
+type shardTotals struct {
+	Keys []string
+	// Sums are numeric columns. Totals are ordered by the first one,
+	// largest first.
+	Sums []string
+}
+
+// shardsColumn counts the shards a total was aggregated from.
+const shardsColumn = "shards"
+
+func (t *shardTotals) aggregate(rows []map[string]any) ([]string, []map[string]any) {
+	columns := slices.Concat(t.Keys, t.Sums, []string{shardsColumn})
+
+	var totals []map[string]any
+	index := map[string]map[string]any{}
+	for _, row := range rows {
+		parts := make([]string, len(t.Keys))
+		for i, key := range t.Keys {
+			parts[i] = fmt.Sprint(row[key])
+		}
+		id := strings.Join(parts, "\x00")
+
+		total, ok := index[id]
+		if !ok {
+			total = map[string]any{shardsColumn: 0}
+			for _, key := range t.Keys {
+				total[key] = row[key]
+			}
+			for _, sum := range t.Sums {
+				total[sum] = 0.0
+			}
+			index[id] = total
+			totals = append(totals, total)
+		}
+		for _, sum := range t.Sums {
+			total[sum] = total[sum].(float64) + numericValue(row[sum])
+		}
+		total[shardsColumn] = total[shardsColumn].(int) + 1
+	}
+
+	for _, total := range totals {
+		for _, sum := range t.Sums {
+			total[sum] = math.Round(total[sum].(float64)*100) / 100
+		}
+	}
+	if len(t.Sums) > 0 {
+		order := t.Sums[0]
+		slices.SortStableFunc(totals, func(a, b map[string]any) int {
+			return cmp.Compare(b[order].(float64), a[order].(float64))
+		})
+	}
+	return columns, totals
+}
+
+// numericValue converts a column value to a number. Drivers return numbers
+// as integers, floats, or their text representation; anything else is 0.
+func numericValue(v any) float64 {
+	switch n := v.(type) {
+	case int64:
+		return float64(n)
+	case uint64:
+		return float64(n)
+	case float64:
+		return n
+	case string:
+		f, _ := strconv.ParseFloat(n, 64)
+		return f
+	default:
+		return 0
+	}
+}
+
+// listShards returns the shards to run checks on with --all-shards, or nil
+// without it.
+func listShards(ctx context.Context, sess *sqlquery.Session, flags *inspectFlags) ([]string, error) {
+	if !flags.allShards {
+		return nil, nil
+	}
+	if flags.concurrency < 1 {
+		return nil, fmt.Errorf("--concurrency must be at least 1")
+	}
+	if sess.Engine() != "mysql" {
+		return nil, fmt.Errorf("--all-shards is only supported on MySQL (Vitess) databases")
+	}
+	return sess.Shards(ctx, flags.keyspace)
+}
+
+// runCheckOnShards runs the check on every shard and tags each row with its
+// shard. Shards the check failed on are reported in result.Shards; the check
+// only fails if it failed on every shard.
+func runCheckOnShards(ctx context.Context, sess *sqlquery.Session, c check, impl *engineSQL, result *CheckResult, shards []string, concurrency int) (*CheckResult, error) {
+	shardRows, err := sess.QueryShards(ctx, impl.SQL, shards, concurrency, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%s check failed: %w", c.Name, err)
+	}
+
+	failed := 0
+	for _, sr := range shardRows {
+		shardResult := sqlquery.ShardResult{Shard: sr.Shard, Status: "ok"}
+		if sr.Err != nil {
+			failed++
+			shardResult.Status = "error"
+			shardResult.Error = sr.Err.Error()
+			result.Shards = append(result.Shards, shardResult)
+			continue
+		}
+		if result.Columns == nil && sr.Columns != nil {
+			result.Columns = append([]string{sqlquery.ShardColumn}, sr.Columns...)
+		}
+		for _, row := range sr.Rows {
+			row[sqlquery.ShardColumn] = sr.Shard
+			result.Rows = append(result.Rows, row)
+		}
+		shardResult.RowCount = len(sr.Rows)
+		result.Shards = append(result.Shards, shardResult)
+	}
+	if failed == len(shardRows) {
+		return nil, fmt.Errorf("%s check failed on every shard: %w", c.Name, shardRows[0].Err)
+	}
+
+	result.RowCount = len(result.Rows)
+	if c.ShardTotals != nil {
+		result.TotalsColumns, result.Totals = c.ShardTotals.aggregate(result.Rows)
+	}
+	return result, nil
+}
+
+// failedShards returns the number of shards the check failed on.
+func failedShards(result *CheckResult) int {
+	failed := 0
+	for _, shard := range result.Shards {
+		if shard.Status != "ok" {
+			failed++
+		}
+	}
+	return failed
+}
+
+// printShardFailures lists the shards a check failed on in human output.
+func printShardFailures(ch *cmdutil.Helper, result *CheckResult) {
+	for _, shard := range result.Shards {
+		if shard.Status != "ok" {
+			ch.Printer.Printf("  failed on shard %s: %s\n", shard.Shard, shard.Error)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package inspect
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestShardTotalsAggregate(t *testing.T) {
+	c := qt.New(t)
+
+	totals := &shardTotals{Keys: []string{"schema", "name"}, Sums: []string{"size_mb", "approx_rows"}}
+	columns, rows := totals.aggregate([]map[string]any{
+		{"_shard": "ks/-80", "schema": "ks", "name": "events", "size_mb": "10.25", "approx_rows": int64(100)},
+		{"_shard": "ks/-80", "schema": "ks", "name": "users", "size_mb": "1.50", "approx_rows": int64(10)},
+		{"_shard": "ks/80-", "schema": "ks", "name": "events", "size_mb": "12.10", "approx_rows": int64(120)},
+		{"_shard": "ks/80-", "schema": "ks", "name": "orders", "size_mb": "30", "approx_rows": nil},
+	})
+
+	c.Assert(columns, qt.DeepEquals, []string{"schema", "name", "size_mb", "approx_rows", "shards"})
+	c.Assert(rows, qt.DeepEquals, []map[string]any{
+		{"schema": "ks", "name": "orders", "size_mb": 30.0, "approx_rows": 0.0, "shards": 1},
+		{"schema": "ks", "name": "events", "size_mb": 22.35, "approx_rows": 220.0, "shards": 2},
+		{"schema": "ks", "name": "users", "size_mb": 1.5, "approx_rows": 10.0, "shards": 1},
+	})
+}
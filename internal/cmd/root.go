@@ -464,13 +464,7 @@ func initConfig() {
 	// Only org/database/branch are accepted from project-scoped files — never
 	// api-url, tokens, or other security-sensitive settings.
 	if cfgFile == "" {
-		var projectDir string
-		if rootDir, err := config.RootGitRepoDir(); err == nil {
-			projectDir = rootDir
-		} else if localDir, err := config.LocalDir(); err == nil {
-			projectDir = localDir
-		}
-		if projectDir != "" {
+		if projectDir := config.ProjectDir(); projectDir != "" {
 			ignored, err := config.MergeProjectConfig(viper.GetViper(), projectDir)
 			if err != nil {
 				fmt.Println(err)
@@ -21,6 +21,8 @@ import (
 	"github.com/planetscale/cli/internal/promptutil"
 	"github.com/planetscale/cli/internal/proxyutil"
 	"github.com/planetscale/cli/internal/roleutil"
+	"github.com/planetscale/cli/internal/sqlquery"
+	"github.com/planetscale/cli/internal/sqlrepl"
 	"vitess.io/vitess/go/mysql"
 )
 
@@ -29,6 +31,7 @@ type shellFlags struct {
 	remoteAddr string
 	role       string
 	replica    bool
+	builtin    bool
 }
 
 func ShellCmd(ch *cmdutil.Helper, sigc chan os.Signal, signals ...os.Signal) *cobra.Command {
@@ -53,7 +56,17 @@ If there are multiple branches for the given database, you'll be prompted to
 choose one. To open a shell instance to a specific branch, pass the branch as a
 second argument:
 
-  pscale shell mydatabase mybranch`,
+  pscale shell mydatabase mybranch
+
+If the client is not installed, or --builtin is given, a built-in SQL shell is
+used instead. It supports multi-line statements, history, tab completion of
+table and column names, and these meta-commands:
+
+  \d [table]        List the tables, or describe the columns of a table
+  \use <name>       Switch to a database (MySQL) or schema (PostgreSQL)
+  \x                Toggle between table and vertical output
+  \format <name>    Set the output format: table, vertical, or json
+  \timing [on|off]  Toggle showing how long each statement took`,
 		PersistentPreRunE: cmdutil.CheckAuthentication(ch.Config),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx, cancel := context.WithCancel(cmd.Context())
@@ -94,22 +107,35 @@ second argument:
 			var authMethod mysql.AuthMethodDescription
 			var isPostgreSQL bool
 
+			var clientErr error
+			builtin := flags.builtin
+
 			switch dbInfo.Kind {
 			case "mysql":
-				clientPath, authMethod, err = cmdutil.MySQLClientPath()
-				if err != nil {
-					return err
+				if !builtin {
+					clientPath, authMethod, clientErr = cmdutil.MySQLClientPath()
 				}
 			case "postgresql", "horizon":
-				clientPath, err = cmdutil.PostgreSQLClientPath()
-				if err != nil {
-					return err
+				if !builtin {
+					clientPath, clientErr = cmdutil.PostgreSQLClientPath()
 				}
 				isPostgreSQL = true
 			default:
 				return fmt.Errorf("unsupported database kind: %s. Only 'mysql' and 'postgresql' are supported", dbInfo.Kind)
 			}
 
+			if clientErr != nil {
+				// The built-in shell can't honor the proxy address flags,
+				// so keep pointing at the missing client in that case.
+				if flags.localAddr != "" || flags.remoteAddr != "" {
+					return clientErr
+				}
+				builtin = true
+			}
+			if builtin && (flags.localAddr != "" || flags.remoteAddr != "") {
+				return errors.New("--local-addr and --remote-addr are not supported by the built-in shell")
+			}
+
 			var branch string
 			if len(args) == 2 {
 				branch = args[1]
@@ -162,6 +188,13 @@ second argument:
 				return errors.New("database branch is not ready yet")
 			}
 
+			if builtin {
+				if clientErr != nil {
+					ch.Printer.Printf("No %s command-line client found, using the built-in SQL shell.\n", clientName(isPostgreSQL))
+				}
+				return startBuiltinShell(ctx, ch, database, branch, dbBranch, role, flags, sigc, signals)
+			}
+
 			if isPostgreSQL {
 				return startShellForPostgres(ctx, ch, client, database, branch, dbBranch, clientPath, role, flags, sigc, signals, runForeground)
 			} else {
@@ -178,6 +211,7 @@ second argument:
 	cmd.PersistentFlags().StringVar(&flags.role, "role",
 		"", "Role defines the access level, allowed values are: reader, writer, readwriter, admin. Defaults to 'reader' for replica passwords, otherwise defaults to 'admin'.")
 	cmd.Flags().BoolVar(&flags.replica, "replica", false, "When enabled, the password will route all reads to the branch's primary replicas and all read-only regions.")
+	cmd.Flags().BoolVar(&flags.builtin, "builtin", false, "Use the built-in SQL shell even if the mysql or psql command-line client is installed.")
 
 	cmd.MarkPersistentFlagRequired("org") // nolint:errcheck
 
@@ -485,3 +519,38 @@ func startShellForMySQL(ctx context.Context, ch *cmdutil.Helper, client *ps.Clie
 		return cmdutil.HandleError(err)
 	}
 }
+
+func clientName(isPostgreSQL bool) string {
+	if isPostgreSQL {
+		return "psql"
+	}
+	return "mysql"
+}
+
+// startBuiltinShell opens a shell with the built-in SQL REPL, for machines
+// without the mysql or psql command-line client.
+func startBuiltinShell(ctx context.Context, ch *cmdutil.Helper, database, branch string, dbBranch *ps.DatabaseBranch, role cmdutil.PasswordRole, flags shellFlags, sigc chan os.Signal, signals []os.Signal) error {
+	sess, err := sqlquery.NewSession(ctx, ch, sqlquery.Options{
+		Organization: ch.Config.Organization,
+		Database:     database,
+		Branch:       branch,
+		Role:         role.ToString(),
+		Replica:      flags.replica,
+	})
+	if err != nil {
+		return cmdutil.HandleError(err)
+	}
+	defer sess.Close()
+
+	// Meta-commands like \use change the state of the connection, so keep
+	// every statement on the same one.
+	if err := sess.Pin(ctx); err != nil {
+		return cmdutil.HandleError(err)
+	}
+
+	historyFile := historyFilePath(ch.Config.Organization, database, branch)
+	if err := sqlrepl.RunTerminal(ctx, sess, formatBranch(database, dbBranch), historyFile, sigc, signals); err != nil {
+		return cmdutil.HandleError(err)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package sql
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/planetscale/cli/internal/cmdutil"
+	"github.com/planetscale/cli/internal/printer"
+	"github.com/planetscale/cli/internal/sqlquery"
+)
+
+// explainStep is a step of a query plan in CSV output, with the tree
+// flattened depth first.
+type explainStep struct {
+	Plan      string `csv:"plan"`
+	Depth     int    `csv:"depth"`
+	Operation string `csv:"operation"`
+	Table     string `csv:"table"`
+	Index     string `csv:"index"`
+	Cost      string `csv:"cost"`
+	Rows      string `csv:"rows"`
+	FullScan  bool   `csv:"full_scan"`
+}
+
+// printExplainResult prints the plan reported by sqlquery.Explain: the
+// normalized plan with --format json, and a tree in human output.
+func printExplainResult(ch *cmdutil.Helper, result *sqlquery.Result, err error, database, branch string) error {
+	if err != nil {
+		return handleExecuteError(ch, err, database, branch)
+	}
+
+	switch ch.Printer.Format() {
+	case printer.JSON:
+		return ch.Printer.PrintJSON(result)
+	case printer.Human:
+		var b strings.Builder
+		writePlan(&b, result.Plan)
+		ch.Printer.Print(b.String())
+		return nil
+	default:
+		var steps []explainStep
+		flattenPlan(&steps, "database", result.Plan.Root, 0)
+		flattenPlan(&steps, "routing", result.Plan.Routing, 0)
+		return ch.Printer.PrintResource(steps)
+	}
+}
+
+// writePlan draws plan as trees: the plan of the database, then the vtgate
+// routing plan, then the warnings.
+func writePlan(w io.Writer, plan *sqlquery.Plan) {
+	header := "Query plan"
+	if plan.Analyzed {
+		header += " (analyzed)"
+	}
+	if plan.Cost != nil {
+		header += ", estimated cost " + formatPlanValue(*plan.Cost)
+	}
+	fmt.Fprintln(w, header)
+	if plan.Root != nil {
+		writePlanNode(w, plan.Root, "", "")
+	}
+	if plan.PlanningTimeMs != nil {
+		fmt.Fprintf(w, "Planning time: %s ms\n", formatPlanValue(*plan.PlanningTimeMs))
+	}
+	if plan.ExecutionTimeMs != nil {
+		fmt.Fprintf(w, "Execution time: %s ms\n", formatPlanValue(*plan.ExecutionTimeMs))
+	}
+
+	if plan.Routing != nil {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Routing plan (vtgate)")
+		writePlanNode(w, plan.Routing, "", "")
+	}
+
+	if len(plan.Warnings) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Warnings:")
+		for _, warning := range plan.Warnings {
+			fmt.Fprintf(w, "  %s %s\n", printer.BoldRed("!"), warning)
+		}
+	}
+}
+
+// writePlanNode writes node and its children as a tree. prefix starts the
+// line of node, and indent the lines of its children.
+func writePlanNode(w io.Writer, node *sqlquery.PlanNode, prefix, indent string) {
+	fmt.Fprintf(w, "%s%s\n", prefix, planNodeLabel(node))
+	for i, child := range node.Children {
+		if i == len(node.Children)-1 {
+			writePlanNode(w, child, indent+"└── ", indent+"    ")
+		} else {
+			writePlanNode(w, child, indent+"├── ", indent+"│   ")
+		}
+	}
+}
+
+func planNodeLabel(node *sqlquery.PlanNode) string {
+	label := node.Operation
+	switch {
+	case node.Keyspace != "" && node.Table != "":
+		label += fmt.Sprintf(" on %s.%s", node.Keyspace, node.Table)
+	case node.Keyspace != "":
+		label += " on " + node.Keyspace
+	case node.Table != "":
+		label += " on " + node.Table
+	}
+	if node.Index != "" {
+		label += " using " + node.Index
+	}
+
+	var details []string
+	if node.Cost != nil {
+		details = append(details, "cost="+formatPlanValue(*node.Cost))
+	}
+	if node.Rows != nil {
+		details = append(details, "rows="+formatPlanValue(*node.Rows))
+	}
+	if node.ActualRows != nil {
+		details = append(details, "actual rows="+formatPlanValue(*node.ActualRows))
+	}
+	if node.ActualTimeMs != nil {
+		details = append(details, "time="+formatPlanValue(*node.ActualTimeMs)+"ms")
+	}
+	if node.Loops != nil && *node.Loops > 1 {
+		details = append(details, "loops="+formatPlanValue(*node.Loops))
+	}
+	if len(details) > 0 {
+		label += "  (" + strings.Join(details, " ") + ")"
+	}
+	if node.FullScan {
+		label += "  " + printer.BoldRed("full scan")
+	}
+	return label
+}
+
+func flattenPlan(steps *[]explainStep, plan string, node *sqlquery.PlanNode, depth int) {
+	if node == nil {
+		return
+	}
+	step := explainStep{
+		Plan:      plan,
+		Depth:     depth,
+		Operation: node.Operation,
+		Table:     node.Table,
+		Index:     node.Index,
+		FullScan:  node.FullScan,
+	}
+	if node.Cost != nil {
+		step.Cost = formatPlanValue(*node.Cost)
+	}
+	if node.Rows != nil {
+		step.Rows = formatPlanValue(*node.Rows)
+	}
+	*steps = append(*steps, step)
+	for _, child := range node.Children {
+		flattenPlan(steps, plan, child, depth+1)
+	}
+}
+
+func formatPlanValue(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
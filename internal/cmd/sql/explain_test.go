@@ -0,0 +1,47 @@
+package sql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/planetscale/cli/internal/sqlquery"
+)
+
+func TestWritePlan(t *testing.T) {
+	cost, rows := 101.25, 1000.0
+	plan := &sqlquery.Plan{
+		Engine: "mysql",
+		Cost:   &cost,
+		Root: &sqlquery.PlanNode{
+			Operation: "Query block #1",
+			Cost:      &cost,
+			Children: []*sqlquery.PlanNode{{
+				Operation: "Nested loop",
+				Children: []*sqlquery.PlanNode{
+					{Operation: "Full table scan", Table: "users", Rows: &rows, FullScan: true},
+					{Operation: "Index lookup", Table: "orders", Index: "idx_user_id"},
+				},
+			}},
+		},
+		Routing:  &sqlquery.PlanNode{Operation: "Route (Unsharded)", Keyspace: "commerce", Table: "users"},
+		Warnings: []string{"Full table scan on users (about 1000 rows)"},
+	}
+
+	var b strings.Builder
+	writePlan(&b, plan)
+	want := `Query plan, estimated cost 101.25
+Query block #1  (cost=101.25)
+└── Nested loop
+    ├── Full table scan on users  (rows=1000)  full scan
+    └── Index lookup on orders using idx_user_id
+
+Routing plan (vtgate)
+Route (Unsharded) on commerce.users
+
+Warnings:
+  ! Full table scan on users (about 1000 rows)
+`
+	if b.String() != want {
+		t.Errorf("writePlan =\n%s\nwant\n%s", b.String(), want)
+	}
+}
@@ -20,11 +20,12 @@ func handleExecuteError(ch *cmdutil.Helper, err error, database, branch string)
 		return err
 	}
 
-	if _, ok := errors.AsType[*sqlquery.DestructiveQueryError](err); ok {
+	if destructiveErr, ok := errors.AsType[*sqlquery.DestructiveQueryError](err); ok {
 		return reportJSON(ch, map[string]any{
 			"status":     "action_required",
 			"query_kind": "destructive",
 			"message":    err.Error(),
+			"risks":      destructiveErr.Risks,
 			"issues": []map[string]string{
 				{
 					"code":        "DESTRUCTIVE_SQL",
@@ -39,6 +40,21 @@ func handleExecuteError(ch *cmdutil.Helper, err error, database, branch string)
 		}, cmdutil.ActionRequestedExitCode)
 	}
 
+	if violation, ok := errors.AsType[*sqlquery.PolicyViolationError](err); ok {
+		return reportJSON(ch, map[string]any{
+			"status":           "error",
+			"error":            err.Error(),
+			"policy_violation": violation,
+			"issues": []map[string]string{
+				{
+					"code":        "SQL_POLICY_DENIED",
+					"message":     violation.Reason,
+					"remediation": "The SQL policy cannot be overridden with --force. Run the query on another branch or role, or ask the user to change the policy",
+				},
+			},
+		}, cmdutil.FatalErrExitCode)
+	}
+
 	return reportJSON(ch, map[string]any{
 		"status": "error",
 		"error":  err.Error(),
@@ -0,0 +1,108 @@
+package sql
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/planetscale/cli/internal/cmdutil"
+	"github.com/planetscale/cli/internal/printer"
+	"github.com/planetscale/cli/internal/sqlquery"
+)
+
+// SavedCmd groups the commands for the saved queries run with --saved.
+func SavedCmd(ch *cmdutil.Helper) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "saved <command>",
+		Short: "Manage the saved queries run with pscale sql --saved",
+		Long: `Saved queries are named queries kept under the queries key of the project .pscale.yml,
+so runbooks live in the repository, or of queries.yml in the PlanetScale config directory
+(~/.config/planetscale) for personal ones. A project query replaces a personal query of the
+same name.
+
+  queries:
+    large-tables:
+      description: Tables above a size, largest first
+      engine: mysql                # optional: mysql or postgresql
+      query: |
+        SELECT table_name, data_length FROM information_schema.tables
+        WHERE table_schema = DATABASE() AND data_length > :min_bytes
+        ORDER BY data_length DESC
+      params:
+        - name: min_bytes
+          description: Minimum table size in bytes
+          default: "1048576"       # parameters without a default are required
+
+Run one with pscale sql <database> <branch> --saved large-tables --param min_bytes=0.`,
+		// Saved queries are local files: listing them needs neither
+		// authentication nor an organization.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return nil },
+	}
+	// Shadows the required --org flag of pscale sql.
+	cmd.PersistentFlags().StringVar(&ch.Config.Organization, "org", ch.Config.Organization, "The organization for the current user")
+
+	cmd.AddCommand(savedListCmd(ch))
+	return cmd
+}
+
+func savedListCmd(ch *cmdutil.Helper) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the saved queries of the project and of the user",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			queries, err := sqlquery.ResolveSavedQueries()
+			if err != nil {
+				return err
+			}
+			if len(queries) == 0 && ch.Printer.Format() == printer.Human {
+				ch.Printer.Println("No saved queries found. Add a queries: section to .pscale.yml.")
+				return nil
+			}
+			return ch.Printer.PrintResource(toSavedQueries(queries))
+		},
+	}
+}
+
+// savedQuery is a saved query in list output.
+type savedQuery struct {
+	Name        string `header:"name" csv:"name"`
+	Description string `header:"description" csv:"description"`
+	Engine      string `header:"engine" csv:"engine"`
+	Params      string `header:"params" csv:"params"`
+	Source      string `header:"source" csv:"source"`
+
+	orig *sqlquery.SavedQuery
+}
+
+func (q *savedQuery) MarshalJSON() ([]byte, error) {
+	return json.MarshalIndent(q.orig, "", "  ")
+}
+
+func toSavedQueries(queries []*sqlquery.SavedQuery) []*savedQuery {
+	out := make([]*savedQuery, 0, len(queries))
+	for _, q := range queries {
+		var params []string
+		for _, p := range q.Params {
+			param := p.Name
+			if p.Default != nil {
+				param += "=" + *p.Default
+			}
+			params = append(params, param)
+		}
+		engine := q.Engine
+		if engine == "" {
+			engine = "any"
+		}
+		out = append(out, &savedQuery{
+			Name:        q.Name,
+			Description: q.Description,
+			Engine:      engine,
+			Params:      strings.Join(params, ", "),
+			Source:      q.Source,
+			orig:        q,
+		})
+	}
+	return out
+}
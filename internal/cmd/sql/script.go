@@ -0,0 +1,73 @@
+package sql
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/planetscale/cli/internal/cmdutil"
+	"github.com/planetscale/cli/internal/printer"
+	"github.com/planetscale/cli/internal/sqlquery"
+)
+
+// readScript reads the SQL script at path, or from stdin when path is "-".
+func readScript(cmd *cobra.Command, path string) (string, error) {
+	var (
+		b   []byte
+		err error
+	)
+	if path == "-" {
+		b, err = io.ReadAll(cmd.InOrStdin())
+	} else {
+		b, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading SQL script: %w", err)
+	}
+	return string(b), nil
+}
+
+// printScriptResult prints the result of a script. Failed statements are
+// part of the result, so it is printed even when err is a
+// *sqlquery.ScriptError.
+func printScriptResult(ch *cmdutil.Helper, result *sqlquery.Result, err error, database, branch string) error {
+	scriptErr, failed := errors.AsType[*sqlquery.ScriptError](err)
+	if err != nil && !failed {
+		return handleExecuteError(ch, err, database, branch)
+	}
+
+	switch ch.Printer.Format() {
+	case printer.JSON:
+		if failed {
+			return reportJSON(ch, result, cmdutil.FatalErrExitCode)
+		}
+		return ch.Printer.PrintJSON(result)
+	case printer.Human:
+		for _, stmt := range result.Statements {
+			ch.Printer.Printf("Statement %d: %s\n", stmt.Index, stmt.Statement)
+			switch {
+			case stmt.Status != "ok":
+				ch.Printer.Printf("Error: %s\n", stmt.Error)
+			case stmt.RowsAffected > 0 && stmt.RowCount == 0:
+				ch.Printer.Printf("Rows affected: %d\n", stmt.RowsAffected)
+			default:
+				ch.Printer.Printf("Returned %d row(s)\n", stmt.RowCount)
+				for i, row := range stmt.Rows {
+					ch.Printer.Printf("%d: %v\n", i+1, row)
+				}
+			}
+		}
+	default:
+		if err := ch.Printer.PrintResource(result.Statements); err != nil {
+			return err
+		}
+	}
+
+	if failed {
+		return scriptErr
+	}
+	return nil
+}
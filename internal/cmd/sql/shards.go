@@ -0,0 +1,53 @@
+package sql
+
+import (
+	"errors"
+
+	"github.com/planetscale/cli/internal/cmdutil"
+	"github.com/planetscale/cli/internal/printer"
+	"github.com/planetscale/cli/internal/sqlquery"
+)
+
+// printShardsResult prints the result of a query run on all shards. Shards
+// the query failed on are part of the result, so it is printed even when err
+// is a *sqlquery.ShardError.
+func printShardsResult(ch *cmdutil.Helper, result *sqlquery.Result, err error, database, branch string) error {
+	shardErr, failed := errors.AsType[*sqlquery.ShardError](err)
+	if err != nil && !failed {
+		return handleExecuteError(ch, err, database, branch)
+	}
+
+	switch ch.Printer.Format() {
+	case printer.JSON:
+		if failed {
+			return reportJSON(ch, result, cmdutil.FatalErrExitCode)
+		}
+		return ch.Printer.PrintJSON(result)
+	case printer.Human:
+		for _, shard := range result.Shards {
+			switch {
+			case shard.Status != "ok":
+				ch.Printer.Printf("Shard %s: error: %s\n", shard.Shard, shard.Error)
+			case shard.RowsAffected > 0 && shard.RowCount == 0:
+				ch.Printer.Printf("Shard %s: rows affected: %d\n", shard.Shard, shard.RowsAffected)
+			default:
+				ch.Printer.Printf("Shard %s: returned %d row(s)\n", shard.Shard, shard.RowCount)
+			}
+		}
+		for i, row := range result.Rows {
+			ch.Printer.Printf("%d: %v\n", i+1, row)
+		}
+		if result.Truncated {
+			ch.Printer.Printf("Output truncated by --max-rows on one or more shards\n")
+		}
+	default:
+		if err := ch.Printer.PrintResource(result.Rows); err != nil {
+			return err
+		}
+	}
+
+	if failed {
+		return shardErr
+	}
+	return nil
+}
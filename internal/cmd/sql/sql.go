@@ -1,6 +1,10 @@
 package sql
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/spf13/cobra"
 
 	"github.com/planetscale/cli/internal/cmdutil"
@@ -11,12 +15,25 @@ import (
 // SQLCmd runs queries without an interactive shell.
 func SQLCmd(ch *cmdutil.Helper) *cobra.Command {
 	var flags struct {
-		query      string
-		keyspace   string
-		postgresDB string
-		role       string
-		replica    bool
-		force      bool
+		query       string
+		file        string
+		stopOnError bool
+		transaction bool
+		stream      string
+		maxRows     int
+		params      []string
+		args        []string
+		keyspace    string
+		postgresDB  string
+		role        string
+		replica     bool
+		force       bool
+		policy      string
+		allShards   bool
+		concurrency int
+		explain     bool
+		analyze     bool
+		saved       string
 	}
 
 	cmd := &cobra.Command{
@@ -24,15 +41,78 @@ func SQLCmd(ch *cmdutil.Helper) *cobra.Command {
 		Short: "Execute a SQL query without an interactive shell",
 		Long: `Execute a single SQL query against a database branch using ephemeral credentials.
 
+Pass --file to run a SQL script instead (--file - reads it from stdin). The statements of
+the script run in order over a single connection, and the result of each is reported.
+A failing statement does not stop the script unless --stop-on-error is passed.
+
+Pass --transaction to run all statements of --query or --file in a single transaction. The
+transaction is rolled back at the first failing statement, which is reported, and committed
+otherwise. On MySQL, DDL statements such as CREATE or ALTER commit implicitly and cannot be
+rolled back.
+
+Pass --stream ndjson, csv, or tsv to write the rows of --query to stdout as they are read,
+without holding the result in memory. Column names are written first. With ndjson, the JSON
+result envelope is the last line; with csv and tsv, it is written to stderr. If the query
+fails part way through, the rows read so far are still written and the envelope has status
+error. Use --max-rows to cap the number of rows returned, with or without --stream.
+
+Pass --all-shards to run --query on every shard of a sharded MySQL (Vitess) keyspace: the one
+given with --keyspace, or every keyspace without it. The shards are listed with SHOW VITESS_SHARDS
+and queried at most --concurrency at a time. Each row is tagged with its shard in the _shard column,
+and the outcome on each shard is reported under shards. When the query fails on some shards, the
+rows of the others are still returned and the command exits with an error. --max-rows applies per
+shard.
+
+Pass --explain to report the plan of --query instead of running it. MySQL (Vitess) databases
+are explained with EXPLAIN FORMAT=JSON and, for the vtgate routing plan across keyspaces and
+shards, VEXPLAIN PLAN. PostgreSQL databases are explained with EXPLAIN (FORMAT JSON); add
+--analyze to run the statement and report the actual rows and timings. Human output draws the
+plan as a tree with costs, row estimates and indexes, and warns about full table scans and
+scatter queries. With --format json, the normalized plan is reported under plan. Since
+--analyze runs the statement, it is subject to the destructive SQL guard and the SQL policy.
+
+Pass --saved <name> to run a saved query of the project .pscale.yml or of your user-level
+queries file instead of --query. Saved queries declare their :name parameters, with optional
+defaults, and may be restricted to one engine. List them with pscale sql saved list, and see
+pscale sql saved --help for the file format.
+
+Never splice values into --query. Bind them with --arg, which fills the positional placeholders
+of the database (? for MySQL, $1, $2, ... for PostgreSQL) in order, or with --param name=value,
+which fills :name placeholders. Values are sent as prepared statement parameters. The JSON result
+reports the type of each column in column_types.
+
 Use --format json for machine-readable output. This command is intended for agents and scripts;
 for interactive sessions use pscale shell instead.
 
 Access flags match pscale shell: --role (reader, writer, readwriter, admin) and --replica.
 Unlike shell, the default role is reader. Pass --role admin (or writer/readwriter) for writes.
 
-Destructive SQL containing DELETE, DROP, or TRUNCATE is blocked unless --force is passed.
+Destructive SQL is blocked unless --force is passed: DELETE, DROP, TRUNCATE, REPLACE, RENAME,
+UPDATE without WHERE, and ALTER TABLE that drops or renames columns, indexes, or partitions.
+Scripts are checked statement by statement, and none of them runs if any is destructive.
+With --format json, the blocked statements are reported under risks.
 Agents must ask the user for approval before using --force.
 
+A SQL policy restricts the statements allowed per branch and role, and --force does not
+override it. The policy is read from the sql-policy key of the project .pscale.yml:
+
+  sql-policy:
+    deny: [ddl]              # denied on every branch, for every role
+    rules:                   # the first rule matching the branch and role applies
+      - branches: [main]
+        allow: [read]        # only reads on main
+      - branches: ["dev-*"]
+        allow: [read, write]
+
+A --policy file is enforced in addition to the project policy: a statement either of them
+denies is denied, and a --policy file without a policy is an error.
+
+Entries are categories (read, write, ddl, other), statement kinds (SELECT, DROP TABLE; DROP
+matches every DROP), or *. CALL is a write and GRANT and REVOKE are ddl. Statements are
+parsed for their kind, the tables they change, and whether an UPDATE or DELETE has a WHERE or
+LIMIT clause; one in a subquery doesn't count. With --format json, a denied statement is
+reported with issue code SQL_POLICY_DENIED under policy_violation.
+
 MySQL (Vitess) databases use the primary keyspace by default (same as pscale shell -D @primary).
 Pass --keyspace when targeting a specific keyspace in a multi-keyspace database. A keyspace may
 include a shard and tablet type (mykeyspace/-80, mykeyspace/-80@replica) to pin the connection to
@@ -51,9 +131,150 @@ Place flags after positional arguments (see Usage). --org is required:
   pscale sql <database> <branch> --org <org> --format json --replica --query "SELECT 1"
 
   # MySQL — keyspace optional (@primary default)
-  pscale sql <database> <branch> --org <org> --format json --keyspace <keyspace> --query "SELECT 1"`,
+  pscale sql <database> <branch> --org <org> --format json --keyspace <keyspace> --query "SELECT 1"
+
+  # Run a SQL script, stopping at the first failing statement
+  pscale sql <database> <branch> --org <org> --format json --role admin --file migrate.sql --stop-on-error
+
+  # Bind values instead of splicing them into the query
+  pscale sql <database> <branch> --org <org> --format json --query "SELECT * FROM users WHERE email = :email" --param email=jane@example.com
+
+  # Count rows on every shard of a keyspace
+  pscale sql <database> <branch> --org <org> --format json --keyspace <keyspace> --all-shards --query "SELECT COUNT(*) AS n FROM events"
+
+  # Show the plan of a query
+  pscale sql <database> <branch> --org <org> --explain --query "SELECT * FROM users WHERE email = 'jane@example.com'"
+
+  # Run a saved query of the project .pscale.yml
+  pscale sql <database> <branch> --org <org> --format json --saved large-tables --param min_bytes=0
+
+  # Export a large result set as CSV
+  pscale sql <database> <branch> --org <org> --stream csv --query "SELECT * FROM events" > events.csv
+
+  # Run several writes all-or-nothing
+  pscale sql <database> <branch> --org <org> --format json --role writer --transaction --file updates.sql
+
+  # Run a SQL script from stdin
+  cat seed.sql | pscale sql <database> <branch> --org <org> --role admin --file -`,
 		PersistentPreRunE: cmdutil.CheckAuthentication(ch.Config),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if flags.maxRows < 0 {
+				return errors.New("--max-rows must not be negative")
+			}
+			if flags.concurrency < 1 {
+				return errors.New("--concurrency must be at least 1")
+			}
+			if flags.analyze && !flags.explain {
+				return errors.New("--analyze requires --explain")
+			}
+			params, err := sqlquery.ParseParams(flags.params)
+			if err != nil {
+				return err
+			}
+			policy, err := sqlquery.ResolvePolicy(flags.policy)
+			if err != nil {
+				return err
+			}
+			var engine string
+			if flags.saved != "" {
+				saved, err := sqlquery.FindSavedQuery(flags.saved)
+				if err != nil {
+					return err
+				}
+				if params, err = saved.BindParams(params); err != nil {
+					return err
+				}
+				if len(params) > 0 && flags.transaction {
+					return fmt.Errorf("saved query %q has parameters, which --transaction does not support", saved.Name)
+				}
+				flags.query = saved.Query
+				engine = saved.Engine
+			}
+			if flags.explain {
+				result, err := sqlquery.Explain(cmd.Context(), ch, sqlquery.Options{
+					Organization: ch.Config.Organization,
+					Database:     args[0],
+					Branch:       args[1],
+					Query:        flags.query,
+					Keyspace:     flags.keyspace,
+					PostgresDB:   flags.postgresDB,
+					Role:         flags.role,
+					Replica:      flags.replica,
+					Force:        flags.force,
+					Params:       params,
+					Args:         flags.args,
+					Policy:       policy,
+					Engine:       engine,
+					Analyze:      flags.analyze,
+				})
+				return printExplainResult(ch, result, err, args[0], args[1])
+			}
+
+			if flags.stream != "" {
+				return streamQuery(cmd, ch, sqlquery.Options{
+					Organization: ch.Config.Organization,
+					Database:     args[0],
+					Branch:       args[1],
+					Query:        flags.query,
+					Keyspace:     flags.keyspace,
+					PostgresDB:   flags.postgresDB,
+					Role:         flags.role,
+					Replica:      flags.replica,
+					Force:        flags.force,
+					MaxRows:      flags.maxRows,
+					Params:       params,
+					Args:         flags.args,
+					Policy:       policy,
+					Engine:       engine,
+				}, flags.stream)
+			}
+
+			if flags.allShards {
+				result, err := sqlquery.ExecuteAllShards(cmd.Context(), ch, sqlquery.Options{
+					Organization: ch.Config.Organization,
+					Database:     args[0],
+					Branch:       args[1],
+					Query:        flags.query,
+					Keyspace:     flags.keyspace,
+					Role:         flags.role,
+					Replica:      flags.replica,
+					Force:        flags.force,
+					MaxRows:      flags.maxRows,
+					Params:       params,
+					Args:         flags.args,
+					Policy:       policy,
+					Engine:       engine,
+					Concurrency:  flags.concurrency,
+				})
+				return printShardsResult(ch, result, err, args[0], args[1])
+			}
+
+			if flags.file != "" || flags.transaction {
+				script := flags.query
+				if flags.file != "" {
+					if script, err = readScript(cmd, flags.file); err != nil {
+						return err
+					}
+				}
+				result, err := sqlquery.ExecuteScript(cmd.Context(), ch, sqlquery.Options{
+					Organization: ch.Config.Organization,
+					Database:     args[0],
+					Branch:       args[1],
+					Query:        script,
+					Keyspace:     flags.keyspace,
+					PostgresDB:   flags.postgresDB,
+					Role:         flags.role,
+					Replica:      flags.replica,
+					Force:        flags.force,
+					StopOnError:  flags.stopOnError,
+					Transaction:  flags.transaction,
+					MaxRows:      flags.maxRows,
+					Policy:       policy,
+					Engine:       engine,
+				})
+				return printScriptResult(ch, result, err, args[0], args[1])
+			}
+
 			result, err := sqlquery.Execute(cmd.Context(), ch, sqlquery.Options{
 				Organization: ch.Config.Organization,
 				Database:     args[0],
@@ -64,6 +285,11 @@ Place flags after positional arguments (see Usage). --org is required:
 				Role:         flags.role,
 				Replica:      flags.replica,
 				Force:        flags.force,
+				MaxRows:      flags.maxRows,
+				Params:       params,
+				Args:         flags.args,
+				Policy:       policy,
+				Engine:       engine,
 			})
 			if err != nil {
 				return handleExecuteError(ch, err, args[0], args[1])
@@ -81,6 +307,9 @@ Place flags after positional arguments (see Usage). --org is required:
 				for i, row := range result.Rows {
 					ch.Printer.Printf("%d: %v\n", i+1, row)
 				}
+				if result.Truncated {
+					ch.Printer.Printf("Output truncated to %d row(s) by --max-rows\n", flags.maxRows)
+				}
 				return nil
 			default:
 				return ch.Printer.PrintResource(result.Rows)
@@ -91,16 +320,57 @@ Place flags after positional arguments (see Usage). --org is required:
 	cmd.PersistentFlags().StringVar(&ch.Config.Organization, "org", ch.Config.Organization,
 		"The organization for the current user")
 	cmd.Flags().StringVar(&flags.query, "query", "", "SQL query to execute")
+	cmd.Flags().StringVar(&flags.saved, "saved", "", "Name of a saved query to run instead of --query (see pscale sql saved list)")
+	cmd.Flags().StringVar(&flags.file, "file", "", "Path to a SQL script to execute statement by statement, or - to read the script from stdin")
+	cmd.Flags().BoolVar(&flags.stopOnError, "stop-on-error", false,
+		"Stop running a --file script at the first failing statement. By default the remaining statements still run.")
+	cmd.Flags().BoolVar(&flags.transaction, "transaction", false,
+		"Run all statements in a single transaction, rolled back at the first failing statement.")
+	cmd.Flags().StringVar(&flags.stream, "stream", "",
+		"Write the rows of --query to stdout as they are read, in the given format. Supported formats are: "+strings.Join(sqlquery.StreamFormats, ", ")+".")
+	cmd.Flags().IntVar(&flags.maxRows, "max-rows", 0, "Maximum number of rows to return for a query. By default all rows are returned.")
+	cmd.Flags().StringArrayVar(&flags.params, "param", nil,
+		"Bind a value to the :name placeholder of --query, as name=value. Can be repeated.")
+	cmd.Flags().StringArrayVar(&flags.args, "arg", nil,
+		"Bind a value to the next positional placeholder of --query (? for MySQL, $1 for PostgreSQL). Can be repeated.")
 	cmd.Flags().StringVar(&flags.keyspace, "keyspace", "", "Vitess keyspace, optionally with a shard and tablet type (e.g. mykeyspace, mykeyspace/-80, mykeyspace/-80@replica). List shards with --query \"SHOW VITESS_SHARDS\". Defaults to @primary, same as pscale shell.")
+	cmd.Flags().BoolVar(&flags.allShards, "all-shards", false,
+		"Run --query on every shard of --keyspace (or of every keyspace) of a MySQL (Vitess) database, tagging each row with its shard.")
+	cmd.Flags().IntVar(&flags.concurrency, "concurrency", sqlquery.DefaultShardConcurrency,
+		"Maximum number of shards queried at once with --all-shards.")
+	cmd.Flags().BoolVar(&flags.explain, "explain", false,
+		"Report the plan of --query instead of running it.")
+	cmd.Flags().BoolVar(&flags.analyze, "analyze", false,
+		"With --explain, run the statement to report its actual rows and timings (PostgreSQL only).")
 	cmd.Flags().StringVar(&flags.postgresDB, "dbname", "postgres", "PostgreSQL database name")
 	cmd.Flags().StringVar(&flags.role, "role",
 		"", "Role defines the access level, allowed values are: reader, writer, readwriter, admin. Defaults to reader (use --role admin for writes).")
 	cmd.Flags().BoolVar(&flags.replica, "replica", false,
 		"When enabled, the password will route all reads to the branch's primary replicas and all read-only regions.")
 	cmd.Flags().BoolVar(&flags.force, "force", false,
-		"Allow destructive SQL (DELETE, DROP, TRUNCATE). Only use after the user explicitly approves.")
-	cmd.MarkFlagRequired("query")         // nolint:errcheck
+		"Allow destructive SQL (DELETE, DROP, TRUNCATE, REPLACE, RENAME, UPDATE without WHERE). Only use after the user explicitly approves.")
+	cmd.Flags().StringVar(&flags.policy, "policy", "",
+		"Path to a SQL policy file, enforced in addition to the sql-policy key of the project .pscale.yml.")
+	cmd.MarkFlagsOneRequired("query", "file", "saved")
+	cmd.MarkFlagsMutuallyExclusive("query", "file", "saved")
+	cmd.MarkFlagsMutuallyExclusive("stream", "file")
+	cmd.MarkFlagsMutuallyExclusive("stream", "transaction")
+	cmd.MarkFlagsMutuallyExclusive("param", "arg")
+	cmd.MarkFlagsMutuallyExclusive("param", "file")
+	cmd.MarkFlagsMutuallyExclusive("arg", "file")
+	cmd.MarkFlagsMutuallyExclusive("param", "transaction")
+	cmd.MarkFlagsMutuallyExclusive("arg", "transaction")
+	cmd.MarkFlagsMutuallyExclusive("all-shards", "file")
+	cmd.MarkFlagsMutuallyExclusive("all-shards", "transaction")
+	cmd.MarkFlagsMutuallyExclusive("all-shards", "stream")
+	cmd.MarkFlagsMutuallyExclusive("explain", "file")
+	cmd.MarkFlagsMutuallyExclusive("explain", "transaction")
+	cmd.MarkFlagsMutuallyExclusive("explain", "stream")
+	cmd.MarkFlagsMutuallyExclusive("explain", "all-shards")
+	cmd.MarkFlagsMutuallyExclusive("explain", "max-rows")
 	cmd.MarkPersistentFlagRequired("org") // nolint:errcheck
 
+	cmd.AddCommand(SavedCmd(ch))
+
 	return cmd
 }
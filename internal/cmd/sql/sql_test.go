@@ -5,6 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/spf13/cobra"
@@ -116,3 +119,240 @@ func TestHandleExecuteErrorReturnsFatalExitForJSONError(t *testing.T) {
 		t.Fatalf("status = %v", resp["status"])
 	}
 }
+
+func TestSQLCmdFileFromStdinBlocksDestructiveStatement(t *testing.T) {
+	format := printer.JSON
+	var out bytes.Buffer
+	ch := &cmdutil.Helper{
+		Printer: printer.NewPrinter(&format),
+		Config:  &config.Config{Organization: "acme", AccessToken: "token"},
+	}
+	ch.Printer.SetResourceOutput(&out)
+	cmd := SQLCmd(ch)
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	cmd.SetIn(strings.NewReader("SELECT 1;\nDROP TABLE users;\n"))
+	cmd.SetArgs([]string{"mydb", "main", "--org", "acme", "--file", "-"})
+	err := cmd.Execute()
+	var cmdErr *cmdutil.Error
+	if !errors.As(err, &cmdErr) || cmdErr.ExitCode != cmdutil.ActionRequestedExitCode {
+		t.Fatalf("expected action required error, got %v", err)
+	}
+
+	var resp map[string]any
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	if resp["query_kind"] != "destructive" {
+		t.Fatalf("query_kind = %v", resp["query_kind"])
+	}
+}
+
+func TestSQLCmdQueryAndFileAreExclusive(t *testing.T) {
+	format := printer.Human
+	ch := &cmdutil.Helper{
+		Printer: printer.NewPrinter(&format),
+		Config:  &config.Config{Organization: "acme", AccessToken: "token"},
+	}
+	for _, args := range [][]string{
+		{"mydb", "main", "--org", "acme"},
+		{"mydb", "main", "--org", "acme", "--query", "SELECT 1", "--file", "-"},
+	} {
+		cmd := SQLCmd(ch)
+		cmd.SetOut(io.Discard)
+		cmd.SetErr(io.Discard)
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err == nil {
+			t.Fatalf("args %v: expected error", args)
+		}
+	}
+}
+
+func TestSQLCmdTransactionChecksEveryStatementOfQuery(t *testing.T) {
+	format := printer.JSON
+	var out bytes.Buffer
+	ch := &cmdutil.Helper{
+		Printer: printer.NewPrinter(&format),
+		Config:  &config.Config{Organization: "acme", AccessToken: "token"},
+	}
+	ch.Printer.SetResourceOutput(&out)
+	cmd := SQLCmd(ch)
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	cmd.SetArgs([]string{"mydb", "main", "--org", "acme", "--transaction", "--query", "UPDATE users SET x = 1; TRUNCATE users"})
+	err := cmd.Execute()
+	var cmdErr *cmdutil.Error
+	if !errors.As(err, &cmdErr) || cmdErr.ExitCode != cmdutil.ActionRequestedExitCode {
+		t.Fatalf("expected action required error, got %v", err)
+	}
+}
+
+func TestSQLCmdStreamFlagValidation(t *testing.T) {
+	format := printer.Human
+	ch := &cmdutil.Helper{
+		Printer: printer.NewPrinter(&format),
+		Config:  &config.Config{Organization: "acme", AccessToken: "token"},
+	}
+	for _, args := range [][]string{
+		{"mydb", "main", "--org", "acme", "--stream", "csv", "--file", "-"},
+		{"mydb", "main", "--org", "acme", "--stream", "csv", "--transaction", "--query", "SELECT 1"},
+		{"mydb", "main", "--org", "acme", "--stream", "xml", "--query", "SELECT 1"},
+		{"mydb", "main", "--org", "acme", "--max-rows", "-1", "--query", "SELECT 1"},
+		{"mydb", "main", "--org", "acme", "--all-shards", "--stream", "csv", "--query", "SELECT 1"},
+		{"mydb", "main", "--org", "acme", "--all-shards", "--file", "-"},
+		{"mydb", "main", "--org", "acme", "--all-shards", "--concurrency", "0", "--query", "SELECT 1"},
+		{"mydb", "main", "--org", "acme", "--analyze", "--query", "SELECT 1"},
+		{"mydb", "main", "--org", "acme", "--explain", "--file", "-"},
+		{"mydb", "main", "--org", "acme", "--explain", "--stream", "csv", "--query", "SELECT 1"},
+		{"mydb", "main", "--org", "acme", "--explain", "--query", "EXPLAIN SELECT 1"},
+	} {
+		cmd := SQLCmd(ch)
+		cmd.SetOut(io.Discard)
+		cmd.SetErr(io.Discard)
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err == nil {
+			t.Fatalf("args %v: expected error", args)
+		}
+	}
+}
+
+func TestSQLCmdExplainAnalyzeBlocksDestructiveStatement(t *testing.T) {
+	format := printer.JSON
+	var out bytes.Buffer
+	ch := &cmdutil.Helper{
+		Printer: printer.NewPrinter(&format),
+		Config:  &config.Config{Organization: "acme", AccessToken: "token"},
+	}
+	ch.Printer.SetResourceOutput(&out)
+	cmd := SQLCmd(ch)
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	cmd.SetArgs([]string{"mydb", "main", "--org", "acme", "--explain", "--analyze", "--query", "DELETE FROM users"})
+	err := cmd.Execute()
+	var cmdErr *cmdutil.Error
+	if !errors.As(err, &cmdErr) || cmdErr.ExitCode != cmdutil.ActionRequestedExitCode {
+		t.Fatalf("expected action required error, got %v", err)
+	}
+}
+
+func TestSQLSavedListDoesNotRequireOrg(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	project := t.TempDir()
+	if err := os.WriteFile(filepath.Join(project, ".pscale.yml"), []byte("queries:\n  users:\n    description: All users\n    query: SELECT * FROM users\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(project)
+
+	format := printer.JSON
+	var out bytes.Buffer
+	ch := &cmdutil.Helper{
+		Printer: printer.NewPrinter(&format),
+		Config:  &config.Config{},
+	}
+	ch.Printer.SetResourceOutput(&out)
+	cmd := SQLCmd(ch)
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"saved", "list"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+
+	var queries []map[string]any
+	if err := json.Unmarshal(out.Bytes(), &queries); err != nil {
+		t.Fatalf("unmarshal %q: %v", out.String(), err)
+	}
+	if len(queries) != 1 || queries[0]["name"] != "users" || queries[0]["description"] != "All users" {
+		t.Errorf("queries = %v", queries)
+	}
+}
+
+func TestSQLSavedListWithoutQueries(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+
+	format := printer.Human
+	var out bytes.Buffer
+	ch := &cmdutil.Helper{
+		Printer: printer.NewPrinter(&format),
+		Config:  &config.Config{},
+	}
+	ch.Printer.SetHumanOutput(&out)
+	cmd := SQLCmd(ch)
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"saved", "list"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+
+	if got, want := out.String(), "No saved queries found. Add a queries: section to .pscale.yml.\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestSQLCmdUnknownSavedQuery(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+
+	format := printer.Human
+	ch := &cmdutil.Helper{
+		Printer: printer.NewPrinter(&format),
+		Config:  &config.Config{Organization: "acme", AccessToken: "token"},
+	}
+	cmd := SQLCmd(ch)
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"mydb", "main", "--org", "acme", "--saved", "missing"})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), `no saved query named "missing"`) {
+		t.Fatalf("expected unknown saved query error, got %v", err)
+	}
+}
+
+func TestSQLCmdPolicyDenialReturnsJSON(t *testing.T) {
+	policy := filepath.Join(t.TempDir(), "policy.yml")
+	if err := os.WriteFile(policy, []byte("rules:\n  - branches: [main]\n    allow: [read]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	format := printer.JSON
+	var out bytes.Buffer
+	ch := &cmdutil.Helper{
+		Printer: printer.NewPrinter(&format),
+		Config:  &config.Config{Organization: "acme", AccessToken: "token"},
+	}
+	ch.Printer.SetResourceOutput(&out)
+	cmd := SQLCmd(ch)
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	// --force does not override the policy.
+	cmd.SetArgs([]string{"mydb", "main", "--org", "acme", "--role", "admin", "--force", "--policy", policy, "--query", "DELETE FROM users"})
+	err := cmd.Execute()
+	var cmdErr *cmdutil.Error
+	if !errors.As(err, &cmdErr) || cmdErr.ExitCode != cmdutil.FatalErrExitCode {
+		t.Fatalf("expected fatal error, got %v", err)
+	}
+
+	var resp struct {
+		Status          string `json:"status"`
+		PolicyViolation struct {
+			Kind     string `json:"kind"`
+			Category string `json:"category"`
+			Branch   string `json:"branch"`
+			Role     string `json:"role"`
+		} `json:"policy_violation"`
+		Issues []struct {
+			Code string `json:"code"`
+		} `json:"issues"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	if len(resp.Issues) != 1 || resp.Issues[0].Code != "SQL_POLICY_DENIED" {
+		t.Fatalf("issues = %+v", resp.Issues)
+	}
+	v := resp.PolicyViolation
+	if v.Kind != "DELETE" || v.Category != "write" || v.Branch != "main" || v.Role != "admin" {
+		t.Fatalf("policy_violation = %+v", v)
+	}
+}
@@ -0,0 +1,61 @@
+package sql
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/planetscale/cli/internal/cmdutil"
+	"github.com/planetscale/cli/internal/printer"
+	"github.com/planetscale/cli/internal/sqlquery"
+)
+
+// streamQuery streams the rows of a query to the resource output. The ndjson
+// writer ends with the result envelope itself; for csv and tsv, it goes to
+// stderr so the rows stay parseable.
+func streamQuery(cmd *cobra.Command, ch *cmdutil.Helper, opts sqlquery.Options, format string) error {
+	rw, err := sqlquery.NewRowWriter(format, ch.Printer.ResourceOutput())
+	if err != nil {
+		return err
+	}
+
+	result, err := sqlquery.Stream(cmd.Context(), ch, opts, rw)
+	if err != nil && result == nil {
+		return handleExecuteError(ch, err, opts.Database, opts.Branch)
+	}
+	// A query that failed part way through its rows has a result reporting
+	// the error, in the ndjson stream or on stderr.
+	if format == "ndjson" {
+		if err != nil && ch.Printer.Format() == printer.JSON {
+			return cmdutil.JSONReportedError(cmdutil.FatalErrExitCode)
+		}
+		return err
+	}
+
+	switch ch.Printer.Format() {
+	case printer.JSON:
+		enc := json.NewEncoder(cmd.ErrOrStderr())
+		enc.SetIndent("", "  ")
+		if encErr := enc.Encode(result); encErr != nil {
+			return encErr
+		}
+		if err != nil {
+			return cmdutil.JSONReportedError(cmdutil.FatalErrExitCode)
+		}
+	case printer.Human:
+		if err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Returned %d row(s) before the query failed\n", result.RowCount)
+			return err
+		}
+		if result.RowsAffected > 0 && result.RowCount == 0 {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Rows affected: %d\n", result.RowsAffected)
+			return nil
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Returned %d row(s)\n", result.RowCount)
+		if result.Truncated {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Output truncated to %d row(s) by --max-rows\n", opts.MaxRows)
+		}
+	}
+	return err
+}
@@ -198,6 +198,16 @@ func commandFormat(cmd *cobra.Command) string {
 // NewZapLogger returns a logger to be used with the sql-proxy. By default it
 // only outputs error leveled messages, unless debug is true.
 func NewZapLogger(debug bool) *zap.Logger {
+	return newZapLogger(debug, os.Stdout)
+}
+
+// NewStderrZapLogger returns a logger like NewZapLogger that writes to
+// stderr, for commands that stream their result to stdout.
+func NewStderrZapLogger(debug bool) *zap.Logger {
+	return newZapLogger(debug, os.Stderr)
+}
+
+func newZapLogger(debug bool, out zapcore.WriteSyncer) *zap.Logger {
 	encoderCfg := zapcore.EncoderConfig{
 		MessageKey:     "msg",
 		LevelKey:       "level",
@@ -213,7 +223,7 @@ func NewZapLogger(debug bool) *zap.Logger {
 		level = zap.DebugLevel
 	}
 
-	logger := zap.New(zapcore.NewCore(zapcore.NewConsoleEncoder(encoderCfg), os.Stdout, level))
+	logger := zap.New(zapcore.NewCore(zapcore.NewConsoleEncoder(encoderCfg), out, level))
 
 	return logger
 }
@@ -143,6 +143,13 @@ func GlobalJSONError(err error) JSONErrorResponse {
 			"Re-run the same command with --force after approval",
 		}
 
+	// SQL policy denials (pscale inspect): --force does not override them.
+	case strings.Contains(msg, "denied by the SQL policy"):
+		code = "SQL_POLICY_DENIED"
+		nextSteps = []string{
+			"Ask the user to change the sql-policy of the project .pscale.yml or the --policy file",
+		}
+
 	// Resource lookups that failed: discovery commands are the way forward.
 	case strings.Contains(msg, "does not exist"):
 		code = "NOT_FOUND"
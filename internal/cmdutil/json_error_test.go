@@ -79,6 +79,16 @@ func TestGlobalJSONErrorConfirmationRequired(t *testing.T) {
 	}
 }
 
+func TestGlobalJSONErrorSQLPolicyDenied(t *testing.T) {
+	resp := GlobalJSONError(errors.New("DROP TABLE statement denied by the SQL policy: DROP TABLE statements are denied on every branch"))
+	if resp.Status != "error" {
+		t.Fatalf("status = %q", resp.Status)
+	}
+	if resp.Code() != "SQL_POLICY_DENIED" {
+		t.Fatalf("code = %q", resp.Code())
+	}
+}
+
 func TestGlobalJSONErrorNotFound(t *testing.T) {
 	resp := GlobalJSONError(errors.New("database foo does not exist in organization bar"))
 	if resp.Code() != "NOT_FOUND" {
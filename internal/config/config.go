@@ -138,6 +138,19 @@ func RootGitRepoDir() (string, error) {
 	return strings.TrimSuffix(string(out), "\n"), nil
 }
 
+// ProjectDir returns the directory of the project .pscale.yml: the root of the
+// current git repository or, outside of one, the working directory. It returns
+// "" if neither can be determined.
+func ProjectDir() string {
+	if rootDir, err := RootGitRepoDir(); err == nil {
+		return rootDir
+	}
+	if localDir, err := LocalDir(); err == nil {
+		return localDir
+	}
+	return ""
+}
+
 func ProjectConfigFile() string {
 	return projectConfigName
 }
@@ -21,6 +21,13 @@ var projectConfigAllowedKeys = map[string]struct{}{
 	"branch":   {},
 }
 
+// projectConfigCommandKeys are accepted in a project .pscale.yml but not merged
+// into the configuration; the commands using them read the file themselves.
+var projectConfigCommandKeys = map[string]struct{}{
+	"sql-policy": {},
+	"queries":    {},
+}
+
 // FilterProjectConfig keeps only allowlisted project settings from raw YAML.
 // Non-allowlisted keys are returned in ignored (sorted) for caller warnings.
 func FilterProjectConfig(raw map[string]interface{}) (allowed map[string]interface{}, ignored []string) {
@@ -31,6 +38,9 @@ func FilterProjectConfig(raw map[string]interface{}) (allowed map[string]interfa
 			allowed[key] = v
 			continue
 		}
+		if _, ok := projectConfigCommandKeys[key]; ok {
+			continue
+		}
 		ignored = append(ignored, k)
 	}
 	sort.Strings(ignored)
@@ -80,6 +90,6 @@ func WarnIgnoredProjectConfigKeys(path string, ignored []string) {
 	if len(ignored) == 0 {
 		return
 	}
-	fmt.Fprintf(os.Stderr, "Warning: ignoring non-allowlisted keys in %s: %s (project config may only set: org, database, branch)\n",
+	fmt.Fprintf(os.Stderr, "Warning: ignoring non-allowlisted keys in %s: %s (project config may only set: org, database, branch, sql-policy, queries)\n",
 		path, strings.Join(ignored, ", "))
 }
@@ -126,3 +126,17 @@ func TestMergeProjectConfigDoesNotOverrideHomeApiURL(t *testing.T) {
 		t.Fatalf("project api-url leaked into viper: %q", got)
 	}
 }
+
+func TestFilterProjectConfigCommandKeys(t *testing.T) {
+	allowed, ignored := FilterProjectConfig(map[string]interface{}{
+		"org":        "acme",
+		"sql-policy": map[string]interface{}{"deny": []string{"ddl"}},
+		"queries":    map[string]interface{}{"count": map[string]interface{}{"query": "SELECT 1"}},
+	})
+	if len(allowed) != 1 || allowed["org"] != "acme" {
+		t.Fatalf("allowed = %#v, want org only", allowed)
+	}
+	if len(ignored) != 0 {
+		t.Fatalf("sql-policy and queries should not be reported as ignored, got %#v", ignored)
+	}
+}
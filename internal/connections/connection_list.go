@@ -115,6 +115,11 @@ func compareDuration(a, b Connection) int {
 	return a.PID - b.PID
 }
 
+// HasWork reports whether c is running a statement rather than sitting idle.
+func (c Connection) HasWork() bool {
+	return durationSortHasWork(c)
+}
+
 func durationSortHasWork(c Connection) bool {
 	state := strings.ToLower(strings.TrimSpace(c.State))
 	if state == "sleep" || state == "idle" || strings.HasPrefix(state, "idle ") {
@@ -0,0 +1,503 @@
+package history
+
+import (
+	"cmp"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/planetscale/cli/internal/connections"
+)
+
+// Analysis summarizes a capture trace for offline incident review.
+type Analysis struct {
+	From         time.Time
+	To           time.Time
+	Samples      int
+	DatabaseKind connections.DatabaseKind
+
+	// LongestQueries are the longest-running queries seen, longest first.
+	LongestQueries []QuerySummary
+	// Blockers are the sessions that held others up, by total blocked time.
+	Blockers []BlockerSummary
+	// LockWaits are the samples in which a session waited on another, in
+	// capture order.
+	LockWaits []LockWaitSample
+	// ConnectionCounts are the connections per user, application and client
+	// address, busiest first within each dimension.
+	ConnectionCounts []ConnectionCount
+	// IdleInTransaction are the sessions seen idle inside an open
+	// transaction, oldest transaction first.
+	IdleInTransaction []IdleTransaction
+}
+
+// QuerySummary is one query execution tracked across samples.
+type QuerySummary struct {
+	PID             int
+	Instance        string
+	Username        string
+	ApplicationName string
+	ClientAddr      string
+	State           string
+	Duration        time.Duration
+	FirstSeen       time.Time
+	LastSeen        time.Time
+	QueryText       string
+}
+
+// BlockerSummary is a session that blocked others. BlockedTime is the time
+// sessions spent waiting on it, directly or through a chain, summed over the
+// samples: two sessions waiting on it for ten seconds count twenty.
+type BlockerSummary struct {
+	PID             int
+	Instance        string
+	Username        string
+	ApplicationName string
+	QueryText       string
+	BlockedTime     time.Duration
+	MaxBlocked      int
+	FirstSeen       time.Time
+	LastSeen        time.Time
+}
+
+// LockWaitSample is the lock-wait state of one sample.
+type LockWaitSample struct {
+	At      time.Time
+	Waiting int
+	Chains  []LockChain
+}
+
+// LockChain is a path from a blocking session to a session waiting on it,
+// blocker first. Wait is how long the last session had been running.
+type LockChain struct {
+	Instance string
+	PIDs     []int
+	Wait     time.Duration
+}
+
+// ConnectionCount is the number of connections sharing a user, application or
+// client address.
+type ConnectionCount struct {
+	Dimension string
+	Value     string
+	Peak      int
+	PeakAt    time.Time
+	Average   float64
+}
+
+// IdleTransaction is a session seen idle inside an open transaction.
+// TransactionAge is the longest the transaction was seen open, and IdleTime
+// the longest the session was seen idle in it.
+type IdleTransaction struct {
+	PID             int
+	Instance        string
+	Username        string
+	ApplicationName string
+	ClientAddr      string
+	TransactionAge  time.Duration
+	IdleTime        time.Duration
+	Samples         int
+	FirstSeen       time.Time
+	LastSeen        time.Time
+	QueryText       string
+}
+
+// Connection count dimensions.
+const (
+	DimensionUser        = "user"
+	DimensionApplication = "application"
+	DimensionClientAddr  = "client_addr"
+)
+
+// AnalysisOptions configures Analyze.
+type AnalysisOptions struct {
+	// Limit caps the longest queries, blockers and idle transactions, and
+	// the connection counts of each dimension. Zero keeps all of them.
+	Limit int
+}
+
+type sessionKey struct {
+	instance string
+	pid      int
+}
+
+// Analyze summarizes captures, which must be in capture order. Each sample
+// stands for the time until the next one, and the last sample for as long as
+// the one before it.
+func Analyze(captures []Capture, opts AnalysisOptions) Analysis {
+	a := Analysis{Samples: len(captures)}
+	if len(captures) == 0 {
+		return a
+	}
+	a.From = captureTime(captures[0])
+	a.To = captureTime(captures[len(captures)-1])
+
+	queries := make(map[string]*QuerySummary)
+	blockers := make(map[sessionKey]*BlockerSummary)
+	idle := make(map[string]*IdleTransaction)
+	counts := newConnectionCounter()
+
+	for i, capture := range captures {
+		at := captureTime(capture)
+		if a.DatabaseKind == "" {
+			a.DatabaseKind = capture.List.DatabaseKind
+		}
+		weight := sampleWeight(captures, i)
+		conns := capture.List.Connections
+
+		counts.add(at, conns)
+		for _, conn := range conns {
+			if isIdleInTransaction(conn) {
+				trackIdleTransaction(idle, at, conn)
+				continue
+			}
+			if hasWork(conn) {
+				trackQuery(queries, at, conn)
+			}
+		}
+
+		if sample, ok := lockWaitSample(at, conns); ok {
+			a.LockWaits = append(a.LockWaits, sample)
+			trackBlockers(blockers, at, weight, conns)
+		}
+	}
+
+	a.LongestQueries = sortedValues(queries, func(x, y *QuerySummary) int {
+		return cmp.Or(cmp.Compare(y.Duration, x.Duration), cmp.Compare(x.PID, y.PID))
+	}, opts.Limit)
+	a.Blockers = sortedValues(blockers, func(x, y *BlockerSummary) int {
+		return cmp.Or(cmp.Compare(y.BlockedTime, x.BlockedTime), cmp.Compare(y.MaxBlocked, x.MaxBlocked), cmp.Compare(x.PID, y.PID))
+	}, opts.Limit)
+	a.IdleInTransaction = sortedValues(idle, func(x, y *IdleTransaction) int {
+		return cmp.Or(cmp.Compare(y.TransactionAge, x.TransactionAge), cmp.Compare(y.IdleTime, x.IdleTime), cmp.Compare(x.PID, y.PID))
+	}, opts.Limit)
+	a.ConnectionCounts = counts.summarize(len(captures), opts.Limit)
+	return a
+}
+
+func captureTime(capture Capture) time.Time {
+	if capture.At.IsZero() {
+		return capture.List.CapturedAt
+	}
+	return capture.At
+}
+
+// sampleWeight is the time sample i stands for.
+func sampleWeight(captures []Capture, i int) time.Duration {
+	switch {
+	case len(captures) < 2:
+		return 0
+	case i+1 < len(captures):
+		return captureTime(captures[i+1]).Sub(captureTime(captures[i]))
+	default:
+		return captureTime(captures[i]).Sub(captureTime(captures[i-1]))
+	}
+}
+
+func normalizedState(conn connections.Connection) string {
+	return strings.ToLower(strings.TrimSpace(conn.State))
+}
+
+// hasWork reports whether conn is running a statement rather than sitting
+// idle.
+func hasWork(conn connections.Connection) bool {
+	state := normalizedState(conn)
+	if state == "sleep" || state == "idle" || strings.HasPrefix(state, "idle ") {
+		return false
+	}
+	return strings.TrimSpace(conn.QueryText) != ""
+}
+
+// isIdleInTransaction reports whether conn holds an open transaction without
+// running a statement: Postgres reports the state directly, MySQL as a
+// sleeping connection with a transaction.
+func isIdleInTransaction(conn connections.Connection) bool {
+	state := normalizedState(conn)
+	if strings.HasPrefix(state, "idle in transaction") {
+		return true
+	}
+	return state == "sleep" && (conn.TransactionID != nil || conn.XactStart != nil)
+}
+
+func trackQuery(queries map[string]*QuerySummary, at time.Time, conn connections.Connection) {
+	key := queryKey(conn)
+	q, ok := queries[key]
+	if !ok {
+		q = &QuerySummary{PID: conn.PID, Instance: conn.Instance, FirstSeen: at}
+		queries[key] = q
+	}
+	q.Username = conn.Username
+	q.ApplicationName = conn.ApplicationName
+	q.ClientAddr = conn.ClientAddr
+	q.State = conn.State
+	q.LastSeen = at
+	q.QueryText = conn.QueryText
+	q.Duration = max(q.Duration, conn.Duration)
+}
+
+// queryKey identifies one execution of a query: a session runs one query at
+// a time, so the session and the query start tell executions apart.
+func queryKey(conn connections.Connection) string {
+	id := conn.QueryText
+	switch {
+	case conn.QueryStart != nil:
+		id = conn.QueryStart.UTC().Format(time.RFC3339Nano)
+	case conn.QueryID != nil:
+		id = *conn.QueryID
+	}
+	return strings.Join([]string{conn.Instance, strconv.Itoa(conn.PID), id}, "\x00")
+}
+
+func trackIdleTransaction(idle map[string]*IdleTransaction, at time.Time, conn connections.Connection) {
+	id := ""
+	switch {
+	case conn.XactStart != nil:
+		id = conn.XactStart.UTC().Format(time.RFC3339Nano)
+	case conn.TransactionID != nil:
+		id = *conn.TransactionID
+	}
+	key := strings.Join([]string{conn.Instance, strconv.Itoa(conn.PID), id}, "\x00")
+
+	t, ok := idle[key]
+	if !ok {
+		t = &IdleTransaction{PID: conn.PID, Instance: conn.Instance, FirstSeen: at}
+		idle[key] = t
+	}
+	t.Username = conn.Username
+	t.ApplicationName = conn.ApplicationName
+	t.ClientAddr = conn.ClientAddr
+	t.Samples++
+	t.LastSeen = at
+	if conn.QueryText != "" {
+		t.QueryText = conn.QueryText
+	}
+	t.IdleTime = max(t.IdleTime, conn.Duration)
+	if conn.XactStart != nil {
+		t.TransactionAge = max(t.TransactionAge, at.Sub(*conn.XactStart))
+	}
+	// Without a transaction start, the transaction is at least as old as
+	// the time the session has been idle in it.
+	t.TransactionAge = max(t.TransactionAge, t.IdleTime)
+}
+
+// byInstance groups conns by instance: PIDs are only unique within one.
+func byInstance(conns []connections.Connection) map[string][]connections.Connection {
+	groups := make(map[string][]connections.Connection)
+	for _, conn := range conns {
+		groups[conn.Instance] = append(groups[conn.Instance], conn)
+	}
+	return groups
+}
+
+func trackBlockers(blockers map[sessionKey]*BlockerSummary, at time.Time, weight time.Duration, conns []connections.Connection) {
+	for instance, group := range byInstance(conns) {
+		sessions := make(map[int]connections.Connection, len(group))
+		for _, conn := range group {
+			sessions[conn.PID] = conn
+		}
+		for pid, blocked := range connections.BlockingCounts(withAbsentBlockers(group)) {
+			key := sessionKey{instance: instance, pid: pid}
+			b, ok := blockers[key]
+			if !ok {
+				b = &BlockerSummary{PID: pid, Instance: instance, FirstSeen: at}
+				blockers[key] = b
+			}
+			if conn, ok := sessions[pid]; ok {
+				b.Username = conn.Username
+				b.ApplicationName = conn.ApplicationName
+				if conn.QueryText != "" {
+					b.QueryText = conn.QueryText
+				}
+			}
+			b.LastSeen = at
+			b.BlockedTime += time.Duration(blocked) * weight
+			b.MaxBlocked = max(b.MaxBlocked, blocked)
+		}
+	}
+}
+
+// withAbsentBlockers adds a placeholder for each blocker missing from group,
+// so BlockingCounts counts the sessions it blocks too.
+func withAbsentBlockers(group []connections.Connection) []connections.Connection {
+	present := make(map[int]bool, len(group))
+	for _, conn := range group {
+		present[conn.PID] = true
+	}
+	out := slices.Clip(group)
+	for _, conn := range group {
+		for _, pid := range conn.BlockedBy {
+			if !present[pid] {
+				present[pid] = true
+				out = append(out, connections.Connection{PID: pid, Instance: conn.Instance})
+			}
+		}
+	}
+	return out
+}
+
+// lockWaitSample returns the lock-wait chains of one sample, and false when
+// no session was waiting.
+func lockWaitSample(at time.Time, conns []connections.Connection) (LockWaitSample, bool) {
+	sample := LockWaitSample{At: at}
+	groups := byInstance(conns)
+	instances := make([]string, 0, len(groups))
+	for instance := range groups {
+		instances = append(instances, instance)
+	}
+	slices.Sort(instances)
+
+	for _, instance := range instances {
+		group := groups[instance]
+		downstream := make(map[int][]int)
+		waits := make(map[int]time.Duration)
+		blocked := make(map[int]bool)
+		for _, conn := range group {
+			waits[conn.PID] = conn.Duration
+			if len(conn.BlockedBy) == 0 {
+				continue
+			}
+			blocked[conn.PID] = true
+			sample.Waiting++
+			for _, blocker := range conn.BlockedBy {
+				downstream[blocker] = append(downstream[blocker], conn.PID)
+			}
+		}
+		if len(blocked) == 0 {
+			continue
+		}
+
+		var roots []int
+		for blocker := range downstream {
+			if !blocked[blocker] {
+				roots = append(roots, blocker)
+			}
+		}
+		slices.Sort(roots)
+		// Sessions in a cycle have no unblocked root: start the remaining
+		// chains from the lowest PID not reached yet.
+		var rest []int
+		for pid := range blocked {
+			rest = append(rest, pid)
+		}
+		slices.Sort(rest)
+
+		seen := make(map[int]bool)
+		var walk func(path []int)
+		walk = func(path []int) {
+			pid := path[len(path)-1]
+			seen[pid] = true
+			var next []int
+			for _, child := range downstream[pid] {
+				if !slices.Contains(path, child) {
+					next = append(next, child)
+				}
+			}
+			if len(next) == 0 {
+				if len(path) > 1 {
+					sample.Chains = append(sample.Chains, LockChain{Instance: instance, PIDs: slices.Clone(path), Wait: waits[pid]})
+				}
+				return
+			}
+			slices.Sort(next)
+			next = slices.Compact(next)
+			for _, child := range next {
+				walk(append(path, child))
+			}
+		}
+		for _, root := range roots {
+			walk([]int{root})
+		}
+		for _, pid := range rest {
+			if !seen[pid] {
+				walk([]int{pid})
+			}
+		}
+	}
+	return sample, sample.Waiting > 0
+}
+
+type connectionCounter struct {
+	totals map[string]map[string]int
+	peaks  map[string]map[string]*ConnectionCount
+}
+
+func newConnectionCounter() *connectionCounter {
+	c := &connectionCounter{
+		totals: make(map[string]map[string]int),
+		peaks:  make(map[string]map[string]*ConnectionCount),
+	}
+	for _, dimension := range []string{DimensionUser, DimensionApplication, DimensionClientAddr} {
+		c.totals[dimension] = make(map[string]int)
+		c.peaks[dimension] = make(map[string]*ConnectionCount)
+	}
+	return c
+}
+
+func (c *connectionCounter) add(at time.Time, conns []connections.Connection) {
+	sample := make(map[string]map[string]int, len(c.totals))
+	for dimension := range c.totals {
+		sample[dimension] = make(map[string]int)
+	}
+	for _, conn := range conns {
+		sample[DimensionUser][conn.Username]++
+		sample[DimensionApplication][conn.ApplicationName]++
+		sample[DimensionClientAddr][clientHost(conn.ClientAddr)]++
+	}
+	for dimension, values := range sample {
+		for value, n := range values {
+			c.totals[dimension][value] += n
+			peak, ok := c.peaks[dimension][value]
+			if !ok {
+				peak = &ConnectionCount{Dimension: dimension, Value: value}
+				c.peaks[dimension][value] = peak
+			}
+			if n > peak.Peak {
+				peak.Peak = n
+				peak.PeakAt = at
+			}
+		}
+	}
+}
+
+func (c *connectionCounter) summarize(samples, limit int) []ConnectionCount {
+	var out []ConnectionCount
+	for _, dimension := range []string{DimensionUser, DimensionApplication, DimensionClientAddr} {
+		counts := sortedValues(c.peaks[dimension], func(x, y *ConnectionCount) int {
+			return cmp.Or(cmp.Compare(y.Peak, x.Peak), cmp.Compare(c.totals[dimension][y.Value], c.totals[dimension][x.Value]), strings.Compare(x.Value, y.Value))
+		}, limit)
+		for _, count := range counts {
+			count.Average = float64(c.totals[dimension][count.Value]) / float64(samples)
+			out = append(out, count)
+		}
+	}
+	return out
+}
+
+// clientHost drops the port of a client address, so the connections of one
+// client host count together.
+func clientHost(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// sortedValues returns the values of m sorted by compare, at most limit of
+// them when limit is positive.
+func sortedValues[K comparable, V any](m map[K]*V, compare func(x, y *V) int, limit int) []V {
+	values := make([]*V, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	slices.SortFunc(values, compare)
+	if limit > 0 && len(values) > limit {
+		values = values[:limit]
+	}
+	out := make([]V, 0, len(values))
+	for _, v := range values {
+		out = append(out, *v)
+	}
+	return out
+}
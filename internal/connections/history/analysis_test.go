@@ -0,0 +1,137 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/planetscale/cli/internal/connections"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestAnalyzeSummarizesTrace(t *testing.T) {
+	c := qt.New(t)
+	base := time.Date(2026, 4, 28, 15, 0, 0, 0, time.UTC)
+	xactStart := base.Add(-time.Minute)
+	queryStart := base.Add(-3 * time.Second)
+
+	sample := func(offset time.Duration, conns ...connections.Connection) Capture {
+		return NewCapture(connections.ConnectionList{
+			CapturedAt:   base.Add(offset),
+			DatabaseKind: connections.DatabaseKindPostgreSQL,
+			Connections:  conns,
+		})
+	}
+	blocker := connections.Connection{PID: 101, Instance: "primary", Username: "app", ApplicationName: "web", ClientAddr: "10.0.0.1:5000", State: "idle in transaction", XactStart: &xactStart, Duration: 20 * time.Second, QueryText: "UPDATE widgets SET n = 1 WHERE id = 1"}
+	waiter := func(pid int, blockedBy int, d time.Duration) connections.Connection {
+		return connections.Connection{PID: pid, Instance: "primary", Username: "app", ApplicationName: "worker", ClientAddr: "10.0.0.2:6000", State: "active", QueryStart: &queryStart, Duration: d, BlockedBy: []int{blockedBy}, QueryText: "UPDATE widgets SET n = 2 WHERE id = 1"}
+	}
+
+	captures := []Capture{
+		sample(0, blocker, waiter(102, 101, 3*time.Second)),
+		sample(2*time.Second, blocker, waiter(102, 101, 5*time.Second), waiter(103, 102, time.Second)),
+		sample(4*time.Second, connections.Connection{PID: 104, Instance: "primary", Username: "report", ApplicationName: "web", ClientAddr: "10.0.0.1:5001", State: "active", Duration: 9 * time.Second, QueryText: "SELECT count(*) FROM widgets"}),
+	}
+
+	a := Analyze(captures, AnalysisOptions{})
+
+	c.Assert(a.Samples, qt.Equals, 3)
+	c.Assert(a.From, qt.Equals, base)
+	c.Assert(a.To, qt.Equals, base.Add(4*time.Second))
+	c.Assert(a.DatabaseKind, qt.Equals, connections.DatabaseKindPostgreSQL)
+
+	c.Assert(a.LongestQueries, qt.HasLen, 3)
+	c.Assert(a.LongestQueries[0].PID, qt.Equals, 104)
+	c.Assert(a.LongestQueries[0].Duration, qt.Equals, 9*time.Second)
+	// PID 102 is tracked as one execution across both samples.
+	c.Assert(a.LongestQueries[1].PID, qt.Equals, 102)
+	c.Assert(a.LongestQueries[1].Duration, qt.Equals, 5*time.Second)
+	c.Assert(a.LongestQueries[1].FirstSeen, qt.Equals, base)
+	c.Assert(a.LongestQueries[1].LastSeen, qt.Equals, base.Add(2*time.Second))
+
+	// 101 blocks one session for 2s, then two sessions for 2s; 102 blocks
+	// one session for 2s.
+	c.Assert(a.Blockers, qt.HasLen, 2)
+	c.Assert(a.Blockers[0].PID, qt.Equals, 101)
+	c.Assert(a.Blockers[0].BlockedTime, qt.Equals, 6*time.Second)
+	c.Assert(a.Blockers[0].MaxBlocked, qt.Equals, 2)
+	c.Assert(a.Blockers[0].QueryText, qt.Equals, "UPDATE widgets SET n = 1 WHERE id = 1")
+	c.Assert(a.Blockers[1].PID, qt.Equals, 102)
+	c.Assert(a.Blockers[1].BlockedTime, qt.Equals, 2*time.Second)
+
+	c.Assert(a.LockWaits, qt.HasLen, 2)
+	c.Assert(a.LockWaits[1].At, qt.Equals, base.Add(2*time.Second))
+	c.Assert(a.LockWaits[1].Waiting, qt.Equals, 2)
+	c.Assert(a.LockWaits[1].Chains, qt.DeepEquals, []LockChain{{Instance: "primary", PIDs: []int{101, 102, 103}, Wait: time.Second}})
+
+	c.Assert(a.IdleInTransaction, qt.HasLen, 1)
+	c.Assert(a.IdleInTransaction[0].PID, qt.Equals, 101)
+	c.Assert(a.IdleInTransaction[0].Samples, qt.Equals, 2)
+	c.Assert(a.IdleInTransaction[0].TransactionAge, qt.Equals, time.Minute+2*time.Second)
+	c.Assert(a.IdleInTransaction[0].IdleTime, qt.Equals, 20*time.Second)
+
+	counts := make(map[string]ConnectionCount)
+	for _, count := range a.ConnectionCounts {
+		counts[count.Dimension+"="+count.Value] = count
+	}
+	c.Assert(counts["user=app"].Peak, qt.Equals, 3)
+	c.Assert(counts["user=app"].PeakAt, qt.Equals, base.Add(2*time.Second))
+	c.Assert(counts["user=app"].Average, qt.Equals, 5.0/3)
+	// Client ports are dropped, so both web connections share a host.
+	c.Assert(counts["client_addr=10.0.0.1"].Peak, qt.Equals, 1)
+	c.Assert(counts["client_addr=10.0.0.2"].Peak, qt.Equals, 2)
+}
+
+func TestAnalyzeLimitsRankings(t *testing.T) {
+	c := qt.New(t)
+	at := time.Date(2026, 4, 28, 15, 0, 0, 0, time.UTC)
+	var conns []connections.Connection
+	for pid := 1; pid <= 5; pid++ {
+		conns = append(conns, connections.Connection{PID: pid, Username: "u" + string(rune('0'+pid)), State: "active", Duration: time.Duration(pid) * time.Second, QueryText: "SELECT 1"})
+	}
+
+	a := Analyze([]Capture{NewCapture(connections.ConnectionList{CapturedAt: at, Connections: conns})}, AnalysisOptions{Limit: 2})
+
+	c.Assert(a.LongestQueries, qt.HasLen, 2)
+	c.Assert(a.LongestQueries[0].PID, qt.Equals, 5)
+	c.Assert(a.LongestQueries[1].PID, qt.Equals, 4)
+	users := 0
+	for _, count := range a.ConnectionCounts {
+		if count.Dimension == DimensionUser {
+			users++
+		}
+	}
+	c.Assert(users, qt.Equals, 2)
+}
+
+func TestAnalyzeReportsLockCycles(t *testing.T) {
+	c := qt.New(t)
+	at := time.Date(2026, 4, 28, 15, 0, 0, 0, time.UTC)
+	list := connections.ConnectionList{CapturedAt: at, Connections: []connections.Connection{
+		{PID: 7, State: "active", BlockedBy: []int{8}, QueryText: "UPDATE a SET x = 1"},
+		{PID: 8, State: "active", BlockedBy: []int{7}, QueryText: "UPDATE b SET x = 1"},
+	}}
+
+	a := Analyze([]Capture{NewCapture(list)}, AnalysisOptions{})
+
+	c.Assert(a.LockWaits, qt.HasLen, 1)
+	c.Assert(a.LockWaits[0].Chains, qt.HasLen, 1)
+	c.Assert(a.LockWaits[0].Chains[0].PIDs, qt.DeepEquals, []int{7, 8})
+}
+
+func TestAnalyzeTreatsSleepingMySQLTransactionsAsIdle(t *testing.T) {
+	c := qt.New(t)
+	at := time.Date(2026, 4, 28, 15, 0, 0, 0, time.UTC)
+	trx := "421"
+	list := connections.ConnectionList{CapturedAt: at, DatabaseKind: connections.DatabaseKindMySQL, Connections: []connections.Connection{
+		{PID: 1, State: "Sleep", TransactionID: &trx, Duration: 30 * time.Second},
+		{PID: 2, State: "Sleep", Duration: time.Hour},
+	}}
+
+	a := Analyze([]Capture{NewCapture(list)}, AnalysisOptions{})
+
+	c.Assert(a.IdleInTransaction, qt.HasLen, 1)
+	c.Assert(a.IdleInTransaction[0].PID, qt.Equals, 1)
+	c.Assert(a.IdleInTransaction[0].TransactionAge, qt.Equals, 30*time.Second)
+	c.Assert(a.LongestQueries, qt.HasLen, 0)
+}
@@ -0,0 +1,391 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is the codec a capture file is written with.
+type Compression string
+
+const (
+	CompressionNone Compression = "none"
+	CompressionGzip Compression = "gzip"
+	CompressionZstd Compression = "zstd"
+)
+
+// ParseCompression parses a --capture-compression value.
+func ParseCompression(value string) (Compression, error) {
+	switch c := Compression(strings.ToLower(value)); c {
+	case CompressionNone, CompressionGzip, CompressionZstd:
+		return c, nil
+	}
+	return "", fmt.Errorf("invalid compression %q: must be none, gzip, or zstd", value)
+}
+
+// CompressionForPath infers the codec from a capture path's extension: .gz
+// is gzip, .zst is zstd, and anything else is uncompressed.
+func CompressionForPath(path string) Compression {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gz", ".gzip":
+		return CompressionGzip
+	case ".zst", ".zstd":
+		return CompressionZstd
+	}
+	return CompressionNone
+}
+
+// CaptureFileOptions configures a capture file opened by OpenCaptureFile.
+type CaptureFileOptions struct {
+	Compression Compression
+	// RotateSize rotates the file once it reaches this many bytes on disk.
+	// Zero disables size-based rotation.
+	RotateSize int64
+	// RotateInterval rotates the file once it has been written to for this
+	// long. Zero disables time-based rotation.
+	RotateInterval time.Duration
+	// Retain is the number of rotated files kept besides the active one; the
+	// oldest are removed past it. Zero keeps every rotated file.
+	Retain int
+
+	now func() time.Time
+}
+
+// Rotates reports whether the options rotate the file at all.
+func (o CaptureFileOptions) Rotates() bool {
+	return o.RotateSize > 0 || o.RotateInterval > 0
+}
+
+// CaptureFile is a capture destination on disk, optionally compressed and
+// rotated. The active segment is always path; on rotation it is renamed to
+// path.1, and older segments shift to path.2, path.3, and so on, the way
+// logrotate numbers files. A CaptureWriter on a CaptureFile starts every
+// segment with the trace header, so each file also reads on its own.
+type CaptureFile struct {
+	path string
+	opts CaptureFileOptions
+
+	file    *os.File
+	counter *countingWriter
+	encoder io.WriteCloser
+	opened  time.Time
+}
+
+// OpenCaptureFile opens path for appending captures. Appending to an existing
+// compressed file adds a new gzip member or zstd frame, which readers decode
+// as one stream.
+func OpenCaptureFile(path string, opts CaptureFileOptions) (*CaptureFile, error) {
+	if opts.Compression == "" {
+		opts.Compression = CompressionNone
+	}
+	if opts.now == nil {
+		opts.now = time.Now
+	}
+	f := &CaptureFile{path: path, opts: opts}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *CaptureFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	f.file = file
+	f.counter = &countingWriter{writer: file, n: info.Size()}
+	f.opened = f.opts.now()
+
+	switch f.opts.Compression {
+	case CompressionGzip:
+		f.encoder = gzip.NewWriter(f.counter)
+	case CompressionZstd:
+		encoder, err := zstd.NewWriter(f.counter, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			_ = file.Close()
+			return err
+		}
+		f.encoder = encoder
+	default:
+		f.encoder = nopWriteCloser{f.counter}
+	}
+	return nil
+}
+
+func (f *CaptureFile) Write(p []byte) (int, error) {
+	return f.encoder.Write(p)
+}
+
+// Flush pushes buffered compressed data to disk, so a capture killed between
+// records loses at most the record being written.
+func (f *CaptureFile) Flush() error {
+	if flusher, ok := f.encoder.(interface{ Flush() error }); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+func (f *CaptureFile) Close() error {
+	encErr := f.encoder.Close()
+	closeErr := f.file.Close()
+	if encErr != nil {
+		return encErr
+	}
+	return closeErr
+}
+
+// ShouldRotate reports whether the active segment has reached the size or age
+// limit.
+func (f *CaptureFile) ShouldRotate() bool {
+	if f.opts.RotateSize > 0 && f.counter.n >= f.opts.RotateSize {
+		return true
+	}
+	return f.opts.RotateInterval > 0 && f.opts.now().Sub(f.opened) >= f.opts.RotateInterval
+}
+
+// Rotate closes the active segment, shifts it and older segments up by one,
+// removes segments past the retention count, and opens a fresh active
+// segment.
+func (f *CaptureFile) Rotate() error {
+	if err := f.Close(); err != nil {
+		return err
+	}
+	rotated, err := rotatedSegments(f.path)
+	if err != nil {
+		return err
+	}
+	for i := len(rotated) - 1; i >= 0; i-- {
+		n := rotated[i].n
+		if f.opts.Retain > 0 && n >= f.opts.Retain {
+			if err := os.Remove(rotated[i].path); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.Rename(rotated[i].path, segmentPath(f.path, n+1)); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(f.path, segmentPath(f.path, 1)); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+type countingWriter struct {
+	writer io.Writer
+	n      int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+type segment struct {
+	path string
+	n    int
+}
+
+func segmentPath(path string, n int) string {
+	return path + "." + strconv.Itoa(n)
+}
+
+// rotatedSegments returns the rotated segments of path, path.1 first.
+func rotatedSegments(path string) ([]segment, error) {
+	var segments []segment
+	for n := 1; ; n++ {
+		p := segmentPath(path, n)
+		if _, err := os.Stat(p); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return segments, nil
+			}
+			return nil, err
+		}
+		segments = append(segments, segment{path: p, n: n})
+	}
+}
+
+// TraceFiles returns the files of the trace at path, oldest first: rotated
+// segments from the highest number down, then path itself. A rotated set
+// whose active segment is missing, for example after the daemon stopped
+// mid-rotation, still lists its rotated segments.
+func TraceFiles(path string) ([]string, error) {
+	rotated, err := rotatedSegments(path)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, len(rotated)+1)
+	for i := len(rotated) - 1; i >= 0; i-- {
+		files = append(files, rotated[i].path)
+	}
+	if _, err := os.Stat(path); err == nil || len(files) == 0 {
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+// OpenTrace opens the trace at path and any rotated segments beside it as a
+// single stream, oldest first. Compressed segments are decompressed, so the
+// result reads as one uncompressed trace with a header at the top of each
+// segment.
+func OpenTrace(path string) (io.ReadCloser, error) {
+	files, err := TraceFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	// Check the newest file up front so a missing trace fails here rather
+	// than on the first read.
+	if _, err := os.Stat(files[len(files)-1]); err != nil {
+		return nil, err
+	}
+	return &traceReader{files: files}, nil
+}
+
+// traceReader streams the segments of a trace one after another, ending each
+// with a newline so a segment cut off mid-record can't run into the header of
+// the next.
+type traceReader struct {
+	files   []string
+	file    *os.File
+	current io.Reader
+	last    byte
+}
+
+func (r *traceReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.files) == 0 {
+				return 0, io.EOF
+			}
+			file, err := os.Open(r.files[0])
+			if err != nil {
+				return 0, err
+			}
+			r.files = r.files[1:]
+			r.file = file
+			r.current = newDecompressingReader(file)
+			r.last = '\n'
+		}
+
+		n, err := r.current.Read(p)
+		if n > 0 {
+			r.last = p[n-1]
+			return n, nil
+		}
+		if err == nil {
+			continue
+		}
+		if err != io.EOF && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, err
+		}
+		if closeErr := r.closeSegment(); closeErr != nil {
+			return 0, closeErr
+		}
+		// The last segment keeps its torn tail, which CaptureReader already
+		// treats as the end of the trace.
+		if r.last != '\n' && len(r.files) > 0 && len(p) > 0 {
+			r.last = '\n'
+			p[0] = '\n'
+			return 1, nil
+		}
+	}
+}
+
+func (r *traceReader) closeSegment() error {
+	if closer, ok := r.current.(io.Closer); ok {
+		closer.Close() // nolint:errcheck
+	}
+	r.current = nil
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+func (r *traceReader) Close() error {
+	if r.file == nil {
+		return nil
+	}
+	return r.closeSegment()
+}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// decompressingReader detects gzip and zstd input by its magic bytes on the
+// first read and decodes it; anything else passes through. A stream cut off
+// mid-frame ends with io.ErrUnexpectedEOF.
+type decompressingReader struct {
+	source  *bufio.Reader
+	decoded io.Reader
+	closer  func()
+	err     error
+}
+
+func newDecompressingReader(r io.Reader) *decompressingReader {
+	return &decompressingReader{source: bufio.NewReader(r)}
+}
+
+func (r *decompressingReader) Read(p []byte) (int, error) {
+	if r.decoded == nil && r.err == nil {
+		r.decoded, r.err = r.detect()
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
+	return r.decoded.Read(p)
+}
+
+func (r *decompressingReader) detect() (io.Reader, error) {
+	magic, _ := r.source.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		decoder, err := gzip.NewReader(r.source)
+		if err != nil {
+			return nil, fmt.Errorf("gzip: %w", err)
+		}
+		r.closer = func() { decoder.Close() } // nolint:errcheck
+		return decoder, nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		decoder, err := zstd.NewReader(r.source, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("zstd: %w", err)
+		}
+		r.closer = decoder.Close
+		return decoder, nil
+	}
+	return r.source, nil
+}
+
+func (r *decompressingReader) Close() error {
+	if r.closer != nil {
+		r.closer()
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package history
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/planetscale/cli/internal/connections"
+
+	qt "github.com/frankban/quicktest"
+)
+
+var captureFileBase = time.Date(2026, 4, 28, 15, 0, 0, 0, time.UTC)
+
+func TestCaptureFileRoundTripsCompressedTrace(t *testing.T) {
+	tests := []struct {
+		name        string
+		compression Compression
+		magic       []byte
+	}{
+		{name: "none", compression: CompressionNone, magic: []byte(`{"`)},
+		{name: "gzip", compression: CompressionGzip, magic: gzipMagic},
+		{name: "zstd", compression: CompressionZstd, magic: zstdMagic},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			path := filepath.Join(t.TempDir(), "trace.jsonl")
+			writeCaptureFile(c, path, CaptureFileOptions{Compression: tt.compression}, 0, 1)
+			// Appending adds a new gzip member or zstd frame to the same file.
+			writeCaptureFile(c, path, CaptureFileOptions{Compression: tt.compression}, 2)
+
+			raw, err := os.ReadFile(path)
+			c.Assert(err, qt.IsNil)
+			c.Assert(strings.HasPrefix(string(raw), string(tt.magic)), qt.IsTrue)
+
+			captures, start := readTrace(c, path)
+			c.Assert(captureMinutes(captures), qt.DeepEquals, []int{0, 1, 2})
+			c.Assert(start.Database, qt.Equals, "prod")
+		})
+	}
+}
+
+func TestCaptureFileRotatesBySizeAndRemovesSegmentsPastRetain(t *testing.T) {
+	c := qt.New(t)
+	path := filepath.Join(t.TempDir(), "trace.jsonl.gz")
+	opts := CaptureFileOptions{Compression: CompressionGzip, RotateSize: 1, Retain: 2}
+
+	writeCaptureFile(c, path, opts, 0, 1, 2, 3)
+
+	files, err := TraceFiles(path)
+	c.Assert(err, qt.IsNil)
+	c.Assert(files, qt.DeepEquals, []string{path + ".2", path + ".1", path})
+	captures, start := readTrace(c, path)
+	c.Assert(captureMinutes(captures), qt.DeepEquals, []int{1, 2, 3})
+	c.Assert(start.Segment, qt.Equals, 1)
+
+	// Each segment starts with the trace header, so it also reads on its own.
+	file, err := os.Open(path)
+	c.Assert(err, qt.IsNil)
+	defer file.Close()
+	reader := NewCaptureReader(file)
+	segment, err := reader.ReadAll()
+	c.Assert(err, qt.IsNil)
+	c.Assert(captureMinutes(segment), qt.DeepEquals, []int{3})
+	header, ok := reader.CaptureStart()
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(header.Database, qt.Equals, "prod")
+	c.Assert(header.Segment, qt.Equals, 3)
+	c.Assert(header.At, qt.Equals, captureFileBase.Add(3*time.Minute))
+}
+
+func TestCaptureFileRotatesByInterval(t *testing.T) {
+	c := qt.New(t)
+	path := filepath.Join(t.TempDir(), "trace.jsonl.zst")
+	now := captureFileBase
+	opts := CaptureFileOptions{
+		Compression:    CompressionZstd,
+		RotateInterval: 2 * time.Minute,
+		now:            func() time.Time { return now },
+	}
+	file, err := OpenCaptureFile(path, opts)
+	c.Assert(err, qt.IsNil)
+	writer := NewCaptureWriter(file)
+	c.Assert(writer.WriteCaptureStart(CaptureStart{At: captureFileBase, Database: "prod"}), qt.IsNil)
+	for minute := range 5 {
+		now = captureFileBase.Add(time.Duration(minute) * time.Minute)
+		c.Assert(writer.Write(captureAtMinute(minute)), qt.IsNil)
+	}
+	c.Assert(writer.Close(), qt.IsNil)
+
+	files, err := TraceFiles(path)
+	c.Assert(err, qt.IsNil)
+	c.Assert(files, qt.DeepEquals, []string{path + ".2", path + ".1", path})
+	captures, _ := readTrace(c, path)
+	c.Assert(captureMinutes(captures), qt.DeepEquals, []int{0, 1, 2, 3, 4})
+}
+
+func TestOpenTraceReadsRotatedSetWithoutActiveSegment(t *testing.T) {
+	c := qt.New(t)
+	path := filepath.Join(t.TempDir(), "trace.jsonl")
+	writeCaptureFile(c, path, CaptureFileOptions{RotateSize: 1}, 0, 1)
+	c.Assert(os.Remove(path), qt.IsNil)
+
+	captures, _ := readTrace(c, path)
+
+	c.Assert(captureMinutes(captures), qt.DeepEquals, []int{0})
+}
+
+func TestOpenTraceRejectsMissingTrace(t *testing.T) {
+	c := qt.New(t)
+
+	_, err := OpenTrace(filepath.Join(t.TempDir(), "missing.jsonl"))
+
+	c.Assert(err, qt.ErrorIs, os.ErrNotExist)
+}
+
+func TestCaptureReaderReadsSchemaVersion1(t *testing.T) {
+	c := qt.New(t)
+	input := `{"type":"capture_start","schema_version":1,"database":"prod"}` + "\n" +
+		`{"at":"2026-04-28T15:00:00Z","capture":{"captured_at":"2026-04-28T15:00:00Z","connections":[{"pid":101}]}}` + "\n"
+	reader := NewCaptureReader(strings.NewReader(input))
+
+	captures, err := reader.ReadAll()
+
+	c.Assert(err, qt.IsNil)
+	c.Assert(captures, qt.HasLen, 1)
+	c.Assert(captures[0].List.Connections[0].PID, qt.Equals, 101)
+	start, ok := reader.CaptureStart()
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(start.SchemaVersion, qt.Equals, 1)
+}
+
+func TestParseCompression(t *testing.T) {
+	c := qt.New(t)
+
+	compression, err := ParseCompression("ZSTD")
+	c.Assert(err, qt.IsNil)
+	c.Assert(compression, qt.Equals, CompressionZstd)
+	_, err = ParseCompression("lz4")
+	c.Assert(err, qt.ErrorMatches, `invalid compression "lz4": must be none, gzip, or zstd`)
+
+	c.Assert(CompressionForPath("trace.jsonl.gz"), qt.Equals, CompressionGzip)
+	c.Assert(CompressionForPath("trace.ZST"), qt.Equals, CompressionZstd)
+	c.Assert(CompressionForPath("trace.jsonl"), qt.Equals, CompressionNone)
+}
+
+func writeCaptureFile(c *qt.C, path string, opts CaptureFileOptions, minutes ...int) {
+	c.Helper()
+	file, err := OpenCaptureFile(path, opts)
+	c.Assert(err, qt.IsNil)
+	writer := NewCaptureWriter(file)
+	c.Assert(writer.WriteCaptureStart(CaptureStart{At: captureFileBase, Database: "prod"}), qt.IsNil)
+	for _, minute := range minutes {
+		c.Assert(writer.Write(captureAtMinute(minute)), qt.IsNil)
+	}
+	c.Assert(writer.Close(), qt.IsNil)
+}
+
+func readTrace(c *qt.C, path string) ([]Capture, CaptureStart) {
+	c.Helper()
+	trace, err := OpenTrace(path)
+	c.Assert(err, qt.IsNil)
+	defer trace.Close()
+	reader := NewCaptureReader(trace)
+	captures, err := reader.ReadAll()
+	c.Assert(err, qt.IsNil)
+	start, _ := reader.CaptureStart()
+	_, err = reader.Read()
+	c.Assert(err, qt.Equals, io.EOF)
+	return captures, start
+}
+
+func captureAtMinute(minute int) Capture {
+	at := captureFileBase.Add(time.Duration(minute) * time.Minute)
+	return NewCapture(connections.NewConnectionList(at, []connections.Connection{{PID: 100 + minute}}, connections.SortByTransactionStart))
+}
+
+func captureMinutes(captures []Capture) []int {
+	minutes := make([]int, 0, len(captures))
+	for _, capture := range captures {
+		minutes = append(minutes, int(capture.At.Sub(captureFileBase)/time.Minute))
+	}
+	return minutes
+}
@@ -2,6 +2,8 @@ package history
 
 import (
 	"slices"
+	"sort"
+	"time"
 
 	"github.com/planetscale/cli/internal/connections"
 )
@@ -125,3 +127,19 @@ func (h *CaptureHistory) Position(cursor CaptureCursor) (n, total int) {
 }
 
 func (h *CaptureHistory) Len() int { return len(h.samples) }
+
+// Seek returns the cursor of the latest capture taken at or before t. A t
+// before the oldest capture snaps to the oldest, so seeking never lands on an
+// empty frame. Returns false only when history is empty.
+func (h *CaptureHistory) Seek(t time.Time) (CaptureCursor, bool) {
+	if len(h.samples) == 0 {
+		return 0, false
+	}
+	idx := sort.Search(len(h.samples), func(i int) bool {
+		return h.samples[i].CapturedAt.After(t)
+	})
+	if idx > 0 {
+		idx--
+	}
+	return h.base + CaptureCursor(idx), true
+}
@@ -148,3 +148,30 @@ func listWithPID(pid int) connections.ConnectionList {
 		connections.SortByTransactionStart,
 	)
 }
+
+func TestCaptureHistorySeekFindsCaptureAtOrBeforeTime(t *testing.T) {
+	c := qt.New(t)
+	h := NewCaptureHistory(10)
+	first := h.Push(listWithPID(10))
+	second := h.Push(listWithPID(20))
+	third := h.Push(listWithPID(30))
+	base := time.Date(2026, 5, 27, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		at   time.Time
+		want CaptureCursor
+	}{
+		{at: base, want: first},
+		{at: base.Add(10 * time.Second), want: first},
+		{at: base.Add(25 * time.Second), want: second},
+		{at: base.Add(time.Hour), want: third},
+	}
+	for _, tt := range tests {
+		cursor, ok := h.Seek(tt.at)
+		c.Assert(ok, qt.IsTrue)
+		c.Assert(cursor, qt.Equals, tt.want, qt.Commentf("seek %s", tt.at))
+	}
+
+	_, ok := NewCaptureHistory(1).Seek(base)
+	c.Assert(ok, qt.IsFalse)
+}
@@ -3,14 +3,16 @@ package history
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
 )
 
 // CaptureReader reads Capture records from a stream previously written by
-// CaptureWriter. It skips capture_start headers and rejects unsupported schema
-// versions on capture_start.
+// CaptureWriter, decompressing gzip and zstd input. It skips capture_start
+// headers, including the ones repeated at the top of each rotated segment, and
+// rejects unsupported schema versions on capture_start.
 type CaptureReader struct {
 	reader       *bufio.Reader
 	line         int
@@ -19,12 +21,13 @@ type CaptureReader struct {
 }
 
 func NewCaptureReader(r io.Reader) *CaptureReader {
-	return &CaptureReader{reader: bufio.NewReader(r)}
+	return &CaptureReader{reader: bufio.NewReader(newDecompressingReader(r))}
 }
 
 // Read returns the next Capture in the stream, or io.EOF when exhausted. A
-// torn final line (no trailing newline, partial JSON) is treated as EOF so
-// captures interrupted by SIGINT remain replayable.
+// torn final line (no trailing newline, partial JSON) or a compressed stream
+// cut off mid-frame is treated as EOF so captures interrupted by SIGINT
+// remain replayable.
 func (r *CaptureReader) Read() (Capture, error) {
 	if r.done {
 		return Capture{}, io.EOF
@@ -32,6 +35,9 @@ func (r *CaptureReader) Read() (Capture, error) {
 
 	for {
 		line, err := r.reader.ReadString('\n')
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			err = io.EOF
+		}
 		if err != nil && err != io.EOF {
 			return Capture{}, err
 		}
@@ -62,11 +68,13 @@ func (r *CaptureReader) Read() (Capture, error) {
 			return Capture{}, fmt.Errorf("capture line %d: %w", r.line, decodeErr)
 		}
 		if envelope.Type == "capture_start" {
-			if envelope.SchemaVersion != traceSchemaVersion {
-				return Capture{}, fmt.Errorf("capture line %d: schema version %d is not supported (expected %d)", r.line, envelope.SchemaVersion, traceSchemaVersion)
+			if envelope.SchemaVersion < minTraceSchemaVersion || envelope.SchemaVersion > traceSchemaVersion {
+				return Capture{}, fmt.Errorf("capture line %d: schema version %d is not supported (expected %d to %d)", r.line, envelope.SchemaVersion, minTraceSchemaVersion, traceSchemaVersion)
 			}
+			// Keep the first header: later ones start rotated segments of
+			// the same trace.
 			var start CaptureStart
-			if decodeErr := json.Unmarshal([]byte(line), &start); decodeErr == nil {
+			if decodeErr := json.Unmarshal([]byte(line), &start); decodeErr == nil && r.captureStart == nil {
 				r.captureStart = &start
 			}
 			if err == io.EOF {
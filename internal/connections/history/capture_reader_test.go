@@ -37,12 +37,12 @@ func TestCaptureReaderReadsCapturesWrittenByCaptureWriter(t *testing.T) {
 
 func TestCaptureReaderRejectsUnsupportedSchemaVersion(t *testing.T) {
 	c := qt.New(t)
-	input := `{"type":"capture_start","schema_version":2}` + "\n"
+	input := `{"type":"capture_start","schema_version":3}` + "\n"
 	reader := NewCaptureReader(strings.NewReader(input))
 
 	_, err := reader.Read()
 
-	c.Assert(err, qt.ErrorMatches, `capture line 1: schema version 2 is not supported \(expected 1\)`)
+	c.Assert(err, qt.ErrorMatches, `capture line 1: schema version 3 is not supported \(expected 1 to 2\)`)
 }
 
 // Tail tolerance: a SIGINT during write can leave a torn final line. The
@@ -6,7 +6,14 @@ import (
 	"time"
 )
 
-const traceSchemaVersion = 1
+// traceSchemaVersion is the version written to capture_start headers. Version
+// 2 traces may be compressed and rotated across files, each starting with a
+// header that carries its segment number; records are unchanged, so readers
+// accept every version from minTraceSchemaVersion up.
+const (
+	traceSchemaVersion    = 2
+	minTraceSchemaVersion = 1
+)
 
 type CaptureStart struct {
 	Type          string         `json:"type"`
@@ -17,6 +24,9 @@ type CaptureStart struct {
 	SchemaVersion int            `json:"schema_version"`
 	Filter        *CaptureFilter `json:"filter,omitempty"`
 	Target        *CaptureTarget `json:"target,omitempty"`
+	// Segment numbers the files of a rotated trace, counting rotations since
+	// the capture started.
+	Segment int `json:"segment,omitempty"`
 }
 
 // CaptureTarget records the Vitess target active at capture time.
@@ -33,9 +43,21 @@ type CaptureFilter struct {
 	Role     string `json:"role,omitempty"`
 }
 
-// CaptureWriter writes captures as newline-delimited JSON records.
+// CaptureWriter writes captures as newline-delimited JSON records. When the
+// destination rotates, as a CaptureFile can, the writer rotates it between
+// records and repeats the capture_start header at the top of the new file.
 type CaptureWriter struct {
 	writer io.Writer
+	start  *CaptureStart
+	// captured reports whether a capture has been written since the last
+	// rotation, so a segment never holds just a header.
+	captured bool
+}
+
+// rotator is a destination that can switch to a new file between records.
+type rotator interface {
+	ShouldRotate() bool
+	Rotate() error
 }
 
 func NewCaptureWriter(writer io.Writer) *CaptureWriter {
@@ -43,14 +65,35 @@ func NewCaptureWriter(writer io.Writer) *CaptureWriter {
 }
 
 func (w *CaptureWriter) Write(capture Capture) error {
+	if r, ok := w.writer.(rotator); ok && w.captured && r.ShouldRotate() {
+		if err := w.rotate(r, capture.At); err != nil {
+			return err
+		}
+	}
+	w.captured = true
 	return w.writeRecord(capture)
 }
 
+func (w *CaptureWriter) rotate(r rotator, at time.Time) error {
+	if err := r.Rotate(); err != nil {
+		return err
+	}
+	w.captured = false
+	if w.start == nil {
+		return nil
+	}
+	start := *w.start
+	start.At = at
+	start.Segment++
+	return w.WriteCaptureStart(start)
+}
+
 func (w *CaptureWriter) WriteCaptureStart(start CaptureStart) error {
 	start.Type = "capture_start"
 	if start.SchemaVersion == 0 {
 		start.SchemaVersion = traceSchemaVersion
 	}
+	w.start = &start
 	return w.writeRecord(start)
 }
 
@@ -0,0 +1,80 @@
+package history
+
+import (
+	"github.com/planetscale/cli/internal/connections"
+)
+
+// DiffStatus is how a connection changed between two captures.
+type DiffStatus int
+
+const (
+	// DiffRunning marks a connection present in both captures.
+	DiffRunning DiffStatus = iota
+	// DiffNew marks a connection that first appears in the later capture.
+	DiffNew
+	// DiffFinished marks a connection that is gone from the later capture.
+	DiffFinished
+)
+
+// ListDiff compares a capture with the one before it.
+type ListDiff struct {
+	// Finished holds the connections of the earlier capture that are gone
+	// from the later one, in the earlier capture's order.
+	Finished []connections.Connection
+	New      int
+	Running  int
+
+	statuses map[diffKey]DiffStatus
+}
+
+// diffKey identifies a session across captures. The connection ID tells a
+// reused PID apart from the session that held it before.
+type diffKey struct {
+	instance     string
+	pid          int
+	connectionID string
+}
+
+func newDiffKey(conn connections.Connection) diffKey {
+	return diffKey{
+		instance:     conn.Instance,
+		pid:          conn.PID,
+		connectionID: connections.DerefString(conn.ConnectionID),
+	}
+}
+
+// DiffLists compares cur with prev, the capture before it.
+func DiffLists(prev, cur connections.ConnectionList) ListDiff {
+	d := ListDiff{statuses: make(map[diffKey]DiffStatus, len(cur.Connections)+len(prev.Connections))}
+	before := make(map[diffKey]bool, len(prev.Connections))
+	for _, conn := range prev.Connections {
+		before[newDiffKey(conn)] = true
+	}
+	after := make(map[diffKey]bool, len(cur.Connections))
+	for _, conn := range cur.Connections {
+		key := newDiffKey(conn)
+		after[key] = true
+		if before[key] {
+			d.statuses[key] = DiffRunning
+			d.Running++
+		} else {
+			d.statuses[key] = DiffNew
+			d.New++
+		}
+	}
+	for _, conn := range prev.Connections {
+		key := newDiffKey(conn)
+		if after[key] {
+			continue
+		}
+		d.statuses[key] = DiffFinished
+		d.Finished = append(d.Finished, conn)
+	}
+	return d
+}
+
+// Status reports how conn changed. Connections in neither capture report
+// DiffRunning.
+func (d ListDiff) Status(conn connections.Connection) DiffStatus {
+	return d.statuses[newDiffKey(conn)]
+}
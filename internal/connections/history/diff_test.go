@@ -0,0 +1,39 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/planetscale/cli/internal/connections"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestDiffListsClassifiesConnections(t *testing.T) {
+	c := qt.New(t)
+	oldID := "conn-old"
+	newID := "conn-new"
+	prev := connections.ConnectionList{Connections: []connections.Connection{
+		{PID: 1, Instance: "primary"},
+		{PID: 2, Instance: "primary"},
+		{PID: 3, Instance: "primary", ConnectionID: &oldID},
+	}}
+	cur := connections.ConnectionList{Connections: []connections.Connection{
+		{PID: 1, Instance: "primary"},
+		{PID: 3, Instance: "primary", ConnectionID: &newID},
+		{PID: 4, Instance: "primary"},
+		{PID: 1, Instance: "replica"},
+	}}
+
+	diff := DiffLists(prev, cur)
+
+	c.Assert(diff.Running, qt.Equals, 1)
+	c.Assert(diff.New, qt.Equals, 3)
+	c.Assert(diff.Finished, qt.HasLen, 2)
+	c.Assert(diff.Finished[0].PID, qt.Equals, 2)
+	c.Assert(diff.Finished[1].PID, qt.Equals, 3)
+	c.Assert(diff.Status(cur.Connections[0]), qt.Equals, DiffRunning)
+	c.Assert(diff.Status(cur.Connections[1]), qt.Equals, DiffNew)
+	c.Assert(diff.Status(cur.Connections[3]), qt.Equals, DiffNew)
+	c.Assert(diff.Status(prev.Connections[1]), qt.Equals, DiffFinished)
+	c.Assert(diff.Status(prev.Connections[2]), qt.Equals, DiffFinished)
+}
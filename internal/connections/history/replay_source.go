@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"io"
+	"time"
 
 	"github.com/planetscale/cli/internal/connections"
 )
@@ -50,3 +51,15 @@ func (s *ReplaySource) Captures() []Capture {
 func (s *ReplaySource) CaptureStart() (CaptureStart, bool) {
 	return s.start, s.hasStart
 }
+
+// Trim narrows the source to the captures taken within [from, to]; a zero
+// from or to leaves that end open. Returns an error, leaving the source
+// unchanged, when no capture falls in the range.
+func (s *ReplaySource) Trim(from, to time.Time) error {
+	captures := TrimCaptures(s.captures, from, to)
+	if len(captures) == 0 {
+		return errors.New("capture file contains no snapshots in the requested time range")
+	}
+	s.captures = captures
+	return nil
+}
@@ -0,0 +1,74 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// timeLayouts are the wall-clock forms ParseTime accepts besides RFC 3339.
+// Layouts without a date take it from the reference time.
+var timeLayouts = []struct {
+	layout string
+	dated  bool
+}{
+	{"2006-01-02 15:04:05", true},
+	{"2006-01-02T15:04:05", true},
+	{"2006-01-02 15:04", true},
+	{"15:04:05", false},
+	{"15:04", false},
+}
+
+// ParseTime parses a trace timestamp typed by an operator. It accepts RFC
+// 3339, a local date and time ("2006-01-02 15:04:05"), a local time of day
+// ("15:04:05" or "15:04") on the local date of ref, and an offset from ref
+// ("+90s", "-5m"). Times without a zone are local, matching the timestamps
+// the TUI header shows.
+func ParseTime(value string, ref time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, errors.New("empty time")
+	}
+	if value[0] == '+' || value[0] == '-' {
+		offset, err := time.ParseDuration(value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time offset %q", value)
+		}
+		return ref.Add(offset), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	ref = ref.Local()
+	for _, l := range timeLayouts {
+		t, err := time.ParseInLocation(l.layout, value, time.Local)
+		if err != nil {
+			continue
+		}
+		if !l.dated {
+			year, month, day := ref.Date()
+			t = time.Date(year, month, day, t.Hour(), t.Minute(), t.Second(), 0, time.Local)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use RFC 3339, \"2006-01-02 15:04:05\", \"15:04:05\", or an offset such as +30s", value)
+}
+
+// TrimCaptures returns the captures taken within [from, to]. A zero from or to
+// leaves that end of the range open.
+func TrimCaptures(captures []Capture, from, to time.Time) []Capture {
+	var out []Capture
+	for _, capture := range captures {
+		at := captureTime(capture)
+		if !from.IsZero() && at.Before(from) {
+			continue
+		}
+		if !to.IsZero() && at.After(to) {
+			continue
+		}
+		out = append(out, capture)
+	}
+	return out
+}
@@ -0,0 +1,76 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/planetscale/cli/internal/connections"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestParseTime(t *testing.T) {
+	ref := time.Date(2026, 4, 28, 15, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr string
+	}{
+		{value: "2026-04-28T14:30:00Z", want: time.Date(2026, 4, 28, 14, 30, 0, 0, time.UTC)},
+		{value: "2026-04-27 09:15:30", want: time.Date(2026, 4, 27, 9, 15, 30, 0, time.Local)},
+		{value: "15:04:05", want: time.Date(2026, 4, 28, 15, 4, 5, 0, time.Local)},
+		{value: " 15:04 ", want: time.Date(2026, 4, 28, 15, 4, 0, 0, time.Local)},
+		{value: "+90s", want: ref.Add(90 * time.Second)},
+		{value: "-5m", want: ref.Add(-5 * time.Minute)},
+		{value: "", wantErr: "empty time"},
+		{value: "+soon", wantErr: `invalid time offset "\+soon"`},
+		{value: "yesterday", wantErr: `invalid time "yesterday".*`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			c := qt.New(t)
+			got, err := ParseTime(tt.value, ref)
+			if tt.wantErr != "" {
+				c.Assert(err, qt.ErrorMatches, tt.wantErr)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(got.Equal(tt.want), qt.IsTrue, qt.Commentf("got %s, want %s", got, tt.want))
+		})
+	}
+}
+
+func TestTrimCapturesKeepsInclusiveRange(t *testing.T) {
+	c := qt.New(t)
+	base := time.Date(2026, 4, 28, 15, 0, 0, 0, time.UTC)
+	var captures []Capture
+	for i := range 5 {
+		captures = append(captures, NewCapture(connections.ConnectionList{CapturedAt: base.Add(time.Duration(i) * time.Minute)}))
+	}
+
+	kept := TrimCaptures(captures, base.Add(time.Minute), base.Add(3*time.Minute))
+	c.Assert(kept, qt.HasLen, 3)
+	c.Assert(kept[0].At, qt.Equals, base.Add(time.Minute))
+	c.Assert(kept[2].At, qt.Equals, base.Add(3*time.Minute))
+
+	c.Assert(TrimCaptures(captures, base.Add(3*time.Minute), time.Time{}), qt.HasLen, 2)
+	c.Assert(TrimCaptures(captures, time.Time{}, base), qt.HasLen, 1)
+	c.Assert(TrimCaptures(captures, base.Add(time.Hour), time.Time{}), qt.HasLen, 0)
+}
+
+func TestReplaySourceTrimRejectsEmptyRange(t *testing.T) {
+	c := qt.New(t)
+	base := time.Date(2026, 4, 28, 15, 0, 0, 0, time.UTC)
+	source := &ReplaySource{captures: []Capture{
+		NewCapture(connections.ConnectionList{CapturedAt: base}),
+		NewCapture(connections.ConnectionList{CapturedAt: base.Add(time.Minute)}),
+	}}
+
+	err := source.Trim(base.Add(time.Hour), time.Time{})
+	c.Assert(err, qt.ErrorMatches, "capture file contains no snapshots in the requested time range")
+	c.Assert(source.Captures(), qt.HasLen, 2)
+
+	c.Assert(source.Trim(base.Add(time.Second), time.Time{}), qt.IsNil)
+	c.Assert(source.Captures(), qt.HasLen, 1)
+}
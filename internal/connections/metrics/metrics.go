@@ -0,0 +1,191 @@
+// Package metrics derives gauges from connection list samples and encodes
+// them for Prometheus and OpenTelemetry collectors.
+package metrics
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/planetscale/cli/internal/connections"
+)
+
+// Metric names.
+const (
+	NameConnections           = "pscale_connections"
+	NameConnectionsWaiting    = "pscale_connections_waiting"
+	NameConnectionsBlocked    = "pscale_connections_blocked"
+	NameLongestTransactionAge = "pscale_connections_longest_transaction_age_seconds"
+	NameLastSampleTimestamp   = "pscale_connections_last_sample_timestamp_seconds"
+)
+
+const (
+	unitConnections = "{connection}"
+	unitSeconds     = "s"
+	// unknownLabelValue stands in for an empty state or role, so every point
+	// of a metric has the same labels.
+	unknownLabelValue = "unknown"
+)
+
+// Label is a metric label, an attribute in OpenTelemetry terms.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Point is the value of a metric for one set of labels.
+type Point struct {
+	Labels []Label
+	Value  float64
+}
+
+// Metric is a gauge and its points.
+type Metric struct {
+	Name   string
+	Help   string
+	Unit   string
+	Points []Point
+}
+
+// Sample is the metrics derived from one connection list.
+type Sample struct {
+	At      time.Time
+	Metrics []Metric
+}
+
+// FromList derives a Sample from list. labels, such as the database and
+// branch, are added to every point. Points are sorted by label values so
+// encodings are stable.
+func FromList(list connections.ConnectionList, labels ...Label) Sample {
+	roles := instanceRoles(list)
+	byState := map[[2]string]int{}
+	waiting := map[[2]string]int{}
+	blocked := map[string]int{}
+	oldest := map[string]time.Duration{}
+	// Every role gets a blocked and transaction age point, zero when nothing
+	// is blocked or no transaction is open.
+	for _, inst := range list.Instances {
+		blocked[labelValue(inst.Role)] = 0
+		oldest[labelValue(inst.Role)] = 0
+	}
+
+	for _, conn := range list.Connections {
+		role := connectionRole(conn, roles)
+		state := labelValue(conn.State)
+		byState[[2]string{state, role}]++
+		if wait := connections.JoinWaitEvents(conn.WaitEventType, conn.WaitEvent); wait != "" {
+			waiting[[2]string{wait, role}]++
+		}
+		n := blocked[role]
+		if len(conn.BlockedBy) > 0 {
+			n++
+		}
+		blocked[role] = n
+		age := time.Duration(0)
+		if conn.XactStart != nil && !list.CapturedAt.IsZero() {
+			age = max(list.CapturedAt.Sub(*conn.XactStart), 0)
+		}
+		oldest[role] = max(oldest[role], age)
+	}
+
+	return Sample{
+		At: list.CapturedAt,
+		Metrics: []Metric{
+			{
+				Name:   NameConnections,
+				Help:   "Connections by state and instance role.",
+				Unit:   unitConnections,
+				Points: pairPoints(byState, "state", labels),
+			},
+			{
+				Name:   NameConnectionsWaiting,
+				Help:   "Connections waiting on a wait event, by wait event and instance role.",
+				Unit:   unitConnections,
+				Points: pairPoints(waiting, "wait_event", labels),
+			},
+			{
+				Name:   NameConnectionsBlocked,
+				Help:   "Connections waiting on a lock held by another session, by instance role.",
+				Unit:   unitConnections,
+				Points: rolePoints(blocked, func(n int) float64 { return float64(n) }, labels),
+			},
+			{
+				Name:   NameLongestTransactionAge,
+				Help:   "Age of the oldest open transaction, by instance role.",
+				Unit:   unitSeconds,
+				Points: rolePoints(oldest, func(d time.Duration) float64 { return d.Seconds() }, labels),
+			},
+			{
+				Name: NameLastSampleTimestamp,
+				Help: "Unix time the connections were last sampled.",
+				Unit: unitSeconds,
+				Points: []Point{{
+					Labels: slices.Clone(labels),
+					Value:  float64(list.CapturedAt.UnixMilli()) / 1000,
+				}},
+			},
+		},
+	}
+}
+
+// instanceRoles maps instance IDs to the roles the server reported for them.
+func instanceRoles(list connections.ConnectionList) map[string]string {
+	roles := make(map[string]string, len(list.Instances))
+	for _, inst := range list.Instances {
+		roles[inst.ID] = inst.Role
+	}
+	return roles
+}
+
+func connectionRole(conn connections.Connection, roles map[string]string) string {
+	if conn.InstanceRole != "" {
+		return conn.InstanceRole
+	}
+	return labelValue(roles[conn.Instance])
+}
+
+func labelValue(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return unknownLabelValue
+	}
+	return value
+}
+
+// pairPoints turns counts keyed by (name value, role) into points labeled name
+// and role.
+func pairPoints(counts map[[2]string]int, name string, labels []Label) []Point {
+	points := make([]Point, 0, len(counts))
+	for key, n := range counts {
+		points = append(points, Point{
+			Labels: append(slices.Clone(labels), Label{Name: name, Value: key[0]}, Label{Name: "role", Value: key[1]}),
+			Value:  float64(n),
+		})
+	}
+	sortPoints(points)
+	return points
+}
+
+func rolePoints[V any](values map[string]V, value func(V) float64, labels []Label) []Point {
+	points := make([]Point, 0, len(values))
+	for role, v := range values {
+		points = append(points, Point{
+			Labels: append(slices.Clone(labels), Label{Name: "role", Value: role}),
+			Value:  value(v),
+		})
+	}
+	sortPoints(points)
+	return points
+}
+
+func sortPoints(points []Point) {
+	slices.SortFunc(points, func(a, b Point) int {
+		for i := range min(len(a.Labels), len(b.Labels)) {
+			if c := cmp.Compare(a.Labels[i].Value, b.Labels[i].Value); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(len(a.Labels), len(b.Labels))
+	})
+}
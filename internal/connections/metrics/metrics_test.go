@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/planetscale/cli/internal/connections"
+
+	qt "github.com/frankban/quicktest"
+)
+
+var sampleAt = time.Date(2026, 4, 28, 15, 0, 0, 0, time.UTC)
+
+func TestFromListAggregatesByStateWaitEventAndRole(t *testing.T) {
+	c := qt.New(t)
+
+	sample := FromList(sampleList(), Label{Name: "database", Value: "prod"})
+
+	c.Assert(sample.At, qt.Equals, sampleAt)
+	c.Assert(metricPoints(c, sample, NameConnections), qt.DeepEquals, map[string]float64{
+		"prod/active/primary":              2,
+		"prod/idle in transaction/primary": 1,
+		"prod/unknown/replica":             1,
+	})
+	c.Assert(metricPoints(c, sample, NameConnectionsWaiting), qt.DeepEquals, map[string]float64{
+		"prod/Lock/transactionid/primary": 1,
+	})
+	c.Assert(metricPoints(c, sample, NameConnectionsBlocked), qt.DeepEquals, map[string]float64{
+		"prod/primary": 1,
+		"prod/replica": 0,
+	})
+	c.Assert(metricPoints(c, sample, NameLongestTransactionAge), qt.DeepEquals, map[string]float64{
+		"prod/primary": 90,
+		"prod/replica": 0,
+	})
+	c.Assert(metricPoints(c, sample, NameLastSampleTimestamp), qt.DeepEquals, map[string]float64{
+		"prod": float64(sampleAt.Unix()),
+	})
+}
+
+func TestFromListReportsRolesWithoutConnections(t *testing.T) {
+	c := qt.New(t)
+	list := connections.ConnectionList{
+		CapturedAt: sampleAt,
+		Instances:  []connections.InstanceMeta{{ID: "primary", Role: "primary"}},
+	}
+
+	sample := FromList(list)
+
+	c.Assert(metricPoints(c, sample, NameConnections), qt.HasLen, 0)
+	c.Assert(metricPoints(c, sample, NameConnectionsBlocked), qt.DeepEquals, map[string]float64{"primary": 0})
+}
+
+func sampleList() connections.ConnectionList {
+	xactStart := sampleAt.Add(-90 * time.Second)
+	recent := sampleAt.Add(-time.Second)
+	list := connections.NewConnectionList(sampleAt, []connections.Connection{
+		{PID: 1, Instance: "primary", State: "active", XactStart: &recent},
+		{PID: 2, Instance: "primary", State: "active", WaitEventType: "Lock", WaitEvent: "transactionid", BlockedBy: []int{3}},
+		{PID: 3, Instance: "primary", State: "idle in transaction", XactStart: &xactStart},
+		{PID: 4, Instance: "replica-a", InstanceRole: "replica"},
+	}, connections.SortByTransactionStart)
+	list.Instances = []connections.InstanceMeta{{ID: "primary", Role: "primary"}, {ID: "replica-a", Role: "replica"}}
+	return list
+}
+
+// metricPoints returns the points of the named metric keyed by their label
+// values joined with slashes.
+func metricPoints(c *qt.C, sample Sample, name string) map[string]float64 {
+	c.Helper()
+	for _, m := range sample.Metrics {
+		if m.Name != name {
+			continue
+		}
+		points := make(map[string]float64, len(m.Points))
+		for _, p := range m.Points {
+			key := ""
+			for i, l := range p.Labels {
+				if i > 0 {
+					key += "/"
+				}
+				key += l.Value
+			}
+			points[key] = p.Value
+		}
+		return points
+	}
+	c.Fatalf("metric %s not found", name)
+	return nil
+}
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// OTLPScope is the instrumentation scope OTLP metrics are reported under.
+const OTLPScope = "github.com/planetscale/cli/internal/connections/metrics"
+
+// The OTLP/HTTP JSON encoding of an ExportMetricsServiceRequest, limited to
+// the gauges a Sample holds.
+type (
+	otlpRequest struct {
+		ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+	}
+	otlpResourceMetrics struct {
+		Resource     otlpResource       `json:"resource"`
+		ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeMetrics struct {
+		Scope   otlpScope    `json:"scope"`
+		Metrics []otlpMetric `json:"metrics"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpMetric struct {
+		Name        string    `json:"name"`
+		Description string    `json:"description"`
+		Unit        string    `json:"unit"`
+		Gauge       otlpGauge `json:"gauge"`
+	}
+	otlpGauge struct {
+		DataPoints []otlpDataPoint `json:"dataPoints"`
+	}
+	otlpDataPoint struct {
+		Attributes   []otlpAttribute `json:"attributes"`
+		TimeUnixNano string          `json:"timeUnixNano"`
+		AsDouble     float64         `json:"asDouble"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue string `json:"stringValue"`
+	}
+)
+
+// MarshalOTLP encodes sample as an OTLP/HTTP JSON metrics export request,
+// the body POSTed to a collector's /v1/metrics endpoint. resource labels, such
+// as service.name, describe the process reporting the metrics.
+func MarshalOTLP(sample Sample, resource ...Label) ([]byte, error) {
+	at := strconv.FormatInt(sample.At.UnixNano(), 10)
+	metrics := make([]otlpMetric, 0, len(sample.Metrics))
+	for _, m := range sample.Metrics {
+		points := make([]otlpDataPoint, 0, len(m.Points))
+		for _, p := range m.Points {
+			points = append(points, otlpDataPoint{
+				Attributes:   otlpAttributes(p.Labels),
+				TimeUnixNano: at,
+				AsDouble:     p.Value,
+			})
+		}
+		metrics = append(metrics, otlpMetric{
+			Name:        m.Name,
+			Description: m.Help,
+			Unit:        m.Unit,
+			Gauge:       otlpGauge{DataPoints: points},
+		})
+	}
+	return json.Marshal(otlpRequest{ResourceMetrics: []otlpResourceMetrics{{
+		Resource: otlpResource{Attributes: otlpAttributes(resource)},
+		ScopeMetrics: []otlpScopeMetrics{{
+			Scope:   otlpScope{Name: OTLPScope},
+			Metrics: metrics,
+		}},
+	}}})
+}
+
+func otlpAttributes(labels []Label) []otlpAttribute {
+	attributes := make([]otlpAttribute, 0, len(labels))
+	for _, l := range labels {
+		attributes = append(attributes, otlpAttribute{Key: l.Name, Value: otlpValue{StringValue: l.Value}})
+	}
+	return attributes
+}
@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"encoding/json"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestMarshalOTLP(t *testing.T) {
+	c := qt.New(t)
+	sample := Sample{At: sampleAt, Metrics: []Metric{{
+		Name:   NameConnectionsBlocked,
+		Help:   "Blocked connections.",
+		Unit:   "{connection}",
+		Points: []Point{{Labels: []Label{{Name: "role", Value: "primary"}}, Value: 3}},
+	}}}
+
+	body, err := MarshalOTLP(sample, Label{Name: "service.name", Value: "pscale"})
+
+	c.Assert(err, qt.IsNil)
+	var got map[string]any
+	c.Assert(json.Unmarshal(body, &got), qt.IsNil)
+	var want map[string]any
+	c.Assert(json.Unmarshal([]byte(`{"resourceMetrics":[{
+		"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"pscale"}}]},
+		"scopeMetrics":[{
+			"scope":{"name":"github.com/planetscale/cli/internal/connections/metrics"},
+			"metrics":[{
+				"name":"pscale_connections_blocked",
+				"description":"Blocked connections.",
+				"unit":"{connection}",
+				"gauge":{"dataPoints":[{
+					"attributes":[{"key":"role","value":{"stringValue":"primary"}}],
+					"timeUnixNano":"1777388400000000000",
+					"asDouble":3
+				}]}
+			}]
+		}]
+	}]}`), &want), qt.IsNil)
+	c.Assert(got, qt.DeepEquals, want)
+}
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// PrometheusContentType is the content type of the Prometheus text exposition
+// format written by WritePrometheus.
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WritePrometheus writes sample in the Prometheus text exposition format.
+// Points carry no timestamp, so Prometheus stamps them at scrape time.
+func WritePrometheus(w io.Writer, sample Sample) error {
+	bw := bufio.NewWriter(w)
+	for _, m := range sample.Metrics {
+		bw.WriteString("# HELP " + m.Name + " " + m.Help + "\n")
+		bw.WriteString("# TYPE " + m.Name + " gauge\n")
+		for _, p := range m.Points {
+			bw.WriteString(m.Name)
+			if len(p.Labels) > 0 {
+				bw.WriteByte('{')
+				for i, l := range p.Labels {
+					if i > 0 {
+						bw.WriteByte(',')
+					}
+					bw.WriteString(l.Name + `="` + labelValueEscaper.Replace(l.Value) + `"`)
+				}
+				bw.WriteByte('}')
+			}
+			bw.WriteString(" " + strconv.FormatFloat(p.Value, 'g', -1, 64) + "\n")
+		}
+	}
+	return bw.Flush()
+}
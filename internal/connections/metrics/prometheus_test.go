@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	c := qt.New(t)
+	sample := Sample{At: sampleAt, Metrics: []Metric{
+		{
+			Name: NameConnections,
+			Help: "Connections by state and instance role.",
+			Points: []Point{
+				{Labels: []Label{{Name: "state", Value: "active"}, {Name: "role", Value: "primary"}}, Value: 2},
+				{Labels: []Label{{Name: "state", Value: `say "hi"\` + "\n"}, {Name: "role", Value: "primary"}}, Value: 1},
+			},
+		},
+		{
+			Name:   NameLongestTransactionAge,
+			Help:   "Age of the oldest open transaction, by instance role.",
+			Points: []Point{{Value: 1.5}},
+		},
+	}}
+	var out strings.Builder
+
+	c.Assert(WritePrometheus(&out, sample), qt.IsNil)
+
+	c.Assert(out.String(), qt.Equals, `# HELP pscale_connections Connections by state and instance role.
+# TYPE pscale_connections gauge
+pscale_connections{state="active",role="primary"} 2
+pscale_connections{state="say \"hi\"\\\n",role="primary"} 1
+# HELP pscale_connections_longest_transaction_age_seconds Age of the oldest open transaction, by instance role.
+# TYPE pscale_connections_longest_transaction_age_seconds gauge
+pscale_connections_longest_transaction_age_seconds 1.5
+`)
+}
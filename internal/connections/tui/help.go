@@ -107,6 +107,12 @@ func helpModalLines(state helpState) []string {
 		controls = append(controls, "s sort")
 	}
 	lines = append(lines,
+		headerStyle.Render("Filter, Seek, And Diff"),
+		"  /  Filter rows by user, app, or query: plain text, /regex/, or user:, app:, query: for one field.",
+		"     The filter stays on while stepping; an empty filter clears it.",
+		"  g  Seek to the capture at or before a time: 15:04:05, 2006-01-02 15:04:05, RFC 3339, or +30s/-5m.",
+		"  d  Diff against the previous capture: + new, - finished (dimmed), unmarked still running.",
+		"",
 		headerStyle.Render("Navigation"),
 		navigation,
 		"  "+strings.Join(controls, "; "),
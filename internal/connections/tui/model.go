@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -182,6 +183,15 @@ type Model struct {
 	helpOpen            bool
 	helpOffset          int
 
+	// match is the operator's persistent row filter. It applies to every
+	// capture shown, so stepping, seeking, and refreshes keep the view scoped.
+	match rowMatch
+	// showDiff compares the displayed capture with the one before it: new
+	// rows are marked, and connections that finished are appended dimmed.
+	showDiff    bool
+	prompt      promptKind
+	promptInput string
+
 	detailOpen     bool
 	detailInstance string
 	detailPID      int
@@ -420,6 +430,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.confirming {
 			return m.handleConfirmKey(msg)
 		}
+		if m.prompt != promptNone {
+			return m.handlePromptKey(msg)
+		}
 		if m.helpOpen {
 			return m.handleHelpKey(msg)
 		}
@@ -468,6 +481,15 @@ func (m Model) handleTableKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	case "[", "]", "{", "}":
 		return m.handleStepKey(msg.String()), nil
+	case "/":
+		return m.openPrompt(promptMatch, m.match.pattern), nil
+	case "g":
+		if m.samples.Len() == 0 {
+			return m, nil
+		}
+		return m.openPrompt(promptSeek, ""), nil
+	case "d":
+		return m.toggleDiff()
 	case "c":
 		if next, rejected := m.rejectReadOnlyAction(); rejected {
 			return next, nil
@@ -476,6 +498,9 @@ func (m Model) handleTableKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		if !ok {
 			return m, nil
 		}
+		if m.isFinished(conn) {
+			return m.rejectFinishedAction(), nil
+		}
 		return m.startConfirm(actionCancelQuery, conn)
 	case "k", "K":
 		if next, rejected := m.rejectReadOnlyAction(); rejected {
@@ -485,6 +510,9 @@ func (m Model) handleTableKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		if !ok {
 			return m, nil
 		}
+		if m.isFinished(conn) {
+			return m.rejectFinishedAction(), nil
+		}
 		kind := actionTerminateTxn
 		if msg.String() == "K" {
 			kind = actionTerminateConn
@@ -616,7 +644,7 @@ func (m Model) handleDetailKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return next, nil
 		}
 		conn, ok := m.actionTargetConnection()
-		if !ok {
+		if !ok || m.isFinished(conn) {
 			return m.rejectEndedDetailAction(), nil
 		}
 		return m.startConfirm(actionCancelQuery, conn)
@@ -625,7 +653,7 @@ func (m Model) handleDetailKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return next, nil
 		}
 		conn, ok := m.actionTargetConnection()
-		if !ok {
+		if !ok || m.isFinished(conn) {
 			return m.rejectEndedDetailAction(), nil
 		}
 		kind := actionTerminateTxn
@@ -701,6 +729,12 @@ func (m Model) handleStepKey(key string) Model {
 	if !ok {
 		return m
 	}
+	return m.moveCursor(cursor)
+}
+
+// moveCursor shows the capture at cursor. Landing on the latest capture
+// resumes following when the source is live.
+func (m Model) moveCursor(cursor history.CaptureCursor) Model {
 	m.cursor = cursor
 	latest, hasLatest := m.samples.Latest()
 	if hasLatest && cursor == latest && m.canResumeLiveFollow() {
@@ -750,6 +784,13 @@ func (m Model) View() string {
 
 func (m Model) tableState() tableState {
 	pos, total := m.stepPosition()
+	var (
+		diff    history.ListDiff
+		hasDiff bool
+	)
+	if m.showDiff {
+		diff, hasDiff = m.captureDiff()
+	}
 	return tableState{
 		List:            m.lastSuccessfulList,
 		HasList:         m.hasList,
@@ -776,12 +817,19 @@ func (m Model) tableState() tableState {
 		StepTotal:       total,
 		Target:          m.target,
 		Filter:          m.filter,
+		Match:           m.match.pattern,
+		Prompt:          m.promptText(),
+		ShowDiff:        m.showDiff,
+		Diff:            diff,
+		HasDiff:         hasDiff,
 		DisplayPreset:   m.displayPreset,
 		Capabilities:    m.capabilities,
 	}
 }
 
-// currentList returns the capture at the model's cursor, sorted by m.sort.
+// currentList returns the capture at the model's cursor, sorted by m.sort and
+// scoped by the row filter. With the diff view on, connections that finished
+// since the previous capture follow the live rows.
 // SortConnections runs in place on the history-owned slice; row order drifts
 // across sort changes but values are unchanged.
 func (m Model) currentList() live.ConnectionList {
@@ -791,6 +839,16 @@ func (m Model) currentList() live.ConnectionList {
 	}
 	live.SortConnections(list.Connections, m.sort)
 	list.Sort = m.sort
+	list.Connections = m.match.filter(list.Connections)
+	if diff, ok := m.captureDiff(); ok {
+		list.Connections = slices.Clip(list.Connections)
+		for _, conn := range diff.Finished {
+			// A finished session no longer blocks anyone; keeping its edges
+			// would count it in the live rows' BLOCK column.
+			conn.BlockedBy = nil
+			list.Connections = append(list.Connections, conn)
+		}
+	}
 	return list
 }
 
@@ -10,6 +10,7 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/x/ansi"
 	live "github.com/planetscale/cli/internal/connections"
+	"github.com/planetscale/cli/internal/connections/history"
 )
 
 type tableState struct {
@@ -38,6 +39,11 @@ type tableState struct {
 	StepTotal       int // total samples held in history
 	Target          Target
 	Filter          string // active row filter chip, e.g. "filter: role=primary"; empty when none
+	Match           string // operator's text or regex row filter; empty when none
+	Prompt          string // open filter or seek prompt; replaces the help line
+	ShowDiff        bool
+	Diff            history.ListDiff // comparison with the previous capture; valid when HasDiff
+	HasDiff         bool
 	DisplayPreset   connectionDisplayPreset
 	Capabilities    ConnectionCapabilities
 }
@@ -111,6 +117,7 @@ type bindings struct {
 	Capture       key.Binding
 	Sort          key.Binding
 	Step          key.Binding
+	Timeline      key.Binding
 	Detail        key.Binding
 	Cancel        key.Binding
 	TerminateTxn  key.Binding
@@ -162,7 +169,7 @@ func (b bindings) ShortHelpFor(readOnlyActions, replay bool, display connectionD
 		help = append(help, b.Sort)
 	}
 	if canStepHistory {
-		help = append(help, b.Step)
+		help = append(help, b.Step, b.Timeline)
 	}
 	if canSelectRow {
 		help = append(help, b.Detail)
@@ -323,6 +330,12 @@ func renderHeaderWithOptions(state tableState, width int, opts headerOptions) st
 	if state.Filter != "" {
 		parts = append(parts, headerPart{text: headerFilterText(state.Filter, width)})
 	}
+	if state.Match != "" {
+		parts = append(parts, headerPart{text: headerFilterText("match: "+state.Match, width)})
+	}
+	if state.ShowDiff {
+		parts = append(parts, headerPart{text: diffHeaderText(state)})
+	}
 	if opts.showSort {
 		parts = append(parts, headerPart{text: sortHeaderText(state)})
 	}
@@ -691,6 +704,8 @@ func renderFooter(state tableState) string {
 		// prompt past the terminal width on standard layouts, and only y/n/esc/
 		// q/ctrl+c are valid keys while confirming anyway.
 		lines = append(lines, errorStyle.Render(clipLine(state.Confirm, tableWidth(state.Width))))
+	case state.Prompt != "":
+		lines = append(lines, clipLine(state.Prompt, tableWidth(state.Width)))
 	default:
 		if state.LastError != "" && state.HasList {
 			lines = append(lines, errorStyle.Render(clipLine("error: "+sanitizeFooterText(state.LastError), tableWidth(state.Width))))
@@ -733,7 +748,33 @@ func renderConnectionTable(state tableState, bodyAvail int) string {
 	selectedInSlice := state.Selected - start
 	counts := live.BlockingCounts(connections)
 	headers, rows := buildConnectionRowsForDisplay(state.DisplayPreset, visible, counts, width, selectedInSlice)
-	return renderConnectionTableTight(state.DisplayPreset, headers, rows, visible, counts, selectedInSlice, width)
+	var statuses []history.DiffStatus
+	if state.HasDiff {
+		statuses = make([]history.DiffStatus, len(visible))
+		for i, conn := range visible {
+			statuses[i] = state.Diff.Status(conn)
+			rows[i][0] += diffMarker(statuses[i])
+		}
+	}
+	return renderConnectionTableTight(state.DisplayPreset, headers, rows, visible, counts, statuses, selectedInSlice, width)
+}
+
+// diffHeaderText summarizes the comparison with the previous capture.
+func diffHeaderText(state tableState) string {
+	if !state.HasDiff {
+		return "diff: no previous capture"
+	}
+	return fmt.Sprintf("diff: %d new, %d finished, %d running", state.Diff.New, len(state.Diff.Finished), state.Diff.Running)
+}
+
+func diffMarker(status history.DiffStatus) string {
+	switch status {
+	case history.DiffNew:
+		return "+"
+	case history.DiffFinished:
+		return "-"
+	}
+	return " "
 }
 
 // renderConnectionTableTight lays the table out with content-based column
@@ -741,7 +782,7 @@ func renderConnectionTable(state tableState, bodyAvail int) string {
 // a width-filling table layout, this keeps columns packed at the left when the
 // content (notably the trailing QUERY column) is short, so uniform/short rows
 // don't get spread across the whole terminal.
-func renderConnectionTableTight(display connectionDisplayPreset, headers []string, rows [][]string, connections []live.Connection, counts map[int]int, selectedInSlice, width int) string {
+func renderConnectionTableTight(display connectionDisplayPreset, headers []string, rows [][]string, connections []live.Connection, counts map[int]int, statuses []history.DiffStatus, selectedInSlice, width int) string {
 	if len(headers) == 0 {
 		return ""
 	}
@@ -756,6 +797,9 @@ func renderConnectionTableTight(display connectionDisplayPreset, headers []strin
 			conn = &c
 			blockCount = counts[c.PID]
 			base = connectionRowStyle(c, blockCount, i == selectedInSlice)
+			if i < len(statuses) {
+				base = diffRowStyle(base, statuses[i], i == selectedInSlice)
+			}
 		}
 		lines = append(lines, renderTightRow(display, row, headers, conn, blockCount, base, columnWidths, width))
 	}
@@ -818,6 +862,21 @@ func connectionRowStyle(conn live.Connection, blockCount int, selected bool) lip
 	return style
 }
 
+// diffRowStyle marks new rows green and dims finished ones. The selection
+// highlight wins so the cursor stays visible.
+func diffRowStyle(style lipgloss.Style, status history.DiffStatus, selected bool) lipgloss.Style {
+	if selected {
+		return style
+	}
+	switch status {
+	case history.DiffNew:
+		return style.Foreground(adaptiveColor(colorActiveGreenLight, colorActiveGreen))
+	case history.DiffFinished:
+		return style.Inherit(mutedStyle).Strikethrough(true)
+	}
+	return style
+}
+
 func connectionColumnStyleForDisplay(display connectionDisplayPreset, style lipgloss.Style, conn live.Connection, blockCount int, col int, headers []string) lipgloss.Style {
 	if display == connectionDisplayProcesslist {
 		if col >= 0 && col < len(headers) && headers[col] == "STATE" {
@@ -1281,6 +1340,7 @@ func defaultBindings() bindings {
 		Capture:       key.NewBinding(key.WithKeys("C"), key.WithHelp("C", "capture")),
 		Sort:          key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "sort")),
 		Step:          key.NewBinding(key.WithKeys("[", "]", "{", "}"), key.WithHelp("[ ] { }", "step history")),
+		Timeline:      key.NewBinding(key.WithKeys("/", "g", "d"), key.WithHelp("/ g d", "filter/seek/diff")),
 		Detail:        key.NewBinding(key.WithKeys("enter", "v"), key.WithHelp("enter/v", "detail")),
 		Cancel:        key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "cancel query")),
 		TerminateTxn:  key.NewBinding(key.WithKeys("k"), key.WithHelp("k", "kill transaction")),
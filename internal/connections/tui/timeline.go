@@ -0,0 +1,241 @@
+package tui
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	live "github.com/planetscale/cli/internal/connections"
+	"github.com/planetscale/cli/internal/connections/history"
+)
+
+type promptKind int
+
+const (
+	promptNone promptKind = iota
+	promptMatch
+	promptSeek
+)
+
+func (m Model) openPrompt(kind promptKind, input string) Model {
+	m.prompt = kind
+	m.promptInput = input
+	return m
+}
+
+func (m Model) handlePromptKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyEsc:
+		m.prompt = promptNone
+		m.promptInput = ""
+		return m, nil
+	case tea.KeyEnter:
+		kind, input := m.prompt, m.promptInput
+		m.prompt = promptNone
+		m.promptInput = ""
+		if kind == promptSeek {
+			return m.seek(input)
+		}
+		return m.applyMatch(input)
+	case tea.KeyBackspace:
+		if runes := []rune(m.promptInput); len(runes) > 0 {
+			m.promptInput = string(runes[:len(runes)-1])
+		}
+		return m, nil
+	case tea.KeySpace:
+		m.promptInput += " "
+		return m, nil
+	case tea.KeyRunes:
+		m.promptInput += string(msg.Runes)
+		return m, nil
+	}
+	return m, nil
+}
+
+func (m Model) promptText() string {
+	switch m.prompt {
+	case promptMatch:
+		return "filter: " + m.promptInput + "▏  text, /regex/, user: app: query: | enter apply, empty clears | esc cancel"
+	case promptSeek:
+		return "seek to: " + m.promptInput + "▏  15:04:05, 2006-01-02 15:04:05, +30s, -5m | enter go | esc cancel"
+	}
+	return ""
+}
+
+// seek moves to the latest capture taken at or before the time the operator
+// typed. Offsets and times of day are relative to the displayed capture.
+func (m Model) seek(input string) (Model, tea.Cmd) {
+	current, _ := m.samples.At(m.cursor)
+	at, err := history.ParseTime(input, current.CapturedAt)
+	if err != nil {
+		return m.setActionError("seek: " + err.Error()), nil
+	}
+	cursor, ok := m.samples.Seek(at)
+	if !ok {
+		return m, nil
+	}
+	m = m.moveCursor(cursor)
+	landed, _ := m.samples.At(cursor)
+	return m.setNotice("showing capture at " + formatCapturedAbsolute(landed.CapturedAt, m.now()))
+}
+
+func (m Model) applyMatch(pattern string) (Model, tea.Cmd) {
+	match, err := parseRowMatch(pattern)
+	if err != nil {
+		return m.setActionError(err.Error()), nil
+	}
+	m.match = match
+	m = m.reloadList()
+	if !match.active() {
+		return m.setNotice("filter cleared")
+	}
+	return m.setNotice("filter applied")
+}
+
+func (m Model) toggleDiff() (Model, tea.Cmd) {
+	m.showDiff = !m.showDiff
+	m = m.reloadList()
+	if !m.showDiff {
+		return m.setNotice("diff off")
+	}
+	return m.setNotice("diff on: compared with the previous capture")
+}
+
+// reloadList rebuilds the displayed list after the row filter or diff view
+// changes, keeping the highlight on the same connection when it is still
+// shown.
+func (m Model) reloadList() Model {
+	if !m.hasList {
+		return m
+	}
+	prevConn, hadSelection := m.selectedConnection()
+	prevIndex := m.selected
+	m.lastSuccessfulList = m.currentList()
+	if hadSelection {
+		m.reanchorSelection(prevConn, prevIndex)
+	}
+	m.clampViewport()
+	m.clampBlockerSelection()
+	m.clampQueryOffset()
+	return m
+}
+
+// captureDiff compares the displayed capture with the one before it, both
+// scoped by the row filter. Returns false when the diff view is off or the
+// displayed capture is the oldest held.
+func (m Model) captureDiff() (history.ListDiff, bool) {
+	if !m.showDiff {
+		return history.ListDiff{}, false
+	}
+	prevCursor, ok := m.samples.Step(m.cursor, -1)
+	if !ok {
+		return history.ListDiff{}, false
+	}
+	prev, ok := m.samples.At(prevCursor)
+	if !ok {
+		return history.ListDiff{}, false
+	}
+	cur, ok := m.samples.At(m.cursor)
+	if !ok {
+		return history.ListDiff{}, false
+	}
+	prev.Connections = m.match.filter(prev.Connections)
+	cur.Connections = m.match.filter(cur.Connections)
+	return history.DiffLists(prev, cur), true
+}
+
+// isFinished reports whether conn is a diff row for a connection that has
+// already gone away, which no action can target.
+func (m Model) isFinished(conn live.Connection) bool {
+	diff, ok := m.captureDiff()
+	return ok && diff.Status(conn) == history.DiffFinished
+}
+
+func (m Model) rejectFinishedAction() Model {
+	return m.setActionError("connection finished before this capture — actions unavailable")
+}
+
+// rowMatch is a text or regular expression filter over a connection's user,
+// application, and query. A "user:", "app:", or "query:" prefix scopes it to
+// one field; a pattern wrapped in slashes is a regular expression, and plain
+// text matches case-insensitively.
+type rowMatch struct {
+	pattern string
+	field   string
+	text    string
+	re      *regexp.Regexp
+}
+
+var rowMatchFields = []string{"user", "app", "query"}
+
+func parseRowMatch(pattern string) (rowMatch, error) {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return rowMatch{}, nil
+	}
+	match := rowMatch{pattern: pattern}
+	expr := pattern
+	if field, rest, ok := strings.Cut(pattern, ":"); ok && slices.Contains(rowMatchFields, field) {
+		match.field = field
+		expr = strings.TrimSpace(rest)
+	}
+	if len(expr) >= 2 && strings.HasPrefix(expr, "/") && strings.HasSuffix(expr, "/") {
+		re, err := regexp.Compile(expr[1 : len(expr)-1])
+		if err != nil {
+			return rowMatch{}, fmt.Errorf("invalid filter regex: %w", err)
+		}
+		match.re = re
+		return match, nil
+	}
+	match.text = strings.ToLower(expr)
+	return match, nil
+}
+
+func (r rowMatch) active() bool {
+	return r.pattern != ""
+}
+
+// filter returns the connections that match. The result never aliases
+// connections when the filter is active, so history-owned slices stay intact.
+func (r rowMatch) filter(connections []live.Connection) []live.Connection {
+	if !r.active() {
+		return connections
+	}
+	out := make([]live.Connection, 0, len(connections))
+	for _, conn := range connections {
+		if r.matches(conn) {
+			out = append(out, conn)
+		}
+	}
+	return out
+}
+
+func (r rowMatch) matches(conn live.Connection) bool {
+	var values []string
+	switch r.field {
+	case "user":
+		values = []string{conn.Username}
+	case "app":
+		values = []string{conn.ApplicationName}
+	case "query":
+		values = []string{conn.QueryText}
+	default:
+		values = []string{conn.Username, conn.ApplicationName, conn.QueryText}
+	}
+	for _, value := range values {
+		if r.re != nil {
+			if r.re.MatchString(value) {
+				return true
+			}
+			continue
+		}
+		if strings.Contains(strings.ToLower(value), r.text) {
+			return true
+		}
+	}
+	return false
+}
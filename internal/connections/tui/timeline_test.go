@@ -0,0 +1,187 @@
+package tui
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	qt "github.com/frankban/quicktest"
+	live "github.com/planetscale/cli/internal/connections"
+	"github.com/planetscale/cli/internal/connections/history"
+)
+
+var timelineBase = time.Date(2026, 4, 28, 15, 0, 0, 0, time.Local)
+
+func timelineReplayModel(lists ...[]live.Connection) Model {
+	h := history.NewCaptureHistory(len(lists))
+	for i, conns := range lists {
+		h.Push(live.NewConnectionList(timelineBase.Add(time.Duration(i)*time.Minute), conns, live.SortByTransactionStart))
+	}
+	return NewModel(context.Background(), &clientStub{}, time.Second, 0).
+		WithCaptureHistory(h).
+		WithReadOnlyActions("not available in replay mode")
+}
+
+func typeKeys(m Model, keys ...string) Model {
+	for _, k := range keys {
+		var msg tea.KeyMsg
+		switch k {
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "esc":
+			msg = tea.KeyMsg{Type: tea.KeyEsc}
+		case "backspace":
+			msg = tea.KeyMsg{Type: tea.KeyBackspace}
+		case "down":
+			msg = tea.KeyMsg{Type: tea.KeyDown}
+		default:
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		}
+		updated, _ := m.Update(msg)
+		m = updated.(Model)
+	}
+	return m
+}
+
+func connectionPIDs(list live.ConnectionList) []int {
+	pids := make([]int, 0, len(list.Connections))
+	for _, conn := range list.Connections {
+		pids = append(pids, conn.PID)
+	}
+	return pids
+}
+
+func TestModelFilterPersistsAcrossSteps(t *testing.T) {
+	c := qt.New(t)
+	model := timelineReplayModel(
+		[]live.Connection{{PID: 1, Instance: "primary", Username: "app"}, {PID: 2, Instance: "primary", Username: "report"}},
+		[]live.Connection{{PID: 1, Instance: "primary", Username: "app"}, {PID: 3, Instance: "primary", ApplicationName: "reporting"}},
+	)
+
+	model = typeKeys(model, "/", "r", "e", "p", "x", "backspace", "enter")
+
+	c.Assert(model.prompt, qt.Equals, promptNone)
+	c.Assert(connectionPIDs(model.lastSuccessfulList), qt.DeepEquals, []int{3})
+	c.Assert(model.View(), qt.Contains, "match: rep")
+
+	model = typeKeys(model, "[")
+	c.Assert(connectionPIDs(model.lastSuccessfulList), qt.DeepEquals, []int{2})
+
+	model = typeKeys(model, "/", "user:")
+	c.Assert(model.View(), qt.Contains, "filter: repuser:")
+	model = typeKeys(model, "esc")
+	c.Assert(model.match.pattern, qt.Equals, "rep")
+
+	// Reopening the prompt starts from the active pattern; clearing it and
+	// applying removes the filter.
+	model = typeKeys(model, "/", "backspace", "backspace", "backspace", "enter")
+	c.Assert(model.match.active(), qt.IsFalse)
+	c.Assert(connectionPIDs(model.lastSuccessfulList), qt.DeepEquals, []int{1, 2})
+}
+
+func TestParseRowMatch(t *testing.T) {
+	conn := live.Connection{Username: "app", ApplicationName: "web", QueryText: "SELECT * FROM Users"}
+
+	tests := []struct {
+		pattern string
+		want    bool
+		wantErr string
+	}{
+		{pattern: "users", want: true},
+		{pattern: "query:/FROM Users$/", want: true},
+		{pattern: "/from users/", want: false},
+		{pattern: "user:web", want: false},
+		{pattern: "app:web", want: true},
+		{pattern: "/[/", wantErr: "invalid filter regex: .*"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			c := qt.New(t)
+			match, err := parseRowMatch(tt.pattern)
+			if tt.wantErr != "" {
+				c.Assert(err, qt.ErrorMatches, tt.wantErr)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(match.matches(conn), qt.Equals, tt.want)
+		})
+	}
+}
+
+func TestModelInvalidFilterKeepsPreviousFilter(t *testing.T) {
+	c := qt.New(t)
+	model := timelineReplayModel([]live.Connection{{PID: 1, Instance: "primary", Username: "app"}})
+	model = typeKeys(model, "/", "a", "p", "p", "enter")
+
+	model = typeKeys(model, "/", "backspace", "backspace", "backspace", "/", "[", "/", "enter")
+
+	c.Assert(model.match.pattern, qt.Equals, "app")
+	c.Assert(model.actionError, qt.Matches, "invalid filter regex: .*")
+}
+
+func TestModelSeekJumpsToCaptureAtOrBeforeTime(t *testing.T) {
+	c := qt.New(t)
+	model := timelineReplayModel(
+		[]live.Connection{{PID: 1, Instance: "primary"}},
+		[]live.Connection{{PID: 2, Instance: "primary"}},
+		[]live.Connection{{PID: 3, Instance: "primary"}},
+	)
+
+	model = typeKeys(model, "g", "15:01:30", "enter")
+
+	c.Assert(connectionPIDs(model.lastSuccessfulList), qt.DeepEquals, []int{2})
+	c.Assert(model.notice.text, qt.Matches, "showing capture at .*15:01:00")
+	c.Assert(model.paused, qt.IsTrue)
+
+	model = typeKeys(model, "g", "-10m", "enter")
+	c.Assert(connectionPIDs(model.lastSuccessfulList), qt.DeepEquals, []int{1})
+
+	model = typeKeys(model, "g", "later", "enter")
+	c.Assert(connectionPIDs(model.lastSuccessfulList), qt.DeepEquals, []int{1})
+	c.Assert(model.actionError, qt.Matches, `seek: invalid time "later".*`)
+}
+
+func TestModelDiffMarksNewFinishedAndRunningRows(t *testing.T) {
+	c := qt.New(t)
+	model := timelineReplayModel(
+		[]live.Connection{{PID: 1, Instance: "primary"}, {PID: 2, Instance: "primary"}},
+		[]live.Connection{{PID: 1, Instance: "primary"}, {PID: 3, Instance: "primary"}},
+	)
+	model.width = 160
+
+	model = typeKeys(model, "d")
+
+	c.Assert(connectionPIDs(model.lastSuccessfulList), qt.DeepEquals, []int{1, 3, 2})
+	view := model.View()
+	c.Assert(view, qt.Contains, "diff: 1 new, 1 finished, 1 running")
+	c.Assert(view, qt.Matches, `(?s).* \+\s+3 .*`)
+	c.Assert(view, qt.Matches, `(?s).* -\s+2 .*`)
+
+	model = typeKeys(model, "[")
+	c.Assert(model.View(), qt.Contains, "diff: no previous capture")
+	c.Assert(connectionPIDs(model.lastSuccessfulList), qt.DeepEquals, []int{1, 2})
+
+	model = typeKeys(model, "]", "d")
+	c.Assert(connectionPIDs(model.lastSuccessfulList), qt.DeepEquals, []int{1, 3})
+	c.Assert(model.View(), qt.Not(qt.Contains), "diff:")
+}
+
+func TestModelDiffRejectsActionsOnFinishedRows(t *testing.T) {
+	c := qt.New(t)
+	queryID := "q-2"
+	client := &clientStub{}
+	model := NewModel(context.Background(), client, time.Second, 0)
+	for i, conns := range [][]live.Connection{
+		{{PID: 2, Instance: "primary", State: "active", QueryID: &queryID}},
+		{{PID: 1, Instance: "primary", State: "active"}},
+	} {
+		updated, _ := model.Update(listMsg{list: live.NewConnectionList(timelineBase.Add(time.Duration(i)*time.Second), conns, live.SortByTransactionStart)})
+		model = updated.(Model)
+	}
+
+	model = typeKeys(model, "d", "down", "c")
+
+	c.Assert(model.confirming, qt.IsFalse)
+	c.Assert(model.actionError, qt.Equals, "connection finished before this capture — actions unavailable")
+}
@@ -0,0 +1,113 @@
+package watch
+
+import (
+	"time"
+
+	"github.com/planetscale/cli/internal/connections"
+)
+
+// Event types.
+const (
+	// EventAlert reports a rule that started to match.
+	EventAlert = "alert"
+	// EventResolved reports a rule that no longer matches.
+	EventResolved = "resolved"
+	// EventRemediation reports an action taken, or in a dry run the action
+	// that would have been taken, on the session of an alert.
+	EventRemediation = "remediation"
+)
+
+// Remediation actions.
+const (
+	ActionCancelQuery         = "cancel_query"
+	ActionTerminateConnection = "terminate_connection"
+)
+
+// Remediation statuses.
+const (
+	StatusDryRun    = "dry_run"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusSkipped   = "skipped"
+)
+
+// Event is a structured watch event, written as one JSON object to stdout,
+// webhooks, commands and the audit log.
+type Event struct {
+	Type         string    `json:"type"`
+	At           time.Time `json:"at"`
+	Organization string    `json:"org,omitempty"`
+	Database     string    `json:"database"`
+	Branch       string    `json:"branch"`
+	Rule         string    `json:"rule"`
+	Message      string    `json:"message"`
+	// Value and Threshold are in milliseconds for duration rules, and
+	// counts otherwise.
+	Value       int64        `json:"value"`
+	Threshold   int64        `json:"threshold"`
+	Application *string      `json:"application,omitempty"`
+	Session     *Session     `json:"session,omitempty"`
+	Remediation *Remediation `json:"remediation,omitempty"`
+}
+
+// Session is the session an event is about, with the server-issued IDs that
+// actions target.
+type Session struct {
+	PID             int        `json:"pid"`
+	Instance        string     `json:"instance"`
+	InstanceRole    string     `json:"instance_role,omitempty"`
+	Username        string     `json:"username"`
+	ApplicationName string     `json:"application_name"`
+	ClientAddr      string     `json:"client_addr"`
+	State           string     `json:"state"`
+	DurationMS      int64      `json:"duration_ms"`
+	XactStart       *time.Time `json:"xact_start,omitempty"`
+	QueryID         *string    `json:"query_id,omitempty"`
+	TransactionID   *string    `json:"transaction_id,omitempty"`
+	ConnectionID    *string    `json:"connection_id,omitempty"`
+	BlockedBy       []int      `json:"blocked_by,omitempty"`
+	QueryText       string     `json:"query_text"`
+}
+
+// Remediation is the outcome of acting on the session of an alert.
+type Remediation struct {
+	Action string `json:"action"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// NewEvent returns an event of type typ for m.
+func NewEvent(typ string, at time.Time, m Match) Event {
+	event := Event{
+		Type:        typ,
+		At:          at,
+		Rule:        m.Rule,
+		Message:     m.Message,
+		Value:       m.Value,
+		Threshold:   m.Threshold,
+		Application: m.Application,
+	}
+	if m.Session != nil {
+		event.Session = newSession(*m.Session)
+	}
+	return event
+}
+
+func newSession(conn connections.Connection) *Session {
+	return &Session{
+		PID:             conn.PID,
+		Instance:        conn.Instance,
+		InstanceRole:    conn.InstanceRole,
+		Username:        conn.Username,
+		ApplicationName: conn.ApplicationName,
+		ClientAddr:      conn.ClientAddr,
+		State:           conn.State,
+		DurationMS:      conn.Duration.Milliseconds(),
+		XactStart:       conn.XactStart,
+		QueryID:         conn.QueryID,
+		TransactionID:   conn.TransactionID,
+		ConnectionID:    conn.ConnectionID,
+		BlockedBy:       conn.BlockedBy,
+		QueryText:       conn.QueryText,
+	}
+}
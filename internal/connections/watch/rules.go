@@ -0,0 +1,255 @@
+// Package watch evaluates alerting rules against live connection lists.
+package watch
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/planetscale/cli/internal/connections"
+)
+
+// Rule names.
+const (
+	RuleTransactionAge = "transaction_age"
+	RuleQueryDuration  = "query_duration"
+	RuleChainDepth     = "chain_depth"
+	RuleAppConnections = "app_connections"
+)
+
+// SessionRules are the rules whose matches name a single session, and so can
+// be remediated by acting on it.
+var SessionRules = []string{RuleTransactionAge, RuleQueryDuration, RuleChainDepth}
+
+// Rules are the thresholds evaluated on each connection list. A zero
+// threshold disables its rule.
+type Rules struct {
+	// MaxTransactionAge matches sessions whose transaction has been open
+	// longer than this.
+	MaxTransactionAge time.Duration
+	// MaxQueryDuration matches sessions running one statement for longer
+	// than this.
+	MaxQueryDuration time.Duration
+	// MaxChainDepth matches sessions at the head of a lock-wait chain more
+	// than this many sessions deep.
+	MaxChainDepth int
+	// MaxAppConnections matches applications holding more than this many
+	// connections.
+	MaxAppConnections int
+}
+
+// Empty reports whether every rule is disabled.
+func (r Rules) Empty() bool {
+	return r == Rules{}
+}
+
+// Match is a rule violation in one connection list.
+type Match struct {
+	Rule string
+	// Key identifies the violation across lists: a long transaction keeps
+	// its key for as long as it stays open.
+	Key     string
+	Message string
+	// Value and Threshold are in milliseconds for duration rules, and
+	// counts otherwise.
+	Value     int64
+	Threshold int64
+	// Session is the session the match names, or nil for rules about many
+	// sessions.
+	Session *connections.Connection
+	// Application is the application an app_connections match names.
+	Application *string
+}
+
+// Evaluate returns the matches of rules in list, in a stable order.
+func (r Rules) Evaluate(list connections.ConnectionList) []Match {
+	var matches []Match
+	for i := range list.Connections {
+		conn := &list.Connections[i]
+		if r.MaxTransactionAge > 0 && conn.XactStart != nil {
+			if age := list.CapturedAt.Sub(*conn.XactStart); age > r.MaxTransactionAge {
+				matches = append(matches, Match{
+					Rule:      RuleTransactionAge,
+					Key:       sessionKey(RuleTransactionAge, conn, conn.XactStart.UTC().Format(time.RFC3339Nano)),
+					Message:   fmt.Sprintf("transaction open for %s (threshold %s)", age.Round(time.Second), r.MaxTransactionAge),
+					Value:     age.Milliseconds(),
+					Threshold: r.MaxTransactionAge.Milliseconds(),
+					Session:   conn,
+				})
+			}
+		}
+		if r.MaxQueryDuration > 0 && conn.HasWork() && conn.Duration > r.MaxQueryDuration {
+			matches = append(matches, Match{
+				Rule:      RuleQueryDuration,
+				Key:       sessionKey(RuleQueryDuration, conn, queryID(conn)),
+				Message:   fmt.Sprintf("query running for %s (threshold %s)", conn.Duration.Round(time.Second), r.MaxQueryDuration),
+				Value:     conn.Duration.Milliseconds(),
+				Threshold: r.MaxQueryDuration.Milliseconds(),
+				Session:   conn,
+			})
+		}
+	}
+	if r.MaxChainDepth > 0 {
+		matches = append(matches, r.chainDepthMatches(list)...)
+	}
+	if r.MaxAppConnections > 0 {
+		matches = append(matches, r.appConnectionMatches(list)...)
+	}
+	return matches
+}
+
+func (r Rules) chainDepthMatches(list connections.ConnectionList) []Match {
+	groups := make(map[string][]connections.Connection)
+	var instances []string
+	for _, conn := range list.Connections {
+		if _, ok := groups[conn.Instance]; !ok {
+			instances = append(instances, conn.Instance)
+		}
+		groups[conn.Instance] = append(groups[conn.Instance], conn)
+	}
+	slices.Sort(instances)
+
+	var matches []Match
+	for _, instance := range instances {
+		group := groups[instance]
+		downstream := make(map[int][]int)
+		sessions := make(map[int]connections.Connection, len(group))
+		blocked := make(map[int]bool)
+		for _, conn := range group {
+			sessions[conn.PID] = conn
+			for _, blocker := range conn.BlockedBy {
+				downstream[blocker] = append(downstream[blocker], conn.PID)
+				blocked[conn.PID] = true
+			}
+		}
+
+		var roots []int
+		for blocker := range downstream {
+			if !blocked[blocker] {
+				roots = append(roots, blocker)
+			}
+		}
+		slices.Sort(roots)
+		for _, root := range roots {
+			depth := chainDepth(root, downstream, map[int]bool{root: true})
+			if depth <= r.MaxChainDepth {
+				continue
+			}
+			conn, ok := sessions[root]
+			if !ok {
+				// The blocker is outside the list, e.g. filtered out.
+				conn = connections.Connection{PID: root, Instance: instance}
+			}
+			matches = append(matches, Match{
+				Rule:      RuleChainDepth,
+				Key:       sessionKey(RuleChainDepth, &conn, ""),
+				Message:   fmt.Sprintf("blocking a lock-wait chain %d sessions deep (threshold %d)", depth, r.MaxChainDepth),
+				Value:     int64(depth),
+				Threshold: int64(r.MaxChainDepth),
+				Session:   &conn,
+			})
+		}
+	}
+	return matches
+}
+
+// chainDepth is the number of sessions in the longest chain waiting on pid.
+func chainDepth(pid int, downstream map[int][]int, seen map[int]bool) int {
+	depth := 0
+	for _, child := range downstream[pid] {
+		if seen[child] {
+			continue
+		}
+		seen[child] = true
+		depth = max(depth, 1+chainDepth(child, downstream, seen))
+		delete(seen, child)
+	}
+	return depth
+}
+
+func (r Rules) appConnectionMatches(list connections.ConnectionList) []Match {
+	counts := make(map[string]int)
+	for _, conn := range list.Connections {
+		counts[conn.ApplicationName]++
+	}
+	apps := make([]string, 0, len(counts))
+	for app := range counts {
+		apps = append(apps, app)
+	}
+	slices.Sort(apps)
+
+	var matches []Match
+	for _, app := range apps {
+		if counts[app] <= r.MaxAppConnections {
+			continue
+		}
+		name := app
+		if name == "" {
+			name = "(no application name)"
+		}
+		matches = append(matches, Match{
+			Rule:        RuleAppConnections,
+			Key:         RuleAppConnections + "\x00" + app,
+			Message:     fmt.Sprintf("%s holds %d connections (threshold %d)", name, counts[app], r.MaxAppConnections),
+			Value:       int64(counts[app]),
+			Threshold:   int64(r.MaxAppConnections),
+			Application: &app,
+		})
+	}
+	return matches
+}
+
+func sessionKey(rule string, conn *connections.Connection, id string) string {
+	return strings.Join([]string{rule, conn.Instance, strconv.Itoa(conn.PID), id}, "\x00")
+}
+
+// queryID tells executions of queries on one session apart.
+func queryID(conn *connections.Connection) string {
+	switch {
+	case conn.QueryID != nil:
+		return *conn.QueryID
+	case conn.QueryStart != nil:
+		return conn.QueryStart.UTC().Format(time.RFC3339Nano)
+	default:
+		return conn.QueryText
+	}
+}
+
+// Tracker follows matches across connection lists, so a violation alerts
+// once when it starts and once when it resolves rather than on every list.
+type Tracker struct {
+	active map[string]Match
+}
+
+// NewTracker returns a Tracker with no active matches.
+func NewTracker() *Tracker {
+	return &Tracker{active: make(map[string]Match)}
+}
+
+// Update records the matches of the latest list and returns the matches that
+// started and the previously active matches that no longer match.
+func (t *Tracker) Update(matches []Match) (started, resolved []Match) {
+	current := make(map[string]bool, len(matches))
+	for _, m := range matches {
+		current[m.Key] = true
+		if _, ok := t.active[m.Key]; !ok {
+			started = append(started, m)
+		}
+		t.active[m.Key] = m
+	}
+
+	var keys []string
+	for key := range t.active {
+		if !current[key] {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		resolved = append(resolved, t.active[key])
+		delete(t.active, key)
+	}
+	return started, resolved
+}
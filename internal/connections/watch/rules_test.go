@@ -0,0 +1,81 @@
+package watch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/planetscale/cli/internal/connections"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestRulesEvaluate(t *testing.T) {
+	c := qt.New(t)
+	at := time.Date(2026, 4, 28, 15, 0, 0, 0, time.UTC)
+	oldXact := at.Add(-10 * time.Minute)
+	newXact := at.Add(-time.Minute)
+	list := connections.ConnectionList{CapturedAt: at, Connections: []connections.Connection{
+		{PID: 1, Instance: "primary", ApplicationName: "web", State: "idle in transaction", XactStart: &oldXact},
+		{PID: 2, Instance: "primary", ApplicationName: "web", State: "active", XactStart: &newXact, Duration: 20 * time.Minute, QueryText: "SELECT sleep(1200)", BlockedBy: []int{1}},
+		{PID: 3, Instance: "primary", ApplicationName: "web", State: "active", Duration: time.Second, QueryText: "UPDATE t SET x = 1", BlockedBy: []int{2}},
+		{PID: 4, Instance: "primary", ApplicationName: "worker", State: "idle", Duration: time.Hour},
+	}}
+	rules := Rules{
+		MaxTransactionAge: 5 * time.Minute,
+		MaxQueryDuration:  10 * time.Minute,
+		MaxChainDepth:     1,
+		MaxAppConnections: 2,
+	}
+
+	matches := rules.Evaluate(list)
+
+	c.Assert(matches, qt.HasLen, 4)
+	c.Assert(matches[0].Rule, qt.Equals, RuleTransactionAge)
+	c.Assert(matches[0].Session.PID, qt.Equals, 1)
+	c.Assert(matches[0].Value, qt.Equals, (10 * time.Minute).Milliseconds())
+	c.Assert(matches[0].Message, qt.Equals, "transaction open for 10m0s (threshold 5m0s)")
+	c.Assert(matches[1].Rule, qt.Equals, RuleQueryDuration)
+	c.Assert(matches[1].Session.PID, qt.Equals, 2)
+	c.Assert(matches[2].Rule, qt.Equals, RuleChainDepth)
+	c.Assert(matches[2].Session.PID, qt.Equals, 1)
+	c.Assert(matches[2].Value, qt.Equals, int64(2))
+	c.Assert(matches[3].Rule, qt.Equals, RuleAppConnections)
+	c.Assert(*matches[3].Application, qt.Equals, "web")
+	c.Assert(matches[3].Session, qt.IsNil)
+}
+
+func TestRulesEvaluateChainDepthWithAbsentBlocker(t *testing.T) {
+	c := qt.New(t)
+	list := connections.ConnectionList{Connections: []connections.Connection{
+		{PID: 2, Instance: "primary", State: "active", BlockedBy: []int{1}},
+		{PID: 3, Instance: "primary", State: "active", BlockedBy: []int{1}},
+	}}
+
+	matches := Rules{MaxChainDepth: 1}.Evaluate(list)
+	c.Assert(matches, qt.HasLen, 0)
+
+	list.Connections[1].BlockedBy = []int{2}
+	matches = Rules{MaxChainDepth: 1}.Evaluate(list)
+	c.Assert(matches, qt.HasLen, 1)
+	c.Assert(matches[0].Session.PID, qt.Equals, 1)
+	c.Assert(matches[0].Session.Instance, qt.Equals, "primary")
+}
+
+func TestTrackerReportsStartsAndResolutions(t *testing.T) {
+	c := qt.New(t)
+	tracker := NewTracker()
+	a := Match{Rule: RuleAppConnections, Key: "a"}
+	b := Match{Rule: RuleAppConnections, Key: "b"}
+
+	started, resolved := tracker.Update([]Match{a})
+	c.Assert(started, qt.DeepEquals, []Match{a})
+	c.Assert(resolved, qt.HasLen, 0)
+
+	started, resolved = tracker.Update([]Match{a, b})
+	c.Assert(started, qt.DeepEquals, []Match{b})
+	c.Assert(resolved, qt.HasLen, 0)
+
+	started, resolved = tracker.Update([]Match{b})
+	c.Assert(started, qt.HasLen, 0)
+	c.Assert(resolved, qt.DeepEquals, []Match{a})
+}
@@ -0,0 +1,390 @@
+package dumper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	checkpointFile        = "checkpoint.json"
+	checkpointJournalFile = "checkpoint.journal"
+	checkpointVersion     = 1
+)
+
+// tableCheckpoint records how far the data of a single table has been dumped.
+type tableCheckpoint struct {
+	// Chunks is the number of chunk files that have been fully written.
+	Chunks int `json:"chunks"`
+	// Rows is the number of rows contained in the written chunk files.
+	Rows uint64 `json:"rows"`
+	// LastKey holds the primary key of the last row of the last written
+	// chunk as SQL literals. It is empty for tables without a usable primary
+	// key, which can only be resumed from the beginning.
+	LastKey []string `json:"last_key,omitempty"`
+	Done    bool     `json:"done"`
+	// Bounds are the upper bounds of the primary key ranges of a table that
+	// is dumped in ranges, so a resumed dump splits it the same way. The
+	// progress of every range is recorded separately.
+	Bounds []string `json:"bounds,omitempty"`
+}
+
+// checkpoint is the resumable state of a dump, stored next to the metadata
+// file in the dump directory. It is rewritten when a table is started in
+// ranges, reset or finished. A committed chunk is only appended to the
+// journal next to it, so the cost of a commit doesn't grow with the number
+// of files dumped.
+type checkpoint struct {
+	mu   sync.Mutex
+	path string
+
+	Version      int                         `json:"version"`
+	OutputFormat string                      `json:"output_format"`
+	Tables       map[string]*tableCheckpoint `json:"tables"`
+	// Files are the manifest entries of the files written so far, so a
+	// resumed dump can still list them in its manifest.
+	Files          []ManifestFile  `json:"files,omitempty"`
+	ManifestTables []ManifestTable `json:"manifest_tables,omitempty"`
+	// JournalID identifies the journal entries written after the checkpoint
+	// file; entries left behind by an earlier version of it are ignored.
+	JournalID int64 `json:"journal_id,omitempty"`
+	// ConsistentSnapshot is set for a dump taken from a consistent snapshot
+	// at GTIDExecuted. The snapshot ends with the dump, so such a dump
+	// cannot be resumed.
+	ConsistentSnapshot bool   `json:"consistent_snapshot,omitempty"`
+	GTIDExecuted       string `json:"gtid_executed,omitempty"`
+
+	manifest *manifest
+	// journal holds the entries read with the checkpoint until they are
+	// replayed into the manifest.
+	journal []checkpointEntry
+	// saved is set once the checkpoint file has been written, or read, so
+	// the journal belongs to it.
+	saved bool
+}
+
+// checkpointEntry is a line of the checkpoint journal, written when a chunk
+// of a table is committed.
+type checkpointEntry struct {
+	JournalID int64           `json:"journal_id"`
+	Key       string          `json:"key"`
+	State     tableCheckpoint `json:"state"`
+	// Files are the manifest entries of the files written since the previous
+	// entry, and Table the committed chunk with its table's columns and key.
+	Files []ManifestFile `json:"files,omitempty"`
+	Table *ManifestTable `json:"table,omitempty"`
+}
+
+func newCheckpoint(outdir, outputFormat string) *checkpoint {
+	return &checkpoint{
+		path:         filepath.Join(outdir, checkpointFile),
+		Version:      checkpointVersion,
+		OutputFormat: outputFormat,
+		Tables:       make(map[string]*tableCheckpoint),
+	}
+}
+
+// loadCheckpoint reads the checkpoint of a previous, interrupted dump.
+func loadCheckpoint(outdir, outputFormat string) (*checkpoint, error) {
+	path := filepath.Join(outdir, checkpointFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no checkpoint found in %s, cannot resume dump", outdir)
+		}
+		return nil, err
+	}
+
+	cp := &checkpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %w", path, err)
+	}
+	if cp.Version != checkpointVersion {
+		return nil, fmt.Errorf("unsupported checkpoint version %d in %s", cp.Version, path)
+	}
+	if cp.OutputFormat != outputFormat {
+		return nil, fmt.Errorf("checkpoint in %s was written with output format %q, cannot resume with %q", outdir, cp.OutputFormat, outputFormat)
+	}
+
+	if cp.ConsistentSnapshot {
+		return nil, fmt.Errorf("cannot resume the dump in %s: it was taken from a consistent snapshot at GTID set %s, which ended with the interrupted dump, start a new dump instead", outdir, cp.GTIDExecuted)
+	}
+
+	cp.path = path
+	cp.saved = true
+	if cp.Tables == nil {
+		cp.Tables = make(map[string]*tableCheckpoint)
+	}
+	if err := cp.readJournal(); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// readJournal applies the journal entries written after the checkpoint
+// file. A torn last line, left by an interruption while it was written, is
+// ignored.
+func (cp *checkpoint) readJournal() error {
+	path := cp.journalPath()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	lines := bytes.Split(bytes.TrimRight(data, "\n"), []byte("\n"))
+	for i, line := range lines {
+		var entry checkpointEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			if i == len(lines)-1 {
+				break
+			}
+			return fmt.Errorf("invalid checkpoint journal %s: %w", path, err)
+		}
+		if entry.JournalID != cp.JournalID {
+			continue
+		}
+		state := entry.State
+		cp.Tables[entry.Key] = &state
+		cp.journal = append(cp.journal, entry)
+	}
+	return nil
+}
+
+func (cp *checkpoint) journalPath() string {
+	return filepath.Join(filepath.Dir(cp.path), checkpointJournalFile)
+}
+
+// trackManifest saves the files and tables recorded in m along with the
+// checkpoint, after adding the ones recorded by the interrupted dump to it.
+func (cp *checkpoint) trackManifest(m *manifest) {
+	if cp == nil {
+		return
+	}
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	m.restore(cp.Files, cp.ManifestTables)
+	for _, entry := range cp.journal {
+		m.replay(entry.Files, entry.Table, entry.State.Chunks)
+	}
+	cp.journal = nil
+	cp.manifest = m
+}
+
+// recordSnapshot records that the dump is taken from a consistent snapshot
+// at the GTID set gtid, so that it is never resumed.
+func (cp *checkpoint) recordSnapshot(gtid string) error {
+	if cp == nil {
+		return nil
+	}
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.ConsistentSnapshot = true
+	cp.GTIDExecuted = gtid
+	return cp.save()
+}
+
+func checkpointKey(database, table string) string {
+	return database + "." + table
+}
+
+// table returns a copy of the recorded state of a table, the zero value if
+// the table has not been started yet.
+func (cp *checkpoint) table(database, table string) tableCheckpoint {
+	if cp == nil {
+		return tableCheckpoint{}
+	}
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	if t, ok := cp.Tables[checkpointKey(database, table)]; ok {
+		return *t
+	}
+	return tableCheckpoint{}
+}
+
+// commitChunk records that chunk file fileNo of a table, or of its range
+// part, has been written. Unless the checkpoint file has not been written
+// yet, the commit is appended to the journal.
+func (cp *checkpoint) commitChunk(database, table string, part, fileNo int, rows uint64, lastKey []string) error {
+	if cp == nil {
+		return nil
+	}
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	key := checkpointKey(database, tableRange{part: part}.checkpointTable(table))
+	t := cp.tableLocked(key)
+	t.Chunks = fileNo
+	t.Rows = rows
+	t.LastKey = lastKey
+	if !cp.saved {
+		return cp.save()
+	}
+
+	entry := checkpointEntry{
+		JournalID: cp.JournalID,
+		Key:       key,
+		State:     *t,
+		Files:     cp.manifest.changedFiles(),
+		Table:     cp.manifest.chunk(database, table, part, fileNo),
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(cp.journalPath(), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// reset forgets any progress of a table that has to be dumped from scratch.
+func (cp *checkpoint) reset(database, table string) error {
+	return cp.update(database, table, func(t *tableCheckpoint) {
+		*t = tableCheckpoint{}
+	})
+}
+
+// split records the bounds of the primary key ranges a table is dumped in.
+func (cp *checkpoint) split(database, table string, bounds []string) error {
+	return cp.update(database, table, func(t *tableCheckpoint) {
+		t.Bounds = bounds
+	})
+}
+
+// unsplit forgets the bounds of a table and the progress of the ranges it
+// was split into.
+func (cp *checkpoint) unsplit(database, table string) error {
+	return cp.update(database, table, func(t *tableCheckpoint) {
+		for _, r := range rangesFromBounds(t.Bounds) {
+			delete(cp.Tables, checkpointKey(database, r.checkpointTable(table)))
+		}
+		*t = tableCheckpoint{}
+	})
+}
+
+// finish marks a table as completely dumped.
+func (cp *checkpoint) finish(database, table string, rows uint64) error {
+	return cp.update(database, table, func(t *tableCheckpoint) {
+		t.Rows = rows
+		t.LastKey = nil
+		t.Done = true
+	})
+}
+
+func (cp *checkpoint) update(database, table string, fn func(t *tableCheckpoint)) error {
+	if cp == nil {
+		return nil
+	}
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	fn(cp.tableLocked(checkpointKey(database, table)))
+	return cp.save()
+}
+
+// tableLocked returns the recorded state of the table with the given key,
+// adding it if the table has not been started yet. Callers must hold cp.mu.
+func (cp *checkpoint) tableLocked(key string) *tableCheckpoint {
+	t, ok := cp.Tables[key]
+	if !ok {
+		t = &tableCheckpoint{}
+		cp.Tables[key] = t
+	}
+	return t
+}
+
+// save atomically replaces the checkpoint file so an interruption never
+// leaves a truncated checkpoint behind, and starts a new journal. Callers
+// must hold cp.mu.
+func (cp *checkpoint) save() error {
+	cp.Files, cp.ManifestTables = cp.manifest.snapshot()
+	cp.JournalID = time.Now().UnixNano()
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := cp.path + ".tmp"
+	if err := writeFile(tmp, string(data)); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, cp.path); err != nil {
+		return err
+	}
+	cp.saved = true
+	if err := os.Remove(cp.journalPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// removeTableChunks deletes data chunk files numbered after the given chunk
+// that were left behind by an earlier, interrupted attempt at dumping a table
+// or one of its primary key ranges.
+func removeTableChunks(outdir, database, table string, part, after int) error {
+	suffix := "."
+	if part > 0 {
+		suffix = fmt.Sprintf(".%05d-", part)
+	}
+	prefix, err := dumpOutputPath(outdir, database, table, suffix)
+	if err != nil {
+		return err
+	}
+	prefix = filepath.Base(prefix)
+
+	entries, err := os.ReadDir(outdir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if n, ok := chunkNumber(e.Name(), prefix); !ok || n <= after {
+			continue
+		}
+		if err := os.Remove(filepath.Join(outdir, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chunkNumber returns the chunk number of name if it is a data chunk file of
+// the table whose file names start with prefix, e.g. "db.table.00001.sql".
+func chunkNumber(name, prefix string) (int, bool) {
+	rest, ok := strings.CutPrefix(name, prefix)
+	if !ok {
+		return 0, false
+	}
+	num, ext, ok := strings.Cut(rest, ".")
+	if !ok || ext == "" || len(num) != 5 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(num)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
@@ -0,0 +1,345 @@
+package dumper
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/xelabs/go-mysqlstack/driver"
+	querypb "github.com/xelabs/go-mysqlstack/sqlparser/depends/query"
+	"github.com/xelabs/go-mysqlstack/sqlparser/depends/sqltypes"
+	"github.com/xelabs/go-mysqlstack/xlog"
+)
+
+func keyedFieldsResult() *sqltypes.Result {
+	id := testRow("id", "")
+	id[3] = sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte("PRI"))
+
+	return &sqltypes.Result{
+		Fields: []*querypb.Field{
+			{Name: "Field", Type: querypb.Type_VARCHAR},
+			{Name: "Type", Type: querypb.Type_VARCHAR},
+			{Name: "Null", Type: querypb.Type_VARCHAR},
+			{Name: "Key", Type: querypb.Type_VARCHAR},
+			{Name: "Default", Type: querypb.Type_VARCHAR},
+			{Name: "Extra", Type: querypb.Type_VARCHAR},
+		},
+		Rows: [][]sqltypes.Value{
+			id,
+			testRow("name", ""),
+		},
+	}
+}
+
+func newResumeTestServer(c *qt.C) (*driver.TestHandler, string) {
+	log := xlog.NewStdLog(xlog.Level(xlog.PANIC))
+	fakedbs := driver.NewTestHandler(log)
+	server, err := driver.MockMysqlServer(log, fakedbs)
+	c.Assert(err, qt.IsNil)
+	c.Cleanup(func() { server.Close() })
+
+	fakedbs.AddQueryPattern("show create table .*", &sqltypes.Result{
+		Fields: []*querypb.Field{
+			{Name: "Table", Type: querypb.Type_VARCHAR},
+			{Name: "Create Table", Type: querypb.Type_VARCHAR},
+		},
+		Rows: [][]sqltypes.Value{
+			{
+				sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte("t1")),
+				sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte("CREATE TABLE `t1` (`id` int, `name` varchar(10), PRIMARY KEY (`id`))")),
+			},
+		},
+	})
+	fakedbs.AddQueryPattern("show tables from .*", &sqltypes.Result{
+		Fields: []*querypb.Field{{Name: "Tables_in_test", Type: querypb.Type_VARCHAR}},
+		Rows: [][]sqltypes.Value{
+			{sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte("t1"))},
+		},
+	})
+	fakedbs.AddQueryPattern("select table_name \n\t\t\t from information_schema.tables \n\t\t\t where table_schema like 'test' \n\t\t\t and table_type = 'view'\n\t\t\t", &sqltypes.Result{
+		Fields: []*querypb.Field{{Name: "TABLE_NAME", Type: querypb.Type_VARCHAR}},
+	})
+	fakedbs.AddQueryPattern("show fields from .*", keyedFieldsResult())
+
+	return fakedbs, server.Addr()
+}
+
+func rowsResult(ids ...string) *sqltypes.Result {
+	qr := &sqltypes.Result{
+		Fields: []*querypb.Field{
+			{Name: "id", Type: querypb.Type_INT32},
+			{Name: "name", Type: querypb.Type_VARCHAR},
+		},
+	}
+	for _, id := range ids {
+		qr.Rows = append(qr.Rows, []sqltypes.Value{
+			sqltypes.MakeTrusted(querypb.Type_INT32, []byte(id)),
+			sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte("name-"+id)),
+		})
+	}
+	return qr
+}
+
+func TestDumperWritesCheckpoint(t *testing.T) {
+	c := qt.New(t)
+
+	fakedbs, address := newResumeTestServer(c)
+	fakedbs.AddQueryPattern("select `id`, `name` from `test`\\.`t1`  order by `id`", rowsResult("1", "2", "3"))
+
+	cfg := NewDefaultConfig()
+	cfg.Database = "test"
+	cfg.Outdir = c.TempDir()
+	cfg.User = "mock"
+	cfg.Password = "mock"
+	cfg.Address = address
+	cfg.ChunksizeInMB = 1
+	cfg.StmtSize = 10000
+	cfg.IntervalMs = 500
+
+	d, err := NewDumper(cfg)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.Run(context.Background()), qt.IsNil)
+
+	cp, err := loadCheckpoint(cfg.Outdir, "sql")
+	c.Assert(err, qt.IsNil)
+	c.Assert(cp.table("test", "t1"), qt.DeepEquals, tableCheckpoint{Rows: 3, Done: true})
+
+	_, err = loadCheckpoint(cfg.Outdir, "csv")
+	c.Assert(err, qt.ErrorMatches, `.*cannot resume with "csv"`)
+}
+
+func TestDumperResumesFromLastChunk(t *testing.T) {
+	c := qt.New(t)
+
+	fakedbs, address := newResumeTestServer(c)
+	fakedbs.AddQueryPattern("select `id`, `name` from `test`\\.`t1`  where \\(`id`\\) > \\(2\\) order by `id`", rowsResult("3", "4"))
+
+	outdir := c.TempDir()
+	firstChunk := filepath.Join(outdir, "test.t1.00001.sql")
+	c.Assert(writeFile(firstChunk, "first chunk"), qt.IsNil)
+	c.Assert(writeFile(filepath.Join(outdir, "test.t1.00002.sql"), "partial"), qt.IsNil)
+	c.Assert(writeFile(filepath.Join(outdir, "test.t1.00003.sql"), "stale"), qt.IsNil)
+
+	cp := newCheckpoint(outdir, "sql")
+	c.Assert(cp.commitChunk("test", "t1", 0, 1, 2, []string{"2"}), qt.IsNil)
+
+	cfg := NewDefaultConfig()
+	cfg.Database = "test"
+	cfg.Outdir = outdir
+	cfg.User = "mock"
+	cfg.Password = "mock"
+	cfg.Address = address
+	cfg.ChunksizeInMB = 1
+	cfg.StmtSize = 10000
+	cfg.IntervalMs = 500
+	cfg.Resume = true
+
+	d, err := NewDumper(cfg)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.Run(context.Background()), qt.IsNil)
+
+	data, err := os.ReadFile(firstChunk)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(data), qt.Equals, "first chunk")
+
+	data, err = os.ReadFile(filepath.Join(outdir, "test.t1.00002.sql"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(data), qt.Equals, "INSERT INTO `t1`(`id`,`name`) VALUES\n(3,\"name-3\"),\n(4,\"name-4\");\n")
+
+	_, err = os.Stat(filepath.Join(outdir, "test.t1.00003.sql"))
+	c.Assert(os.IsNotExist(err), qt.IsTrue)
+
+	cp, err = loadCheckpoint(outdir, "sql")
+	c.Assert(err, qt.IsNil)
+	c.Assert(cp.table("test", "t1"), qt.DeepEquals, tableCheckpoint{Chunks: 1, Rows: 4, Done: true})
+}
+
+func TestDumperResumesCompositeKeyInKeyOrder(t *testing.T) {
+	c := qt.New(t)
+
+	fakedbs, address := newResumeTestServer(c)
+	// The key is (a, b), but b comes first in the table.
+	b, a := testRow("b", ""), testRow("a", "")
+	b[3] = sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte("PRI"))
+	a[3] = sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte("PRI"))
+	fields := keyedFieldsResult()
+	fields.Rows = [][]sqltypes.Value{b, a, testRow("name", "")}
+	fakedbs.AddQuery("show fields from `t1`", fields)
+	fakedbs.AddQuery("show index from `t1` where key_name = 'primary'", &sqltypes.Result{
+		Fields: []*querypb.Field{
+			{Name: "Table", Type: querypb.Type_VARCHAR},
+			{Name: "Key_name", Type: querypb.Type_VARCHAR},
+			{Name: "Seq_in_index", Type: querypb.Type_INT64},
+			{Name: "Column_name", Type: querypb.Type_VARCHAR},
+		},
+		Rows: [][]sqltypes.Value{
+			{
+				sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte("t1")),
+				sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte("PRIMARY")),
+				sqltypes.MakeTrusted(querypb.Type_INT64, []byte("2")),
+				sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte("b")),
+			},
+			{
+				sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte("t1")),
+				sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte("PRIMARY")),
+				sqltypes.MakeTrusted(querypb.Type_INT64, []byte("1")),
+				sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte("a")),
+			},
+		},
+	})
+	query := "select `b`, `a`, `name` from `test`.`t1`  where (`a`, `b`) > (1, 2) order by `a`, `b`"
+	fakedbs.AddQuery(query, &sqltypes.Result{
+		Fields: []*querypb.Field{
+			{Name: "b", Type: querypb.Type_INT32},
+			{Name: "a", Type: querypb.Type_INT32},
+			{Name: "name", Type: querypb.Type_VARCHAR},
+		},
+	})
+
+	outdir := c.TempDir()
+	cp := newCheckpoint(outdir, "sql")
+	c.Assert(cp.commitChunk("test", "t1", 0, 1, 2, []string{"1", "2"}), qt.IsNil)
+
+	cfg := NewDefaultConfig()
+	cfg.Database = "test"
+	cfg.Outdir = outdir
+	cfg.User = "mock"
+	cfg.Password = "mock"
+	cfg.Address = address
+	cfg.ChunksizeInMB = 1
+	cfg.StmtSize = 10000
+	cfg.IntervalMs = 500
+	cfg.Resume = true
+
+	d, err := NewDumper(cfg)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.Run(context.Background()), qt.IsNil)
+	c.Assert(fakedbs.GetQueryCalledNum(query), qt.Equals, 1)
+}
+
+func TestDumperResumeSkipsFinishedTables(t *testing.T) {
+	c := qt.New(t)
+
+	// No SELECT pattern is registered, so dumping the table would fail.
+	_, address := newResumeTestServer(c)
+
+	outdir := c.TempDir()
+	cp := newCheckpoint(outdir, "sql")
+	c.Assert(cp.finish("test", "t1", 10), qt.IsNil)
+
+	cfg := NewDefaultConfig()
+	cfg.Database = "test"
+	cfg.Outdir = outdir
+	cfg.User = "mock"
+	cfg.Password = "mock"
+	cfg.Address = address
+	cfg.IntervalMs = 500
+	cfg.Resume = true
+
+	d, err := NewDumper(cfg)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.Run(context.Background()), qt.IsNil)
+}
+
+func TestDumperResumeRequiresCheckpoint(t *testing.T) {
+	c := qt.New(t)
+
+	_, address := newResumeTestServer(c)
+
+	cfg := NewDefaultConfig()
+	cfg.Database = "test"
+	cfg.Outdir = c.TempDir()
+	cfg.User = "mock"
+	cfg.Password = "mock"
+	cfg.Address = address
+	cfg.IntervalMs = 500
+	cfg.Resume = true
+
+	d, err := NewDumper(cfg)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.Run(context.Background()), qt.ErrorMatches, "no checkpoint found in .*")
+}
+
+func TestCheckpointJournalsCommittedChunks(t *testing.T) {
+	c := qt.New(t)
+
+	outdir := c.TempDir()
+	cfg := &Config{Outdir: outdir, manifest: newManifest()}
+	cp := newCheckpoint(outdir, "sql")
+	cp.trackManifest(cfg.manifest)
+
+	commit := func(table string, part, fileNo int, rows uint64, key string) {
+		c.Helper()
+		name := filepath.Join(outdir, "test."+table+chunkID{part: part, fileNo: fileNo}.suffix("sql"))
+		c.Assert(writeDataFile(cfg, name, "chunk "+key, rows), qt.IsNil)
+		cfg.manifest.startTable("test", table, part, []string{"id"}, []string{"id"}, fileNo-1)
+		cfg.manifest.addChunk("test", table, ManifestChunk{Part: part, Rows: rows, LastKey: []string{key}})
+		c.Assert(cp.commitChunk("test", table, part, fileNo, rows, []string{key}), qt.IsNil)
+	}
+
+	// The first commit writes the checkpoint file, later ones only append to
+	// the journal.
+	commit("t1", 0, 1, 2, "2")
+	saved, err := os.ReadFile(filepath.Join(outdir, checkpointFile))
+	c.Assert(err, qt.IsNil)
+	commit("t1", 0, 2, 4, "4")
+	commit("t2", 3, 1, 1, "7")
+	data, err := os.ReadFile(filepath.Join(outdir, checkpointFile))
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(data), qt.Equals, string(saved))
+
+	// A line torn by an interruption is ignored.
+	f, err := os.OpenFile(filepath.Join(outdir, checkpointJournalFile), os.O_WRONLY|os.O_APPEND, 0o644)
+	c.Assert(err, qt.IsNil)
+	_, err = f.WriteString(`{"journal_id":`)
+	c.Assert(err, qt.IsNil)
+	c.Assert(f.Close(), qt.IsNil)
+
+	loaded, err := loadCheckpoint(outdir, "sql")
+	c.Assert(err, qt.IsNil)
+	c.Assert(loaded.table("test", "t1"), qt.DeepEquals, tableCheckpoint{Chunks: 2, Rows: 4, LastKey: []string{"4"}})
+	c.Assert(loaded.table("test", "t2#00003"), qt.DeepEquals, tableCheckpoint{Chunks: 1, Rows: 1, LastKey: []string{"7"}})
+
+	m := newManifest()
+	loaded.trackManifest(m)
+	c.Assert(m.list(), qt.DeepEquals, cfg.manifest.list())
+	c.Assert(m.tableList(), qt.DeepEquals, cfg.manifest.tableList())
+
+	// Finishing a table rewrites the checkpoint file and starts a new
+	// journal, so the entries of the old one are not replayed again.
+	c.Assert(loaded.finish("test", "t1", 4), qt.IsNil)
+	_, err = os.Stat(filepath.Join(outdir, checkpointJournalFile))
+	c.Assert(err, qt.ErrorIs, os.ErrNotExist)
+	loaded, err = loadCheckpoint(outdir, "sql")
+	c.Assert(err, qt.IsNil)
+	c.Assert(loaded.table("test", "t1"), qt.DeepEquals, tableCheckpoint{Rows: 4, Done: true, Chunks: 2})
+	c.Assert(loaded.ManifestTables, qt.DeepEquals, cfg.manifest.tableList())
+}
+
+func TestResumeWhere(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := &dumpContext{fieldNames: []string{"a", "b", "c"}, keyIndexes: []int{0, 2}}
+	c.Assert(ctx.resumeWhere([]string{"1", "'x'"}), qt.Equals, " WHERE (`a`, `c`) > (1, 'x')")
+	c.Assert(ctx.orderBy(), qt.Equals, " ORDER BY `a`, `c`")
+
+	ctx.where = " WHERE b = 1 OR b = 2"
+	c.Assert(ctx.resumeWhere([]string{"1", "'x'"}), qt.Equals, " WHERE (b = 1 OR b = 2) AND (`a`, `c`) > (1, 'x')")
+}
+
+func TestChunkNumber(t *testing.T) {
+	c := qt.New(t)
+
+	n, ok := chunkNumber("db.t1.00042.sql", "db.t1.")
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(n, qt.Equals, 42)
+
+	_, ok = chunkNumber("db.t1-schema.sql", "db.t1.")
+	c.Assert(ok, qt.IsFalse)
+	_, ok = chunkNumber("db.t10.00001.sql", "db.t1.")
+	c.Assert(ok, qt.IsFalse)
+	_, ok = chunkNumber("db.t1.0001.sql", "db.t1.")
+	c.Assert(ok, qt.IsFalse)
+}
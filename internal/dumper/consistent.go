@@ -0,0 +1,162 @@
+package dumper
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+
+	"go.uber.org/zap"
+)
+
+// maxSnapshotAttempts bounds how often opening the worker snapshots is
+// retried when writes land while the snapshots are being taken.
+const maxSnapshotAttempts = 10
+
+const gtidExecutedQuery = "SELECT @@global.gtid_executed"
+
+// checkSnapshotTarget fails if one of the databases is a sharded Vitess
+// keyspace, as the shards cannot provide a single consistent view.
+func (d *Dumper) checkSnapshotTarget(conn *Connection, databases []string) error {
+	if d.cfg.Shard != "" {
+		// The session is pinned to a single shard.
+		return nil
+	}
+
+	qr, err := conn.Fetch("SHOW VITESS_SHARDS")
+	if err != nil {
+		// Not served by Vitess, so there is nothing to fan out to.
+		d.log.Debug("unable to list vitess shards", zap.Error(err))
+		return nil
+	}
+
+	shards := make(map[string]int)
+	for _, row := range qr.Rows {
+		if len(row) == 0 {
+			continue
+		}
+		keyspace, _, _ := strings.Cut(row[0].String(), "/")
+		shards[keyspace]++
+	}
+
+	for _, database := range databases {
+		if n := shards[database]; n > 1 {
+			return fmt.Errorf("cannot take a consistent snapshot of keyspace %s: it has %d shards, dump one shard at a time with --shard instead", database, n)
+		}
+	}
+	return nil
+}
+
+// startConsistentSnapshot opens a transaction WITH CONSISTENT SNAPSHOT on
+// every worker connection so all of them read the same point in time. It
+// returns the GTID set the snapshot corresponds to.
+//
+// A snapshot is known to be at GTID set G when gtid_executed is G both
+// right before and right after the transaction was started, so no commit
+// could have slipped in. The snapshots are retried until every connection
+// agrees on G.
+func (d *Dumper) startConsistentSnapshot(ctx context.Context, pools []*Pool) (string, error) {
+	var conns []*Connection
+	for _, pool := range pools {
+		drained := pool.drain()
+		defer func() {
+			for _, conn := range drained {
+				pool.Put(conn)
+			}
+		}()
+		conns = append(conns, drained...)
+	}
+
+	for attempt := 1; attempt <= maxSnapshotAttempts; attempt++ {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+
+		gtids := make([]string, len(conns))
+		eg := errgroup.Group{}
+		for i, conn := range conns {
+			eg.Go(func() error {
+				gtid, err := startSnapshot(conn, attempt > 1)
+				if err != nil {
+					return err
+				}
+				gtids[i] = gtid
+				return nil
+			})
+		}
+		if err := eg.Wait(); err != nil {
+			return "", fmt.Errorf("cannot take a consistent snapshot: %w", err)
+		}
+
+		if gtid, ok := sameGTID(gtids); ok {
+			d.log.Info(
+				"consistent snapshot started",
+				zap.String("gtid_executed", gtid),
+				zap.Int("connections", len(conns)),
+				zap.Int("attempt", attempt),
+			)
+			return gtid, nil
+		}
+
+		d.log.Info("writes while taking consistent snapshot, retrying ...", zap.Int("attempt", attempt))
+	}
+
+	return "", fmt.Errorf("cannot take a consistent snapshot: the database kept changing while opening %d connections, retry with fewer --threads or during lower write traffic", len(conns))
+}
+
+// startSnapshot starts a consistent snapshot transaction on conn. It returns
+// an empty GTID set if the snapshot position could not be pinned down.
+func startSnapshot(conn *Connection, rollback bool) (string, error) {
+	if rollback {
+		if err := conn.Execute("ROLLBACK"); err != nil {
+			return "", err
+		}
+	}
+
+	before, err := fetchGTIDExecuted(conn)
+	if err != nil {
+		return "", err
+	}
+	if err := conn.Execute("START TRANSACTION WITH CONSISTENT SNAPSHOT"); err != nil {
+		return "", err
+	}
+	after, err := fetchGTIDExecuted(conn)
+	if err != nil {
+		return "", err
+	}
+
+	if before != after {
+		return "", nil
+	}
+	return after, nil
+}
+
+func fetchGTIDExecuted(conn *Connection) (string, error) {
+	qr, err := conn.Fetch(gtidExecutedQuery)
+	if err != nil {
+		return "", err
+	}
+	if len(qr.Rows) == 0 || len(qr.Rows[0]) == 0 {
+		return "", fmt.Errorf("server did not report gtid_executed")
+	}
+
+	gtid := strings.ReplaceAll(qr.Rows[0][0].String(), "\n", "")
+	if gtid == "" {
+		return "", fmt.Errorf("server has no GTID position, GTIDs must be enabled")
+	}
+	return gtid, nil
+}
+
+// sameGTID reports whether every snapshot was pinned to the same GTID set.
+func sameGTID(gtids []string) (string, bool) {
+	if len(gtids) == 0 || gtids[0] == "" {
+		return "", false
+	}
+	for _, gtid := range gtids[1:] {
+		if gtid != gtids[0] {
+			return "", false
+		}
+	}
+	return gtids[0], true
+}
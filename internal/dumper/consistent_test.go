@@ -0,0 +1,117 @@
+package dumper
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	querypb "github.com/xelabs/go-mysqlstack/sqlparser/depends/query"
+	"github.com/xelabs/go-mysqlstack/sqlparser/depends/sqltypes"
+)
+
+const testGTID = "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5"
+
+func gtidResult(gtid string) *sqltypes.Result {
+	return &sqltypes.Result{
+		Fields: []*querypb.Field{{Name: "@@global.gtid_executed", Type: querypb.Type_VARCHAR}},
+		Rows: [][]sqltypes.Value{
+			{sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte(gtid))},
+		},
+	}
+}
+
+func shardsResult(shards ...string) *sqltypes.Result {
+	qr := &sqltypes.Result{
+		Fields: []*querypb.Field{{Name: "Shards", Type: querypb.Type_VARCHAR}},
+	}
+	for _, shard := range shards {
+		qr.Rows = append(qr.Rows, []sqltypes.Value{sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte(shard))})
+	}
+	return qr
+}
+
+func consistentTestConfig(address, outdir string) *Config {
+	cfg := NewDefaultConfig()
+	cfg.Database = "test"
+	cfg.Outdir = outdir
+	cfg.User = "mock"
+	cfg.Password = "mock"
+	cfg.Address = address
+	cfg.ChunksizeInMB = 1
+	cfg.StmtSize = 10000
+	cfg.IntervalMs = 500
+	cfg.Threads = 4
+	cfg.Consistent = true
+	return cfg
+}
+
+func TestDumperConsistentSnapshot(t *testing.T) {
+	c := qt.New(t)
+
+	fakedbs, address := newResumeTestServer(c)
+	fakedbs.AddQueryPattern("select `id`, `name` from `test`\\.`t1`  order by `id`", rowsResult("1", "2"))
+	fakedbs.AddQueryPattern("show vitess_shards", shardsResult("test/-"))
+	fakedbs.AddQueryPattern("select @@global.gtid_executed", gtidResult(testGTID))
+	fakedbs.AddQueryPattern("start transaction with consistent snapshot", &sqltypes.Result{})
+
+	cfg := consistentTestConfig(address, c.TempDir())
+	d, err := NewDumper(cfg)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.Run(context.Background()), qt.IsNil)
+
+	c.Assert(fakedbs.GetQueryCalledNum("start transaction with consistent snapshot"), qt.Equals, 4)
+
+	data, err := os.ReadFile(filepath.Join(cfg.Outdir, "metadata"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(data), qt.Equals, "consistent_snapshot: true\ngtid_executed: "+testGTID+"\n")
+
+	// The snapshot is gone, so the dump cannot be resumed without mixing in
+	// rows from after it.
+	cfg.Consistent = false
+	cfg.Resume = true
+	d, err = NewDumper(cfg)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.Run(context.Background()), qt.ErrorMatches, "cannot resume the dump in .*: it was taken from a consistent snapshot at GTID set "+testGTID+", .*")
+}
+
+func TestDumperConsistentSnapshotRejectsShardedKeyspace(t *testing.T) {
+	c := qt.New(t)
+
+	fakedbs, address := newResumeTestServer(c)
+	fakedbs.AddQueryPattern("show vitess_shards", shardsResult("test/-80", "test/80-", "other/-"))
+
+	cfg := consistentTestConfig(address, c.TempDir())
+	d, err := NewDumper(cfg)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.Run(context.Background()), qt.ErrorMatches, "cannot take a consistent snapshot of keyspace test: it has 2 shards.*")
+}
+
+func TestDumperConsistentSnapshotRequiresGTID(t *testing.T) {
+	c := qt.New(t)
+
+	fakedbs, address := newResumeTestServer(c)
+	fakedbs.AddQueryPattern("select @@global.gtid_executed", gtidResult(""))
+	fakedbs.AddQueryPattern("start transaction with consistent snapshot", &sqltypes.Result{})
+
+	cfg := consistentTestConfig(address, c.TempDir())
+	d, err := NewDumper(cfg)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.Run(context.Background()), qt.ErrorMatches, "cannot take a consistent snapshot: server has no GTID position.*")
+}
+
+func TestSameGTID(t *testing.T) {
+	c := qt.New(t)
+
+	gtid, ok := sameGTID([]string{"a:1-3", "a:1-3"})
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(gtid, qt.Equals, "a:1-3")
+
+	_, ok = sameGTID([]string{"a:1-3", "a:1-4"})
+	c.Assert(ok, qt.IsFalse)
+	_, ok = sameGTID([]string{"", ""})
+	c.Assert(ok, qt.IsFalse)
+	_, ok = sameGTID(nil)
+	c.Assert(ok, qt.IsFalse)
+}
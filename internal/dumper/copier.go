@@ -0,0 +1,361 @@
+package dumper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/dustin/go-humanize"
+	"github.com/planetscale/cli/internal/printer"
+	"go.uber.org/zap"
+)
+
+// Copier copies the tables of a database into another database without an
+// intermediate dump directory. Rows are streamed from the source and inserted
+// into the destination in statements of at most StmtSize bytes, so memory use
+// does not grow with the size of the tables.
+//
+// The source is given by Address, User, Password and Database, the
+// destination by ToAddress, ToUser, ToPassword and ToDatabase. Progress is
+// reported per table through Printer, if set.
+type Copier struct {
+	cfg *Config
+	log *zap.Logger
+	// dumper selects the tables and columns copied, the same way they are
+	// selected for a dump.
+	dumper *Dumper
+}
+
+func NewCopier(cfg *Config) (*Copier, error) {
+	d, err := NewDumper(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Copier{
+		cfg:    cfg,
+		log:    d.log,
+		dumper: d,
+	}, nil
+}
+
+// Run copies the schema and data of the tables.
+func (c *Copier) Run(ctx context.Context) error {
+	if c.cfg.Engine == EnginePostgres || c.cfg.ToEngine == EnginePostgres {
+		return errors.New("copying is only supported between MySQL databases")
+	}
+
+	src, err := NewPool(c.log, c.cfg.Threads, c.cfg.Address, c.cfg.User, c.cfg.Password, c.cfg.SessionVars, c.cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := NewPool(c.log, c.cfg.Threads, c.cfg.ToAddress, c.cfg.ToUser, c.cfg.ToPassword, []string{"SET FOREIGN_KEY_CHECKS=0"}, c.cfg.ToDatabase)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	srcConn := src.Get()
+	tables, views, err := c.tables(srcConn)
+	if err != nil {
+		src.Put(srcConn)
+		return err
+	}
+
+	if !c.cfg.DataOnly {
+		dstConn := dst.Get()
+		err := c.copySchema(srcConn, dstConn, tables, views)
+		dst.Put(dstConn)
+		if err != nil {
+			src.Put(srcConn)
+			return err
+		}
+	}
+	src.Put(srcConn)
+
+	if c.cfg.SchemaOnly {
+		return nil
+	}
+
+	t := time.Now()
+	tick := time.NewTicker(time.Millisecond * time.Duration(max(c.cfg.IntervalMs, 1)))
+	defer tick.Stop()
+	go func() {
+		for range tick.C {
+			diff := time.Since(t).Seconds()
+			allbytesMB := float64(atomic.LoadUint64(&c.cfg.Allbytes) / 1024 / 1024)
+			c.log.Info(
+				"copying rates ...",
+				zap.Float64("allbytes", allbytesMB),
+				zap.Uint64("allrows", atomic.LoadUint64(&c.cfg.Allrows)),
+				zap.Float64("time_sec", diff),
+				zap.Float64("rates_mb_sec", allbytesMB/diff),
+			)
+		}
+	}()
+
+	numberOfTables := len(tables)
+	for _, table := range tables {
+		if views[table] {
+			numberOfTables--
+		}
+	}
+	var tablesDone atomic.Int64
+
+	eg, egCtx := errgroup.WithContext(ctx)
+	for _, table := range tables {
+		if views[table] {
+			continue
+		}
+
+		// Allows for quicker exit when using Ctrl+C at the Terminal:
+		if egCtx.Err() != nil {
+			break
+		}
+
+		srcConn, dstConn := src.Get(), dst.Get()
+		eg.Go(func() error {
+			defer src.Put(srcConn)
+			defer dst.Put(dstConn)
+
+			if egCtx.Err() != nil {
+				return egCtx.Err()
+			}
+
+			rows, err := c.copyTable(egCtx, srcConn, dstConn, table)
+			if err != nil {
+				c.log.Error("error copying table", zap.String("table", table), zap.Error(err))
+				return fmt.Errorf("copying table %s: %w", table, err)
+			}
+			c.printf("%s: %s with %s rows (Table %d of %d, %s rows copied so far)\n",
+				printer.BoldGreen("Finished Copying Table"), printer.BoldBlue(table), humanize.Comma(int64(rows)),
+				tablesDone.Add(1), numberOfTables, humanize.Comma(int64(atomic.LoadUint64(&c.cfg.Allrows))))
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	elapsed := time.Since(t)
+	c.log.Info(
+		"copying all done",
+		zap.Duration("elapsed_time", elapsed),
+		zap.Uint64("allrows", c.cfg.Allrows),
+		zap.Uint64("allbytes", c.cfg.Allbytes),
+	)
+	return nil
+}
+
+// printf reports progress through the configured printer.
+func (c *Copier) printf(format string, a ...any) {
+	if c.cfg.Printer != nil {
+		c.cfg.Printer.Printf(format, a...)
+	}
+}
+
+// tables returns the tables and views to copy. Views are copied after all
+// tables, as they may refer to them.
+func (c *Copier) tables(conn *Connection) ([]string, map[string]bool, error) {
+	views, err := c.dumper.allViews(conn, c.cfg.Database)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var tables []string
+	if c.cfg.Table != "" {
+		tables = strings.Split(c.cfg.Table, ",")
+	} else if tables, err = c.dumper.allTables(conn, c.cfg.Database); err != nil {
+		return nil, nil, err
+	}
+
+	ghost := regexp.MustCompile(VITESS_GHOST_TABLE_REGEX)
+	copied := make([]string, 0, len(tables))
+	for _, table := range tables {
+		if !ghost.MatchString(table) && !views[table] {
+			copied = append(copied, table)
+		}
+	}
+	for _, table := range tables {
+		if views[table] {
+			copied = append(copied, table)
+		}
+	}
+	return copied, views, nil
+}
+
+func (c *Copier) copySchema(src, dst *Connection, tables []string, views map[string]bool) error {
+	for idx, table := range tables {
+		qr, err := src.Fetch(fmt.Sprintf("SHOW CREATE TABLE %s.%s", quoteIdentifier(c.cfg.Database), quoteIdentifier(table)))
+		if err != nil {
+			return err
+		}
+		if len(qr.Rows) != 1 || len(qr.Rows[0]) < 2 {
+			return fmt.Errorf("unexpected result of SHOW CREATE TABLE for %s", table)
+		}
+
+		kind := "TABLE"
+		if views[table] {
+			kind = "VIEW"
+		}
+		if c.cfg.OverwriteTables {
+			if err := dst.Execute(fmt.Sprintf("DROP %s IF EXISTS %s", kind, quoteIdentifier(table))); err != nil {
+				return err
+			}
+		}
+		create := qr.Rows[0][1].String()
+		if views[table] {
+			create = copiedViewDefinition(create, c.cfg.Database)
+		}
+		if err := dst.Execute(create); err != nil {
+			return fmt.Errorf("creating %s %s: %w", strings.ToLower(kind), table, err)
+		}
+
+		c.log.Info("copying schema ...", zap.String("table", table))
+		c.printf("%s: %s (Table %d of %d)\n", printer.BoldGreen("Copied Schema"), printer.BoldBlue(table), idx+1, len(tables))
+	}
+	return nil
+}
+
+// viewDefiner matches the DEFINER clause of SHOW CREATE VIEW, such as
+// DEFINER=`root`@`%`.
+var viewDefiner = regexp.MustCompile("DEFINER=(`[^`]*`|'[^']*'|[^@\\s]+)@(`[^`]*`|'[^']*'|\\S+)\\s+")
+
+// copiedViewDefinition returns the SHOW CREATE VIEW statement of a view of
+// database so that it can be run in the destination database: the definer,
+// a user that may not exist there, is dropped so that the view is defined by
+// the copying user, and the tables it selects from are no longer qualified
+// with the source database.
+func copiedViewDefinition(create, database string) string {
+	create = viewDefiner.ReplaceAllString(create, "")
+
+	qualifier := quoteIdentifier(database) + "."
+	var b strings.Builder
+	for i := 0; i < len(create); {
+		end := i + 1
+		switch create[i] {
+		case '`':
+			if strings.HasPrefix(create[i:], qualifier) {
+				i += len(qualifier)
+				continue
+			}
+			end = quotedEnd(create, i)
+		case '\'':
+			end = quotedEnd(create, i)
+		}
+		b.WriteString(create[i:end])
+		i = end
+	}
+	return b.String()
+}
+
+// quotedEnd returns the offset after the identifier or string literal that
+// starts with the quote at i. Doubled quotes and, in strings, backslash
+// escapes are part of it.
+func quotedEnd(s string, i int) int {
+	quote := s[i]
+	for i++; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote == '\'':
+			i++
+		case s[i] == quote && i+1 < len(s) && s[i+1] == quote:
+			i++
+		case s[i] == quote:
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// copyTable streams the rows of a table from src into dst and returns the
+// number of rows copied.
+func (c *Copier) copyTable(ctx context.Context, src, dst *Connection, table string) (rows uint64, err error) {
+	dumpCtx, err := c.dumper.tableDumpContext(src, table)
+	if err != nil {
+		return 0, err
+	}
+
+	writer := newSQLWriter(c.cfg, table)
+	if err := writer.Initialize(dumpCtx.fieldNames); err != nil {
+		return 0, err
+	}
+
+	cursor, err := src.StreamFetch(fmt.Sprintf("SELECT %s FROM %s.%s %s", strings.Join(dumpCtx.selfields, ", "), quoteIdentifier(c.cfg.Database), quoteIdentifier(table), dumpCtx.where))
+	if err != nil {
+		return 0, err
+	}
+	closed := false
+	defer func() {
+		if closed {
+			return
+		}
+		if cerr := cursor.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	insert := func() error {
+		for _, stmt := range writer.takeInserts() {
+			if err := dst.Execute(stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var bytes uint64
+	for cursor.Next() {
+		row, err := cursor.RowValues()
+		if err != nil {
+			return rows, err
+		}
+
+		if ctx.Err() != nil {
+			return rows, ctx.Err()
+		}
+
+		c.cfg.Mask.maskRow(dumpCtx.masks, row)
+		n, err := writer.WriteRow(row)
+		if err != nil {
+			return rows, err
+		}
+		rows++
+		bytes += uint64(n)
+		atomic.AddUint64(&c.cfg.Allbytes, uint64(n))
+		atomic.AddUint64(&c.cfg.Allrows, 1)
+
+		if err := insert(); err != nil {
+			return rows, err
+		}
+	}
+
+	closed = true
+	if err := cursor.Close(); err != nil {
+		return rows, err
+	}
+
+	writer.finishInsert()
+	if err := insert(); err != nil {
+		return rows, err
+	}
+
+	c.log.Info(
+		"copying table done...",
+		zap.String("table", table),
+		zap.Uint64("all_rows", rows),
+		zap.Any("all_bytes", bytes/1024/1024),
+		zap.Int("thread_conn_id", src.ID),
+	)
+	return rows, nil
+}
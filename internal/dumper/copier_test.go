@@ -0,0 +1,220 @@
+package dumper
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/planetscale/cli/internal/printer"
+	"github.com/xelabs/go-mysqlstack/driver"
+	querypb "github.com/xelabs/go-mysqlstack/sqlparser/depends/query"
+	"github.com/xelabs/go-mysqlstack/sqlparser/depends/sqltypes"
+	"github.com/xelabs/go-mysqlstack/xlog"
+)
+
+func TestCopier(t *testing.T) {
+	c := qt.New(t)
+
+	log := xlog.NewStdLog(xlog.Level(xlog.INFO))
+	srcdbs := driver.NewTestHandler(log)
+	srcServer, err := driver.MockMysqlServer(log, srcdbs)
+	c.Assert(err, qt.IsNil)
+	c.Cleanup(func() { srcServer.Close() })
+
+	dstdbs := driver.NewTestHandler(log)
+	dstServer, err := driver.MockMysqlServer(log, dstdbs)
+	c.Assert(err, qt.IsNil)
+	c.Cleanup(func() { dstServer.Close() })
+
+	selectResult := &sqltypes.Result{
+		Fields: []*querypb.Field{
+			{Name: "id", Type: querypb.Type_INT32},
+			{Name: "name", Type: querypb.Type_VARCHAR},
+		},
+		Rows: make([][]sqltypes.Value, 0, 10),
+	}
+	for i := 0; i < 10; i++ {
+		selectResult.Rows = append(selectResult.Rows, []sqltypes.Value{
+			sqltypes.MakeTrusted(querypb.Type_INT32, []byte("11")),
+			sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte("11\"xx\"")),
+		})
+	}
+
+	fieldsResult := &sqltypes.Result{
+		Fields: []*querypb.Field{
+			{Name: "Field", Type: querypb.Type_VARCHAR},
+			{Name: "Type", Type: querypb.Type_VARCHAR},
+			{Name: "Null", Type: querypb.Type_VARCHAR},
+			{Name: "Key", Type: querypb.Type_VARCHAR},
+			{Name: "Default", Type: querypb.Type_VARCHAR},
+			{Name: "Extra", Type: querypb.Type_VARCHAR},
+		},
+		Rows: [][]sqltypes.Value{
+			testRow("id", ""),
+			testRow("name", ""),
+		},
+	}
+
+	createTable := "CREATE TABLE `t1` (`id` int, `name` varchar(255)) ENGINE=InnoDB"
+	{
+		srcdbs.AddQueryPattern("use .*", &sqltypes.Result{})
+		srcdbs.AddQueryPattern("set .*", &sqltypes.Result{})
+		srcdbs.AddQueryPattern("select table_name \n\t\t\t from information_schema.tables .*", &sqltypes.Result{
+			Fields: []*querypb.Field{{Name: "TABLE_NAME", Type: querypb.Type_VARCHAR}},
+		})
+		srcdbs.AddQueryPattern("show create table .*", &sqltypes.Result{
+			Fields: []*querypb.Field{
+				{Name: "Table", Type: querypb.Type_VARCHAR},
+				{Name: "Create Table", Type: querypb.Type_VARCHAR},
+			},
+			Rows: [][]sqltypes.Value{{
+				sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte("t1")),
+				sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte(createTable)),
+			}},
+		})
+		srcdbs.AddQueryPattern("show fields from .*", fieldsResult)
+		srcdbs.AddQueryPattern("select `id`, `name` from `test`\\.`t1` .*", selectResult)
+
+		dstdbs.AddQueryPattern("use .*", &sqltypes.Result{})
+		dstdbs.AddQueryPattern("set .*", &sqltypes.Result{})
+		dstdbs.AddQueryPattern("drop table .*", &sqltypes.Result{})
+		dstdbs.AddQueryPattern("create table .*", &sqltypes.Result{})
+		dstdbs.AddQueryPattern("insert into .*", &sqltypes.Result{})
+	}
+
+	newConfig := func() *Config {
+		return &Config{
+			Database:        "test",
+			Table:           "t1",
+			User:            "mock",
+			Password:        "mock",
+			Address:         srcServer.Addr(),
+			ToDatabase:      "copy",
+			ToUser:          "mock",
+			ToPassword:      "mock",
+			ToAddress:       dstServer.Addr(),
+			Threads:         2,
+			StmtSize:        50,
+			IntervalMs:      500,
+			OverwriteTables: true,
+		}
+	}
+
+	var out bytes.Buffer
+	format := printer.Human
+	cfg := newConfig()
+	cfg.Printer = printer.NewPrinter(&format)
+	cfg.Printer.SetHumanOutput(&out)
+	copier, err := NewCopier(cfg)
+	c.Assert(err, qt.IsNil)
+	c.Assert(copier.Run(context.Background()), qt.IsNil)
+
+	c.Assert(cfg.Allrows, qt.Equals, uint64(10))
+	c.Assert(out.String(), qt.Equals, "Copied Schema: t1 (Table 1 of 1)\n"+
+		"Finished Copying Table: t1 with 10 rows (Table 1 of 1, 10 rows copied so far)\n")
+	c.Assert(dstdbs.GetQueryCalledNum("DROP TABLE IF EXISTS `t1`"), qt.Equals, 1)
+	c.Assert(dstdbs.GetQueryCalledNum(createTable), qt.Equals, 1)
+	// Each row is 15 bytes, so a statement is finished with the fourth row,
+	// once it reaches the 50 bytes of StmtSize.
+	row := `(11,"11\"xx\"")`
+	c.Assert(dstdbs.GetQueryCalledNum("INSERT INTO `t1`(`id`,`name`) VALUES\n"+row+",\n"+row+",\n"+row+",\n"+row), qt.Equals, 2)
+	c.Assert(dstdbs.GetQueryCalledNum("INSERT INTO `t1`(`id`,`name`) VALUES\n"+row+",\n"+row), qt.Equals, 1)
+
+	// Data only copies skip the schema.
+	cfg = newConfig()
+	cfg.DataOnly = true
+	copier, err = NewCopier(cfg)
+	c.Assert(err, qt.IsNil)
+	c.Assert(copier.Run(context.Background()), qt.IsNil)
+	c.Assert(cfg.Allrows, qt.Equals, uint64(10))
+	c.Assert(dstdbs.GetQueryCalledNum(createTable), qt.Equals, 1)
+
+	// Schema only copies skip the data.
+	cfg = newConfig()
+	cfg.SchemaOnly = true
+	copier, err = NewCopier(cfg)
+	c.Assert(err, qt.IsNil)
+	c.Assert(copier.Run(context.Background()), qt.IsNil)
+	c.Assert(cfg.Allrows, qt.Equals, uint64(0))
+	c.Assert(dstdbs.GetQueryCalledNum(createTable), qt.Equals, 2)
+}
+
+func TestCopierView(t *testing.T) {
+	c := qt.New(t)
+
+	log := xlog.NewStdLog(xlog.Level(xlog.INFO))
+	srcdbs := driver.NewTestHandler(log)
+	srcServer, err := driver.MockMysqlServer(log, srcdbs)
+	c.Assert(err, qt.IsNil)
+	c.Cleanup(func() { srcServer.Close() })
+
+	dstdbs := driver.NewTestHandler(log)
+	dstServer, err := driver.MockMysqlServer(log, dstdbs)
+	c.Assert(err, qt.IsNil)
+	c.Cleanup(func() { dstServer.Close() })
+
+	showCreate := func(name, create string) *sqltypes.Result {
+		return &sqltypes.Result{
+			Fields: []*querypb.Field{
+				{Name: "Table", Type: querypb.Type_VARCHAR},
+				{Name: "Create Table", Type: querypb.Type_VARCHAR},
+			},
+			Rows: [][]sqltypes.Value{{
+				sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte(name)),
+				sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte(create)),
+			}},
+		}
+	}
+
+	createTable := "CREATE TABLE `t1` (`id` int, `name` varchar(255)) ENGINE=InnoDB"
+	createView := "CREATE ALGORITHM=UNDEFINED DEFINER=`admin`@`%` SQL SECURITY DEFINER VIEW `v1` AS " +
+		"select `test`.`t1`.`id` AS `id` from `test`.`t1` where (`test`.`t1`.`name` <> '`test`.')"
+	{
+		srcdbs.AddQueryPattern("use .*", &sqltypes.Result{})
+		srcdbs.AddQueryPattern("set .*", &sqltypes.Result{})
+		srcdbs.AddQueryPattern("select table_name \n\t\t\t from information_schema.tables .*", &sqltypes.Result{
+			Fields: []*querypb.Field{{Name: "TABLE_NAME", Type: querypb.Type_VARCHAR}},
+			Rows:   [][]sqltypes.Value{{sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte("v1"))}},
+		})
+		srcdbs.AddQueryPattern("show create table `test`\\.`v1`", showCreate("v1", createView))
+		srcdbs.AddQueryPattern("show create table `test`\\.`t1`", showCreate("t1", createTable))
+
+		dstdbs.AddQueryPattern("use .*", &sqltypes.Result{})
+		dstdbs.AddQueryPattern("set .*", &sqltypes.Result{})
+		dstdbs.AddQueryPattern("drop .*", &sqltypes.Result{})
+		dstdbs.AddQueryPattern("create .*", &sqltypes.Result{})
+	}
+
+	var out bytes.Buffer
+	format := printer.Human
+	cfg := &Config{
+		Database:        "test",
+		Table:           "v1,t1",
+		User:            "mock",
+		Password:        "mock",
+		Address:         srcServer.Addr(),
+		ToDatabase:      "copy",
+		ToUser:          "mock",
+		ToPassword:      "mock",
+		ToAddress:       dstServer.Addr(),
+		Threads:         1,
+		IntervalMs:      500,
+		OverwriteTables: true,
+		SchemaOnly:      true,
+		Printer:         printer.NewPrinter(&format),
+	}
+	cfg.Printer.SetHumanOutput(&out)
+	copier, err := NewCopier(cfg)
+	c.Assert(err, qt.IsNil)
+	c.Assert(copier.Run(context.Background()), qt.IsNil)
+
+	// The view is created after the table it selects from, without the
+	// definer and the source database, which don't exist in the destination.
+	c.Assert(out.String(), qt.Equals, "Copied Schema: t1 (Table 1 of 2)\n"+
+		"Copied Schema: v1 (Table 2 of 2)\n")
+	c.Assert(dstdbs.GetQueryCalledNum(createTable), qt.Equals, 1)
+	c.Assert(dstdbs.GetQueryCalledNum("DROP VIEW IF EXISTS `v1`"), qt.Equals, 1)
+	c.Assert(dstdbs.GetQueryCalledNum("CREATE ALGORITHM=UNDEFINED SQL SECURITY DEFINER VIEW `v1` AS "+
+		"select `t1`.`id` AS `id` from `t1` where (`t1`.`name` <> '`test`.')"), qt.Equals, 1)
+}
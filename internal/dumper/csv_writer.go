@@ -3,7 +3,6 @@ package dumper
 import (
 	"bytes"
 	"encoding/csv"
-	"fmt"
 
 	"github.com/xelabs/go-mysqlstack/sqlparser/depends/sqltypes"
 )
@@ -14,6 +13,7 @@ type csvWriter struct {
 	csvBuffer  bytes.Buffer
 	writer     *csv.Writer
 	chunkbytes int
+	chunkrows  uint64
 }
 
 func newCSVWriter(cfg *Config) *csvWriter {
@@ -49,6 +49,7 @@ func (w *csvWriter) WriteRow(row []sqltypes.Value) (int, error) {
 	}
 	w.writer.Flush()
 
+	w.chunkrows++
 	rowBytes := w.csvBuffer.Len()
 	bytesAdded := rowBytes - w.chunkbytes
 	w.chunkbytes = rowBytes
@@ -59,12 +60,12 @@ func (w *csvWriter) ShouldFlush() bool {
 	return (w.chunkbytes / 1024 / 1024) >= w.cfg.ChunksizeInMB
 }
 
-func (w *csvWriter) Flush(outdir, database, table string, fileNo int) error {
-	file, err := dumpOutputPath(outdir, database, table, fmt.Sprintf(".%05d.csv", fileNo))
+func (w *csvWriter) Flush(outdir, database, table string, chunk chunkID) error {
+	file, err := dumpOutputPath(outdir, database, table, chunk.suffix("csv"))
 	if err != nil {
 		return err
 	}
-	err = writeFile(file, w.csvBuffer.String())
+	err = writeDataFile(w.cfg, file, w.csvBuffer.String(), w.chunkrows)
 	if err != nil {
 		return err
 	}
@@ -76,12 +77,13 @@ func (w *csvWriter) Flush(outdir, database, table string, fileNo int) error {
 	}
 	w.writer.Flush()
 	w.chunkbytes = 0
+	w.chunkrows = 0
 	return nil
 }
 
-func (w *csvWriter) Close(outdir, database, table string, fileNo int) error {
+func (w *csvWriter) Close(outdir, database, table string, chunk chunkID) error {
 	if w.csvBuffer.Len() > 0 {
-		return w.Flush(outdir, database, table, fileNo)
+		return w.Flush(outdir, database, table, chunk)
 	}
 	return nil
 }
@@ -137,7 +137,7 @@ func TestDumpTableReportsCursorCloseError(t *testing.T) {
 		log: cmdutil.NewZapLogger(false),
 	}
 
-	err := d.dumpTable(context.Background(), conn, "test", "t1")
+	err := d.dumpTable(context.Background(), conn, "test", "t1", tableRange{})
 	c.Assert(err, qt.ErrorIs, closeErr)
 }
 
@@ -182,7 +182,7 @@ func TestDumpTableClosesCursorOnRowValuesError(t *testing.T) {
 		log: cmdutil.NewZapLogger(false),
 	}
 
-	err := d.dumpTable(context.Background(), conn, "test", "t1")
+	err := d.dumpTable(context.Background(), conn, "test", "t1", tableRange{})
 	c.Assert(err, qt.ErrorIs, rowErr)
 	c.Assert(rows.closeCalled, qt.IsTrue)
 }
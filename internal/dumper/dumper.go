@@ -3,11 +3,14 @@ package dumper
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -15,6 +18,7 @@ import (
 
 	"github.com/planetscale/cli/internal/cmdutil"
 	"github.com/planetscale/cli/internal/printer"
+	"github.com/xelabs/go-mysqlstack/sqlparser/depends/sqltypes"
 	"golang.org/x/sync/errgroup"
 
 	"go.uber.org/zap"
@@ -25,21 +29,27 @@ const VITESS_GHOST_TABLE_REGEX = "_vt_([a-zA-Z0-9]{3})_([0-f]{32})_([0-9]{14})_"
 
 // Config describes the settings to dump from a database.
 type Config struct {
-	User                      string
-	Password                  string
-	Address                   string
-	ToUser                    string
-	ToPassword                string
-	ToAddress                 string
-	ToDatabase                string
-	ToEngine                  string
-	Database                  string
-	DatabaseRegexp            string
-	DatabaseInvertRegexp      bool
-	Shard                     string
-	Table                     string
-	Outdir                    string
-	OutputFormat              string
+	User       string
+	Password   string
+	Address    string
+	ToUser     string
+	ToPassword string
+	ToAddress  string
+	ToDatabase string
+	ToEngine   string
+	// Engine is the engine of the database, EngineMySQL if empty.
+	Engine               string
+	Database             string
+	DatabaseRegexp       string
+	DatabaseInvertRegexp bool
+	Shard                string
+	Table                string
+	Outdir               string
+	OutputFormat         string
+	Compression          string
+	// Archive, when set, receives the dump as a tar stream instead of
+	// writing files into Outdir.
+	Archive                   io.Writer
 	SessionVars               []string
 	Threads                   int
 	ChunksizeInMB             int
@@ -47,6 +57,8 @@ type Config struct {
 	Allbytes                  uint64
 	Allrows                   uint64
 	OverwriteTables           bool
+	Resume                    bool
+	Consistent                bool
 	SchemaOnly                bool
 	DataOnly                  bool
 	ShowDetails               bool
@@ -58,6 +70,12 @@ type Config struct {
 	Selects                   map[string]map[string]string
 	Filters                   map[string]map[string]string
 	ColumnIncludes            map[string]map[string]bool
+	// Mask holds the transforms applied to column values before they are
+	// written.
+	Mask *MaskConfig
+
+	archive  *archiveWriter
+	manifest *manifest
 
 	// Interval in millisecond.
 	IntervalMs int
@@ -73,14 +91,21 @@ func NewDefaultConfig() *Config {
 }
 
 type Dumper struct {
-	cfg *Config
-	log *zap.Logger
+	cfg        *Config
+	log        *zap.Logger
+	checkpoint *checkpoint
 }
 
 func NewDumper(cfg *Config) (*Dumper, error) {
+	log := cmdutil.NewZapLogger(cfg.Debug)
+	if cfg.Archive != nil {
+		// Keep stdout free for the archive.
+		log = cmdutil.NewStderrZapLogger(cfg.Debug)
+	}
+
 	return &Dumper{
 		cfg: cfg,
-		log: cmdutil.NewZapLogger(cfg.Debug),
+		log: log,
 	}, nil
 }
 
@@ -88,9 +113,42 @@ type dumpContext struct {
 	fieldNames []string
 	selfields  []string
 	where      string
+	// keyIndexes are the positions of the primary key columns in fieldNames,
+	// used to order the dump and to resume it after an interruption.
+	keyIndexes []int
+	// masks are the rules of the masked columns, keyed by their position
+	// in fieldNames.
+	masks map[int]MaskRule
 }
 
-func (d *Dumper) Run(ctx context.Context) error {
+func (d *Dumper) Run(ctx context.Context) (err error) {
+	if d.cfg.Archive != nil {
+		if d.cfg.Resume {
+			return errors.New("cannot resume a dump that is streamed as an archive")
+		}
+
+		d.cfg.archive = newArchiveWriter(d.cfg.Archive)
+		defer func() {
+			if cerr := d.cfg.archive.close(); cerr != nil && err == nil {
+				err = cerr
+			}
+			d.cfg.archive = nil
+		}()
+	}
+
+	// The manifest is only written once the dump completed.
+	d.cfg.manifest = newManifest()
+	defer func() {
+		if err == nil {
+			err = writeManifest(d.cfg)
+		}
+		d.cfg.manifest = nil
+	}()
+
+	if d.cfg.Engine == EnginePostgres {
+		return d.runPostgres(ctx)
+	}
+
 	// dumpTableSchema runs against initPool, so it needs --shard's USE pin in SessionVars too.
 	initPool, err := NewPool(d.log, d.cfg.Threads, d.cfg.Address, d.cfg.User, d.cfg.Password, d.cfg.SessionVars, "")
 	if err != nil {
@@ -98,11 +156,17 @@ func (d *Dumper) Run(ctx context.Context) error {
 	}
 	defer initPool.Close()
 
-	// Meta data.
-	err = writeMetaData(d.cfg.Outdir)
+	// A streamed archive cannot be resumed, so it has no checkpoint.
+	switch {
+	case d.cfg.Resume:
+		d.checkpoint, err = loadCheckpoint(d.cfg.Outdir, d.cfg.OutputFormat)
+	case d.cfg.archive == nil:
+		d.checkpoint = newCheckpoint(d.cfg.Outdir, d.cfg.OutputFormat)
+	}
 	if err != nil {
 		return err
 	}
+	d.checkpoint.trackManifest(d.cfg.manifest)
 
 	// database.
 	conn := initPool.Get()
@@ -144,10 +208,15 @@ func (d *Dumper) Run(ctx context.Context) error {
 			}
 		}
 	}
+
+	if d.cfg.Consistent && !d.cfg.SchemaOnly {
+		if err := d.checkSnapshotTarget(conn, databases); err != nil {
+			return err
+		}
+	}
 	initPool.Put(conn)
 
-	// Adding the context here helps down below if a query issue is encountered to prevent further processing:
-	eg, egCtx := errgroup.WithContext(ctx)
+	pools := make([]*Pool, len(databases))
 	for i, database := range databases {
 		pool, err := NewPool(d.log, d.cfg.Threads/len(databases), d.cfg.Address, d.cfg.User, d.cfg.Password, d.cfg.SessionVars, database)
 		if err != nil {
@@ -155,6 +224,30 @@ func (d *Dumper) Run(ctx context.Context) error {
 		}
 
 		defer pool.Close()
+		pools[i] = pool
+	}
+
+	var snapshotGTID string
+	if d.cfg.Consistent && !d.cfg.SchemaOnly {
+		snapshotGTID, err = d.startConsistentSnapshot(ctx, pools)
+		if err != nil {
+			return err
+		}
+		if err := d.checkpoint.recordSnapshot(snapshotGTID); err != nil {
+			return err
+		}
+	}
+
+	// Meta data.
+	err = writeMetaData(d.cfg, snapshotGTID)
+	if err != nil {
+		return err
+	}
+
+	// Adding the context here helps down below if a query issue is encountered to prevent further processing:
+	eg, egCtx := errgroup.WithContext(ctx)
+	for i, database := range databases {
+		pool := pools[i]
 		for _, table := range tables[i] {
 			// Skip vitess ghost tables
 			if regexp.MustCompile(VITESS_GHOST_TABLE_REGEX).MatchString(table) {
@@ -184,29 +277,60 @@ func (d *Dumper) Run(ctx context.Context) error {
 				continue
 			}
 
-			conn = pool.Get()
-
-			eg.Go(func() error {
-				defer pool.Put(conn)
-
-				if egCtx.Err() != nil {
-					return egCtx.Err()
-				}
-
+			if d.checkpoint.table(database, table).Done {
 				d.log.Info(
-					"dumping table ...",
+					"skipping table already dumped ...",
 					zap.String("database", database),
 					zap.String("table", table),
-					zap.Int("thread_conn_id", conn.ID),
 				)
+				continue
+			}
 
-				if err := d.dumpTable(ctx, conn, database, table); err != nil {
-					d.log.Error("error dumping table", zap.Error(err))
-					return err
-				}
+			conn = pool.Get()
+			ranges, err := d.splitTable(conn, database, table, d.cfg.Threads/len(databases))
+			if err != nil {
+				pool.Put(conn)
+				return err
+			}
 
-				return nil
-			})
+			for _, r := range ranges {
+				if r.part > 0 && d.checkpoint.table(database, r.checkpointTable(table)).Done {
+					continue
+				}
+				rangeConn := conn
+				if rangeConn == nil {
+					rangeConn = pool.Get()
+				}
+				conn = nil
+
+				eg.Go(func() error {
+					conn := rangeConn
+					defer pool.Put(conn)
+
+					if egCtx.Err() != nil {
+						return egCtx.Err()
+					}
+
+					d.log.Info(
+						"dumping table ...",
+						zap.String("database", database),
+						zap.String("table", table),
+						zap.Int("range", r.part),
+						zap.Int("thread_conn_id", conn.ID),
+					)
+
+					if err := d.dumpTable(ctx, conn, database, table, r); err != nil {
+						d.log.Error("error dumping table", zap.Error(err))
+						return err
+					}
+
+					return nil
+				})
+			}
+			if conn != nil {
+				// Every range was finished by an interrupted dump.
+				pool.Put(conn)
+			}
 		}
 	}
 
@@ -245,9 +369,16 @@ func (d *Dumper) Run(ctx context.Context) error {
 	return nil
 }
 
-func writeMetaData(outdir string) error {
-	file := fmt.Sprintf("%s/metadata", outdir)
-	return writeFile(file, "")
+// writeMetaData writes the metadata file of the dump, recording the GTID set
+// of the snapshot for consistent dumps.
+func writeMetaData(cfg *Config, snapshotGTID string) error {
+	file := fmt.Sprintf("%s/metadata", cfg.Outdir)
+
+	var data string
+	if snapshotGTID != "" {
+		data = fmt.Sprintf("consistent_snapshot: true\ngtid_executed: %s\n", snapshotGTID)
+	}
+	return writeDumpFile(cfg, file, data)
 }
 
 func (d *Dumper) dumpTableSchema(conn *Connection, database string, table string, views map[string]bool) error {
@@ -266,7 +397,7 @@ func (d *Dumper) dumpTableSchema(conn *Connection, database string, table string
 		return err
 	}
 
-	err = writeFile(file, schema)
+	err = writeDumpFile(d.cfg, file, schema)
 	if err != nil {
 		return err
 	}
@@ -275,8 +406,8 @@ func (d *Dumper) dumpTableSchema(conn *Connection, database string, table string
 	return nil
 }
 
-// Dump a table in the configured output format
-func (d *Dumper) dumpTable(ctx context.Context, conn *Connection, database string, table string) (err error) {
+// Dump a table, or a primary key range of it, in the configured output format
+func (d *Dumper) dumpTable(ctx context.Context, conn *Connection, database string, table string, r tableRange) (err error) {
 	var writer TableWriter
 
 	switch d.cfg.OutputFormat {
@@ -284,6 +415,8 @@ func (d *Dumper) dumpTable(ctx context.Context, conn *Connection, database strin
 		writer = newJSONWriter(d.cfg)
 	case "csv":
 		writer = newCSVWriter(d.cfg)
+	case "parquet":
+		writer = newParquetWriter(d.cfg, table)
 	default:
 		writer = newSQLWriter(d.cfg, table)
 	}
@@ -292,15 +425,62 @@ func (d *Dumper) dumpTable(ctx context.Context, conn *Connection, database strin
 	if err != nil {
 		return err
 	}
+	if r.part > 0 {
+		if len(dumpCtx.keyIndexes) != 1 {
+			return fmt.Errorf("cannot dump range %d of table %s.%s: it has no single column primary key", r.part, database, table)
+		}
+		dumpCtx.where = dumpCtx.andWhere(r.where(dumpCtx.keyColumns()[0]))
+	}
+
+	var allBytes uint64
+	var allRows uint64
+	fileNo := 1
+
+	state := d.checkpoint.table(database, r.checkpointTable(table))
+	if state.Chunks > 0 && len(state.LastKey) == len(dumpCtx.keyIndexes) && len(state.LastKey) > 0 {
+		// Continue after the last row of the last committed chunk.
+		if err := removeTableChunks(d.cfg.Outdir, database, table, r.part, state.Chunks); err != nil {
+			return err
+		}
+		dumpCtx.where = dumpCtx.resumeWhere(state.LastKey)
+		fileNo = state.Chunks + 1
+		allRows = state.Rows
+
+		d.log.Info(
+			"resuming table ...",
+			zap.String("database", database),
+			zap.String("table", table),
+			zap.Int("range", r.part),
+			zap.Int("part", fileNo),
+			zap.Uint64("rows", allRows),
+		)
+	} else if state.Chunks > 0 {
+		// Without a primary key there is no stable position to resume from.
+		if err := removeTableChunks(d.cfg.Outdir, database, table, r.part, 0); err != nil {
+			return err
+		}
+		if err := d.checkpoint.reset(database, r.checkpointTable(table)); err != nil {
+			return err
+		}
+	}
 
 	if err := writer.Initialize(dumpCtx.fieldNames); err != nil {
 		return err
 	}
+	d.cfg.manifest.startTable(database, table, r.part, dumpCtx.fieldNames, dumpCtx.keyNames(), fileNo-1)
+	var chunkRows, chunkCRC uint64
 
-	cursor, err := conn.StreamFetch(fmt.Sprintf("SELECT %s FROM %s.%s %s", strings.Join(dumpCtx.selfields, ", "), quoteIdentifier(database), quoteIdentifier(table), dumpCtx.where))
+	cursor, err := conn.StreamFetch(fmt.Sprintf("SELECT %s FROM %s.%s %s%s", strings.Join(dumpCtx.selfields, ", "), quoteIdentifier(database), quoteIdentifier(table), dumpCtx.where, dumpCtx.orderBy()))
 	if err != nil {
 		return err
 	}
+	if tw, ok := writer.(fieldTypeWriter); ok {
+		if err := tw.SetFieldTypes(dumpCtx.maskFields(cursor.Fields())); err != nil {
+			cursor.Close()
+			return err
+		}
+	}
+
 	// Always close the stream so pooled connections are not left mid-result-set.
 	// Preserve the primary error; only surface Close failures when the dump otherwise succeeded.
 	closed := false
@@ -313,9 +493,6 @@ func (d *Dumper) dumpTable(ctx context.Context, conn *Connection, database strin
 		}
 	}()
 
-	var allBytes uint64
-	var allRows uint64
-	fileNo := 1
 	for cursor.Next() {
 		row, rowErr := cursor.RowValues()
 		if rowErr != nil {
@@ -329,6 +506,7 @@ func (d *Dumper) dumpTable(ctx context.Context, conn *Connection, database strin
 			return err
 		}
 
+		d.cfg.Mask.maskRow(dumpCtx.masks, row)
 		bytesAdded, writeErr := writer.WriteRow(row)
 		if writeErr != nil {
 			err = writeErr
@@ -336,20 +514,32 @@ func (d *Dumper) dumpTable(ctx context.Context, conn *Connection, database strin
 		}
 
 		allRows++
+		chunkRows++
+		chunkCRC += uint64(rowCRC(row))
 		allBytes += uint64(bytesAdded)
 		atomic.AddUint64(&d.cfg.Allbytes, uint64(bytesAdded))
 		atomic.AddUint64(&d.cfg.Allrows, 1)
 
 		if writer.ShouldFlush() {
-			if flushErr := writer.Flush(d.cfg.Outdir, database, table, fileNo); flushErr != nil {
+			if flushErr := writer.Flush(d.cfg.Outdir, database, table, chunkID{part: r.part, fileNo: fileNo}); flushErr != nil {
 				err = flushErr
 				return err
 			}
 
+			lastKey := dumpCtx.rowKey(row)
+			d.cfg.manifest.addChunk(database, table, ManifestChunk{Part: r.part, Rows: chunkRows, CRC: chunkCRC, LastKey: lastKey})
+			chunkRows, chunkCRC = 0, 0
+
+			if cpErr := d.checkpoint.commitChunk(database, table, r.part, fileNo, allRows, lastKey); cpErr != nil {
+				err = cpErr
+				return err
+			}
+
 			d.log.Info(
 				"dumping table ...",
 				zap.String("database", database),
 				zap.String("table", table),
+				zap.Int("range", r.part),
 				zap.Uint64("rows", allRows),
 				zap.Any("bytes_mb", (allBytes/1024/1024)),
 				zap.Int("part", fileNo),
@@ -360,9 +550,18 @@ func (d *Dumper) dumpTable(ctx context.Context, conn *Connection, database strin
 		}
 	}
 
-	if err = writer.Close(d.cfg.Outdir, database, table, fileNo); err != nil {
+	if err = writer.Close(d.cfg.Outdir, database, table, chunkID{part: r.part, fileNo: fileNo}); err != nil {
 		return err
 	}
+	switch {
+	case r.upper != "":
+		// The last chunk of a range ends at the range bound, so the whole
+		// range is covered when a restore is verified.
+		d.cfg.manifest.addChunk(database, table, ManifestChunk{Part: r.part, Rows: chunkRows, CRC: chunkCRC, LastKey: []string{r.upper}})
+	case chunkRows > 0:
+		// The last chunk has no upper bound, so its last key is not needed.
+		d.cfg.manifest.addChunk(database, table, ManifestChunk{Part: r.part, Rows: chunkRows, CRC: chunkCRC})
+	}
 
 	// Close before the success log so a stream/network error is not preceded by "done".
 	cerr := cursor.Close()
@@ -372,10 +571,15 @@ func (d *Dumper) dumpTable(ctx context.Context, conn *Connection, database strin
 		return err
 	}
 
+	if err = d.checkpoint.finish(database, r.checkpointTable(table), allRows); err != nil {
+		return err
+	}
+
 	d.log.Info(
 		"dumping table done...",
 		zap.String("database", database),
 		zap.String("table", table),
+		zap.Int("range", r.part),
 		zap.Uint64("all_rows", allRows),
 		zap.Any("all_bytes", (allBytes/1024/1024)),
 		zap.Int("thread_conn_id", conn.ID),
@@ -386,7 +590,7 @@ func (d *Dumper) dumpTable(ctx context.Context, conn *Connection, database strin
 func (d *Dumper) tableDumpContext(conn *Connection, table string) (*dumpContext, error) {
 	ctx := &dumpContext{}
 
-	flds, err := d.dumpableFieldNames(conn, table)
+	flds, keys, err := d.dumpableFieldNames(conn, table)
 	if err != nil {
 		return nil, err
 	}
@@ -428,9 +632,92 @@ func (d *Dumper) tableDumpContext(conn *Connection, table string) (*dumpContext,
 		ctx.where = fmt.Sprintf(" WHERE %v", v)
 	}
 
+	for name, rule := range d.cfg.Mask.tableRules(table) {
+		if !slices.Contains(flds, name) {
+			return nil, fmt.Errorf("masked column %q does not exist in table %q", name, table)
+		}
+		if idx := slices.Index(ctx.fieldNames, name); idx >= 0 {
+			if ctx.masks == nil {
+				ctx.masks = make(map[int]MaskRule)
+			}
+			ctx.masks[idx] = rule
+		}
+	}
+
+	// The primary key can only be used to resume the dump if all of its
+	// columns are dumped unmodified.
+	for _, key := range keys {
+		idx := slices.Index(ctx.fieldNames, key)
+		_, masked := ctx.masks[idx]
+		if _, replaced := d.cfg.Selects[table][key]; idx < 0 || replaced || masked {
+			ctx.keyIndexes = nil
+			break
+		}
+		ctx.keyIndexes = append(ctx.keyIndexes, idx)
+	}
+
 	return ctx, nil
 }
 
+// orderBy returns the ORDER BY clause that makes the chunk boundaries of a
+// table stable between runs, or an empty string if it has no primary key.
+func (ctx *dumpContext) orderBy() string {
+	if len(ctx.keyIndexes) == 0 {
+		return ""
+	}
+	return " ORDER BY " + strings.Join(ctx.keyColumns(), ", ")
+}
+
+func (ctx *dumpContext) keyColumns() []string {
+	cols := ctx.keyNames()
+	for i, name := range cols {
+		cols[i] = quoteIdentifier(name)
+	}
+	return cols
+}
+
+// keyNames returns the primary key columns, nil if the table has none.
+func (ctx *dumpContext) keyNames() []string {
+	if len(ctx.keyIndexes) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(ctx.keyIndexes))
+	for _, idx := range ctx.keyIndexes {
+		names = append(names, ctx.fieldNames[idx])
+	}
+	return names
+}
+
+// rowKey returns the primary key of row encoded as SQL literals.
+func (ctx *dumpContext) rowKey(row []sqltypes.Value) []string {
+	if len(ctx.keyIndexes) == 0 {
+		return nil
+	}
+
+	key := make([]string, 0, len(ctx.keyIndexes))
+	for _, idx := range ctx.keyIndexes {
+		var buf bytes.Buffer
+		row[idx].EncodeSQL(&buf)
+		key = append(key, buf.String())
+	}
+	return key
+}
+
+// resumeWhere returns the WHERE clause that selects the rows after lastKey,
+// combined with any user provided filter.
+func (ctx *dumpContext) resumeWhere(lastKey []string) string {
+	return ctx.andWhere(fmt.Sprintf("(%s) > (%s)", strings.Join(ctx.keyColumns(), ", "), strings.Join(lastKey, ", ")))
+}
+
+// andWhere returns the WHERE clause of the table combined with cond.
+func (ctx *dumpContext) andWhere(cond string) string {
+	if filter, ok := strings.CutPrefix(ctx.where, " WHERE "); ok {
+		return fmt.Sprintf(" WHERE (%s) AND %s", filter, cond)
+	}
+	return " WHERE " + cond
+}
+
 func (d *Dumper) allTables(conn *Connection, database string) ([]string, error) {
 	qr, err := conn.Fetch(fmt.Sprintf("SHOW TABLES FROM %s", quoteIdentifier(database)))
 	if err != nil {
@@ -490,22 +777,28 @@ func (d *Dumper) filterDatabases(conn *Connection, filter *regexp.Regexp, invert
 	return databases, nil
 }
 
-// dumpableFieldNames returns a slice that contains valid field names for the dump.
-func (d *Dumper) dumpableFieldNames(conn *Connection, table string) ([]string, error) {
+// dumpableFieldNames returns a slice that contains valid field names for the
+// dump, along with the columns of the table's primary key.
+func (d *Dumper) dumpableFieldNames(conn *Connection, table string) ([]string, []string, error) {
 	qr, err := conn.Fetch(fmt.Sprintf("SHOW FIELDS FROM %s", quoteIdentifier(table)))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	fields := make([]string, 0, len(qr.Rows))
+	var keys []string
 	for _, t := range qr.Rows {
 		if len(t) != 6 {
-			return nil, fmt.Errorf("error fetching fields, expecting to have 6 columns, have: %d", len(t))
+			return nil, nil, fmt.Errorf("error fetching fields, expecting to have 6 columns, have: %d", len(t))
 		}
 
 		name := t[0].String()
 		extra := t[5].String()
 
+		if t[3].String() == "PRI" {
+			keys = append(keys, name)
+		}
+
 		// Can be either "VIRTUAL GENERATED" or "STORED GENERATED"
 		// https://dev.mysql.com/doc/refman/8.0/en/show-columns.html
 		if strings.Contains(extra, "VIRTUAL GENERATED") || strings.Contains(extra, "STORED GENERATED") {
@@ -516,7 +809,47 @@ func (d *Dumper) dumpableFieldNames(conn *Connection, table string) ([]string, e
 		}
 	}
 
-	return fields, nil
+	// SHOW FIELDS lists the columns of a composite key in table order, not
+	// in the order of the key.
+	if len(keys) > 1 {
+		if keys, err = primaryKeyColumns(conn, table); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return fields, keys, nil
+}
+
+// primaryKeyColumns returns the columns of the primary key of a table in
+// the order of the key.
+func primaryKeyColumns(conn *Connection, table string) ([]string, error) {
+	qr, err := conn.Fetch(fmt.Sprintf("SHOW INDEX FROM %s WHERE Key_name = 'PRIMARY'", quoteIdentifier(table)))
+	if err != nil {
+		return nil, err
+	}
+
+	seqIdx, nameIdx := -1, -1
+	for i, field := range qr.Fields {
+		switch field.Name {
+		case "Seq_in_index":
+			seqIdx = i
+		case "Column_name":
+			nameIdx = i
+		}
+	}
+	if seqIdx < 0 || nameIdx < 0 {
+		return nil, fmt.Errorf("error fetching the primary key of %s, expecting Seq_in_index and Column_name columns", table)
+	}
+
+	keys := make([]string, len(qr.Rows))
+	for _, t := range qr.Rows {
+		seq, err := strconv.Atoi(t[seqIdx].String())
+		if err != nil || seq < 1 || seq > len(keys) || keys[seq-1] != "" {
+			return nil, fmt.Errorf("error fetching the primary key of %s, unexpected Seq_in_index %q", table, t[seqIdx].String())
+		}
+		keys[seq-1] = t[nameIdx].String()
+	}
+	return keys, nil
 }
 
 func dumpOutputPath(outdir, database, table, suffix string) (string, error) {
@@ -14,6 +14,7 @@ import (
 	"github.com/xelabs/go-mysqlstack/xlog"
 
 	qt "github.com/frankban/quicktest"
+	"github.com/parquet-go/parquet-go"
 )
 
 func testRow(name, extra string) []sqltypes.Value {
@@ -1861,6 +1862,26 @@ func TestDumper_OutputFormats(t *testing.T) {
 				}
 			},
 		},
+		{
+			format: "parquet",
+			verify: func(c *qt.C, data string) {
+				type parquetRow struct {
+					ID    *int32  `parquet:"id,optional"`
+					Name  *string `parquet:"name,optional"`
+					Value *string `parquet:"value,optional"`
+				}
+
+				rows, err := parquet.Read[parquetRow](strings.NewReader(data), int64(len(data)))
+				c.Assert(err, qt.IsNil)
+				c.Assert(len(rows), qt.Equals, numTestRows)
+
+				for _, row := range rows {
+					c.Assert(*row.ID, qt.Equals, int32(42))
+					c.Assert(*row.Name, qt.Equals, "test")
+					c.Assert(*row.Value, qt.Equals, "123.45")
+				}
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -2,7 +2,6 @@ package dumper
 
 import (
 	"encoding/json"
-	"fmt"
 	"strings"
 
 	"github.com/xelabs/go-mysqlstack/sqlparser/depends/sqltypes"
@@ -54,12 +53,12 @@ func (w *jsonWriter) ShouldFlush() bool {
 	return (w.chunkbytes / 1024 / 1024) >= w.cfg.ChunksizeInMB
 }
 
-func (w *jsonWriter) Flush(outdir, database, table string, fileNo int) error {
-	file, err := dumpOutputPath(outdir, database, table, fmt.Sprintf(".%05d.json", fileNo))
+func (w *jsonWriter) Flush(outdir, database, table string, chunk chunkID) error {
+	file, err := dumpOutputPath(outdir, database, table, chunk.suffix("json"))
 	if err != nil {
 		return err
 	}
-	err = writeFile(file, strings.Join(w.jsonLines, ""))
+	err = writeDataFile(w.cfg, file, strings.Join(w.jsonLines, ""), uint64(len(w.jsonLines)))
 	if err != nil {
 		return err
 	}
@@ -69,9 +68,9 @@ func (w *jsonWriter) Flush(outdir, database, table string, fileNo int) error {
 	return nil
 }
 
-func (w *jsonWriter) Close(outdir, database, table string, fileNo int) error {
+func (w *jsonWriter) Close(outdir, database, table string, chunk chunkID) error {
 	if len(w.jsonLines) > 0 {
-		return w.Flush(outdir, database, table, fileNo)
+		return w.Flush(outdir, database, table, chunk)
 	}
 	return nil
 }
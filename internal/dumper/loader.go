@@ -24,6 +24,12 @@ type Files struct {
 	schemas   []string
 	views     []string
 	tables    []string
+
+	// Files only found in Postgres dumps.
+	preData     []string
+	postData    []string
+	foreignKeys []string
+	sequences   []string
 }
 
 const (
@@ -47,6 +53,25 @@ func NewLoader(cfg *Config) (*Loader, error) {
 
 // Run used to start the loader worker.
 func (l *Loader) Run(ctx context.Context) error {
+	engine := l.cfg.Engine
+	if engine == "" {
+		engine = EngineMySQL
+	}
+	dumpEngine, err := readDumpEngine(l.cfg.Outdir)
+	if err != nil {
+		return err
+	}
+	if dumpEngine != engine {
+		return fmt.Errorf("the dump in %s was taken from a %s database and cannot be restored into a %s database", l.cfg.Outdir, dumpEngine, engine)
+	}
+	if err := l.verifyManifest(); err != nil {
+		return err
+	}
+
+	if engine == EnginePostgres {
+		return l.runPostgres(ctx)
+	}
+
 	pool, err := NewPool(l.log, l.cfg.Threads, l.cfg.Address, l.cfg.User, l.cfg.Password, l.cfg.SessionVars, "")
 	if err != nil {
 		return err
@@ -193,13 +218,29 @@ func (l *Loader) loadFiles(dir string) (*Files, error) {
 
 		if !info.IsDir() {
 			tbl := tableNameFromFilename(path)
+			// Compressed files are classified by their uncompressed name.
+			name := trimCompressionSuffix(path)
 			switch {
-			case strings.HasSuffix(path, dbSuffix):
+			case filepath.Base(name) == pgPreDataFile:
+				files.preData = append(files.preData, path)
+			case strings.HasSuffix(name, pgPostDataSuffix):
+				if l.canIncludeTable(tbl) {
+					files.postData = append(files.postData, path)
+				}
+			case strings.HasSuffix(name, pgForeignKeySuffix):
+				if l.canIncludeTable(tbl) {
+					files.foreignKeys = append(files.foreignKeys, path)
+				}
+			case strings.HasSuffix(name, pgSequenceSuffix):
+				if l.canIncludeTable(tbl) {
+					files.sequences = append(files.sequences, path)
+				}
+			case strings.HasSuffix(name, dbSuffix):
 				files.databases = append(files.databases, path)
 				if l.cfg.ShowDetails {
 					l.cfg.Printer.Println("Database file: " + filepath.Base(path))
 				}
-			case strings.HasSuffix(path, schemaSuffix):
+			case strings.HasSuffix(name, schemaSuffix):
 				if l.canIncludeTable(tbl) {
 					files.schemas = append(files.schemas, path)
 					if l.cfg.ShowDetails {
@@ -208,13 +249,13 @@ func (l *Loader) loadFiles(dir string) (*Files, error) {
 				} else {
 					l.cfg.Printer.Printf("Skipping files associated with the %s table...\n", printer.BoldBlue(tbl))
 				}
-			case strings.HasSuffix(path, viewSuffix):
+			case strings.HasSuffix(name, viewSuffix):
 				files.views = append(files.views, path)
 				if l.cfg.ShowDetails {
 					l.cfg.Printer.Println("  |- View file: " + printer.BoldBlue(filepath.Base(path)))
 				}
 			default:
-				if strings.HasSuffix(path, tableSuffix) {
+				if strings.HasSuffix(name, tableSuffix) {
 					if l.canIncludeTable(tbl) {
 						files.tables = append(files.tables, path)
 						if l.cfg.ShowDetails {
@@ -234,9 +275,9 @@ func (l *Loader) loadFiles(dir string) (*Files, error) {
 func (l *Loader) restoreDatabaseSchema(dbs []string, conn *Connection) error {
 	for _, db := range dbs {
 		base := filepath.Base(db)
-		name := strings.TrimSuffix(base, dbSuffix)
+		name := strings.TrimSuffix(trimCompressionSuffix(base), dbSuffix)
 
-		data, err := os.ReadFile(db)
+		data, err := readDumpFile(db)
 		if err != nil {
 			return err
 		}
@@ -267,7 +308,7 @@ func (l *Loader) restoreTableSchema(overwrite bool, tables []string, conn *Conne
 
 	for idx, table := range tables {
 		base := filepath.Base(table)
-		name := strings.TrimSuffix(base, schemaSuffix)
+		name := strings.TrimSuffix(trimCompressionSuffix(base), schemaSuffix)
 		db := l.databaseNameFromFilename(name)
 		tbl := strings.Split(name, ".")[1]
 		name = fmt.Sprintf("%s.%s", quoteIdentifier(db), quoteIdentifier(tbl))
@@ -288,7 +329,7 @@ func (l *Loader) restoreTableSchema(overwrite bool, tables []string, conn *Conne
 			return err
 		}
 
-		data, err := os.ReadFile(table)
+		data, err := readDumpFile(table)
 		if err != nil {
 			return err
 		}
@@ -383,7 +424,7 @@ func (l *Loader) restoreViews(overwrite bool, views []string, conn *Connection)
 
 	for idx, viewFilename := range views {
 		base := filepath.Base(viewFilename)
-		name := strings.TrimSuffix(base, viewSuffix)
+		name := strings.TrimSuffix(trimCompressionSuffix(base), viewSuffix)
 		db := strings.Split(name, ".")[0]
 		view := strings.Split(name, ".")[1]
 		name = fmt.Sprintf("%s.%s", quoteIdentifier(db), quoteIdentifier(view))
@@ -404,7 +445,7 @@ func (l *Loader) restoreViews(overwrite bool, views []string, conn *Connection)
 			return err
 		}
 
-		data, err := os.ReadFile(viewFilename)
+		data, err := readDumpFile(viewFilename)
 		if err != nil {
 			return err
 		}
@@ -461,7 +502,7 @@ func (l *Loader) restoreTable(ctx context.Context, table string, conn *Connectio
 	bytes := 0
 	part := "0"
 	base := filepath.Base(table)
-	name := strings.TrimSuffix(base, tableSuffix)
+	name := strings.TrimSuffix(trimCompressionSuffix(base), tableSuffix)
 
 	splits := strings.Split(name, ".")
 	if len(splits) < 2 {
@@ -493,7 +534,7 @@ func (l *Loader) restoreTable(ctx context.Context, table string, conn *Connectio
 		return 0, err
 	}
 
-	data, err := os.ReadFile(table)
+	data, err := readDumpFile(table)
 	if err != nil {
 		return 0, err
 	}
@@ -598,9 +639,12 @@ func (l *Loader) canRestoreData() bool {
 }
 
 func tableNameFromFilename(filename string) string {
-	base := filepath.Base(filename)
+	base := trimCompressionSuffix(filepath.Base(filename))
 	name := strings.TrimSuffix(base, dbSuffix)
 	name = strings.TrimSuffix(name, schemaSuffix)
+	name = strings.TrimSuffix(name, pgPostDataSuffix)
+	name = strings.TrimSuffix(name, pgForeignKeySuffix)
+	name = strings.TrimSuffix(name, pgSequenceSuffix)
 	name = strings.TrimSuffix(name, tableSuffix)
 
 	splits := strings.Split(name, ".")
@@ -613,6 +657,53 @@ func tableNameFromFilename(filename string) string {
 	return tbl
 }
 
+// verifyManifest refuses to restore a dump whose files do not match the
+// checksums recorded when it was taken. Files not listed in the manifest are
+// only reported. Dumps without a manifest, taken by older versions, are
+// restored unchecked.
+func (l *Loader) verifyManifest() error {
+	result, err := VerifyDump(l.cfg.Outdir)
+	if errors.Is(err, ErrNoManifest) {
+		if l.cfg.ShowDetails {
+			l.cfg.Printer.Printf("The dump in %s has no %s, its files cannot be verified.\n", l.cfg.Outdir, manifestFile)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(result.Warnings) > 0 {
+		l.cfg.Printer.Printf("Warning: %d files in %s are not listed in its %s, first: %s.\n",
+			len(result.Warnings), l.cfg.Outdir, manifestFile, result.Warnings[0].File)
+	}
+	if len(result.Problems) > 0 {
+		p := result.Problems[0]
+		return fmt.Errorf("the dump in %s does not match its %s (%d problems, first: %s: %s), run 'pscale database verify-dump' for details",
+			l.cfg.Outdir, manifestFile, len(result.Problems), p.File, p.Problem)
+	}
+	return nil
+}
+
+// readDumpEngine returns the engine a dump was taken from, as recorded in
+// its metadata file. Dumps without it were taken from MySQL.
+func readDumpEngine(dir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "metadata"))
+	if errors.Is(err, os.ErrNotExist) {
+		return EngineMySQL, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if engine, ok := strings.CutPrefix(line, "engine: "); ok {
+			return strings.TrimSpace(engine), nil
+		}
+	}
+	return EngineMySQL, nil
+}
+
 // https://stackoverflow.com/a/51196697
 func (l *Loader) substringRunes(s string, startIndex int, count int) string {
 	runes := []rune(s)
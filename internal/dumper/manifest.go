@@ -0,0 +1,440 @@
+package dumper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	manifestFile    = "manifest.json"
+	manifestVersion = 1
+)
+
+// ErrNoManifest is returned when verifying a dump without a manifest, such
+// as dumps taken by older versions.
+var ErrNoManifest = errors.New("dump has no " + manifestFile)
+
+// Manifest lists the files of a dump with their checksums, so a truncated
+// or modified dump is detected before it is restored.
+type Manifest struct {
+	Version int             `json:"version"`
+	Files   []ManifestFile  `json:"files"`
+	Tables  []ManifestTable `json:"tables,omitempty"`
+}
+
+// ManifestFile is a schema or data file of a dump.
+type ManifestFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	// Rows is the number of rows in a data file, nil for other files.
+	Rows *uint64 `json:"rows,omitempty"`
+}
+
+// ManifestTable records the rows dumped for a table, so a restore can be
+// verified against them.
+type ManifestTable struct {
+	// Database is the database of MySQL tables and the schema of Postgres
+	// tables.
+	Database string `json:"database"`
+	Table    string `json:"table"`
+	Rows     uint64 `json:"rows"`
+	// Columns and Key are the dumped columns and primary key columns of
+	// MySQL tables. Key is empty if the table has no usable primary key.
+	Columns []string `json:"columns,omitempty"`
+	Key     []string `json:"key,omitempty"`
+	// Chunks are the data files of MySQL tables in primary key order.
+	Chunks []ManifestChunk `json:"chunks,omitempty"`
+}
+
+// ManifestChunk is the checksum of the rows of a single data file.
+type ManifestChunk struct {
+	// Part is the primary key range of a table dumped in ranges.
+	Part int    `json:"part,omitempty"`
+	Rows uint64 `json:"rows"`
+	// CRC is the sum of the CRC-32 of every row, see rowCRC.
+	CRC uint64 `json:"crc"`
+	// LastKey holds the primary key of the last row as SQL literals.
+	LastKey []string `json:"last_key,omitempty"`
+}
+
+// manifest collects the files written by a dump.
+type manifest struct {
+	mu     sync.Mutex
+	files  map[string]ManifestFile
+	tables map[string]*ManifestTable
+	// changed are the names of the files recorded since the last snapshot or
+	// changedFiles, which the checkpoint journals.
+	changed map[string]bool
+}
+
+func newManifest() *manifest {
+	return &manifest{
+		files:   make(map[string]ManifestFile),
+		tables:  make(map[string]*ManifestTable),
+		changed: make(map[string]bool),
+	}
+}
+
+// add records a written file, replacing an earlier version of it.
+func (m *manifest) add(name string, data []byte, rows *uint64) {
+	if m == nil {
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[name] = ManifestFile{
+		Name:   name,
+		Size:   int64(len(data)),
+		SHA256: hex.EncodeToString(sum[:]),
+		Rows:   rows,
+	}
+	m.changed[name] = true
+}
+
+// list returns the recorded files sorted by name.
+func (m *manifest) list() []ManifestFile {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.listLocked()
+}
+
+func (m *manifest) listLocked() []ManifestFile {
+	files := make([]ManifestFile, 0, len(m.files))
+	for _, f := range m.files {
+		files = append(files, f)
+	}
+	slices.SortFunc(files, func(a, b ManifestFile) int { return strings.Compare(a.Name, b.Name) })
+	return files
+}
+
+// snapshot returns the recorded files and tables, see list and tableList,
+// and forgets the changed files.
+func (m *manifest) snapshot() ([]ManifestFile, []ManifestTable) {
+	if m == nil {
+		return nil, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clear(m.changed)
+	return m.listLocked(), m.tableListLocked()
+}
+
+// changedFiles returns the files recorded since the last snapshot or call
+// to changedFiles.
+func (m *manifest) changedFiles() []ManifestFile {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	files := make([]ManifestFile, 0, len(m.changed))
+	for name := range m.changed {
+		files = append(files, m.files[name])
+	}
+	clear(m.changed)
+	slices.SortFunc(files, func(a, b ManifestFile) int { return strings.Compare(a.Name, b.Name) })
+	return files
+}
+
+// startTable records a table, or a primary key range of it, whose data is
+// being dumped. Only the first chunks of the range are kept, the ones that
+// have already been written by an interrupted dump.
+func (m *manifest) startTable(database, table string, part int, columns, key []string, chunks int) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.startTableLocked(database, table, part, columns, key, chunks)
+}
+
+func (m *manifest) startTableLocked(database, table string, part int, columns, key []string, chunks int) {
+	t, ok := m.tables[checkpointKey(database, table)]
+	if !ok {
+		t = &ManifestTable{Database: database, Table: table}
+		m.tables[checkpointKey(database, table)] = t
+	}
+	t.Columns = columns
+	t.Key = key
+	t.Chunks = slices.DeleteFunc(t.Chunks, func(c ManifestChunk) bool {
+		if c.Part != part {
+			return false
+		}
+		chunks--
+		return chunks < 0
+	})
+}
+
+// chunk returns the columns and key of a table with the n-th chunk of its
+// range part as its only chunk, so the chunk can be journaled.
+func (m *manifest) chunk(database, table string, part, n int) *ManifestTable {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tables[checkpointKey(database, table)]
+	if !ok {
+		return nil
+	}
+	for _, c := range t.Chunks {
+		if c.Part != part {
+			continue
+		}
+		if n--; n == 0 {
+			return &ManifestTable{Database: database, Table: table, Columns: t.Columns, Key: t.Key, Chunks: []ManifestChunk{c}}
+		}
+	}
+	return nil
+}
+
+// resetTable forgets the chunks recorded for a table.
+func (m *manifest) resetTable(database, table string) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.tables, checkpointKey(database, table))
+}
+
+// addChunk records the checksum of a written data file of a table.
+func (m *manifest) addChunk(database, table string, chunk ManifestChunk) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t, ok := m.tables[checkpointKey(database, table)]; ok {
+		t.Chunks = append(t.Chunks, chunk)
+	}
+}
+
+// finishTable records the number of rows dumped for a table without chunk
+// checksums.
+func (m *manifest) finishTable(database, table string, rows uint64) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := checkpointKey(database, table)
+	t, ok := m.tables[key]
+	if !ok {
+		t = &ManifestTable{Database: database, Table: table}
+		m.tables[key] = t
+	}
+	t.Rows = rows
+}
+
+// tableList returns the recorded tables sorted by database and name, with
+// the chunks of tables dumped in ranges ordered by range.
+func (m *manifest) tableList() []ManifestTable {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.tableListLocked()
+}
+
+func (m *manifest) tableListLocked() []ManifestTable {
+	tables := make([]ManifestTable, 0, len(m.tables))
+	for _, t := range m.tables {
+		t := *t
+		t.Chunks = slices.Clone(t.Chunks)
+		slices.SortStableFunc(t.Chunks, func(a, b ManifestChunk) int { return a.Part - b.Part })
+		if len(t.Chunks) > 0 {
+			t.Rows = 0
+			for _, c := range t.Chunks {
+				t.Rows += c.Rows
+			}
+		}
+		tables = append(tables, t)
+	}
+	slices.SortFunc(tables, func(a, b ManifestTable) int {
+		return strings.Compare(checkpointKey(a.Database, a.Table), checkpointKey(b.Database, b.Table))
+	})
+	return tables
+}
+
+// restore records the files and tables of an interrupted dump that is being
+// resumed.
+func (m *manifest) restore(files []ManifestFile, tables []ManifestTable) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, f := range files {
+		m.files[f.Name] = f
+	}
+	for _, t := range tables {
+		m.tables[checkpointKey(t.Database, t.Table)] = &t
+	}
+}
+
+// replay records the files and the committed chunk of a journaled checkpoint
+// entry, the n-th chunk of its range. Chunks of the range after it, recorded
+// before an interruption, are dropped.
+func (m *manifest) replay(files []ManifestFile, table *ManifestTable, n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, f := range files {
+		m.files[f.Name] = f
+	}
+	if table == nil || len(table.Chunks) != 1 {
+		return
+	}
+	c := table.Chunks[0]
+	m.startTableLocked(table.Database, table.Table, c.Part, table.Columns, table.Key, n-1)
+	t := m.tables[checkpointKey(table.Database, table.Table)]
+	t.Chunks = append(t.Chunks, c)
+}
+
+// writeManifest writes the manifest of a finished dump. Files recorded by an
+// interrupted attempt that have since been removed are left out.
+func writeManifest(cfg *Config) error {
+	files := cfg.manifest.list()
+	if cfg.archive == nil {
+		files = slices.DeleteFunc(files, func(f ManifestFile) bool {
+			_, err := os.Stat(filepath.Join(cfg.Outdir, f.Name))
+			return err != nil
+		})
+	}
+
+	data, err := json.MarshalIndent(Manifest{Version: manifestVersion, Files: files, Tables: cfg.manifest.tableList()}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if cfg.archive != nil {
+		return cfg.archive.add(manifestFile, append(data, '\n'))
+	}
+	return writeFile(filepath.Join(cfg.Outdir, manifestFile), string(data)+"\n")
+}
+
+// ReadManifest reads the manifest of the dump in dir.
+func ReadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNoManifest
+		}
+		return nil, err
+	}
+
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", manifestFile, err)
+	}
+	if m.Version != manifestVersion {
+		return nil, fmt.Errorf("unsupported %s version %d", manifestFile, m.Version)
+	}
+	return m, nil
+}
+
+// DumpProblem is a file of a dump that does not match its manifest.
+type DumpProblem struct {
+	File    string `json:"file"`
+	Problem string `json:"problem"`
+}
+
+// DumpVerification is the result of checking a dump against its manifest.
+// Problems are missing or damaged files; Warnings are files that are not
+// listed in the manifest, which are reported but do not make the dump
+// unusable.
+type DumpVerification struct {
+	Files    int           `json:"files"`
+	Bytes    int64         `json:"bytes"`
+	Rows     uint64        `json:"rows"`
+	Problems []DumpProblem `json:"problems"`
+	Warnings []DumpProblem `json:"warnings"`
+}
+
+// VerifyDump checks every file listed in the manifest of the dump in dir
+// for its size and checksum, and warns about files missing from the
+// manifest.
+func VerifyDump(dir string) (*DumpVerification, error) {
+	m, err := ReadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DumpVerification{Problems: []DumpProblem{}, Warnings: []DumpProblem{}}
+	listed := make(map[string]bool, len(m.Files))
+	for _, f := range m.Files {
+		listed[f.Name] = true
+		result.Files++
+		result.Bytes += f.Size
+		if f.Rows != nil {
+			result.Rows += *f.Rows
+		}
+
+		if problem := verifyManifestFile(dir, f); problem != "" {
+			result.Problems = append(result.Problems, DumpProblem{File: f.Name, Problem: problem})
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || listed[name] || name == manifestFile || name == checkpointFile || name == checkpointJournalFile {
+			continue
+		}
+		result.Warnings = append(result.Warnings, DumpProblem{File: name, Problem: "not listed in " + manifestFile})
+	}
+	return result, nil
+}
+
+func verifyManifestFile(dir string, f ManifestFile) string {
+	if filepath.Base(f.Name) != f.Name {
+		return "invalid file name"
+	}
+
+	file, err := os.Open(filepath.Join(dir, f.Name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "missing"
+		}
+		return err.Error()
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, file)
+	if err != nil {
+		return err.Error()
+	}
+	if size != f.Size {
+		return fmt.Sprintf("size is %d bytes, expected %d", size, f.Size)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != f.SHA256 {
+		return "checksum mismatch"
+	}
+	return ""
+}
@@ -0,0 +1,154 @@
+package dumper
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/planetscale/cli/internal/printer"
+)
+
+func manifestTestConfig(c *qt.C, address string) *Config {
+	cfg := NewDefaultConfig()
+	cfg.Database = "test"
+	cfg.Outdir = c.TempDir()
+	cfg.User = "mock"
+	cfg.Password = "mock"
+	cfg.Address = address
+	cfg.ChunksizeInMB = 1
+	cfg.StmtSize = 10000
+	cfg.IntervalMs = 500
+	return cfg
+}
+
+func TestDumperWritesManifest(t *testing.T) {
+	c := qt.New(t)
+
+	fakedbs, address := newResumeTestServer(c)
+	fakedbs.AddQueryPattern("select `id`, `name` from `test`\\.`t1`  order by `id`", rowsResult("1", "2", "3"))
+
+	cfg := manifestTestConfig(c, address)
+	d, err := NewDumper(cfg)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.Run(context.Background()), qt.IsNil)
+
+	m, err := ReadManifest(cfg.Outdir)
+	c.Assert(err, qt.IsNil)
+
+	names := make([]string, 0, len(m.Files))
+	for _, f := range m.Files {
+		names = append(names, f.Name)
+		if f.Name == "test.t1.00001.sql" {
+			c.Assert(f.Rows, qt.IsNotNil)
+			c.Assert(*f.Rows, qt.Equals, uint64(3))
+		} else {
+			c.Assert(f.Rows, qt.IsNil)
+		}
+	}
+	c.Assert(names, qt.DeepEquals, []string{"metadata", "test.t1-schema.sql", "test.t1.00001.sql"})
+
+	result, err := VerifyDump(cfg.Outdir)
+	c.Assert(err, qt.IsNil)
+	c.Assert(result.Files, qt.Equals, 3)
+	c.Assert(result.Rows, qt.Equals, uint64(3))
+	c.Assert(result.Problems, qt.HasLen, 0)
+}
+
+func TestVerifyDumpReportsProblems(t *testing.T) {
+	c := qt.New(t)
+
+	cfg := NewDefaultConfig()
+	cfg.Outdir = c.TempDir()
+	cfg.manifest = newManifest()
+	for _, name := range []string{"a.t1.00001.sql", "a.t2.00001.sql", "a.t3.00001.sql"} {
+		c.Assert(writeDataFile(cfg, filepath.Join(cfg.Outdir, name), "INSERT INTO t VALUES (1);\n", 1), qt.IsNil)
+	}
+	c.Assert(writeManifest(cfg), qt.IsNil)
+
+	c.Assert(writeFile(filepath.Join(cfg.Outdir, "a.t1.00001.sql"), "INSERT INTO t VALUES (2);\n"), qt.IsNil)
+	c.Assert(writeFile(filepath.Join(cfg.Outdir, "a.t2.00001.sql"), "INSERT"), qt.IsNil)
+	c.Assert(os.Remove(filepath.Join(cfg.Outdir, "a.t3.00001.sql")), qt.IsNil)
+	c.Assert(writeFile(filepath.Join(cfg.Outdir, "a.t4.00001.sql"), ""), qt.IsNil)
+
+	result, err := VerifyDump(cfg.Outdir)
+	c.Assert(err, qt.IsNil)
+	c.Assert(result.Rows, qt.Equals, uint64(3))
+	c.Assert(result.Problems, qt.DeepEquals, []DumpProblem{
+		{File: "a.t1.00001.sql", Problem: "checksum mismatch"},
+		{File: "a.t2.00001.sql", Problem: "size is 6 bytes, expected 26"},
+		{File: "a.t3.00001.sql", Problem: "missing"},
+	})
+	c.Assert(result.Warnings, qt.DeepEquals, []DumpProblem{
+		{File: "a.t4.00001.sql", Problem: "not listed in manifest.json"},
+	})
+
+	_, err = VerifyDump(c.TempDir())
+	c.Assert(err, qt.Equals, ErrNoManifest)
+}
+
+func TestLoaderRefusesDumpNotMatchingManifest(t *testing.T) {
+	c := qt.New(t)
+
+	format := printer.Human
+	cfg := NewDefaultConfig()
+	cfg.Printer = printer.NewPrinter(&format)
+	cfg.Outdir = c.TempDir()
+	cfg.manifest = newManifest()
+	file := filepath.Join(cfg.Outdir, "test.t1.00001.sql")
+	c.Assert(writeDataFile(cfg, file, "INSERT INTO `t1` VALUES (1);\n", 1), qt.IsNil)
+	c.Assert(writeManifest(cfg), qt.IsNil)
+	c.Assert(writeFile(file, "INSERT INTO `t1` VALUES (2);\n"), qt.IsNil)
+
+	l, err := NewLoader(cfg)
+	c.Assert(err, qt.IsNil)
+	c.Assert(l.Run(t.Context()), qt.ErrorMatches, `the dump in .* does not match its manifest.json \(1 problems, first: test.t1.00001.sql: checksum mismatch\), .*`)
+}
+
+func TestLoaderWarnsAboutFilesNotInManifest(t *testing.T) {
+	c := qt.New(t)
+
+	var buf bytes.Buffer
+	format := printer.Human
+	cfg := NewDefaultConfig()
+	cfg.Printer = printer.NewPrinter(&format)
+	cfg.Printer.SetHumanOutput(&buf)
+	cfg.Outdir = c.TempDir()
+	cfg.manifest = newManifest()
+	c.Assert(writeDataFile(cfg, filepath.Join(cfg.Outdir, "test.t1.00001.sql"), "INSERT INTO `t1` VALUES (1);\n", 1), qt.IsNil)
+	c.Assert(writeManifest(cfg), qt.IsNil)
+	c.Assert(writeFile(filepath.Join(cfg.Outdir, "notes.txt"), "restore on Monday"), qt.IsNil)
+
+	l, err := NewLoader(cfg)
+	c.Assert(err, qt.IsNil)
+	c.Assert(l.verifyManifest(), qt.IsNil)
+	c.Assert(buf.String(), qt.Equals, "Warning: 1 files in "+cfg.Outdir+" are not listed in its manifest.json, first: notes.txt.\n")
+}
+
+func TestDumperResumeKeepsManifestEntries(t *testing.T) {
+	c := qt.New(t)
+
+	fakedbs, address := newResumeTestServer(c)
+	fakedbs.AddQueryPattern("select `id`, `name` from `test`\\.`t1`  where \\(`id`\\) > \\(2\\) order by `id`", rowsResult("3", "4"))
+
+	cfg := manifestTestConfig(c, address)
+	cfg.manifest = newManifest()
+	c.Assert(writeDataFile(cfg, filepath.Join(cfg.Outdir, "test.t1.00001.sql"), "first chunk", 2), qt.IsNil)
+
+	cp := newCheckpoint(cfg.Outdir, "sql")
+	cp.trackManifest(cfg.manifest)
+	c.Assert(cp.commitChunk("test", "t1", 0, 1, 2, []string{"2"}), qt.IsNil)
+
+	cfg.manifest = nil
+	cfg.Resume = true
+	d, err := NewDumper(cfg)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.Run(context.Background()), qt.IsNil)
+
+	result, err := VerifyDump(cfg.Outdir)
+	c.Assert(err, qt.IsNil)
+	c.Assert(result.Problems, qt.HasLen, 0)
+	c.Assert(result.Rows, qt.Equals, uint64(4))
+}
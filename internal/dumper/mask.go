@@ -0,0 +1,222 @@
+package dumper
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	querypb "github.com/xelabs/go-mysqlstack/sqlparser/depends/query"
+	"github.com/xelabs/go-mysqlstack/sqlparser/depends/sqltypes"
+	"gopkg.in/yaml.v2"
+)
+
+// Masking transforms.
+const (
+	// MaskHash replaces a value with the hex encoded HMAC-SHA256 of it,
+	// optionally cut to Length characters.
+	MaskHash = "hash"
+	// MaskEmail replaces a value with a fake email address derived from it.
+	MaskEmail = "email"
+	// MaskNull replaces a value with NULL.
+	MaskNull = "null"
+	// MaskFixed replaces a value with Value.
+	MaskFixed = "fixed"
+	// MaskTruncate keeps the first Length characters of a value.
+	MaskTruncate = "truncate"
+	// MaskDigits replaces every digit of a value with a digit derived from
+	// it, keeping its format, such as the dashes of a phone number.
+	MaskDigits = "digits"
+)
+
+const defaultMaskEmailDomain = "example.com"
+
+// MaskConfig holds the columns masked in a dump. The transforms are keyed
+// with Salt, so equal values are masked equally in every table and foreign
+// keys still match after masking.
+type MaskConfig struct {
+	Salt string `yaml:"salt"`
+	// Columns maps "table.column" to the transform applied to its values.
+	Columns map[string]MaskRule `yaml:"columns"`
+}
+
+// MaskRule is the transform applied to a masked column.
+type MaskRule struct {
+	Transform string `yaml:"transform"`
+	// Value is the replacement of MaskFixed and the domain of MaskEmail.
+	Value string `yaml:"value"`
+	// Length is the number of characters kept by MaskTruncate and MaskHash.
+	Length int `yaml:"length"`
+}
+
+// ReadMaskConfig reads and validates a masking config file.
+func ReadMaskConfig(path string) (*MaskConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg MaskConfig
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid mask config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+func (c *MaskConfig) validate() error {
+	if len(c.Columns) == 0 {
+		return errors.New("no columns to mask")
+	}
+
+	columns := make([]string, 0, len(c.Columns))
+	for column := range c.Columns {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	for _, column := range columns {
+		rule := c.Columns[column]
+		if table, name, ok := strings.Cut(column, "."); !ok || table == "" || name == "" {
+			return fmt.Errorf("column %q is not in the form table.column", column)
+		}
+
+		switch rule.Transform {
+		case MaskHash, MaskEmail, MaskDigits:
+			if c.Salt == "" {
+				return fmt.Errorf("column %q: the %s transform requires a salt", column, rule.Transform)
+			}
+		case MaskTruncate:
+			if rule.Length <= 0 {
+				return fmt.Errorf("column %q: the truncate transform requires a length greater than 0", column)
+			}
+		case MaskNull, MaskFixed:
+		case "":
+			return fmt.Errorf("column %q has no transform", column)
+		default:
+			return fmt.Errorf("column %q has unknown transform %q (valid transforms: %s)", column, rule.Transform,
+				strings.Join([]string{MaskHash, MaskEmail, MaskNull, MaskFixed, MaskTruncate, MaskDigits}, ", "))
+		}
+		if rule.Length < 0 {
+			return fmt.Errorf("column %q: length cannot be negative", column)
+		}
+	}
+	return nil
+}
+
+// tableRules returns the rules of the masked columns of a table, keyed by
+// column name.
+func (c *MaskConfig) tableRules(table string) map[string]MaskRule {
+	if c == nil {
+		return nil
+	}
+
+	var rules map[string]MaskRule
+	for column, rule := range c.Columns {
+		t, name, _ := strings.Cut(column, ".")
+		if t != table {
+			continue
+		}
+		if rules == nil {
+			rules = make(map[string]MaskRule)
+		}
+		rules[name] = rule
+	}
+	return rules
+}
+
+// keepsType reports whether the masked values of a column keep its type.
+// Other transforms produce strings.
+func (r MaskRule) keepsType() bool {
+	switch r.Transform {
+	case MaskNull, MaskTruncate, MaskDigits:
+		return true
+	}
+	return false
+}
+
+// mask returns the masked value of v. NULL values stay NULL.
+func (c *MaskConfig) mask(r MaskRule, v sqltypes.Value) sqltypes.Value {
+	if v.IsNull() {
+		return v
+	}
+
+	switch r.Transform {
+	case MaskNull:
+		return sqltypes.NULL
+	case MaskFixed:
+		return sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte(r.Value))
+	case MaskTruncate:
+		if runes := []rune(string(v.Raw())); len(runes) > r.Length {
+			return sqltypes.MakeTrusted(v.Type(), []byte(string(runes[:r.Length])))
+		}
+		return v
+	case MaskHash:
+		sum := hex.EncodeToString(c.digest(v.Raw()))
+		if r.Length > 0 && r.Length < len(sum) {
+			sum = sum[:r.Length]
+		}
+		return sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte(sum))
+	case MaskEmail:
+		domain := r.Value
+		if domain == "" {
+			domain = defaultMaskEmailDomain
+		}
+		user := hex.EncodeToString(c.digest(v.Raw()))[:16]
+		return sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte("user-"+user+"@"+domain))
+	case MaskDigits:
+		var seed [32]byte
+		copy(seed[:], c.digest(v.Raw()))
+		rng := rand.New(rand.NewChaCha8(seed))
+
+		masked := make([]byte, len(v.Raw()))
+		for i, b := range v.Raw() {
+			if b >= '0' && b <= '9' {
+				b = '0' + byte(rng.IntN(10))
+			}
+			masked[i] = b
+		}
+		return sqltypes.MakeTrusted(v.Type(), masked)
+	}
+	return v
+}
+
+func (c *MaskConfig) digest(b []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(c.Salt))
+	mac.Write(b)
+	return mac.Sum(nil)
+}
+
+// maskRow masks the values of row in place.
+func (c *MaskConfig) maskRow(masks map[int]MaskRule, row []sqltypes.Value) {
+	for idx, rule := range masks {
+		row[idx] = c.mask(rule, row[idx])
+	}
+}
+
+// maskFields returns the result fields of a table with the type of masked
+// columns changed to the type of their masked values.
+func (ctx *dumpContext) maskFields(fields []*querypb.Field) []*querypb.Field {
+	if len(ctx.masks) == 0 {
+		return fields
+	}
+
+	masked := slices.Clone(fields)
+	for idx, rule := range ctx.masks {
+		if idx >= len(masked) || rule.keepsType() {
+			continue
+		}
+		f := *masked[idx]
+		f.Type = querypb.Type_VARCHAR
+		masked[idx] = &f
+	}
+	return masked
+}
@@ -0,0 +1,201 @@
+package dumper
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/xelabs/go-mysqlstack/driver"
+	querypb "github.com/xelabs/go-mysqlstack/sqlparser/depends/query"
+	"github.com/xelabs/go-mysqlstack/sqlparser/depends/sqltypes"
+	"github.com/xelabs/go-mysqlstack/xlog"
+)
+
+func TestMaskTransforms(t *testing.T) {
+	c := qt.New(t)
+
+	cfg := &MaskConfig{Salt: "pepper"}
+	varchar := func(s string) sqltypes.Value {
+		return sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte(s))
+	}
+
+	hash := cfg.mask(MaskRule{Transform: MaskHash}, varchar("alice"))
+	c.Assert(hash.String(), qt.HasLen, 64)
+	c.Assert(cfg.mask(MaskRule{Transform: MaskHash}, varchar("alice")).String(), qt.Equals, hash.String())
+	c.Assert(cfg.mask(MaskRule{Transform: MaskHash}, varchar("bob")).String(), qt.Not(qt.Equals), hash.String())
+	c.Assert(cfg.mask(MaskRule{Transform: MaskHash, Length: 8}, varchar("alice")).String(), qt.Equals, hash.String()[:8])
+
+	other := &MaskConfig{Salt: "salt"}
+	c.Assert(other.mask(MaskRule{Transform: MaskHash}, varchar("alice")).String(), qt.Not(qt.Equals), hash.String())
+
+	email := cfg.mask(MaskRule{Transform: MaskEmail}, varchar("alice@planetscale.com")).String()
+	c.Assert(email, qt.Matches, `user-[0-9a-f]{16}@example\.com`)
+	c.Assert(cfg.mask(MaskRule{Transform: MaskEmail, Value: "test.invalid"}, varchar("alice@planetscale.com")).String(), qt.Matches, `user-[0-9a-f]{16}@test\.invalid`)
+
+	c.Assert(cfg.mask(MaskRule{Transform: MaskNull}, varchar("alice")).IsNull(), qt.IsTrue)
+	c.Assert(cfg.mask(MaskRule{Transform: MaskFixed, Value: "redacted"}, varchar("alice")).String(), qt.Equals, "redacted")
+	c.Assert(cfg.mask(MaskRule{Transform: MaskTruncate, Length: 3}, varchar("ålice")).String(), qt.Equals, "åli")
+	c.Assert(cfg.mask(MaskRule{Transform: MaskTruncate, Length: 10}, varchar("alice")).String(), qt.Equals, "alice")
+
+	phone := cfg.mask(MaskRule{Transform: MaskDigits}, varchar("+1 (555) 010-9999"))
+	c.Assert(phone.String(), qt.Matches, `\+\d \(\d{3}\) \d{3}-\d{4}`)
+	c.Assert(cfg.mask(MaskRule{Transform: MaskDigits}, varchar("+1 (555) 010-9999")).String(), qt.Equals, phone.String())
+
+	number := cfg.mask(MaskRule{Transform: MaskDigits}, sqltypes.MakeTrusted(querypb.Type_INT64, []byte("-12345")))
+	c.Assert(number.Type(), qt.Equals, querypb.Type_INT64)
+	c.Assert(number.String(), qt.Matches, `-\d{5}`)
+
+	for _, transform := range []string{MaskHash, MaskEmail, MaskNull, MaskFixed, MaskTruncate, MaskDigits} {
+		c.Assert(cfg.mask(MaskRule{Transform: transform, Length: 1}, sqltypes.NULL).IsNull(), qt.IsTrue, qt.Commentf("transform: %s", transform))
+	}
+}
+
+func TestReadMaskConfig(t *testing.T) {
+	c := qt.New(t)
+
+	write := func(content string) string {
+		path := filepath.Join(c.TempDir(), "mask.yml")
+		c.Assert(os.WriteFile(path, []byte(content), 0o600), qt.IsNil)
+		return path
+	}
+
+	cfg, err := ReadMaskConfig(write(`
+salt: pepper
+columns:
+  users.email:
+    transform: email
+  users.name:
+    transform: truncate
+    length: 1
+  orders.user_email:
+    transform: email
+`))
+	c.Assert(err, qt.IsNil)
+	c.Assert(cfg.tableRules("users"), qt.DeepEquals, map[string]MaskRule{
+		"email": {Transform: MaskEmail},
+		"name":  {Transform: MaskTruncate, Length: 1},
+	})
+	c.Assert(cfg.tableRules("payments"), qt.IsNil)
+
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"columns:\n  users.email:\n    transform: email\n", `.*column "users.email": the email transform requires a salt`},
+		{"columns:\n  email:\n    transform: null\n", `.*column "email" is not in the form table.column`},
+		{"columns:\n  users.email:\n    transform: scramble\n", `.*column "users.email" has unknown transform "scramble".*`},
+		{"columns:\n  users.name:\n    transform: truncate\n", `.*column "users.name": the truncate transform requires a length greater than 0`},
+		{"columns:\n  users.name:\n    transfrom: null\n", `(?s)parse .*field transfrom not found.*`},
+		{"salt: pepper\n", `.*no columns to mask`},
+	}
+	for _, tt := range tests {
+		_, err := ReadMaskConfig(write(tt.content))
+		c.Assert(err, qt.ErrorMatches, tt.want, qt.Commentf("config: %s", tt.content))
+	}
+}
+
+func TestDumperMasksColumns(t *testing.T) {
+	c := qt.New(t)
+
+	log := xlog.NewStdLog(xlog.Level(xlog.INFO))
+	fakedbs := driver.NewTestHandler(log)
+	server, err := driver.MockMysqlServer(log, fakedbs)
+	c.Assert(err, qt.IsNil)
+	c.Cleanup(func() { server.Close() })
+
+	selectResult := &sqltypes.Result{
+		Fields: []*querypb.Field{
+			{Name: "id", Type: querypb.Type_INT32},
+			{Name: "name", Type: querypb.Type_VARCHAR},
+			{Name: "email", Type: querypb.Type_VARCHAR},
+		},
+		Rows: [][]sqltypes.Value{
+			{
+				sqltypes.MakeTrusted(querypb.Type_INT32, []byte("1")),
+				sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte("alice")),
+				sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte("alice@planetscale.com")),
+			},
+			{
+				sqltypes.MakeTrusted(querypb.Type_INT32, []byte("2")),
+				sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte("bob")),
+				sqltypes.NULL,
+			},
+		},
+	}
+
+	fieldsResult := &sqltypes.Result{
+		Fields: []*querypb.Field{
+			{Name: "Field", Type: querypb.Type_VARCHAR},
+			{Name: "Type", Type: querypb.Type_VARCHAR},
+			{Name: "Null", Type: querypb.Type_VARCHAR},
+			{Name: "Key", Type: querypb.Type_VARCHAR},
+			{Name: "Default", Type: querypb.Type_VARCHAR},
+			{Name: "Extra", Type: querypb.Type_VARCHAR},
+		},
+		Rows: [][]sqltypes.Value{
+			testRow("id", ""),
+			testRow("name", ""),
+			testRow("email", ""),
+		},
+	}
+
+	{
+		fakedbs.AddQueryPattern("use .*", &sqltypes.Result{})
+		fakedbs.AddQueryPattern("show create table .*", &sqltypes.Result{
+			Fields: []*querypb.Field{
+				{Name: "Table", Type: querypb.Type_VARCHAR},
+				{Name: "Create Table", Type: querypb.Type_VARCHAR},
+			},
+			Rows: [][]sqltypes.Value{{
+				sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte("users")),
+				sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte("CREATE TABLE `users` (`id` int, `name` varchar(255), `email` varchar(255)) ENGINE=InnoDB")),
+			}},
+		})
+		fakedbs.AddQueryPattern("show fields from .*", fieldsResult)
+		fakedbs.AddQueryPattern("select `id`, `name`, `email` from `test`\\.`users` .*", selectResult)
+		fakedbs.AddQueryPattern("set .*", &sqltypes.Result{})
+	}
+
+	mask := &MaskConfig{
+		Salt: "pepper",
+		Columns: map[string]MaskRule{
+			"users.name":  {Transform: MaskFixed, Value: "redacted"},
+			"users.email": {Transform: MaskEmail},
+		},
+	}
+	cfg := &Config{
+		Database:      "test",
+		Table:         "users",
+		Outdir:        c.TempDir(),
+		User:          "mock",
+		Password:      "mock",
+		Address:       server.Addr(),
+		ChunksizeInMB: 1,
+		Threads:       1,
+		StmtSize:      10000,
+		IntervalMs:    500,
+		Mask:          mask,
+	}
+
+	d, err := NewDumper(cfg)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.Run(context.Background()), qt.IsNil)
+
+	dat, err := os.ReadFile(filepath.Join(cfg.Outdir, "test.users.00001.sql"))
+	c.Assert(err, qt.IsNil)
+
+	email := mask.mask(MaskRule{Transform: MaskEmail}, sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte("alice@planetscale.com"))).String()
+	c.Assert(string(dat), qt.Contains, `(1,"redacted","`+email+`")`)
+	c.Assert(string(dat), qt.Contains, `(2,"redacted",NULL)`)
+	c.Assert(string(dat), qt.Not(qt.Contains), "alice")
+	c.Assert(string(dat), qt.Not(qt.Contains), "bob")
+
+	// A masked column missing from the table fails the dump.
+	mask.Columns["users.phone"] = MaskRule{Transform: MaskNull}
+	cfg.Outdir = c.TempDir()
+	d, err = NewDumper(cfg)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.Run(context.Background()), qt.ErrorMatches, `.*masked column "phone" does not exist in table "users".*`)
+}
@@ -0,0 +1,171 @@
+package dumper
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	gzipSuffix = ".gz"
+	zstdSuffix = ".zst"
+)
+
+// compressionSuffixes maps the supported --compress values to the suffix
+// appended to compressed data files.
+var compressionSuffixes = map[string]string{
+	"gzip": gzipSuffix,
+	"zstd": zstdSuffix,
+}
+
+// ValidCompression reports whether name is a supported compression, the
+// empty string meaning no compression.
+func ValidCompression(name string) bool {
+	if name == "" {
+		return true
+	}
+	_, ok := compressionSuffixes[name]
+	return ok
+}
+
+// archiveWriter serializes dump files from concurrent table dumps into a
+// single tar stream.
+type archiveWriter struct {
+	mu sync.Mutex
+	tw *tar.Writer
+}
+
+func newArchiveWriter(w io.Writer) *archiveWriter {
+	return &archiveWriter{tw: tar.NewWriter(w)}
+}
+
+func (a *archiveWriter) add(name string, data []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	hdr := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0o644,
+		Size:     int64(len(data)),
+		ModTime:  time.Now(),
+	}
+	if err := a.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := a.tw.Write(data)
+	return err
+}
+
+func (a *archiveWriter) close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.tw.Close()
+}
+
+// writeDumpFile writes a schema or metadata file of the dump.
+func writeDumpFile(cfg *Config, file string, data string) error {
+	return storeDumpFile(cfg, file, []byte(data), nil)
+}
+
+// storeDumpFile writes a file of the dump, either into the output directory
+// or, when streaming, as an entry of the tar archive, and records it in the
+// manifest. rows is set for data files.
+func storeDumpFile(cfg *Config, file string, data []byte, rows *uint64) error {
+	name := filepath.Base(file)
+	var err error
+	if cfg.archive != nil {
+		err = cfg.archive.add(name, data)
+	} else {
+		err = writeFile(file, string(data))
+	}
+	if err != nil {
+		return err
+	}
+
+	cfg.manifest.add(name, data, rows)
+	return nil
+}
+
+// writeDataFile writes a data chunk file holding rows rows, compressing it
+// if configured.
+func writeDataFile(cfg *Config, file string, data string, rows uint64) error {
+	suffix, ok := compressionSuffixes[cfg.Compression]
+	if !ok {
+		return storeDumpFile(cfg, file, []byte(data), &rows)
+	}
+
+	var buf bytes.Buffer
+	var zw io.WriteCloser
+	switch cfg.Compression {
+	case "zstd":
+		enc, err := zstd.NewWriter(&buf)
+		if err != nil {
+			return err
+		}
+		zw = enc
+	default:
+		zw = gzip.NewWriter(&buf)
+	}
+
+	if _, err := io.WriteString(zw, data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return storeDumpFile(cfg, file+suffix, buf.Bytes(), &rows)
+}
+
+// trimCompressionSuffix strips the suffix of a compressed data file, so
+// "db.t1.00001.sql.gz" is classified like "db.t1.00001.sql".
+func trimCompressionSuffix(name string) string {
+	for _, suffix := range compressionSuffixes {
+		if trimmed, ok := strings.CutSuffix(name, suffix); ok {
+			return trimmed
+		}
+	}
+	return name
+}
+
+// readDumpFile reads a file of the dump, decompressing it based on its suffix.
+func readDumpFile(file string) ([]byte, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	switch {
+	case strings.HasSuffix(file, gzipSuffix):
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", file, err)
+		}
+		defer gr.Close()
+		r = gr
+	case strings.HasSuffix(file, zstdSuffix):
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", file, err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", file, err)
+	}
+	return data, nil
+}
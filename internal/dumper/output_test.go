@@ -0,0 +1,118 @@
+package dumper
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/planetscale/cli/internal/printer"
+)
+
+func TestWriteDataFileCompression(t *testing.T) {
+	for _, compression := range []string{"gzip", "zstd"} {
+		t.Run(compression, func(t *testing.T) {
+			c := qt.New(t)
+
+			cfg := NewDefaultConfig()
+			cfg.Compression = compression
+			file := filepath.Join(c.TempDir(), "test.t1.00001.sql")
+
+			c.Assert(writeDataFile(cfg, file, "INSERT INTO `t1` VALUES (1);\n", 1), qt.IsNil)
+
+			compressed := file + compressionSuffixes[compression]
+			c.Assert(trimCompressionSuffix(compressed), qt.Equals, file)
+
+			data, err := readDumpFile(compressed)
+			c.Assert(err, qt.IsNil)
+			c.Assert(string(data), qt.Equals, "INSERT INTO `t1` VALUES (1);\n")
+		})
+	}
+}
+
+func TestValidCompression(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(ValidCompression(""), qt.IsTrue)
+	c.Assert(ValidCompression("gzip"), qt.IsTrue)
+	c.Assert(ValidCompression("zstd"), qt.IsTrue)
+	c.Assert(ValidCompression("lz4"), qt.IsFalse)
+}
+
+func TestLoaderRecognizesCompressedFiles(t *testing.T) {
+	c := qt.New(t)
+
+	dir := c.TempDir()
+	cfg := NewDefaultConfig()
+	cfg.Compression = "zstd"
+	c.Assert(writeFile(filepath.Join(dir, "test.t1-schema.sql"), "CREATE TABLE `t1` (`id` int);\n"), qt.IsNil)
+	c.Assert(writeDataFile(cfg, filepath.Join(dir, "test.t1.00001.sql"), "INSERT INTO `t1` VALUES (1);\n", 1), qt.IsNil)
+	cfg.Compression = "gzip"
+	c.Assert(writeDataFile(cfg, filepath.Join(dir, "test.t1.00002.sql"), "INSERT INTO `t1` VALUES (2);\n", 1), qt.IsNil)
+
+	format := printer.Human
+	cfg.Printer = printer.NewPrinter(&format)
+	l, err := NewLoader(cfg)
+	c.Assert(err, qt.IsNil)
+
+	files, err := l.loadFiles(dir)
+	c.Assert(err, qt.IsNil)
+	c.Assert(files.schemas, qt.DeepEquals, []string{filepath.Join(dir, "test.t1-schema.sql")})
+	c.Assert(files.tables, qt.DeepEquals, []string{
+		filepath.Join(dir, "test.t1.00001.sql.zst"),
+		filepath.Join(dir, "test.t1.00002.sql.gz"),
+	})
+	c.Assert(tableNameFromFilename(files.tables[1]), qt.Equals, "t1")
+}
+
+func TestDumperStreamsArchive(t *testing.T) {
+	c := qt.New(t)
+
+	fakedbs, address := newResumeTestServer(c)
+	fakedbs.AddQueryPattern("select `id`, `name` from `test`\\.`t1`  order by `id`", rowsResult("1", "2"))
+
+	var out bytes.Buffer
+	cfg := NewDefaultConfig()
+	cfg.Database = "test"
+	cfg.User = "mock"
+	cfg.Password = "mock"
+	cfg.Address = address
+	cfg.ChunksizeInMB = 1
+	cfg.StmtSize = 10000
+	cfg.IntervalMs = 500
+	cfg.Compression = "gzip"
+	cfg.Archive = &out
+
+	d, err := NewDumper(cfg)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.Run(context.Background()), qt.IsNil)
+
+	var names []string
+	tr := tar.NewReader(&out)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		c.Assert(err, qt.IsNil)
+		names = append(names, hdr.Name)
+	}
+	sort.Strings(names)
+	c.Assert(names, qt.DeepEquals, []string{"manifest.json", "metadata", "test.t1-schema.sql", "test.t1.00001.sql.gz"})
+}
+
+func TestDumperArchiveCannotResume(t *testing.T) {
+	c := qt.New(t)
+
+	cfg := NewDefaultConfig()
+	cfg.Archive = io.Discard
+	cfg.Resume = true
+
+	d, err := NewDumper(cfg)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.Run(context.Background()), qt.ErrorMatches, "cannot resume a dump that is streamed as an archive")
+}
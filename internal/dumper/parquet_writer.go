@@ -0,0 +1,355 @@
+package dumper
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	querypb "github.com/xelabs/go-mysqlstack/sqlparser/depends/query"
+	"github.com/xelabs/go-mysqlstack/sqlparser/depends/sqltypes"
+)
+
+// parquetKind describes how a MySQL column is stored in a parquet file.
+type parquetKind int
+
+const (
+	parquetString parquetKind = iota
+	parquetBinary
+	parquetInt32
+	parquetUint32
+	parquetInt64
+	parquetUint64
+	parquetFloat
+	parquetDouble
+	parquetDecimal
+	parquetDate
+	parquetTimestamp
+)
+
+// maxInt64DecimalPrecision is the largest decimal precision that can be
+// stored as an INT64 backed parquet decimal.
+const maxInt64DecimalPrecision = 18
+
+type parquetColumn struct {
+	kind  parquetKind
+	scale int
+	// precision is only set for decimals.
+	precision int
+}
+
+// parquetWriter writes every chunk as a parquet file holding a single row
+// group. Column types are derived from the MySQL result set fields.
+type parquetWriter struct {
+	cfg        *Config
+	table      string
+	fieldNames []string
+	columns    []parquetColumn
+	schema     *parquet.Schema
+	rows       []parquet.Row
+	chunkbytes int
+}
+
+func newParquetWriter(cfg *Config, table string) *parquetWriter {
+	return &parquetWriter{
+		cfg:   cfg,
+		table: table,
+		rows:  make([]parquet.Row, 0, 256),
+	}
+}
+
+func (w *parquetWriter) Initialize(fieldNames []string) error {
+	w.fieldNames = fieldNames
+	// Until the field types are known every column is stored as a string.
+	w.columns = make([]parquetColumn, len(fieldNames))
+	return w.buildSchema()
+}
+
+// SetFieldTypes derives the parquet column types from the fields of the
+// result set being dumped.
+func (w *parquetWriter) SetFieldTypes(fields []*querypb.Field) error {
+	if len(fields) != len(w.fieldNames) {
+		return nil
+	}
+
+	for i, f := range fields {
+		w.columns[i] = parquetColumnFor(f)
+	}
+	return w.buildSchema()
+}
+
+func (w *parquetWriter) buildSchema() error {
+	group := parquet.Group{}
+	for i, name := range w.fieldNames {
+		if _, ok := group[name]; ok {
+			return fmt.Errorf("duplicate column %q in parquet output for table %q", name, w.table)
+		}
+		group[name] = parquet.Optional(w.columns[i].node())
+	}
+	w.schema = parquet.NewSchema(w.table, orderedGroup{Group: group, names: w.fieldNames})
+	return nil
+}
+
+func (w *parquetWriter) WriteRow(row []sqltypes.Value) (int, error) {
+	prow := make(parquet.Row, len(row))
+	rowBytes := 0
+	for i, v := range row {
+		pv, err := w.columns[i].value(v)
+		if err != nil {
+			return 0, fmt.Errorf("column %q: %w", w.fieldNames[i], err)
+		}
+		if pv.IsNull() {
+			prow[i] = pv.Level(0, 0, i)
+		} else {
+			prow[i] = pv.Level(0, 1, i)
+		}
+		rowBytes += v.Len()
+	}
+
+	w.rows = append(w.rows, prow)
+	w.chunkbytes += rowBytes
+	return rowBytes, nil
+}
+
+func (w *parquetWriter) ShouldFlush() bool {
+	return (w.chunkbytes / 1024 / 1024) >= w.cfg.ChunksizeInMB
+}
+
+func (w *parquetWriter) Flush(outdir, database, table string, chunk chunkID) error {
+	file, err := dumpOutputPath(outdir, database, table, chunk.suffix("parquet"))
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	pw := parquet.NewWriter(&buf, w.schema, parquet.Compression(&parquet.Snappy))
+	if _, err := pw.WriteRows(w.rows); err != nil {
+		return err
+	}
+	if err := pw.Close(); err != nil {
+		return err
+	}
+
+	// Parquet compresses its pages itself, so --compress does not apply.
+	rows := uint64(len(w.rows))
+	err = storeDumpFile(w.cfg, file, buf.Bytes(), &rows)
+	if err != nil {
+		return err
+	}
+
+	w.rows = w.rows[:0]
+	w.chunkbytes = 0
+	return nil
+}
+
+func (w *parquetWriter) Close(outdir, database, table string, chunk chunkID) error {
+	if len(w.rows) > 0 {
+		return w.Flush(outdir, database, table, chunk)
+	}
+	return nil
+}
+
+// orderedGroup is a parquet group that keeps the columns in the order of the
+// dumped table instead of sorting them by name.
+type orderedGroup struct {
+	parquet.Group
+	names []string
+}
+
+func (g orderedGroup) Fields() []parquet.Field {
+	fields := g.Group.Fields()
+	slices.SortFunc(fields, func(a, b parquet.Field) int {
+		return slices.Index(g.names, a.Name()) - slices.Index(g.names, b.Name())
+	})
+	return fields
+}
+
+func parquetColumnFor(f *querypb.Field) parquetColumn {
+	unsigned := f.Flags&uint32(querypb.MySqlFlag_UNSIGNED_FLAG) != 0
+
+	switch f.Type {
+	case querypb.Type_INT8, querypb.Type_UINT8, querypb.Type_INT16, querypb.Type_UINT16,
+		querypb.Type_INT24, querypb.Type_UINT24, querypb.Type_INT32, querypb.Type_YEAR:
+		return parquetColumn{kind: parquetInt32}
+	case querypb.Type_UINT32:
+		return parquetColumn{kind: parquetUint32}
+	case querypb.Type_INT64:
+		return parquetColumn{kind: parquetInt64}
+	case querypb.Type_UINT64:
+		return parquetColumn{kind: parquetUint64}
+	case querypb.Type_FLOAT32:
+		return parquetColumn{kind: parquetFloat}
+	case querypb.Type_FLOAT64:
+		return parquetColumn{kind: parquetDouble}
+	case querypb.Type_DECIMAL:
+		if f.ColumnLength == 0 {
+			// Without the column definition the scale is unknown.
+			return parquetColumn{kind: parquetString}
+		}
+		// The column length of DECIMAL(M,D) includes the sign and the
+		// decimal point: M + (D > 0 ? 1 : 0) + (unsigned ? 0 : 1).
+		scale := int(f.Decimals)
+		precision := int(f.ColumnLength)
+		if scale > 0 {
+			precision--
+		}
+		if !unsigned {
+			precision--
+		}
+		precision = max(precision, scale, 1)
+		return parquetColumn{kind: parquetDecimal, scale: scale, precision: precision}
+	case querypb.Type_DATE:
+		return parquetColumn{kind: parquetDate}
+	case querypb.Type_DATETIME, querypb.Type_TIMESTAMP:
+		return parquetColumn{kind: parquetTimestamp}
+	case querypb.Type_BINARY, querypb.Type_VARBINARY, querypb.Type_BLOB, querypb.Type_BIT, querypb.Type_GEOMETRY:
+		return parquetColumn{kind: parquetBinary}
+	default:
+		return parquetColumn{kind: parquetString}
+	}
+}
+
+func (c parquetColumn) node() parquet.Node {
+	switch c.kind {
+	case parquetBinary:
+		return parquet.Leaf(parquet.ByteArrayType)
+	case parquetInt32:
+		return parquet.Int(32)
+	case parquetUint32:
+		return parquet.Uint(32)
+	case parquetInt64:
+		return parquet.Int(64)
+	case parquetUint64:
+		return parquet.Uint(64)
+	case parquetFloat:
+		return parquet.Leaf(parquet.FloatType)
+	case parquetDouble:
+		return parquet.Leaf(parquet.DoubleType)
+	case parquetDecimal:
+		if c.precision <= maxInt64DecimalPrecision {
+			return parquet.Decimal(c.scale, c.precision, parquet.Int64Type)
+		}
+		return parquet.Decimal(c.scale, c.precision, parquet.ByteArrayType)
+	case parquetDate:
+		return parquet.Date()
+	case parquetTimestamp:
+		// MySQL DATETIME values carry no time zone.
+		return parquet.TimestampAdjusted(parquet.Microsecond, false)
+	default:
+		return parquet.String()
+	}
+}
+
+const secondsPerDay = 24 * 60 * 60
+
+func (c parquetColumn) value(v sqltypes.Value) (parquet.Value, error) {
+	if v.IsNull() || v.Raw() == nil {
+		return parquet.NullValue(), nil
+	}
+
+	s := v.String()
+	switch c.kind {
+	case parquetInt32:
+		n, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.Int32Value(int32(n)), nil
+	case parquetUint32:
+		n, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.Int32Value(int32(uint32(n))), nil
+	case parquetInt64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.Int64Value(n), nil
+	case parquetUint64:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.Int64Value(int64(n)), nil
+	case parquetFloat:
+		f, err := strconv.ParseFloat(s, 32)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.FloatValue(float32(f)), nil
+	case parquetDouble:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.DoubleValue(f), nil
+	case parquetDecimal:
+		unscaled, err := unscaledDecimal(s, c.scale)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		if c.precision <= maxInt64DecimalPrecision {
+			return parquet.Int64Value(unscaled.Int64()), nil
+		}
+		return parquet.ByteArrayValue(twosComplement(unscaled)), nil
+	case parquetDate:
+		if isZeroDate(s) {
+			return parquet.NullValue(), nil
+		}
+		t, err := time.Parse(time.DateOnly, s)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.Int32Value(int32(t.Unix() / secondsPerDay)), nil
+	case parquetTimestamp:
+		if isZeroDate(s) {
+			return parquet.NullValue(), nil
+		}
+		t, err := time.Parse("2006-01-02 15:04:05.999999999", s)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.Int64Value(t.UnixMicro()), nil
+	default:
+		return parquet.ByteArrayValue(v.Raw()), nil
+	}
+}
+
+// isZeroDate reports whether s is one of MySQL's zero dates, which have no
+// parquet representation and are written as NULL.
+func isZeroDate(s string) bool {
+	return strings.HasPrefix(s, "0000-00-00")
+}
+
+// unscaledDecimal returns the decimal s multiplied by 10^scale.
+func unscaledDecimal(s string, scale int) (*big.Int, error) {
+	intPart, frac, _ := strings.Cut(s, ".")
+	if len(frac) > scale {
+		return nil, fmt.Errorf("decimal %q has more than %d fractional digits", s, scale)
+	}
+	frac += strings.Repeat("0", scale-len(frac))
+
+	n, ok := new(big.Int).SetString(intPart+frac, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid decimal %q", s)
+	}
+	return n, nil
+}
+
+// twosComplement encodes n as a big-endian two's complement integer,
+// the representation of byte array backed parquet decimals.
+func twosComplement(n *big.Int) []byte {
+	size := n.BitLen()/8 + 1
+	if n.Sign() >= 0 {
+		return n.FillBytes(make([]byte, size))
+	}
+	v := new(big.Int).Lsh(big.NewInt(1), uint(size*8))
+	v.Add(v, n)
+	return v.FillBytes(make([]byte, size))
+}
@@ -0,0 +1,106 @@
+package dumper
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/parquet-go/parquet-go"
+	querypb "github.com/xelabs/go-mysqlstack/sqlparser/depends/query"
+	"github.com/xelabs/go-mysqlstack/sqlparser/depends/sqltypes"
+)
+
+func TestParquetWriter(t *testing.T) {
+	c := qt.New(t)
+
+	cfg := NewDefaultConfig()
+	cfg.ChunksizeInMB = 128
+
+	w := newParquetWriter(cfg, "t1")
+	c.Assert(w.Initialize([]string{"id", "price", "big", "created_at", "day", "name", "data"}), qt.IsNil)
+	c.Assert(w.SetFieldTypes([]*querypb.Field{
+		{Name: "id", Type: querypb.Type_UINT64},
+		{Name: "price", Type: querypb.Type_DECIMAL, ColumnLength: 12, Decimals: 2},
+		{Name: "big", Type: querypb.Type_DECIMAL, ColumnLength: 32, Decimals: 0},
+		{Name: "created_at", Type: querypb.Type_DATETIME},
+		{Name: "day", Type: querypb.Type_DATE},
+		{Name: "name", Type: querypb.Type_VARCHAR},
+		{Name: "data", Type: querypb.Type_BLOB},
+	}), qt.IsNil)
+
+	_, err := w.WriteRow([]sqltypes.Value{
+		sqltypes.MakeTrusted(querypb.Type_UINT64, []byte("7")),
+		sqltypes.MakeTrusted(querypb.Type_DECIMAL, []byte("-12.5")),
+		sqltypes.MakeTrusted(querypb.Type_DECIMAL, []byte("-123456789012345678901234567890")),
+		sqltypes.MakeTrusted(querypb.Type_DATETIME, []byte("2024-03-01 12:30:00.5")),
+		sqltypes.MakeTrusted(querypb.Type_DATE, []byte("1970-01-03")),
+		sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte("alice")),
+		sqltypes.NULL,
+	})
+	c.Assert(err, qt.IsNil)
+
+	_, err = w.WriteRow([]sqltypes.Value{
+		sqltypes.MakeTrusted(querypb.Type_UINT64, []byte("8")),
+		sqltypes.NULL,
+		sqltypes.NULL,
+		sqltypes.MakeTrusted(querypb.Type_DATETIME, []byte("0000-00-00 00:00:00")),
+		sqltypes.NULL,
+		sqltypes.NULL,
+		sqltypes.MakeTrusted(querypb.Type_BLOB, []byte{0x00, 0xff}),
+	})
+	c.Assert(err, qt.IsNil)
+
+	outdir := c.TempDir()
+	c.Assert(w.Close(outdir, "test", "t1", chunkID{fileNo: 1}), qt.IsNil)
+
+	data, err := os.ReadFile(filepath.Join(outdir, "test.t1.00001.parquet"))
+	c.Assert(err, qt.IsNil)
+
+	f, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	c.Assert(err, qt.IsNil)
+	c.Assert(f.RowGroups(), qt.HasLen, 1)
+	c.Assert(f.NumRows(), qt.Equals, int64(2))
+
+	var names []string
+	for _, field := range f.Schema().Fields() {
+		names = append(names, field.Name())
+	}
+	c.Assert(names, qt.DeepEquals, []string{"id", "price", "big", "created_at", "day", "name", "data"})
+
+	rows := make([]parquet.Row, 2)
+	n, err := f.RowGroups()[0].Rows().ReadRows(rows)
+	if err != io.EOF {
+		c.Assert(err, qt.IsNil)
+	}
+	c.Assert(n, qt.Equals, 2)
+
+	first := rows[0]
+	c.Assert(first[0].Int64(), qt.Equals, int64(7))
+	c.Assert(first[1].Int64(), qt.Equals, int64(-1250))
+	unscaled, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+	c.Assert(first[2].ByteArray(), qt.DeepEquals, twosComplement(unscaled))
+	c.Assert(first[3].Int64(), qt.Equals, int64(1709296200500000))
+	c.Assert(first[4].Int32(), qt.Equals, int32(2))
+	c.Assert(string(first[5].ByteArray()), qt.Equals, "alice")
+	c.Assert(first[6].IsNull(), qt.IsTrue)
+
+	second := rows[1]
+	c.Assert(second[1].IsNull(), qt.IsTrue)
+	c.Assert(second[3].IsNull(), qt.IsTrue)
+	c.Assert(second[6].ByteArray(), qt.DeepEquals, []byte{0x00, 0xff})
+}
+
+func TestTwosComplement(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(twosComplement(big.NewInt(0)), qt.DeepEquals, []byte{0x00})
+	c.Assert(twosComplement(big.NewInt(127)), qt.DeepEquals, []byte{0x7f})
+	c.Assert(twosComplement(big.NewInt(128)), qt.DeepEquals, []byte{0x00, 0x80})
+	c.Assert(twosComplement(big.NewInt(-1)), qt.DeepEquals, []byte{0xff})
+	c.Assert(twosComplement(big.NewInt(-128)), qt.DeepEquals, []byte{0xff, 0x80})
+	c.Assert(twosComplement(big.NewInt(-129)), qt.DeepEquals, []byte{0xff, 0x7f})
+}
@@ -83,6 +83,21 @@ func (p *Pool) Put(conn *Connection) {
 	p.conns <- conn
 }
 
+// drain takes every connection out of the pool, for setup that has to run on
+// all of them at once. The connections must be returned with Put.
+func (p *Pool) drain() []*Connection {
+	conns := p.getConns()
+	if conns == nil {
+		return nil
+	}
+
+	all := make([]*Connection, 0, cap(conns))
+	for i := 0; i < cap(conns); i++ {
+		all = append(all, <-conns)
+	}
+	return all
+}
+
 // Close used to close the pool and the connections.
 func (p *Pool) Close() {
 	p.mu.Lock()
@@ -0,0 +1,373 @@
+package dumper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/planetscale/cli/internal/postgres"
+	"golang.org/x/sync/errgroup"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// EngineMySQL and EnginePostgres select the database engine a dump is
+	// taken from or restored to.
+	EngineMySQL    = "mysql"
+	EnginePostgres = "postgresql"
+)
+
+const (
+	pgPreDataFile      = "pre-data.sql"
+	pgPostDataSuffix   = "-post-data.sql"
+	pgForeignKeySuffix = "-foreign-keys.sql"
+	pgSequenceSuffix   = "-sequences.sql"
+	// pgCopyTerminator ends the rows of a COPY ... FROM stdin block.
+	pgCopyTerminator = "\\.\n"
+)
+
+// connectPostgres opens a connection to the Postgres database of the dump.
+func connectPostgres(ctx context.Context, cfg *Config) (*pgx.Conn, error) {
+	host, portStr, err := net.SplitHostPort(cfg.Address)
+	if err != nil {
+		host, portStr = cfg.Address, "5432"
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port in address %q: %w", cfg.Address, err)
+	}
+
+	connStr := postgres.BuildConnectionString(&postgres.Config{
+		Host:     host,
+		Port:     port,
+		User:     cfg.User,
+		Password: cfg.Password,
+		Database: cfg.Database,
+		SSLMode:  "verify-full",
+	})
+	return pgx.Connect(ctx, connStr)
+}
+
+// runPostgres dumps a Postgres database. Every worker reads from a snapshot
+// exported by the initial connection, so the dump is consistent across
+// tables.
+func (d *Dumper) runPostgres(ctx context.Context) error {
+	if d.cfg.Resume {
+		return errors.New("resuming a dump is not supported for Postgres databases")
+	}
+	if d.cfg.OutputFormat != "sql" {
+		return fmt.Errorf("output format %s is not supported for Postgres databases", d.cfg.OutputFormat)
+	}
+
+	conn, err := connectPostgres(ctx, d.cfg)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "BEGIN ISOLATION LEVEL REPEATABLE READ READ ONLY"); err != nil {
+		return err
+	}
+	var snapshot string
+	if err := conn.QueryRow(ctx, "SELECT pg_catalog.pg_export_snapshot()").Scan(&snapshot); err != nil {
+		return fmt.Errorf("cannot export snapshot: %w", err)
+	}
+
+	t := time.Now()
+	tables, views, partitioned, err := listPostgresRelations(ctx, conn)
+	if err != nil {
+		return err
+	}
+	for _, p := range partitioned {
+		d.log.Warn("skipping partitioned table, partitioned tables are not supported", zap.String("table", p.Schema+"."+p.Name))
+	}
+
+	if d.cfg.Table != "" {
+		names := strings.Split(d.cfg.Table, ",")
+		tables = filterPostgresTables(tables, names)
+		views = filterPostgresTables(views, names)
+	}
+
+	if err := writeDumpFile(d.cfg, filepath.Join(d.cfg.Outdir, "metadata"), "engine: "+EnginePostgres+"\n"); err != nil {
+		return err
+	}
+
+	var schemas []string
+	for _, table := range tables {
+		if !slices.Contains(schemas, table.Schema) {
+			schemas = append(schemas, table.Schema)
+		}
+	}
+	preData, err := preDataSQL(ctx, conn, schemas)
+	if err != nil {
+		return err
+	}
+	if err := writeDumpFile(d.cfg, filepath.Join(d.cfg.Outdir, pgPreDataFile), preData); err != nil {
+		return err
+	}
+
+	schemasByTable := make(map[pgTable]*pgTableSchema, len(tables))
+	for _, table := range tables {
+		schema, err := fetchPostgresTableSchema(ctx, conn, table)
+		if err != nil {
+			return fmt.Errorf("reading schema of %s: %w", table.ident(), err)
+		}
+		if err := d.writePostgresTableSchema(schema); err != nil {
+			return err
+		}
+		schemasByTable[table] = schema
+	}
+
+	for _, view := range views {
+		var definition string
+		if err := conn.QueryRow(ctx, pgViewQuery, view.OID).Scan(&definition); err != nil {
+			return fmt.Errorf("reading definition of view %s: %w", view.ident(), err)
+		}
+		file, err := dumpOutputPath(d.cfg.Outdir, view.Schema, view.Name, viewSuffix)
+		if err != nil {
+			return err
+		}
+		if err := writeDumpFile(d.cfg, file, createViewSQL(view, definition)); err != nil {
+			return err
+		}
+	}
+
+	if d.cfg.SchemaOnly {
+		return nil
+	}
+
+	threads := max(d.cfg.Threads, 1)
+	workers := make(chan *pgx.Conn, threads)
+	defer func() {
+		close(workers)
+		for worker := range workers {
+			worker.Close(context.Background())
+		}
+	}()
+	for range threads {
+		worker, err := connectPostgres(ctx, d.cfg)
+		if err != nil {
+			return err
+		}
+		workers <- worker
+		if _, err := worker.Exec(ctx, "BEGIN ISOLATION LEVEL REPEATABLE READ READ ONLY"); err != nil {
+			return err
+		}
+		if _, err := worker.Exec(ctx, "SET TRANSACTION SNAPSHOT "+postgres.QuoteLiteral(snapshot)); err != nil {
+			return fmt.Errorf("cannot import snapshot: %w", err)
+		}
+	}
+
+	eg, egCtx := errgroup.WithContext(ctx)
+	for _, table := range tables {
+		schema := schemasByTable[table]
+		eg.Go(func() error {
+			worker := <-workers
+			defer func() { workers <- worker }()
+
+			if egCtx.Err() != nil {
+				return egCtx.Err()
+			}
+
+			d.log.Info("dumping table ...", zap.String("schema", table.Schema), zap.String("table", table.Name))
+			if err := d.dumpPostgresTable(egCtx, worker, schema); err != nil {
+				d.log.Error("error dumping table", zap.Error(err))
+				return fmt.Errorf("dumping %s: %w", table.ident(), err)
+			}
+			return nil
+		})
+	}
+
+	tick := time.NewTicker(time.Millisecond * time.Duration(d.cfg.IntervalMs))
+	defer tick.Stop()
+	go func() {
+		for range tick.C {
+			diff := time.Since(t).Seconds()
+			allbytesMB := float64(atomic.LoadUint64(&d.cfg.Allbytes) / 1024 / 1024)
+			d.log.Info(
+				"dumping rates ...",
+				zap.Float64("allbytes", allbytesMB),
+				zap.Uint64("allrows", atomic.LoadUint64(&d.cfg.Allrows)),
+				zap.Float64("time_sec", diff),
+				zap.Float64("rates_mb_sec", allbytesMB/diff),
+			)
+		}
+	}()
+
+	if err := eg.Wait(); err != nil {
+		d.log.Error("error dumping", zap.Error(err))
+		return err
+	}
+
+	elapsed := time.Since(t)
+	d.log.Info(
+		"dumping all done",
+		zap.Duration("elapsed_time", elapsed),
+		zap.Uint64("allrows", d.cfg.Allrows),
+		zap.Uint64("allbytes", d.cfg.Allbytes),
+		zap.Float64("rate_mb_seconds", (float64(d.cfg.Allbytes/1024/1024)/elapsed.Seconds())),
+	)
+	return nil
+}
+
+// filterPostgresTables keeps the tables named in names, either by their
+// name or qualified with their schema.
+func filterPostgresTables(tables []pgTable, names []string) []pgTable {
+	var filtered []pgTable
+	for _, table := range tables {
+		if slices.Contains(names, table.Name) || slices.Contains(names, table.Schema+"."+table.Name) {
+			filtered = append(filtered, table)
+		}
+	}
+	return filtered
+}
+
+// lookupTableOption returns the option set for table, keyed either by its
+// qualified or plain name.
+func lookupTableOption[V any](options map[string]V, table pgTable) (V, bool) {
+	if v, ok := options[table.Schema+"."+table.Name]; ok {
+		return v, true
+	}
+	v, ok := options[table.Name]
+	return v, ok
+}
+
+func (d *Dumper) writePostgresTableSchema(schema *pgTableSchema) error {
+	files := map[string]string{
+		schemaSuffix:       createTableSQL(schema),
+		pgPostDataSuffix:   postDataSQL(schema),
+		pgForeignKeySuffix: foreignKeysSQL(schema),
+	}
+	if !d.cfg.SchemaOnly {
+		// Sequence values only make sense along with the data.
+		files[pgSequenceSuffix] = sequenceValuesSQL(schema)
+	}
+
+	for suffix, data := range files {
+		if data == "" {
+			continue
+		}
+		file, err := dumpOutputPath(d.cfg.Outdir, schema.Table.Schema, schema.Table.Name, suffix)
+		if err != nil {
+			return err
+		}
+		if err := writeDumpFile(d.cfg, file, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dumpPostgresTable streams the rows of the table with COPY and writes them
+// into chunk files of ChunksizeInMB, each a COPY ... FROM stdin block.
+func (d *Dumper) dumpPostgresTable(ctx context.Context, conn *pgx.Conn, schema *pgTableSchema) error {
+	table := schema.Table
+	includes, _ := lookupTableOption(d.cfg.ColumnIncludes, table)
+	columns := copyColumns(schema.Columns, includes)
+	if len(columns) == 0 {
+		return fmt.Errorf("no columns to dump")
+	}
+
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = postgres.QuoteIdentifier(col)
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(quoted, ", "), table.ident())
+	if where, ok := lookupTableOption(d.cfg.Wheres, table); ok && where != "" {
+		query += " WHERE " + where
+	}
+
+	w := &copyChunkWriter{
+		cfg:       d.cfg,
+		table:     table,
+		header:    copyFromSQL(table, columns),
+		chunkSize: d.cfg.ChunksizeInMB * 1024 * 1024,
+	}
+	if _, err := conn.PgConn().CopyTo(ctx, w, "COPY ("+query+") TO STDOUT"); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	d.cfg.manifest.finishTable(table.Schema, table.Name, w.rows)
+
+	d.log.Info(
+		"dumping table done...",
+		zap.String("schema", table.Schema),
+		zap.String("table", table.Name),
+		zap.Uint64("all_rows", w.rows),
+		zap.Int("file_count", w.fileNo),
+	)
+	return nil
+}
+
+// copyChunkWriter splits the COPY text stream of a table into chunk files.
+// Rows in the text format are newline terminated, with newlines inside
+// values escaped, so chunks are only ever cut after a newline.
+type copyChunkWriter struct {
+	cfg       *Config
+	table     pgTable
+	header    string
+	chunkSize int
+
+	buf    bytes.Buffer
+	rows   uint64
+	fileNo int
+}
+
+func (w *copyChunkWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	if w.buf.Len() < w.chunkSize {
+		return len(p), nil
+	}
+
+	data := w.buf.Bytes()
+	end := bytes.LastIndexByte(data, '\n')
+	if end < 0 {
+		return len(p), nil
+	}
+	if err := w.flush(data[:end+1]); err != nil {
+		return 0, err
+	}
+	rest := slices.Clone(data[end+1:])
+	w.buf.Reset()
+	w.buf.Write(rest)
+	return len(p), nil
+}
+
+// Close writes the remaining rows. A table without rows still gets one
+// (empty) chunk, so restoring it is explicit.
+func (w *copyChunkWriter) Close() error {
+	if w.buf.Len() == 0 && w.fileNo > 0 {
+		return nil
+	}
+	if w.buf.Len() > 0 && w.buf.Bytes()[w.buf.Len()-1] != '\n' {
+		return fmt.Errorf("incomplete row at the end of the COPY stream")
+	}
+	return w.flush(w.buf.Bytes())
+}
+
+func (w *copyChunkWriter) flush(rows []byte) error {
+	w.fileNo++
+	file, err := dumpOutputPath(w.cfg.Outdir, w.table.Schema, w.table.Name, fmt.Sprintf(".%05d.sql", w.fileNo))
+	if err != nil {
+		return err
+	}
+
+	n := uint64(bytes.Count(rows, []byte{'\n'}))
+	w.rows += n
+	atomic.AddUint64(&w.cfg.Allrows, n)
+	atomic.AddUint64(&w.cfg.Allbytes, uint64(len(rows)))
+
+	return writeDataFile(w.cfg, file, w.header+string(rows)+pgCopyTerminator, n)
+}
@@ -0,0 +1,276 @@
+package dumper
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/planetscale/cli/internal/postgres"
+	"github.com/planetscale/cli/internal/printer"
+	"golang.org/x/sync/errgroup"
+
+	"go.uber.org/zap"
+)
+
+// runPostgres restores a Postgres dump. Tables are created without their
+// indexes and unique keys, which are built once the data is loaded, and
+// foreign keys are added last.
+func (l *Loader) runPostgres(ctx context.Context) error {
+	files, err := l.loadFiles(l.cfg.Outdir)
+	if err != nil {
+		return err
+	}
+
+	threads := max(l.cfg.Threads, 1)
+	workers := make(chan *pgx.Conn, threads)
+	defer func() {
+		close(workers)
+		for worker := range workers {
+			worker.Close(context.Background())
+		}
+	}()
+	for range threads {
+		worker, err := connectPostgres(ctx, l.cfg)
+		if err != nil {
+			return err
+		}
+		workers <- worker
+	}
+
+	if l.canRestoreSchema() {
+		if err := l.restorePostgresFiles(ctx, workers, files.preData, 1); err != nil {
+			return err
+		}
+		if err := l.restorePostgresTableSchema(ctx, workers, files.schemas); err != nil {
+			return err
+		}
+	} else {
+		l.cfg.Printer.Println("Skipping restoring table definitions...")
+	}
+
+	if l.canRestoreData() {
+		if err := l.restorePostgresData(ctx, workers, files.tables); err != nil {
+			return err
+		}
+		if err := l.restorePostgresFiles(ctx, workers, files.sequences, 1); err != nil {
+			return err
+		}
+	} else {
+		l.cfg.Printer.Println("Skipping restoring data files...")
+	}
+
+	if l.canRestoreSchema() {
+		if l.cfg.ShowDetails {
+			l.cfg.Printer.Println("Creating indexes and constraints ...")
+		}
+		if err := l.restorePostgresFiles(ctx, workers, files.postData, threads); err != nil {
+			return err
+		}
+		if err := l.restorePostgresFiles(ctx, workers, files.foreignKeys, 1); err != nil {
+			return err
+		}
+		if err := l.restorePostgresViews(ctx, workers, files.views); err != nil {
+			return err
+		}
+	} else {
+		l.cfg.Printer.Println("Skipping restoring view definitions...")
+	}
+
+	return nil
+}
+
+// restorePostgresFiles executes each file as a whole, running up to threads
+// files at a time.
+func (l *Loader) restorePostgresFiles(ctx context.Context, workers chan *pgx.Conn, files []string, threads int) error {
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(threads)
+	for _, file := range files {
+		eg.Go(func() error {
+			worker := <-workers
+			defer func() { workers <- worker }()
+
+			if l.cfg.ShowDetails {
+				l.cfg.Printer.Println("Executing file: " + printer.BoldBlue(filepath.Base(file)))
+			}
+			return execPostgresFile(egCtx, worker, file)
+		})
+	}
+	return eg.Wait()
+}
+
+func execPostgresFile(ctx context.Context, conn *pgx.Conn, file string) error {
+	data, err := readDumpFile(file)
+	if err != nil {
+		return err
+	}
+	// The simple query protocol runs all statements of the file at once.
+	if _, err := conn.PgConn().Exec(ctx, string(data)).ReadAll(); err != nil {
+		return fmt.Errorf("restoring %s: %w", filepath.Base(file), err)
+	}
+	return nil
+}
+
+func (l *Loader) restorePostgresTableSchema(ctx context.Context, workers chan *pgx.Conn, files []string) error {
+	conn := <-workers
+	defer func() { workers <- conn }()
+
+	for idx, file := range files {
+		name := strings.TrimSuffix(trimCompressionSuffix(filepath.Base(file)), schemaSuffix)
+		schema, table, ok := strings.Cut(name, ".")
+		if !ok {
+			return fmt.Errorf("expected schema.table, but got: %q", name)
+		}
+		ident := postgres.QuoteIdentifier(schema) + "." + postgres.QuoteIdentifier(table)
+
+		if l.cfg.OverwriteTables {
+			if l.cfg.ShowDetails {
+				l.cfg.Printer.Println("Dropping Existing Table (if it exists): " + printer.BoldBlue(ident))
+			}
+			if _, err := conn.Exec(ctx, "DROP TABLE IF EXISTS "+ident+" CASCADE"); err != nil {
+				return err
+			}
+		}
+
+		if l.cfg.ShowDetails {
+			l.cfg.Printer.Printf("Creating Table: %s (Table %d of %d)\n", printer.BoldBlue(ident), idx+1, len(files))
+		}
+		if err := execPostgresFile(ctx, conn, file); err != nil {
+			return err
+		}
+		l.log.Info("restoring schema", zap.String("schema", schema), zap.String("table ", table))
+	}
+	return nil
+}
+
+// restorePostgresViews creates the views. Views can depend on each other,
+// so views failing to be created are retried as long as others succeed.
+func (l *Loader) restorePostgresViews(ctx context.Context, workers chan *pgx.Conn, files []string) error {
+	conn := <-workers
+	defer func() { workers <- conn }()
+
+	if l.cfg.OverwriteTables {
+		for _, file := range files {
+			name := strings.TrimSuffix(trimCompressionSuffix(filepath.Base(file)), viewSuffix)
+			schema, view, _ := strings.Cut(name, ".")
+			ident := postgres.QuoteIdentifier(schema) + "." + postgres.QuoteIdentifier(view)
+			if _, err := conn.Exec(ctx, "DROP VIEW IF EXISTS "+ident+" CASCADE"); err != nil {
+				return err
+			}
+		}
+	}
+
+	pending := files
+	for len(pending) > 0 {
+		var failed []string
+		var lastErr error
+		for _, file := range pending {
+			if l.cfg.ShowDetails {
+				l.cfg.Printer.Println("Creating View: " + printer.BoldBlue(filepath.Base(file)))
+			}
+			if err := execPostgresFile(ctx, conn, file); err != nil {
+				failed = append(failed, file)
+				lastErr = err
+			}
+		}
+		if len(failed) == len(pending) {
+			return lastErr
+		}
+		pending = failed
+	}
+	return nil
+}
+
+func (l *Loader) restorePostgresData(ctx context.Context, workers chan *pgx.Conn, files []string) error {
+	var bytes uint64
+	t := time.Now()
+
+	eg, egCtx := errgroup.WithContext(ctx)
+	for idx, file := range files {
+		eg.Go(func() error {
+			worker := <-workers
+			defer func() { workers <- worker }()
+
+			if egCtx.Err() != nil {
+				return egCtx.Err()
+			}
+
+			if l.cfg.ShowDetails {
+				l.cfg.Printer.Printf("%s: %s (File %d of %d)\n", printer.BoldGreen("Started Processing Data File"), printer.BoldBlue(filepath.Base(file)), idx+1, len(files))
+			}
+			n, err := restorePostgresCopy(egCtx, worker, file)
+			if err != nil {
+				return err
+			}
+			atomic.AddUint64(&bytes, uint64(n))
+			return nil
+		})
+	}
+
+	tick := time.NewTicker(time.Millisecond * time.Duration(l.cfg.IntervalMs))
+	defer tick.Stop()
+	go func() {
+		for range tick.C {
+			diff := time.Since(t).Seconds()
+			bytes := float64(atomic.LoadUint64(&bytes) / 1024 / 1024)
+			l.log.Info(
+				"restoring ...",
+				zap.Float64("all_bytes", bytes),
+				zap.Float64("time_diff", diff),
+				zap.Float64("rates", bytes/diff),
+			)
+		}
+	}()
+
+	if err := eg.Wait(); err != nil {
+		l.log.Error("error restoring", zap.Error(err))
+		return err
+	}
+
+	elapsed := time.Since(t)
+	l.log.Info(
+		"restoring all done",
+		zap.Duration("elapsed_time", elapsed),
+		zap.Float64("all_bytes", (float64(bytes/1024/1024))),
+		zap.Float64("rate_mb_seconds", (float64(bytes/1024/1024)/elapsed.Seconds())),
+	)
+	return nil
+}
+
+// restorePostgresCopy loads a data file written by the dump through COPY
+// FROM STDIN. It returns the number of bytes loaded.
+func restorePostgresCopy(ctx context.Context, conn *pgx.Conn, file string) (int, error) {
+	data, err := readDumpFile(file)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt, rows, err := splitCopyFile(data)
+	if err != nil {
+		return 0, fmt.Errorf("restoring %s: %w", filepath.Base(file), err)
+	}
+	if _, err := conn.PgConn().CopyFrom(ctx, bytes.NewReader(rows), stmt); err != nil {
+		return 0, fmt.Errorf("restoring %s: %w", filepath.Base(file), err)
+	}
+	return len(rows), nil
+}
+
+// splitCopyFile splits a data file into its COPY statement and the rows
+// that follow it.
+func splitCopyFile(data []byte) (string, []byte, error) {
+	header, rows, ok := bytes.Cut(data, []byte("\n"))
+	stmt := strings.TrimSuffix(strings.TrimSpace(string(header)), ";")
+	if !ok || !strings.HasPrefix(stmt, "COPY ") || !strings.HasSuffix(stmt, " FROM stdin") {
+		return "", nil, fmt.Errorf("not a COPY data file")
+	}
+
+	rows, ok = bytes.CutSuffix(rows, []byte(pgCopyTerminator))
+	if !ok {
+		return "", nil, fmt.Errorf("missing end of COPY data marker")
+	}
+	return stmt, rows, nil
+}
@@ -0,0 +1,464 @@
+package dumper
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/planetscale/cli/internal/postgres"
+)
+
+// pgTable is a table or view of a Postgres dump, identified by its schema.
+type pgTable struct {
+	OID    uint32
+	Schema string
+	Name   string
+}
+
+func (t pgTable) ident() string {
+	return postgres.QuoteIdentifier(t.Schema) + "." + postgres.QuoteIdentifier(t.Name)
+}
+
+type pgColumn struct {
+	Name    string
+	Type    string
+	NotNull bool
+	Default string
+	// Identity is "a" for GENERATED ALWAYS and "d" for GENERATED BY DEFAULT
+	// identity columns.
+	Identity string
+	// Generated is "s" for stored generated columns, whose Default holds the
+	// generation expression.
+	Generated string
+}
+
+type pgConstraint struct {
+	Name       string
+	Type       string
+	Definition string
+}
+
+type pgSequence struct {
+	Schema    string
+	Name      string
+	DataType  string
+	Start     int64
+	Min       int64
+	Max       int64
+	Increment int64
+	Cache     int64
+	Cycle     bool
+	// LastValue is nil if the sequence was never used.
+	LastValue *int64
+	// Column the sequence is owned by, empty for standalone sequences.
+	Column string
+	// Identity is set for sequences backing identity columns, which are
+	// created along with the table.
+	Identity bool
+}
+
+func (s pgSequence) ident() string {
+	return postgres.QuoteIdentifier(s.Schema) + "." + postgres.QuoteIdentifier(s.Name)
+}
+
+// pgTableSchema holds the definition of a table split into the statements
+// that run before its data is loaded and those that run after it.
+type pgTableSchema struct {
+	Table       pgTable
+	Columns     []pgColumn
+	Constraints []pgConstraint
+	Indexes     []string
+	Sequences   []pgSequence
+}
+
+const pgListRelationsQuery = `SELECT c.oid, n.nspname, c.relname, c.relkind
+FROM pg_catalog.pg_class c
+JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relkind IN ('r', 'v', 'p')
+  AND NOT c.relispartition
+  AND n.nspname <> 'information_schema'
+  AND n.nspname NOT LIKE 'pg\_%'
+  AND NOT EXISTS (
+    SELECT 1 FROM pg_catalog.pg_depend d
+    WHERE d.classid = 'pg_catalog.pg_class'::regclass AND d.objid = c.oid AND d.deptype = 'e'
+  )
+ORDER BY n.nspname, c.relname`
+
+// listPostgresRelations returns the user tables and views of the database.
+// Partitioned tables are returned separately, as they are not supported.
+func listPostgresRelations(ctx context.Context, conn *pgx.Conn) (tables, views, partitioned []pgTable, err error) {
+	rows, err := conn.Query(ctx, pgListRelationsQuery)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t pgTable
+		var kind string
+		if err := rows.Scan(&t.OID, &t.Schema, &t.Name, &kind); err != nil {
+			return nil, nil, nil, err
+		}
+		switch kind {
+		case "v":
+			views = append(views, t)
+		case "p":
+			partitioned = append(partitioned, t)
+		default:
+			tables = append(tables, t)
+		}
+	}
+	return tables, views, partitioned, rows.Err()
+}
+
+const pgColumnsQuery = `SELECT a.attname, pg_catalog.format_type(a.atttypid, a.atttypmod), a.attnotnull,
+  COALESCE(pg_catalog.pg_get_expr(d.adbin, d.adrelid), ''), a.attidentity::text, a.attgenerated::text
+FROM pg_catalog.pg_attribute a
+LEFT JOIN pg_catalog.pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+WHERE a.attrelid = $1 AND a.attnum > 0 AND NOT a.attisdropped
+ORDER BY a.attnum`
+
+const pgConstraintsQuery = `SELECT conname, contype::text, pg_catalog.pg_get_constraintdef(oid)
+FROM pg_catalog.pg_constraint
+WHERE conrelid = $1 AND contype IN ('c', 'p', 'u', 'x', 'f')
+ORDER BY conname`
+
+const pgIndexesQuery = `SELECT pg_catalog.pg_get_indexdef(i.indexrelid)
+FROM pg_catalog.pg_index i
+WHERE i.indrelid = $1 AND NOT EXISTS (
+  SELECT 1 FROM pg_catalog.pg_constraint c
+  WHERE c.conrelid = i.indrelid AND c.conindid = i.indexrelid AND c.contype IN ('p', 'u', 'x')
+)
+ORDER BY i.indexrelid`
+
+const pgSequencesQuery = `SELECT s.schemaname, s.sequencename, s.data_type::text, s.start_value, s.min_value,
+  s.max_value, s.increment_by, s.cache_size, s.cycle, s.last_value,
+  COALESCE(a.attname, ''), COALESCE(d.deptype = 'i', false)
+FROM pg_catalog.pg_sequences s
+JOIN pg_catalog.pg_namespace n ON n.nspname = s.schemaname
+JOIN pg_catalog.pg_class c ON c.relnamespace = n.oid AND c.relname = s.sequencename
+LEFT JOIN pg_catalog.pg_depend d ON d.classid = 'pg_catalog.pg_class'::regclass AND d.objid = c.oid
+  AND d.refclassid = 'pg_catalog.pg_class'::regclass AND d.deptype IN ('a', 'i')
+LEFT JOIN pg_catalog.pg_attribute a ON a.attrelid = d.refobjid AND a.attnum = d.refobjsubid
+WHERE COALESCE(d.refobjid, 0) = $1
+ORDER BY s.schemaname, s.sequencename`
+
+// fetchPostgresTableSchema reads the columns, constraints, indexes and owned
+// sequences of table.
+func fetchPostgresTableSchema(ctx context.Context, conn *pgx.Conn, table pgTable) (*pgTableSchema, error) {
+	schema := &pgTableSchema{Table: table}
+
+	rows, err := conn.Query(ctx, pgColumnsQuery, table.OID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var col pgColumn
+		if err := rows.Scan(&col.Name, &col.Type, &col.NotNull, &col.Default, &col.Identity, &col.Generated); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		schema.Columns = append(schema.Columns, col)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = conn.Query(ctx, pgConstraintsQuery, table.OID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var con pgConstraint
+		if err := rows.Scan(&con.Name, &con.Type, &con.Definition); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		schema.Constraints = append(schema.Constraints, con)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = conn.Query(ctx, pgIndexesQuery, table.OID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var def string
+		if err := rows.Scan(&def); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		schema.Indexes = append(schema.Indexes, def)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	schema.Sequences, err = fetchPostgresSequences(ctx, conn, table.OID)
+	if err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// fetchPostgresSequences returns the sequences owned by the table with the
+// given OID, or the standalone sequences if the OID is zero.
+func fetchPostgresSequences(ctx context.Context, conn *pgx.Conn, owner uint32) ([]pgSequence, error) {
+	rows, err := conn.Query(ctx, pgSequencesQuery, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sequences []pgSequence
+	for rows.Next() {
+		var s pgSequence
+		if err := rows.Scan(&s.Schema, &s.Name, &s.DataType, &s.Start, &s.Min, &s.Max, &s.Increment,
+			&s.Cache, &s.Cycle, &s.LastValue, &s.Column, &s.Identity); err != nil {
+			return nil, err
+		}
+		sequences = append(sequences, s)
+	}
+	return sequences, rows.Err()
+}
+
+// createSequenceSQL returns the statement creating the sequence.
+func createSequenceSQL(s pgSequence) string {
+	cycle := "NO CYCLE"
+	if s.Cycle {
+		cycle = "CYCLE"
+	}
+	return fmt.Sprintf("CREATE SEQUENCE %s AS %s START WITH %d INCREMENT BY %d MINVALUE %d MAXVALUE %d CACHE %d %s;\n",
+		s.ident(), s.DataType, s.Start, s.Increment, s.Min, s.Max, s.Cache, cycle)
+}
+
+// setSequenceSQL returns the statement restoring the current value of the
+// sequence.
+func setSequenceSQL(s pgSequence) string {
+	name := postgres.QuoteLiteral(s.ident())
+	if s.LastValue == nil {
+		return fmt.Sprintf("SELECT pg_catalog.setval(%s, %d, false);\n", name, s.Start)
+	}
+	return fmt.Sprintf("SELECT pg_catalog.setval(%s, %d, true);\n", name, *s.LastValue)
+}
+
+// createTableSQL returns the statements creating the table without its
+// indexes, unique and foreign key constraints, which are added after the
+// data is loaded. Owned sequences are created first, as the column
+// defaults refer to them.
+func createTableSQL(schema *pgTableSchema) string {
+	var b strings.Builder
+	for _, s := range schema.Sequences {
+		if !s.Identity {
+			b.WriteString(createSequenceSQL(s))
+		}
+	}
+
+	var defs []string
+	for _, col := range schema.Columns {
+		def := postgres.QuoteIdentifier(col.Name) + " " + col.Type
+		switch {
+		case col.Generated == "s":
+			def += fmt.Sprintf(" GENERATED ALWAYS AS (%s) STORED", col.Default)
+		case col.Identity == "a":
+			def += " GENERATED ALWAYS AS IDENTITY"
+		case col.Identity == "d":
+			def += " GENERATED BY DEFAULT AS IDENTITY"
+		case col.Default != "":
+			def += " DEFAULT " + col.Default
+		}
+		if col.NotNull {
+			def += " NOT NULL"
+		}
+		defs = append(defs, def)
+	}
+	for _, con := range schema.Constraints {
+		if con.Type == "c" {
+			defs = append(defs, fmt.Sprintf("CONSTRAINT %s %s", postgres.QuoteIdentifier(con.Name), con.Definition))
+		}
+	}
+	fmt.Fprintf(&b, "CREATE TABLE %s (\n  %s\n);\n", schema.Table.ident(), strings.Join(defs, ",\n  "))
+
+	for _, s := range schema.Sequences {
+		if !s.Identity && s.Column != "" {
+			fmt.Fprintf(&b, "ALTER SEQUENCE %s OWNED BY %s.%s;\n", s.ident(), schema.Table.ident(), postgres.QuoteIdentifier(s.Column))
+		}
+	}
+	return b.String()
+}
+
+// postDataSQL returns the primary key, unique and exclusion constraints and
+// the indexes of the table.
+func postDataSQL(schema *pgTableSchema) string {
+	var b strings.Builder
+	for _, con := range schema.Constraints {
+		switch con.Type {
+		case "p", "u", "x":
+			fmt.Fprintf(&b, "ALTER TABLE %s ADD CONSTRAINT %s %s;\n", schema.Table.ident(), postgres.QuoteIdentifier(con.Name), con.Definition)
+		}
+	}
+	for _, def := range schema.Indexes {
+		b.WriteString(def + ";\n")
+	}
+	return b.String()
+}
+
+// foreignKeysSQL returns the foreign key constraints of the table, added
+// once every table has its data and unique keys.
+func foreignKeysSQL(schema *pgTableSchema) string {
+	var b strings.Builder
+	for _, con := range schema.Constraints {
+		if con.Type == "f" {
+			fmt.Fprintf(&b, "ALTER TABLE %s ADD CONSTRAINT %s %s;\n", schema.Table.ident(), postgres.QuoteIdentifier(con.Name), con.Definition)
+		}
+	}
+	return b.String()
+}
+
+// sequenceValuesSQL returns the statements restoring the values of the
+// sequences owned by the table.
+func sequenceValuesSQL(schema *pgTableSchema) string {
+	var b strings.Builder
+	for _, s := range schema.Sequences {
+		b.WriteString(setSequenceSQL(s))
+	}
+	return b.String()
+}
+
+// copyColumns returns the columns whose data is dumped: generated columns
+// are recomputed on restore, and includes limits the columns if set.
+func copyColumns(columns []pgColumn, includes map[string]bool) []string {
+	var names []string
+	for _, col := range columns {
+		if col.Generated != "" {
+			continue
+		}
+		if len(includes) > 0 && !includes[col.Name] {
+			continue
+		}
+		names = append(names, col.Name)
+	}
+	return names
+}
+
+// copyFromSQL returns the COPY statement heading each data file, in the
+// same format pg_dump uses for plain dumps.
+func copyFromSQL(table pgTable, columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = postgres.QuoteIdentifier(col)
+	}
+	return fmt.Sprintf("COPY %s (%s) FROM stdin;\n", table.ident(), strings.Join(quoted, ", "))
+}
+
+const pgViewQuery = `SELECT pg_catalog.pg_get_viewdef($1::oid, true)`
+
+// createViewSQL returns the statement creating the view.
+func createViewSQL(view pgTable, definition string) string {
+	return fmt.Sprintf("CREATE VIEW %s AS\n%s\n", view.ident(), strings.TrimSpace(definition))
+}
+
+const pgEnumTypesQuery = `SELECT n.nspname, t.typname,
+  array_agg(e.enumlabel ORDER BY e.enumsortorder)::text[]
+FROM pg_catalog.pg_type t
+JOIN pg_catalog.pg_namespace n ON n.oid = t.typnamespace
+JOIN pg_catalog.pg_enum e ON e.enumtypid = t.oid
+WHERE n.nspname <> 'information_schema' AND n.nspname NOT LIKE 'pg\_%'
+GROUP BY n.nspname, t.typname
+ORDER BY n.nspname, t.typname`
+
+const pgExtensionsQuery = `SELECT e.extname, n.nspname
+FROM pg_catalog.pg_extension e
+JOIN pg_catalog.pg_namespace n ON n.oid = e.extnamespace
+WHERE e.extname <> 'plpgsql'
+ORDER BY e.extname`
+
+// preDataSQL returns the statements creating the schemas, extensions, enum
+// types and standalone sequences the tables depend on. schemas are the
+// schemas of the dumped tables.
+func preDataSQL(ctx context.Context, conn *pgx.Conn, schemas []string) (string, error) {
+	var b strings.Builder
+	needed := make(map[string]bool)
+	for _, schema := range schemas {
+		needed[schema] = true
+	}
+
+	rows, err := conn.Query(ctx, pgExtensionsQuery)
+	if err != nil {
+		return "", err
+	}
+	for rows.Next() {
+		var name, schema string
+		if err := rows.Scan(&name, &schema); err != nil {
+			rows.Close()
+			return "", err
+		}
+		needed[schema] = true
+		fmt.Fprintf(&b, "CREATE EXTENSION IF NOT EXISTS %s WITH SCHEMA %s;\n", postgres.QuoteIdentifier(name), postgres.QuoteIdentifier(schema))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	rows, err = conn.Query(ctx, pgEnumTypesQuery)
+	if err != nil {
+		return "", err
+	}
+	for rows.Next() {
+		var schema, name string
+		var labels []string
+		if err := rows.Scan(&schema, &name, &labels); err != nil {
+			rows.Close()
+			return "", err
+		}
+		needed[schema] = true
+		b.WriteString(createEnumSQL(schema, name, labels))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	sequences, err := fetchPostgresSequences(ctx, conn, 0)
+	if err != nil {
+		return "", err
+	}
+	for _, s := range sequences {
+		needed[s.Schema] = true
+		b.WriteString(createSequenceSQL(s))
+		b.WriteString(setSequenceSQL(s))
+	}
+
+	return createSchemasSQL(needed) + b.String(), nil
+}
+
+// createSchemasSQL returns the statements creating the schemas, except the
+// public schema every database has.
+func createSchemasSQL(schemas map[string]bool) string {
+	names := slices.Sorted(maps.Keys(schemas))
+
+	var b strings.Builder
+	for _, schema := range names {
+		if schema != "public" {
+			fmt.Fprintf(&b, "CREATE SCHEMA IF NOT EXISTS %s;\n", postgres.QuoteIdentifier(schema))
+		}
+	}
+	return b.String()
+}
+
+func createEnumSQL(schema, name string, labels []string) string {
+	quoted := make([]string, len(labels))
+	for i, label := range labels {
+		quoted[i] = postgres.QuoteLiteral(label)
+	}
+	return fmt.Sprintf("CREATE TYPE %s.%s AS ENUM (%s);\n", postgres.QuoteIdentifier(schema), postgres.QuoteIdentifier(name), strings.Join(quoted, ", "))
+}
@@ -0,0 +1,210 @@
+package dumper
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/planetscale/cli/internal/printer"
+)
+
+func testTableSchema() *pgTableSchema {
+	last := int64(42)
+	return &pgTableSchema{
+		Table: pgTable{Schema: "app", Name: "users"},
+		Columns: []pgColumn{
+			{Name: "id", Type: "integer", NotNull: true, Default: "nextval('app.users_id_seq'::regclass)"},
+			{Name: "seq", Type: "bigint", NotNull: true, Identity: "d"},
+			{Name: "email", Type: "text", NotNull: true},
+			{Name: "email_lower", Type: "text", Default: "lower(email)", Generated: "s"},
+			{Name: "org_id", Type: "integer"},
+		},
+		Constraints: []pgConstraint{
+			{Name: "users_email_check", Type: "c", Definition: "CHECK ((email <> ''::text))"},
+			{Name: "users_org_id_fkey", Type: "f", Definition: "FOREIGN KEY (org_id) REFERENCES app.orgs(id)"},
+			{Name: "users_pkey", Type: "p", Definition: "PRIMARY KEY (id)"},
+		},
+		Indexes: []string{"CREATE INDEX users_org_id_idx ON app.users USING btree (org_id)"},
+		Sequences: []pgSequence{
+			{Schema: "app", Name: "users_id_seq", DataType: "integer", Start: 1, Min: 1, Max: 2147483647, Increment: 1, Cache: 1, LastValue: &last, Column: "id"},
+			{Schema: "app", Name: "users_seq_seq", DataType: "bigint", Start: 1, Min: 1, Max: 9223372036854775807, Increment: 1, Cache: 1, Column: "seq", Identity: true},
+		},
+	}
+}
+
+func TestCreateTableSQL(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(createTableSQL(testTableSchema()), qt.Equals, `CREATE SEQUENCE "app"."users_id_seq" AS integer START WITH 1 INCREMENT BY 1 MINVALUE 1 MAXVALUE 2147483647 CACHE 1 NO CYCLE;
+CREATE TABLE "app"."users" (
+  "id" integer DEFAULT nextval('app.users_id_seq'::regclass) NOT NULL,
+  "seq" bigint GENERATED BY DEFAULT AS IDENTITY NOT NULL,
+  "email" text NOT NULL,
+  "email_lower" text GENERATED ALWAYS AS (lower(email)) STORED,
+  "org_id" integer,
+  CONSTRAINT "users_email_check" CHECK ((email <> ''::text))
+);
+ALTER SEQUENCE "app"."users_id_seq" OWNED BY "app"."users"."id";
+`)
+}
+
+func TestPostDataSQL(t *testing.T) {
+	c := qt.New(t)
+
+	schema := testTableSchema()
+	c.Assert(postDataSQL(schema), qt.Equals, `ALTER TABLE "app"."users" ADD CONSTRAINT "users_pkey" PRIMARY KEY (id);
+CREATE INDEX users_org_id_idx ON app.users USING btree (org_id);
+`)
+	c.Assert(foreignKeysSQL(schema), qt.Equals, `ALTER TABLE "app"."users" ADD CONSTRAINT "users_org_id_fkey" FOREIGN KEY (org_id) REFERENCES app.orgs(id);
+`)
+	c.Assert(sequenceValuesSQL(schema), qt.Equals, `SELECT pg_catalog.setval('"app"."users_id_seq"', 42, true);
+SELECT pg_catalog.setval('"app"."users_seq_seq"', 1, false);
+`)
+}
+
+func TestCopyColumns(t *testing.T) {
+	c := qt.New(t)
+
+	columns := testTableSchema().Columns
+	c.Assert(copyColumns(columns, nil), qt.DeepEquals, []string{"id", "seq", "email", "org_id"})
+	c.Assert(copyColumns(columns, map[string]bool{"id": true, "email_lower": true}), qt.DeepEquals, []string{"id"})
+	c.Assert(copyFromSQL(pgTable{Schema: "app", Name: "users"}, []string{"id", "email"}), qt.Equals,
+		"COPY \"app\".\"users\" (\"id\", \"email\") FROM stdin;\n")
+}
+
+func TestFilterPostgresTables(t *testing.T) {
+	c := qt.New(t)
+
+	tables := []pgTable{
+		{Schema: "public", Name: "users"},
+		{Schema: "app", Name: "users"},
+		{Schema: "app", Name: "orders"},
+	}
+	c.Assert(filterPostgresTables(tables, []string{"users"}), qt.DeepEquals, tables[:2])
+	c.Assert(filterPostgresTables(tables, []string{"app.users", "orders"}), qt.DeepEquals, tables[1:])
+
+	wheres := map[string]string{"users": "id > 1", "app.users": "id > 2"}
+	where, ok := lookupTableOption(wheres, tables[0])
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(where, qt.Equals, "id > 1")
+	where, _ = lookupTableOption(wheres, tables[1])
+	c.Assert(where, qt.Equals, "id > 2")
+	_, ok = lookupTableOption(wheres, tables[2])
+	c.Assert(ok, qt.IsFalse)
+}
+
+func TestCopyChunkWriter(t *testing.T) {
+	c := qt.New(t)
+
+	cfg := NewDefaultConfig()
+	cfg.Outdir = c.TempDir()
+	table := pgTable{Schema: "app", Name: "users"}
+	w := &copyChunkWriter{
+		cfg:       cfg,
+		table:     table,
+		header:    copyFromSQL(table, []string{"id", "note"}),
+		chunkSize: 10,
+	}
+
+	// Rows arrive in arbitrary pieces, chunks are only cut after a row.
+	for _, piece := range []string{"1\tfirst\n2\tsec", "ond\\nline\n3\tthird\n", "4\t\\N\n"} {
+		_, err := w.Write([]byte(piece))
+		c.Assert(err, qt.IsNil)
+	}
+	c.Assert(w.Close(), qt.IsNil)
+	c.Assert(w.rows, qt.Equals, uint64(4))
+	c.Assert(w.fileNo, qt.Equals, 3)
+
+	var rows []byte
+	for i, want := range []string{"1\tfirst\n", "2\tsecond\\nline\n3\tthird\n", "4\t\\N\n"} {
+		data, err := os.ReadFile(filepath.Join(cfg.Outdir, fmt.Sprintf("app.users.%05d.sql", i+1)))
+		c.Assert(err, qt.IsNil)
+
+		stmt, chunk, err := splitCopyFile(data)
+		c.Assert(err, qt.IsNil)
+		c.Assert(stmt, qt.Equals, `COPY "app"."users" ("id", "note") FROM stdin`)
+		c.Assert(string(chunk), qt.Equals, want)
+		rows = append(rows, chunk...)
+	}
+	c.Assert(string(rows), qt.Equals, "1\tfirst\n2\tsecond\\nline\n3\tthird\n4\t\\N\n")
+}
+
+func TestCopyChunkWriterEmptyTable(t *testing.T) {
+	c := qt.New(t)
+
+	cfg := NewDefaultConfig()
+	cfg.Outdir = c.TempDir()
+	table := pgTable{Schema: "public", Name: "empty"}
+	w := &copyChunkWriter{cfg: cfg, table: table, header: copyFromSQL(table, []string{"id"}), chunkSize: 10}
+	c.Assert(w.Close(), qt.IsNil)
+
+	data, err := os.ReadFile(filepath.Join(cfg.Outdir, "public.empty.00001.sql"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(data), qt.Equals, "COPY \"public\".\"empty\" (\"id\") FROM stdin;\n\\.\n")
+}
+
+func TestSplitCopyFile(t *testing.T) {
+	c := qt.New(t)
+
+	_, _, err := splitCopyFile([]byte("INSERT INTO t VALUES (1);\n"))
+	c.Assert(err, qt.ErrorMatches, "not a COPY data file")
+
+	_, _, err = splitCopyFile([]byte("COPY \"t\" (\"id\") FROM stdin;\n1\n"))
+	c.Assert(err, qt.ErrorMatches, "missing end of COPY data marker")
+}
+
+func TestLoaderPostgresFiles(t *testing.T) {
+	c := qt.New(t)
+
+	dir := c.TempDir()
+	for _, name := range []string{
+		"metadata",
+		"pre-data.sql",
+		"app.users-schema.sql",
+		"app.users-post-data.sql",
+		"app.users-foreign-keys.sql",
+		"app.users-sequences.sql",
+		"app.users.00001.sql",
+		"app.active_users-schema-view.sql",
+	} {
+		c.Assert(writeFile(filepath.Join(dir, name), ""), qt.IsNil)
+	}
+
+	format := printer.Human
+	cfg := NewDefaultConfig()
+	cfg.Printer = printer.NewPrinter(&format)
+	l, err := NewLoader(cfg)
+	c.Assert(err, qt.IsNil)
+
+	files, err := l.loadFiles(dir)
+	c.Assert(err, qt.IsNil)
+	c.Assert(files.preData, qt.DeepEquals, []string{filepath.Join(dir, "pre-data.sql")})
+	c.Assert(files.schemas, qt.DeepEquals, []string{filepath.Join(dir, "app.users-schema.sql")})
+	c.Assert(files.postData, qt.DeepEquals, []string{filepath.Join(dir, "app.users-post-data.sql")})
+	c.Assert(files.foreignKeys, qt.DeepEquals, []string{filepath.Join(dir, "app.users-foreign-keys.sql")})
+	c.Assert(files.sequences, qt.DeepEquals, []string{filepath.Join(dir, "app.users-sequences.sql")})
+	c.Assert(files.tables, qt.DeepEquals, []string{filepath.Join(dir, "app.users.00001.sql")})
+	c.Assert(files.views, qt.DeepEquals, []string{filepath.Join(dir, "app.active_users-schema-view.sql")})
+	c.Assert(tableNameFromFilename(files.postData[0]), qt.Equals, "users")
+}
+
+func TestLoaderRejectsDumpOfOtherEngine(t *testing.T) {
+	c := qt.New(t)
+
+	dir := c.TempDir()
+	c.Assert(writeFile(filepath.Join(dir, "metadata"), "engine: postgresql\n"), qt.IsNil)
+
+	format := printer.Human
+	cfg := NewDefaultConfig()
+	cfg.Printer = printer.NewPrinter(&format)
+	cfg.Outdir = dir
+	l, err := NewLoader(cfg)
+	c.Assert(err, qt.IsNil)
+	c.Assert(l.Run(t.Context()), qt.ErrorMatches, "the dump in .* was taken from a postgresql database and cannot be restored into a mysql database")
+
+	engine, err := readDumpEngine(c.TempDir())
+	c.Assert(err, qt.IsNil)
+	c.Assert(engine, qt.Equals, EngineMySQL)
+}
@@ -0,0 +1,205 @@
+package dumper
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// tableRange is a primary key range of a table that is dumped on its own
+// connection. Large tables are split into ranges, so --threads also speeds up
+// dumping a single big table.
+type tableRange struct {
+	// part is the number of the range, starting at 1, or 0 if the table is
+	// dumped as a whole.
+	part int
+	// lower is the exclusive and upper the inclusive bound of the range, as
+	// SQL literals. Empty bounds are open.
+	lower, upper string
+}
+
+// checkpointTable returns the name the progress of the range is recorded
+// under in the checkpoint.
+func (r tableRange) checkpointTable(table string) string {
+	if r.part == 0 {
+		return table
+	}
+	return fmt.Sprintf("%s#%05d", table, r.part)
+}
+
+// where returns the condition selecting the rows of the range.
+func (r tableRange) where(column string) string {
+	var conds []string
+	if r.lower != "" {
+		conds = append(conds, fmt.Sprintf("%s > %s", column, r.lower))
+	}
+	if r.upper != "" {
+		conds = append(conds, fmt.Sprintf("%s <= %s", column, r.upper))
+	}
+	return strings.Join(conds, " AND ")
+}
+
+// rangesFromBounds returns the ranges ending at the given upper bounds,
+// followed by a range without upper bound.
+func rangesFromBounds(bounds []string) []tableRange {
+	if len(bounds) == 0 {
+		return []tableRange{{}}
+	}
+
+	ranges := make([]tableRange, 0, len(bounds)+1)
+	lower := ""
+	for i, upper := range bounds {
+		ranges = append(ranges, tableRange{part: i + 1, lower: lower, upper: upper})
+		lower = upper
+	}
+	return append(ranges, tableRange{part: len(bounds) + 1, lower: lower})
+}
+
+// splitTable returns the ranges a table is dumped in. A resumed dump uses
+// the ranges recorded in the checkpoint.
+func (d *Dumper) splitTable(conn *Connection, database, table string, threads int) ([]tableRange, error) {
+	state := d.checkpoint.table(database, table)
+	if len(state.Bounds) > 0 {
+		dumpCtx, err := d.tableDumpContext(conn, table)
+		if err != nil {
+			return nil, err
+		}
+		if len(dumpCtx.keyIndexes) == 1 {
+			return rangesFromBounds(state.Bounds), nil
+		}
+		// The primary key, or the columns selected for it, changed since the
+		// ranges were recorded, so they can't be resumed.
+		d.log.Warn(
+			"primary key changed, dumping table again without ranges ...",
+			zap.String("database", database),
+			zap.String("table", table),
+		)
+		if err := d.discardRanges(database, table, state.Bounds); err != nil {
+			return nil, err
+		}
+		return rangesFromBounds(nil), nil
+	}
+
+	switch {
+	case state.Chunks > 0 || threads < 2:
+		// A table whose dump was started as a whole is resumed as a whole.
+		return rangesFromBounds(nil), nil
+	}
+
+	bounds, err := d.splitBounds(conn, database, table, threads)
+	if err != nil {
+		// Splitting only speeds up the dump, the table can still be dumped
+		// as a whole.
+		d.log.Warn(
+			"cannot split table into ranges ...",
+			zap.String("database", database),
+			zap.String("table", table),
+			zap.Error(err),
+		)
+		return rangesFromBounds(nil), nil
+	}
+	if len(bounds) == 0 {
+		return rangesFromBounds(nil), nil
+	}
+
+	if err := d.checkpoint.split(database, table, bounds); err != nil {
+		return nil, err
+	}
+	d.log.Info(
+		"splitting table into ranges ...",
+		zap.String("database", database),
+		zap.String("table", table),
+		zap.Int("ranges", len(bounds)+1),
+	)
+	return rangesFromBounds(bounds), nil
+}
+
+// discardRanges forgets the progress of the ranges ending at bounds that a
+// table was split into and removes their chunk files.
+func (d *Dumper) discardRanges(database, table string, bounds []string) error {
+	for _, r := range rangesFromBounds(bounds) {
+		if err := removeTableChunks(d.cfg.Outdir, database, table, r.part, 0); err != nil {
+			return err
+		}
+	}
+	d.cfg.manifest.resetTable(database, table)
+	return d.checkpoint.unsplit(database, table)
+}
+
+// splitBounds returns the upper bounds of all but the last range of a table
+// larger than a chunk, which is split into at most threads ranges of about
+// the same width. Only tables with a single integer primary key column are
+// split.
+func (d *Dumper) splitBounds(conn *Connection, database, table string, threads int) ([]string, error) {
+	qr, err := conn.Fetch(fmt.Sprintf("SELECT DATA_LENGTH FROM information_schema.TABLES WHERE TABLE_SCHEMA = %s AND TABLE_NAME = %s",
+		quoteStringLiteral(database), quoteStringLiteral(table)))
+	if err != nil {
+		return nil, err
+	}
+	if len(qr.Rows) != 1 || qr.Rows[0][0].IsNull() {
+		return nil, nil
+	}
+	size, err := strconv.ParseUint(qr.Rows[0][0].String(), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	chunk := uint64(max(d.cfg.ChunksizeInMB, 1)) * 1024 * 1024
+	parts := min(uint64(threads), size/chunk)
+	if parts < 2 {
+		return nil, nil
+	}
+
+	dumpCtx, err := d.tableDumpContext(conn, table)
+	if err != nil {
+		return nil, err
+	}
+	if len(dumpCtx.keyIndexes) != 1 {
+		return nil, nil
+	}
+
+	key := dumpCtx.keyColumns()[0]
+	qr, err = conn.Fetch(fmt.Sprintf("SELECT MIN(%s), MAX(%s) FROM %s.%s%s", key, key, quoteIdentifier(database), quoteIdentifier(table), dumpCtx.where))
+	if err != nil {
+		return nil, err
+	}
+	if len(qr.Rows) != 1 || len(qr.Rows[0]) != 2 {
+		return nil, nil
+	}
+	lo, hi := qr.Rows[0][0], qr.Rows[0][1]
+	if lo.IsNull() || hi.IsNull() || !lo.IsIntegral() {
+		return nil, nil
+	}
+
+	low, ok := new(big.Int).SetString(lo.String(), 10)
+	if !ok {
+		return nil, fmt.Errorf("unexpected primary key value %q", lo.String())
+	}
+	high, ok := new(big.Int).SetString(hi.String(), 10)
+	if !ok {
+		return nil, fmt.Errorf("unexpected primary key value %q", hi.String())
+	}
+	return splitIntRange(low, high, int(parts)), nil
+}
+
+// splitIntRange returns the upper bounds of all but the last of parts ranges
+// of about the same width between low and high.
+func splitIntRange(low, high *big.Int, parts int) []string {
+	width := new(big.Int).Sub(high, low)
+
+	var bounds []string
+	for i := 1; i < parts; i++ {
+		b := new(big.Int).Mul(width, big.NewInt(int64(i)))
+		b.Quo(b, big.NewInt(int64(parts)))
+		b.Add(b, low)
+		if b.Cmp(high) >= 0 {
+			break
+		}
+		if s := b.String(); len(bounds) == 0 || bounds[len(bounds)-1] != s {
+			bounds = append(bounds, s)
+		}
+	}
+	return bounds
+}
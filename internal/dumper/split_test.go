@@ -0,0 +1,182 @@
+package dumper
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/go-cmp/cmp"
+	querypb "github.com/xelabs/go-mysqlstack/sqlparser/depends/query"
+	"github.com/xelabs/go-mysqlstack/sqlparser/depends/sqltypes"
+)
+
+func TestSplitIntRange(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(splitIntRange(big.NewInt(1), big.NewInt(100), 4), qt.DeepEquals, []string{"25", "50", "75"})
+	c.Assert(splitIntRange(big.NewInt(-10), big.NewInt(10), 2), qt.DeepEquals, []string{"0"})
+	c.Assert(splitIntRange(big.NewInt(1), big.NewInt(2), 4), qt.DeepEquals, []string{"1"})
+	c.Assert(splitIntRange(big.NewInt(7), big.NewInt(7), 4), qt.IsNil)
+
+	high, _ := new(big.Int).SetString("18446744073709551615", 10)
+	c.Assert(splitIntRange(big.NewInt(0), high, 2), qt.DeepEquals, []string{"9223372036854775807"})
+}
+
+func TestRangesFromBounds(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(rangesFromBounds(nil), qt.CmpEquals(cmp.AllowUnexported(tableRange{})), []tableRange{{}})
+
+	ranges := rangesFromBounds([]string{"10", "20"})
+	c.Assert(ranges, qt.CmpEquals(cmp.AllowUnexported(tableRange{})), []tableRange{
+		{part: 1, upper: "10"},
+		{part: 2, lower: "10", upper: "20"},
+		{part: 3, lower: "20"},
+	})
+	c.Assert(ranges[0].where("`id`"), qt.Equals, "`id` <= 10")
+	c.Assert(ranges[1].where("`id`"), qt.Equals, "`id` > 10 AND `id` <= 20")
+	c.Assert(ranges[2].where("`id`"), qt.Equals, "`id` > 20")
+	c.Assert(ranges[1].checkpointTable("t1"), qt.Equals, "t1#00002")
+	c.Assert(tableRange{}.checkpointTable("t1"), qt.Equals, "t1")
+
+	c.Assert(chunkID{fileNo: 3}.suffix("sql"), qt.Equals, ".00003.sql")
+	c.Assert(chunkID{part: 2, fileNo: 3}.suffix("csv"), qt.Equals, ".00002-00003.csv")
+	c.Assert(tableNameFromFilename("test.t1.00002-00003.sql"), qt.Equals, "t1")
+
+	n, ok := chunkNumber("test.t1.00002-00003.sql", "test.t1.00002-")
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(n, qt.Equals, 3)
+	_, ok = chunkNumber("test.t1.00002-00003.sql", "test.t1.")
+	c.Assert(ok, qt.IsFalse)
+}
+
+func splitTestServer(c *qt.C) (string, func(*Config)) {
+	fakedbs, address := newResumeTestServer(c)
+	fakedbs.AddQueryPattern("select data_length from information_schema.tables where table_schema = 'test' and table_name = 't1'", &sqltypes.Result{
+		Fields: []*querypb.Field{{Name: "DATA_LENGTH", Type: querypb.Type_UINT64}},
+		Rows:   [][]sqltypes.Value{{sqltypes.MakeTrusted(querypb.Type_UINT64, []byte("10485760"))}},
+	})
+	fakedbs.AddQueryPattern("select min\\(`id`\\), max\\(`id`\\) from `test`\\.`t1`", &sqltypes.Result{
+		Fields: []*querypb.Field{
+			{Name: "MIN(`id`)", Type: querypb.Type_INT32},
+			{Name: "MAX(`id`)", Type: querypb.Type_INT32},
+		},
+		Rows: [][]sqltypes.Value{{
+			sqltypes.MakeTrusted(querypb.Type_INT32, []byte("1")),
+			sqltypes.MakeTrusted(querypb.Type_INT32, []byte("100")),
+		}},
+	})
+	fakedbs.AddQueryPattern("select `id`, `name` from `test`\\.`t1`  where `id` <= 50 order by `id`", rowsResult("1", "50"))
+	fakedbs.AddQueryPattern("select `id`, `name` from `test`\\.`t1`  where `id` > 50 order by `id`", rowsResult("51", "100"))
+
+	return address, func(cfg *Config) {
+		cfg.Threads = 2
+	}
+}
+
+func TestDumperSplitsLargeTables(t *testing.T) {
+	c := qt.New(t)
+
+	address, configure := splitTestServer(c)
+	cfg := manifestTestConfig(c, address)
+	configure(cfg)
+
+	d, err := NewDumper(cfg)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.Run(context.Background()), qt.IsNil)
+
+	data, err := os.ReadFile(filepath.Join(cfg.Outdir, "test.t1.00001-00001.sql"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(data), qt.Equals, "INSERT INTO `t1`(`id`,`name`) VALUES\n(1,\"name-1\"),\n(50,\"name-50\");\n")
+	data, err = os.ReadFile(filepath.Join(cfg.Outdir, "test.t1.00002-00001.sql"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(data), qt.Equals, "INSERT INTO `t1`(`id`,`name`) VALUES\n(51,\"name-51\"),\n(100,\"name-100\");\n")
+
+	cp, err := loadCheckpoint(cfg.Outdir, "sql")
+	c.Assert(err, qt.IsNil)
+	c.Assert(cp.table("test", "t1"), qt.DeepEquals, tableCheckpoint{Bounds: []string{"50"}})
+	c.Assert(cp.table("test", "t1#00001"), qt.DeepEquals, tableCheckpoint{Rows: 2, Done: true})
+	c.Assert(cp.table("test", "t1#00002"), qt.DeepEquals, tableCheckpoint{Rows: 2, Done: true})
+
+	m, err := ReadManifest(cfg.Outdir)
+	c.Assert(err, qt.IsNil)
+	c.Assert(m.Tables, qt.HasLen, 1)
+	c.Assert(m.Tables[0].Rows, qt.Equals, uint64(4))
+	c.Assert(m.Tables[0].Chunks, qt.DeepEquals, []ManifestChunk{
+		{Part: 1, Rows: 2, CRC: rowsCRC(rowsResult("1", "50")), LastKey: []string{"50"}},
+		{Part: 2, Rows: 2, CRC: rowsCRC(rowsResult("51", "100"))},
+	})
+}
+
+func TestDumperResumesSplitTable(t *testing.T) {
+	c := qt.New(t)
+
+	address, configure := splitTestServer(c)
+	cfg := manifestTestConfig(c, address)
+	configure(cfg)
+	cfg.Resume = true
+
+	// The first range was finished before the interruption. Its bounds are
+	// reused even though the table would be split differently now.
+	cp := newCheckpoint(cfg.Outdir, "sql")
+	c.Assert(cp.split("test", "t1", []string{"50"}), qt.IsNil)
+	c.Assert(cp.finish("test", "t1#00001", 2), qt.IsNil)
+	c.Assert(writeFile(filepath.Join(cfg.Outdir, "test.t1.00001-00001.sql"), "first range"), qt.IsNil)
+	c.Assert(writeFile(filepath.Join(cfg.Outdir, "test.t1.00002-00001.sql"), "partial"), qt.IsNil)
+
+	d, err := NewDumper(cfg)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.Run(context.Background()), qt.IsNil)
+
+	data, err := os.ReadFile(filepath.Join(cfg.Outdir, "test.t1.00001-00001.sql"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(data), qt.Equals, "first range")
+	data, err = os.ReadFile(filepath.Join(cfg.Outdir, "test.t1.00002-00001.sql"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(data), qt.Equals, "INSERT INTO `t1`(`id`,`name`) VALUES\n(51,\"name-51\"),\n(100,\"name-100\");\n")
+}
+
+func TestDumperResumeDiscardsRangesWithoutUsableKey(t *testing.T) {
+	c := qt.New(t)
+
+	fakedbs, address := newResumeTestServer(c)
+	fakedbs.AddQueryPattern("select `id` \\+ 0 as `id`, `name` from `test`\\.`t1` ", rowsResult("1", "100"))
+	cfg := manifestTestConfig(c, address)
+	cfg.Threads = 2
+	cfg.Resume = true
+	// The key is now selected through an override, so the recorded ranges
+	// can no longer be selected by it.
+	cfg.Selects = map[string]map[string]string{"t1": {"id": "`id` + 0"}}
+
+	cp := newCheckpoint(cfg.Outdir, "sql")
+	c.Assert(cp.split("test", "t1", []string{"50"}), qt.IsNil)
+	c.Assert(cp.finish("test", "t1#00001", 2), qt.IsNil)
+	c.Assert(writeFile(filepath.Join(cfg.Outdir, "test.t1.00001-00001.sql"), "first range"), qt.IsNil)
+	c.Assert(writeFile(filepath.Join(cfg.Outdir, "test.t1.00002-00001.sql"), "partial"), qt.IsNil)
+
+	d, err := NewDumper(cfg)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.Run(context.Background()), qt.IsNil)
+
+	data, err := os.ReadFile(filepath.Join(cfg.Outdir, "test.t1.00001.sql"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(data), qt.Equals, "INSERT INTO `t1`(`id`,`name`) VALUES\n(1,\"name-1\"),\n(100,\"name-100\");\n")
+	for _, name := range []string{"test.t1.00001-00001.sql", "test.t1.00002-00001.sql"} {
+		_, err := os.Stat(filepath.Join(cfg.Outdir, name))
+		c.Assert(err, qt.ErrorIs, os.ErrNotExist)
+	}
+
+	cp, err = loadCheckpoint(cfg.Outdir, "sql")
+	c.Assert(err, qt.IsNil)
+	c.Assert(cp.table("test", "t1"), qt.DeepEquals, tableCheckpoint{Rows: 2, Done: true})
+	c.Assert(cp.table("test", "t1#00001"), qt.DeepEquals, tableCheckpoint{})
+
+	m, err := ReadManifest(cfg.Outdir)
+	c.Assert(err, qt.IsNil)
+	c.Assert(m.Tables, qt.HasLen, 1)
+	c.Assert(m.Tables[0].Rows, qt.Equals, uint64(2))
+	c.Assert(m.Tables[0].Key, qt.HasLen, 0)
+}
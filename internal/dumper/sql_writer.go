@@ -2,6 +2,7 @@ package dumper
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	querypb "github.com/xelabs/go-mysqlstack/sqlparser/depends/query"
@@ -16,6 +17,7 @@ type sqlWriter struct {
 	inserts    []string
 	stmtsize   int
 	chunkbytes int
+	chunkrows  uint64
 }
 
 func newSQLWriter(cfg *Config, table string) *sqlWriter {
@@ -56,44 +58,63 @@ func (w *sqlWriter) WriteRow(row []sqltypes.Value) (int, error) {
 	rowBytes := len(r)
 	w.stmtsize += rowBytes
 	w.chunkbytes += rowBytes
+	w.chunkrows++
 
 	if w.stmtsize >= w.cfg.StmtSize {
-		insertone := fmt.Sprintf("INSERT INTO %s(%s) VALUES\n%s", w.table, strings.Join(w.fields, ","), strings.Join(w.rows, ",\n"))
-		w.inserts = append(w.inserts, insertone)
-		w.rows = w.rows[:0]
-		w.stmtsize = 0
+		w.finishInsert()
 	}
 
 	return rowBytes, nil
 }
 
+// finishInsert turns the pending rows into an INSERT statement.
+func (w *sqlWriter) finishInsert() {
+	if len(w.rows) == 0 {
+		return
+	}
+	insertone := fmt.Sprintf("INSERT INTO %s(%s) VALUES\n%s", w.table, strings.Join(w.fields, ","), strings.Join(w.rows, ",\n"))
+	w.inserts = append(w.inserts, insertone)
+	w.rows = w.rows[:0]
+	w.stmtsize = 0
+}
+
 func (w *sqlWriter) ShouldFlush() bool {
 	return (w.chunkbytes / 1024 / 1024) >= w.cfg.ChunksizeInMB
 }
 
-func (w *sqlWriter) Flush(outdir, database, table string, fileNo int) error {
+func (w *sqlWriter) Flush(outdir, database, table string, chunk chunkID) error {
+	// Every chunk ends with the last row written so far, which keeps the
+	// chunk boundaries usable as resume points.
+	w.finishInsert()
 	query := strings.Join(w.inserts, ";\n") + ";\n"
-	file, err := dumpOutputPath(outdir, database, table, fmt.Sprintf(".%05d.sql", fileNo))
+	file, err := dumpOutputPath(outdir, database, table, chunk.suffix("sql"))
 	if err != nil {
 		return err
 	}
-	err = writeFile(file, query)
+	err = writeDataFile(w.cfg, file, query, w.chunkrows)
 	if err != nil {
 		return err
 	}
 
 	w.inserts = w.inserts[:0]
 	w.chunkbytes = 0
+	w.chunkrows = 0
 	return nil
 }
 
-func (w *sqlWriter) Close(outdir, database, table string, fileNo int) error {
+func (w *sqlWriter) Close(outdir, database, table string, chunk chunkID) error {
 	if w.chunkbytes > 0 {
-		if len(w.rows) > 0 {
-			insertone := fmt.Sprintf("INSERT INTO %s(%s) VALUES\n%s", w.table, strings.Join(w.fields, ","), strings.Join(w.rows, ",\n"))
-			w.inserts = append(w.inserts, insertone)
-		}
-		return w.Flush(outdir, database, table, fileNo)
+		return w.Flush(outdir, database, table, chunk)
 	}
 	return nil
 }
+
+// takeInserts returns the finished INSERT statements and removes them from
+// the writer.
+func (w *sqlWriter) takeInserts() []string {
+	inserts := slices.Clone(w.inserts)
+	w.inserts = w.inserts[:0]
+	w.chunkbytes = 0
+	w.chunkrows = 0
+	return inserts
+}
@@ -0,0 +1,252 @@
+package dumper
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strconv"
+	"strings"
+
+	"github.com/xelabs/go-mysqlstack/sqlparser/depends/sqltypes"
+	"golang.org/x/sync/errgroup"
+)
+
+// Checksum results of a verified table.
+const (
+	ChecksumMatch    = "match"
+	ChecksumMismatch = "mismatch"
+)
+
+// TableVerification compares a restored table with the rows recorded in the
+// manifest when it was dumped.
+type TableVerification struct {
+	Database     string `json:"database"`
+	Table        string `json:"table"`
+	DumpRows     uint64 `json:"dump_rows"`
+	RestoredRows uint64 `json:"restored_rows"`
+	// Checksum is ChecksumMatch or ChecksumMismatch if checksums were
+	// verified, empty otherwise.
+	Checksum string `json:"checksum,omitempty"`
+	// MismatchedChunks are the positions, starting at 1, of the chunks in
+	// the manifest whose primary key range does not match the restored
+	// rows. For tables not dumped in ranges these are the file numbers.
+	MismatchedChunks []int `json:"mismatched_chunks,omitempty"`
+	Match            bool  `json:"match"`
+}
+
+// rowCRC returns the CRC-32 of a row. Chunk checksums add these up, so they
+// do not depend on the order rows are read in.
+func rowCRC(row []sqltypes.Value) uint32 {
+	var crc uint32
+	var size [5]byte
+	for _, v := range row {
+		if v.IsNull() {
+			crc = crc32.Update(crc, crc32.IEEETable, size[:1])
+			continue
+		}
+		raw := v.Raw()
+		size[0] = 1
+		binary.BigEndian.PutUint32(size[1:], uint32(len(raw)))
+		crc = crc32.Update(crc, crc32.IEEETable, size[:])
+		crc = crc32.Update(crc, crc32.IEEETable, raw)
+		size[0] = 0
+	}
+	return crc
+}
+
+// Verify compares the restored tables with the row counts recorded in the
+// manifest of the dump. With checksums, the rows of MySQL tables are read
+// back and compared with the checksum of every data file.
+func (l *Loader) Verify(ctx context.Context, checksums bool) ([]*TableVerification, error) {
+	m, err := ReadManifest(l.cfg.Outdir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot verify the restore: %w", err)
+	}
+	if len(m.Tables) == 0 {
+		return nil, fmt.Errorf("cannot verify the restore: the dump in %s has no row counts, it was taken by an older version", l.cfg.Outdir)
+	}
+
+	var tables []ManifestTable
+	for _, t := range m.Tables {
+		if l.canIncludeTable(t.Table) {
+			tables = append(tables, t)
+		}
+	}
+
+	if l.cfg.Engine == EnginePostgres {
+		if checksums {
+			return nil, errors.New("checksums can only be verified for MySQL databases")
+		}
+		return l.verifyPostgres(ctx, tables)
+	}
+
+	pool, err := NewPool(l.log, max(l.cfg.Threads, 1), l.cfg.Address, l.cfg.User, l.cfg.Password, l.cfg.SessionVars, "")
+	if err != nil {
+		return nil, err
+	}
+	defer pool.Close()
+
+	results := make([]*TableVerification, len(tables))
+	eg, egCtx := errgroup.WithContext(ctx)
+	for i, t := range tables {
+		conn := pool.Get()
+		eg.Go(func() error {
+			defer pool.Put(conn)
+
+			if egCtx.Err() != nil {
+				return egCtx.Err()
+			}
+
+			v, err := l.verifyTable(conn, t, checksums)
+			if err != nil {
+				return fmt.Errorf("verifying table %s: %w", t.Table, err)
+			}
+			results[i] = v
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (l *Loader) verifyTable(conn *Connection, t ManifestTable, checksums bool) (*TableVerification, error) {
+	database := l.databaseNameFromFilename(t.Database + ".")
+	name := quoteIdentifier(database) + "." + quoteIdentifier(t.Table)
+	v := &TableVerification{Database: database, Table: t.Table, DumpRows: t.Rows}
+
+	qr, err := conn.Fetch("SELECT COUNT(*) FROM " + name)
+	if err != nil {
+		return nil, err
+	}
+	if v.RestoredRows, err = countResult(qr); err != nil {
+		return nil, err
+	}
+	v.Match = v.RestoredRows == v.DumpRows
+
+	if !checksums || len(t.Columns) == 0 {
+		return v, nil
+	}
+
+	columns := make([]string, 0, len(t.Columns))
+	for _, c := range t.Columns {
+		columns = append(columns, quoteIdentifier(c))
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ", "), name)
+
+	v.Checksum = ChecksumMatch
+	for i, want := range chunkRanges(t) {
+		rows, crc, err := checksumRows(conn, query+want.where)
+		if err != nil {
+			return nil, err
+		}
+		if rows != want.rows || crc != want.crc {
+			v.Checksum = ChecksumMismatch
+			v.Match = false
+			if len(t.Key) > 0 {
+				v.MismatchedChunks = append(v.MismatchedChunks, i+1)
+			}
+		}
+	}
+	return v, nil
+}
+
+type chunkRange struct {
+	where string
+	rows  uint64
+	crc   uint64
+}
+
+// chunkRanges returns the primary key range of every data file of a table.
+// Tables without a primary key are checked as a whole.
+func chunkRanges(t ManifestTable) []chunkRange {
+	if len(t.Key) == 0 {
+		whole := chunkRange{}
+		for _, c := range t.Chunks {
+			whole.rows += c.Rows
+			whole.crc += c.CRC
+		}
+		return []chunkRange{whole}
+	}
+
+	key := make([]string, 0, len(t.Key))
+	for _, k := range t.Key {
+		key = append(key, quoteIdentifier(k))
+	}
+	columns := strings.Join(key, ", ")
+
+	ranges := make([]chunkRange, 0, len(t.Chunks))
+	for i, c := range t.Chunks {
+		var conds []string
+		if i > 0 {
+			conds = append(conds, fmt.Sprintf("(%s) > (%s)", columns, strings.Join(t.Chunks[i-1].LastKey, ", ")))
+		}
+		// The last chunk is left open, so rows missing from the dump are
+		// found as well.
+		if i < len(t.Chunks)-1 {
+			conds = append(conds, fmt.Sprintf("(%s) <= (%s)", columns, strings.Join(c.LastKey, ", ")))
+		}
+
+		r := chunkRange{rows: c.Rows, crc: c.CRC}
+		if len(conds) > 0 {
+			r.where = " WHERE " + strings.Join(conds, " AND ")
+		}
+		ranges = append(ranges, r)
+	}
+	if len(ranges) == 0 {
+		ranges = append(ranges, chunkRange{})
+	}
+	return ranges
+}
+
+func checksumRows(conn *Connection, query string) (rows, crc uint64, err error) {
+	cursor, err := conn.StreamFetch(query)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() {
+		if cerr := cursor.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	for cursor.Next() {
+		row, err := cursor.RowValues()
+		if err != nil {
+			return 0, 0, err
+		}
+		rows++
+		crc += uint64(rowCRC(row))
+	}
+	return rows, crc, nil
+}
+
+func countResult(qr *sqltypes.Result) (uint64, error) {
+	if len(qr.Rows) != 1 || len(qr.Rows[0]) != 1 {
+		return 0, errors.New("unexpected result of COUNT(*)")
+	}
+	return strconv.ParseUint(qr.Rows[0][0].String(), 10, 64)
+}
+
+func (l *Loader) verifyPostgres(ctx context.Context, tables []ManifestTable) ([]*TableVerification, error) {
+	conn, err := connectPostgres(ctx, l.cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(context.Background())
+
+	results := make([]*TableVerification, 0, len(tables))
+	for _, t := range tables {
+		v := &TableVerification{Database: t.Database, Table: t.Table, DumpRows: t.Rows}
+		table := pgTable{Schema: t.Database, Name: t.Table}
+		if err := conn.QueryRow(ctx, "SELECT count(*) FROM "+table.ident()).Scan(&v.RestoredRows); err != nil {
+			return nil, fmt.Errorf("verifying table %s.%s: %w", t.Database, t.Table, err)
+		}
+		v.Match = v.RestoredRows == v.DumpRows
+		results = append(results, v)
+	}
+	return results, nil
+}
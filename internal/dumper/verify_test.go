@@ -0,0 +1,154 @@
+package dumper
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/go-cmp/cmp"
+	"github.com/planetscale/cli/internal/printer"
+	querypb "github.com/xelabs/go-mysqlstack/sqlparser/depends/query"
+	"github.com/xelabs/go-mysqlstack/sqlparser/depends/sqltypes"
+)
+
+func countRowsResult(n string) *sqltypes.Result {
+	return &sqltypes.Result{
+		Fields: []*querypb.Field{{Name: "COUNT(*)", Type: querypb.Type_INT64}},
+		Rows:   [][]sqltypes.Value{{sqltypes.MakeTrusted(querypb.Type_INT64, []byte(n))}},
+	}
+}
+
+func rowsCRC(qr *sqltypes.Result) uint64 {
+	var crc uint64
+	for _, row := range qr.Rows {
+		crc += uint64(rowCRC(row))
+	}
+	return crc
+}
+
+func TestDumperRecordsTableChecksums(t *testing.T) {
+	c := qt.New(t)
+
+	fakedbs, address := newResumeTestServer(c)
+	fakedbs.AddQueryPattern("select `id`, `name` from `test`\\.`t1`  order by `id`", rowsResult("1", "2", "3"))
+
+	cfg := manifestTestConfig(c, address)
+	d, err := NewDumper(cfg)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.Run(context.Background()), qt.IsNil)
+
+	m, err := ReadManifest(cfg.Outdir)
+	c.Assert(err, qt.IsNil)
+	c.Assert(m.Tables, qt.DeepEquals, []ManifestTable{{
+		Database: "test",
+		Table:    "t1",
+		Rows:     3,
+		Columns:  []string{"id", "name"},
+		Key:      []string{"id"},
+		Chunks:   []ManifestChunk{{Rows: 3, CRC: rowsCRC(rowsResult("1", "2", "3"))}},
+	}})
+}
+
+func TestRowCRC(t *testing.T) {
+	c := qt.New(t)
+
+	value := func(s string) sqltypes.Value { return sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte(s)) }
+	null := sqltypes.NULL
+
+	c.Assert(rowCRC([]sqltypes.Value{value("ab"), value("c")}), qt.Not(qt.Equals), rowCRC([]sqltypes.Value{value("a"), value("bc")}))
+	c.Assert(rowCRC([]sqltypes.Value{null}), qt.Not(qt.Equals), rowCRC([]sqltypes.Value{value("")}))
+	c.Assert(rowCRC([]sqltypes.Value{value("x"), null}), qt.Equals, rowCRC([]sqltypes.Value{value("x"), null}))
+}
+
+func TestChunkRanges(t *testing.T) {
+	c := qt.New(t)
+
+	table := ManifestTable{
+		Key: []string{"a", "b"},
+		Chunks: []ManifestChunk{
+			{Rows: 2, CRC: 10, LastKey: []string{"1", "'x'"}},
+			{Rows: 2, CRC: 20, LastKey: []string{"2", "'y'"}},
+			{Rows: 1, CRC: 30},
+		},
+	}
+	c.Assert(chunkRanges(table), qt.CmpEquals(cmp.AllowUnexported(chunkRange{})), []chunkRange{
+		{where: " WHERE (`a`, `b`) <= (1, 'x')", rows: 2, crc: 10},
+		{where: " WHERE (`a`, `b`) > (1, 'x') AND (`a`, `b`) <= (2, 'y')", rows: 2, crc: 20},
+		{where: " WHERE (`a`, `b`) > (2, 'y')", rows: 1, crc: 30},
+	})
+
+	table.Key = nil
+	c.Assert(chunkRanges(table), qt.CmpEquals(cmp.AllowUnexported(chunkRange{})), []chunkRange{{rows: 5, crc: 60}})
+}
+
+func TestLoaderVerify(t *testing.T) {
+	c := qt.New(t)
+
+	fakedbs, address := newResumeTestServer(c)
+	first, second := rowsResult("1", "2"), rowsResult("3", "4")
+	fakedbs.AddQueryPattern("select count\\(\\*\\) from `test`\\.`t1`", countRowsResult("4"))
+	fakedbs.AddQueryPattern("select count\\(\\*\\) from `test`\\.`t2`", countRowsResult("1"))
+	fakedbs.AddQueryPattern("select `id`, `name` from `test`\\.`t1` where \\(`id`\\) <= \\(2\\)", first)
+	fakedbs.AddQueryPattern("select `id`, `name` from `test`\\.`t1` where \\(`id`\\) > \\(2\\)", rowsResult("3", "5"))
+
+	dir := c.TempDir()
+	data, err := json.Marshal(Manifest{
+		Version: manifestVersion,
+		Tables: []ManifestTable{
+			{
+				Database: "test",
+				Table:    "t1",
+				Rows:     4,
+				Columns:  []string{"id", "name"},
+				Key:      []string{"id"},
+				Chunks: []ManifestChunk{
+					{Rows: 2, CRC: rowsCRC(first), LastKey: []string{"2"}},
+					{Rows: 2, CRC: rowsCRC(second)},
+				},
+			},
+			{Database: "test", Table: "t2", Rows: 2},
+		},
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(writeFile(filepath.Join(dir, manifestFile), string(data)), qt.IsNil)
+
+	format := printer.Human
+	cfg := NewDefaultConfig()
+	cfg.Printer = printer.NewPrinter(&format)
+	cfg.Outdir = dir
+	cfg.User = "mock"
+	cfg.Password = "mock"
+	cfg.Address = address
+	l, err := NewLoader(cfg)
+	c.Assert(err, qt.IsNil)
+
+	results, err := l.Verify(context.Background(), false)
+	c.Assert(err, qt.IsNil)
+	c.Assert(results, qt.DeepEquals, []*TableVerification{
+		{Database: "test", Table: "t1", DumpRows: 4, RestoredRows: 4, Match: true},
+		{Database: "test", Table: "t2", DumpRows: 2, RestoredRows: 1, Match: false},
+	})
+
+	results, err = l.Verify(context.Background(), true)
+	c.Assert(err, qt.IsNil)
+	c.Assert(results[0], qt.DeepEquals, &TableVerification{
+		Database:         "test",
+		Table:            "t1",
+		DumpRows:         4,
+		RestoredRows:     4,
+		Checksum:         ChecksumMismatch,
+		MismatchedChunks: []int{2},
+		Match:            false,
+	})
+
+	cfg.EndingTable = "t1"
+	results, err = l.Verify(context.Background(), false)
+	c.Assert(err, qt.IsNil)
+	c.Assert(results, qt.HasLen, 1)
+
+	cfg.Outdir = c.TempDir()
+	_, err = l.Verify(context.Background(), false)
+	c.Assert(err, qt.ErrorMatches, "cannot verify the restore: dump has no manifest.json")
+}
@@ -1,11 +1,40 @@
 package dumper
 
-import "github.com/xelabs/go-mysqlstack/sqlparser/depends/sqltypes"
+import (
+	"fmt"
+
+	querypb "github.com/xelabs/go-mysqlstack/sqlparser/depends/query"
+	"github.com/xelabs/go-mysqlstack/sqlparser/depends/sqltypes"
+)
 
 type TableWriter interface {
 	Initialize(fieldNames []string) error
 	WriteRow(row []sqltypes.Value) (bytesAdded int, err error)
 	ShouldFlush() bool
-	Flush(outdir, database, table string, fileNo int) error
-	Close(outdir, database, table string, fileNo int) error
+	Flush(outdir, database, table string, chunk chunkID) error
+	Close(outdir, database, table string, chunk chunkID) error
+}
+
+// chunkID identifies a data file of a table. Tables dumped in primary key
+// ranges number the files of every range separately.
+type chunkID struct {
+	// part is the number of the primary key range, 0 if the table is dumped
+	// as a whole.
+	part   int
+	fileNo int
+}
+
+// suffix returns the file name suffix of the chunk, e.g. ".00001.sql" or
+// ".00002-00001.sql" for the first file of the second range.
+func (c chunkID) suffix(ext string) string {
+	if c.part == 0 {
+		return fmt.Sprintf(".%05d.%s", c.fileNo, ext)
+	}
+	return fmt.Sprintf(".%05d-%05d.%s", c.part, c.fileNo, ext)
+}
+
+// fieldTypeWriter is implemented by writers that need the types of the
+// dumped columns, which are only known once the result set is streaming.
+type fieldTypeWriter interface {
+	SetFieldTypes(fields []*querypb.Field) error
 }
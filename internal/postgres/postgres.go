@@ -207,6 +207,17 @@ func QuoteIdentifier(name string) string {
 	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
 }
 
+// QuoteLiteral escapes a PostgreSQL string literal. Literals containing
+// backslashes use the E” syntax so they parse the same regardless of
+// standard_conforming_strings.
+func QuoteLiteral(literal string) string {
+	literal = strings.ReplaceAll(literal, `'`, `''`)
+	if strings.Contains(literal, `\`) {
+		return `E'` + strings.ReplaceAll(literal, `\`, `\\`) + `'`
+	}
+	return `'` + literal + `'`
+}
+
 func RedactPassword(connStr string) string {
 	if strings.HasPrefix(connStr, "postgresql://") || strings.HasPrefix(connStr, "postgres://") {
 		u, err := url.Parse(connStr)
@@ -218,3 +218,36 @@ func TestQuoteIdentifier(t *testing.T) {
 		})
 	}
 }
+
+func TestQuoteLiteral(t *testing.T) {
+	tests := []struct {
+		name    string
+		literal string
+		want    string
+	}{
+		{
+			name:    "simple literal",
+			literal: "public.users_id_seq",
+			want:    `'public.users_id_seq'`,
+		},
+		{
+			name:    "literal with quotes",
+			literal: `it's`,
+			want:    `'it''s'`,
+		},
+		{
+			name:    "literal with backslash",
+			literal: `a\b`,
+			want:    `E'a\\b'`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := QuoteLiteral(tt.literal)
+			if got != tt.want {
+				t.Errorf("QuoteLiteral() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/planetscale/cli/internal/cmdutil"
 	ps "github.com/planetscale/cli/internal/planetscale"
 )
 
@@ -56,3 +57,39 @@ func (r *Role) Cleanup(ctx context.Context, successor string) error {
 		Successor:    successor, // Empty string for no successor
 	})
 }
+
+// Renew keeps the temporary role from expiring until ctx is done, renewing
+// it at half its TTL.
+func (r *Role) Renew(ctx context.Context) error {
+	if r.opts.TTL == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(r.opts.TTL / 2)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+		}
+
+		_, err := r.client.PostgresRoles.Renew(ctx, &ps.RenewPostgresRoleRequest{
+			Organization: r.opts.Organization,
+			Database:     r.opts.Database,
+			Branch:       r.opts.Branch,
+			RoleId:       r.Role.ID,
+		})
+		if err != nil {
+			switch cmdutil.ErrCode(err) {
+			case ps.ErrNotFound, ps.ErrRetry:
+				return fmt.Errorf("role failed to renew: %w", err)
+			}
+			// on failure to renew, retry a bit more aggressively
+			timer.Reset(r.opts.TTL / 8)
+		} else {
+			timer.Reset(r.opts.TTL / 2)
+		}
+	}
+}
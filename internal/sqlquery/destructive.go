@@ -1,6 +1,7 @@
 package sqlquery
 
 import (
+	"fmt"
 	"slices"
 	"strings"
 )
@@ -9,21 +10,67 @@ var destructiveWords = []string{"DELETE", "DROP", "TRUNCATE"}
 
 // DestructiveQueryError is returned when a query would delete or drop data or
 // schema objects and --force was not passed.
-type DestructiveQueryError struct{}
+type DestructiveQueryError struct {
+	// Risks are the reports of the destructive statements of the query.
+	Risks []StatementRisk
+}
 
 func (e *DestructiveQueryError) Error() string {
-	return "destructive SQL requires explicit user approval (ask the user, then re-run with --force)"
+	msg := "destructive SQL requires explicit user approval (ask the user, then re-run with --force)"
+	if len(e.Risks) == 0 {
+		return msg
+	}
+	reasons := make([]string, 0, len(e.Risks))
+	for _, risk := range e.Risks {
+		reason := risk.Reason
+		if len(risk.Objects) > 0 {
+			reason = fmt.Sprintf("%s (%s)", reason, strings.Join(risk.Objects, ", "))
+		}
+		reasons = append(reasons, reason)
+	}
+	return msg + ": " + strings.Join(reasons, "; ")
 }
 
-// IsDestructiveQuery reports whether the query deletes or drops resources.
-// This is a best-effort guardrail for agents, not a SQL parser. Statements
-// led by DELETE, DROP, or TRUNCATE (including after CTEs) require approval.
+// IsDestructiveQuery reports whether the query deletes or drops resources on
+// either engine. This is a best-effort guardrail for agents; see
+// ClassifyQuery for what is considered destructive. The MySQL classification
+// is used: it includes the keyword check PostgreSQL statements get.
 func IsDestructiveQuery(query string) bool {
-	return slices.ContainsFunc(splitSQLStatements(stripSQLGuardIgnoredText(query)), isDestructiveStatement)
+	return slices.ContainsFunc(ClassifyQuery(query, "mysql"), func(risk StatementRisk) bool {
+		return risk.Destructive
+	})
+}
+
+// checkDestructive returns a *DestructiveQueryError if any statement of the
+// query is destructive on engine.
+func checkDestructive(query, engine string) error {
+	var risks []StatementRisk
+	for _, risk := range ClassifyQuery(query, engine) {
+		if risk.Destructive {
+			risks = append(risks, risk)
+		}
+	}
+	if len(risks) > 0 {
+		return &DestructiveQueryError{Risks: risks}
+	}
+	return nil
 }
 
 func splitSQLStatements(query string) []string {
-	out := make([]string, 0, strings.Count(query, ";")+1)
+	bounds := sqlStatementBounds(query)
+	out := make([]string, 0, len(bounds))
+	for _, b := range bounds {
+		if trimmed := strings.TrimSpace(query[b[0]:b[1]]); trimmed != "" {
+			out = append(out, trimmed)
+		}
+	}
+	return out
+}
+
+// sqlStatementBounds returns the start and end offsets of the text between
+// the top-level semicolons of query.
+func sqlStatementBounds(query string) [][2]int {
+	out := make([][2]int, 0, strings.Count(query, ";")+1)
 	start := 0
 	quote := byte(0)
 	for i := 0; i < len(query); i++ {
@@ -42,16 +89,11 @@ func splitSQLStatements(query string) []string {
 		case '\'', '"', '`':
 			quote = c
 		case ';':
-			if trimmed := strings.TrimSpace(query[start:i]); trimmed != "" {
-				out = append(out, trimmed)
-			}
+			out = append(out, [2]int{start, i})
 			start = i + 1
 		}
 	}
-	if trimmed := strings.TrimSpace(query[start:]); trimmed != "" {
-		out = append(out, trimmed)
-	}
-	return out
+	return append(out, [2]int{start, len(query)})
 }
 
 func isDestructiveStatement(stmt string) bool {
@@ -129,24 +171,29 @@ func isDestructiveSegment(stmt string) bool {
 }
 
 func leadingStatementKeyword(stmt string) string {
+	if words := statementWords(stmt, 1); len(words) > 0 {
+		return words[0]
+	}
+	return ""
+}
+
+// statementWords returns the first n words of stmt in upper case, skipping
+// punctuation and operators between them.
+func statementWords(stmt string, n int) []string {
+	var words []string
 	trimmed := strings.TrimSpace(stmt)
-	for i := 0; i < len(trimmed); {
-		for i < len(trimmed) && trimmed[i] <= ' ' {
-			i++
-		}
-		if i >= len(trimmed) {
-			break
-		}
+	for i := 0; i < len(trimmed) && len(words) < n; {
 		start := i
 		for i < len(trimmed) && isIdentifierChar(trimmed[i]) {
 			i++
 		}
 		if start < i {
-			return strings.ToUpper(trimmed[start:i])
+			words = append(words, strings.ToUpper(trimmed[start:i]))
+			continue
 		}
 		i++
 	}
-	return ""
+	return words
 }
 
 func stripSQLGuardIgnoredText(stmt string) string {
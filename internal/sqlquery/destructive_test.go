@@ -15,7 +15,20 @@ func TestIsDestructiveQuery(t *testing.T) {
 	}{
 		{query: "SELECT 1", want: false},
 		{query: "INSERT INTO t VALUES (1)", want: false},
-		{query: "UPDATE t SET x = 1", want: false},
+		{query: "UPDATE t SET x = 1", want: true},
+		{query: "UPDATE t SET x = 1 WHERE id = 1", want: false},
+		{query: "UPDATE t SET x = 1 LIMIT 10", want: false},
+		{query: "UPDATE \"t\" SET x = 1", want: true},
+		{query: "UPDATE \"t\" SET x = 1 WHERE id = 1", want: false},
+		{query: "REPLACE INTO t VALUES (1)", want: true},
+		{query: "INSERT INTO t VALUES (1) ON DUPLICATE KEY UPDATE x = 1", want: false},
+		{query: "RENAME TABLE a TO b", want: true},
+		{query: "ALTER TABLE t RENAME TO u", want: true},
+		{query: "ALTER TABLE t RENAME COLUMN a TO b", want: true},
+		{query: "ALTER TABLE t ADD COLUMN c int", want: false},
+		{query: "ALTER TABLE t DROP INDEX idx", want: true},
+		{query: "ALTER TABLE t TRUNCATE PARTITION p0", want: true},
+		{query: "DROP SCHEMA app CASCADE", want: true},
 		{query: "DELETE FROM users WHERE id = 1", want: true},
 		{query: "drop table users", want: true},
 		{query: "TRUNCATE TABLE users", want: true},
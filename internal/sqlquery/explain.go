@@ -0,0 +1,497 @@
+package sqlquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/planetscale/cli/internal/cmdutil"
+)
+
+// Plan is the query plan reported by Explain, normalized across engines.
+type Plan struct {
+	// Engine is "mysql" or "postgresql".
+	Engine string `json:"engine"`
+	// Analyzed is set when the statement was run to measure the plan
+	// (PostgreSQL EXPLAIN ANALYZE).
+	Analyzed bool `json:"analyzed,omitempty"`
+	// Cost is the estimated cost of the whole plan, in the engine's units.
+	Cost *float64 `json:"cost,omitempty"`
+	// PlanningTimeMs and ExecutionTimeMs are reported by EXPLAIN ANALYZE.
+	PlanningTimeMs  *float64 `json:"planning_time_ms,omitempty"`
+	ExecutionTimeMs *float64 `json:"execution_time_ms,omitempty"`
+	// Root is the plan of the database: EXPLAIN FORMAT=JSON on MySQL,
+	// EXPLAIN (FORMAT JSON) on PostgreSQL.
+	Root *PlanNode `json:"root,omitempty"`
+	// Routing is the vtgate plan of a MySQL (Vitess) database, from VEXPLAIN
+	// PLAN. It shows how the statement is routed to keyspaces and shards.
+	Routing *PlanNode `json:"routing,omitempty"`
+	// Warnings point out full scans, scatter queries, and plans that could
+	// not be read.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// PlanNode is a step of a query plan. Fields the engine does not report are
+// omitted.
+type PlanNode struct {
+	Operation string `json:"operation"`
+	Table     string `json:"table,omitempty"`
+	Keyspace  string `json:"keyspace,omitempty"`
+	// Index is the index the step reads; PossibleKeys are the indexes MySQL
+	// considered.
+	Index        string   `json:"index,omitempty"`
+	PossibleKeys []string `json:"possible_keys,omitempty"`
+	Cost         *float64 `json:"cost,omitempty"`
+	// Rows is the estimated number of rows read by the step.
+	Rows *float64 `json:"rows,omitempty"`
+	// ActualRows, ActualTimeMs, and Loops are measured by EXPLAIN ANALYZE.
+	ActualRows   *float64 `json:"actual_rows,omitempty"`
+	ActualTimeMs *float64 `json:"actual_time_ms,omitempty"`
+	Loops        *float64 `json:"loops,omitempty"`
+	Condition    string   `json:"condition,omitempty"`
+	// FullScan is set for steps reading every row of a table.
+	FullScan bool        `json:"full_scan,omitempty"`
+	Children []*PlanNode `json:"children,omitempty"`
+}
+
+// Explain reports the plan of opts.Query instead of running it: EXPLAIN
+// FORMAT=JSON and VEXPLAIN PLAN on MySQL (Vitess), EXPLAIN (FORMAT JSON) on
+// PostgreSQL. With opts.Analyze, PostgreSQL runs the statement to measure
+// the plan, so the destructive SQL guard and the SQL policy apply to it as if
+// it were run.
+func Explain(ctx context.Context, ch *cmdutil.Helper, opts Options) (*Result, error) {
+	stmt, err := explainTarget(opts.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	// The guard sees the statement as it would reach the database. It
+	// recognizes EXPLAIN ANALYZE as running the statement it explains.
+	guarded := opts
+	guarded.Query = "EXPLAIN " + stmt
+	if opts.Analyze {
+		guarded.Query = "EXPLAIN ANALYZE " + stmt
+	}
+	if err := guardQuery(guarded, opts.Engine); err != nil {
+		return nil, err
+	}
+
+	sess, err := NewSession(ctx, ch, guarded)
+	if err != nil {
+		return nil, err
+	}
+	defer sess.Close()
+
+	engine := sess.Engine()
+	if opts.Analyze && engine == "mysql" {
+		return nil, fmt.Errorf("--analyze is only supported for PostgreSQL databases")
+	}
+	query, args, err := bindParams(stmt, engine, opts.Params, opts.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan *Plan
+	if engine == "mysql" {
+		plan, err = explainMySQL(ctx, sess.queryer(), query, args)
+	} else {
+		plan, err = explainPostgres(ctx, sess.queryer(), query, opts.Analyze, args)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Status:   "ok",
+		Database: opts.Database,
+		Branch:   opts.Branch,
+		Kind:     sess.Kind,
+		Role:     sess.role,
+		Replica:  opts.Replica,
+		Plan:     plan,
+		NextSteps: []string{
+			cmdutil.AgentSQLCmd(opts.Organization, opts.Database, opts.Branch, false),
+		},
+	}, nil
+}
+
+// explainTarget returns the single statement of query to explain.
+func explainTarget(query string) (string, error) {
+	statements := SplitStatements(query)
+	switch {
+	case len(statements) == 0:
+		return "", fmt.Errorf("query is required")
+	case len(statements) > 1:
+		return "", fmt.Errorf("--explain takes a single statement, got %d", len(statements))
+	}
+
+	stmt := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(statements[0]), ";"))
+	switch leadingStatementKeyword(stripSQLGuardIgnoredText(stmt)) {
+	case "EXPLAIN", "VEXPLAIN", "DESCRIBE", "DESC":
+		return "", fmt.Errorf("--explain adds EXPLAIN to the query; pass the statement to explain on its own")
+	}
+	return stmt, nil
+}
+
+func explainMySQL(ctx context.Context, db queryer, query string, args []any) (*Plan, error) {
+	plan := &Plan{Engine: "mysql"}
+
+	explainErr := func() error {
+		raw, err := explainOutput(ctx, db, "EXPLAIN FORMAT=JSON "+query, args)
+		if err != nil {
+			return err
+		}
+		plan.Root, plan.Cost, err = parseMySQLPlan(raw)
+		return err
+	}()
+
+	// VEXPLAIN is specific to Vitess, and fails on plain MySQL.
+	routingErr := func() error {
+		raw, err := explainOutput(ctx, db, "VEXPLAIN PLAN "+query, args)
+		if err != nil {
+			return err
+		}
+		plan.Routing, err = parseVitessPlan(raw)
+		return err
+	}()
+
+	switch {
+	case explainErr != nil && routingErr != nil:
+		return nil, explainErr
+	case explainErr != nil:
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("MySQL plan unavailable, showing the vtgate routing plan only: %s", explainErr))
+	}
+	plan.Warnings = append(plan.Warnings, planWarnings(plan)...)
+	return plan, nil
+}
+
+func explainPostgres(ctx context.Context, db queryer, query string, analyze bool, args []any) (*Plan, error) {
+	prefix := "EXPLAIN (FORMAT JSON) "
+	if analyze {
+		prefix = "EXPLAIN (ANALYZE, FORMAT JSON) "
+	}
+	raw, err := explainOutput(ctx, db, prefix+query, args)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := parsePostgresPlan(raw)
+	if err != nil {
+		return nil, err
+	}
+	plan.Analyzed = analyze
+	plan.Warnings = append(plan.Warnings, planWarnings(plan)...)
+	return plan, nil
+}
+
+// explainOutput runs an EXPLAIN statement and returns the plan document in
+// the first column of its first row.
+func explainOutput(ctx context.Context, db queryer, query string, args []any) ([]byte, error) {
+	outcome, err := runQuery(ctx, db, query, 0, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(outcome.rows) == 0 || len(outcome.columns) == 0 {
+		return nil, fmt.Errorf("%s returned no plan", leadingStatementKeyword(query))
+	}
+	switch v := outcome.rows[0][outcome.columns[0]].(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("%s returned a plan of type %T", leadingStatementKeyword(query), v)
+	}
+}
+
+// parseMySQLPlan normalizes the output of EXPLAIN FORMAT=JSON. The plan is a
+// tree of query blocks holding tables, nested loops, and operations such as
+// sorting and grouping.
+func parseMySQLPlan(raw []byte) (*PlanNode, *float64, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, nil, fmt.Errorf("reading MySQL plan: %w", err)
+	}
+	block, ok := doc["query_block"].(map[string]any)
+	if !ok {
+		return nil, nil, fmt.Errorf("reading MySQL plan: no query_block")
+	}
+	root := mysqlQueryBlock(block)
+	return root, root.Cost, nil
+}
+
+// mysqlOperations are the keys of a MySQL plan that wrap the rest of a query
+// block, in the order they are applied.
+var mysqlOperations = []struct {
+	key       string
+	operation string
+}{
+	{"windowing", "Window"},
+	{"ordering_operation", "Sort"},
+	{"grouping_operation", "Group"},
+	{"duplicates_removal", "Distinct"},
+}
+
+func mysqlQueryBlock(block map[string]any) *PlanNode {
+	node := &PlanNode{Operation: "Query block"}
+	if id, ok := planNumber(block["select_id"]); ok {
+		node.Operation = fmt.Sprintf("Query block #%g", *id)
+	}
+	if cost, ok := block["cost_info"].(map[string]any); ok {
+		node.Cost, _ = planNumber(cost["query_cost"])
+	}
+	node.Children = mysqlChildren(block)
+	return node
+}
+
+// mysqlChildren returns the steps under a query block or operation.
+func mysqlChildren(obj map[string]any) []*PlanNode {
+	var children []*PlanNode
+	for _, op := range mysqlOperations {
+		inner, ok := obj[op.key].(map[string]any)
+		if !ok {
+			continue
+		}
+		node := &PlanNode{Operation: op.operation}
+		if op.key == "ordering_operation" && inner["using_filesort"] == true {
+			node.Operation = "Sort (filesort)"
+		}
+		if inner["using_temporary_table"] == true {
+			node.Operation += " using temporary table"
+		}
+		node.Children = mysqlChildren(inner)
+		children = append(children, node)
+	}
+	if table, ok := obj["table"].(map[string]any); ok {
+		children = append(children, mysqlTable(table))
+	}
+	if loop, ok := obj["nested_loop"].([]any); ok {
+		node := &PlanNode{Operation: "Nested loop"}
+		for _, item := range loop {
+			if step, ok := item.(map[string]any); ok {
+				node.Children = append(node.Children, mysqlChildren(step)...)
+			}
+		}
+		children = append(children, node)
+	}
+	if union, ok := obj["union_result"].(map[string]any); ok {
+		node := &PlanNode{Operation: "Union"}
+		specs, _ := union["query_specifications"].([]any)
+		for _, spec := range specs {
+			if spec, ok := spec.(map[string]any); ok {
+				if block, ok := spec["query_block"].(map[string]any); ok {
+					node.Children = append(node.Children, mysqlQueryBlock(block))
+				}
+			}
+		}
+		children = append(children, node)
+	}
+	for _, key := range []string{"attached_subqueries", "optimized_away_subqueries"} {
+		subqueries, _ := obj[key].([]any)
+		for _, sub := range subqueries {
+			if sub, ok := sub.(map[string]any); ok {
+				if block, ok := sub["query_block"].(map[string]any); ok {
+					children = append(children, mysqlQueryBlock(block))
+				}
+			}
+		}
+	}
+	return children
+}
+
+// mysqlAccessTypes names the access types of MySQL tables.
+var mysqlAccessTypes = map[string]string{
+	"ALL":         "Full table scan",
+	"index":       "Full index scan",
+	"range":       "Index range scan",
+	"ref":         "Index lookup",
+	"eq_ref":      "Unique index lookup",
+	"ref_or_null": "Index lookup",
+	"const":       "Constant lookup",
+	"system":      "Constant lookup",
+	"fulltext":    "Fulltext index lookup",
+}
+
+func mysqlTable(table map[string]any) *PlanNode {
+	accessType, _ := table["access_type"].(string)
+	node := &PlanNode{Operation: "Table access"}
+	if name, ok := mysqlAccessTypes[accessType]; ok {
+		node.Operation = name
+	} else if accessType != "" {
+		node.Operation = "Table access (" + accessType + ")"
+	}
+	node.Table, _ = table["table_name"].(string)
+	node.Index, _ = table["key"].(string)
+	keys, _ := table["possible_keys"].([]any)
+	for _, key := range keys {
+		if key, ok := key.(string); ok {
+			node.PossibleKeys = append(node.PossibleKeys, key)
+		}
+	}
+	node.Rows, _ = planNumber(table["rows_examined_per_scan"])
+	if cost, ok := table["cost_info"].(map[string]any); ok {
+		node.Cost, _ = planNumber(cost["prefix_cost"])
+	}
+	node.Condition, _ = table["attached_condition"].(string)
+	node.FullScan = accessType == "ALL"
+
+	if derived, ok := table["materialized_from_subquery"].(map[string]any); ok {
+		if block, ok := derived["query_block"].(map[string]any); ok {
+			node.Children = append(node.Children, mysqlQueryBlock(block))
+		}
+	}
+	node.Children = append(node.Children, mysqlChildren(map[string]any{
+		"attached_subqueries": table["attached_subqueries"],
+	})...)
+	return node
+}
+
+// parsePostgresPlan normalizes the output of EXPLAIN (FORMAT JSON), an array
+// holding a single document with the plan tree under "Plan".
+func parsePostgresPlan(raw []byte) (*Plan, error) {
+	var docs []map[string]any
+	if err := json.Unmarshal(raw, &docs); err != nil {
+		return nil, fmt.Errorf("reading PostgreSQL plan: %w", err)
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("reading PostgreSQL plan: empty plan")
+	}
+	root, ok := docs[0]["Plan"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("reading PostgreSQL plan: no Plan")
+	}
+
+	plan := &Plan{Engine: "postgresql", Root: postgresNode(root)}
+	plan.Cost = plan.Root.Cost
+	plan.PlanningTimeMs, _ = planNumber(docs[0]["Planning Time"])
+	plan.ExecutionTimeMs, _ = planNumber(docs[0]["Execution Time"])
+	return plan, nil
+}
+
+func postgresNode(obj map[string]any) *PlanNode {
+	nodeType, _ := obj["Node Type"].(string)
+	node := &PlanNode{Operation: nodeType}
+	if join, ok := obj["Join Type"].(string); ok && join != "Inner" {
+		node.Operation += " (" + join + ")"
+	}
+	node.Table, _ = obj["Relation Name"].(string)
+	node.Index, _ = obj["Index Name"].(string)
+	node.Cost, _ = planNumber(obj["Total Cost"])
+	node.Rows, _ = planNumber(obj["Plan Rows"])
+	node.ActualRows, _ = planNumber(obj["Actual Rows"])
+	node.ActualTimeMs, _ = planNumber(obj["Actual Total Time"])
+	node.Loops, _ = planNumber(obj["Actual Loops"])
+	for _, key := range []string{"Index Cond", "Hash Cond", "Merge Cond", "Filter"} {
+		if cond, ok := obj[key].(string); ok {
+			node.Condition = cond
+			break
+		}
+	}
+	node.FullScan = nodeType == "Seq Scan"
+
+	children, _ := obj["Plans"].([]any)
+	for _, child := range children {
+		if child, ok := child.(map[string]any); ok {
+			node.Children = append(node.Children, postgresNode(child))
+		}
+	}
+	return node
+}
+
+// parseVitessPlan normalizes the output of VEXPLAIN PLAN, a tree of vtgate
+// operators with the routes to keyspaces at the leaves.
+func parseVitessPlan(raw []byte) (*PlanNode, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("reading vtgate plan: %w", err)
+	}
+	// Newer versions of Vitess wrap the operators with the statement type.
+	if instructions, ok := doc["Instructions"].(map[string]any); ok {
+		doc = instructions
+	}
+	return vitessNode(doc), nil
+}
+
+func vitessNode(obj map[string]any) *PlanNode {
+	operator, _ := obj["OperatorType"].(string)
+	node := &PlanNode{Operation: operator}
+	if variant, ok := obj["Variant"].(string); ok && variant != "" {
+		node.Operation += " (" + variant + ")"
+	}
+	if keyspace, ok := obj["Keyspace"].(map[string]any); ok {
+		node.Keyspace, _ = keyspace["Name"].(string)
+	}
+	node.Table, _ = obj["Table"].(string)
+	node.FullScan = obj["Variant"] == "Scatter"
+
+	inputs, _ := obj["Inputs"].([]any)
+	for _, input := range inputs {
+		if input, ok := input.(map[string]any); ok {
+			node.Children = append(node.Children, vitessNode(input))
+		}
+	}
+	return node
+}
+
+// planWarnings points out the full scans of the plan, and the scatter
+// queries of its vtgate plan.
+func planWarnings(plan *Plan) []string {
+	var warnings []string
+	walkPlan(plan.Root, func(node *PlanNode) {
+		if !node.FullScan {
+			return
+		}
+		warning := "Full table scan on " + node.Table
+		if node.Rows != nil {
+			warning += fmt.Sprintf(" (about %s rows)", formatPlanNumber(*node.Rows))
+		}
+		if len(node.PossibleKeys) > 0 {
+			warning += ", possible indexes not used: " + strings.Join(node.PossibleKeys, ", ")
+		}
+		warnings = append(warnings, warning)
+	})
+	walkPlan(plan.Routing, func(node *PlanNode) {
+		if !node.FullScan {
+			return
+		}
+		warning := "Scatter query sent to every shard"
+		if node.Keyspace != "" {
+			warning += " of keyspace " + node.Keyspace
+		}
+		if node.Table != "" {
+			warning += " for " + node.Table
+		}
+		warnings = append(warnings, warning)
+	})
+	return warnings
+}
+
+func walkPlan(node *PlanNode, fn func(*PlanNode)) {
+	if node == nil {
+		return
+	}
+	fn(node)
+	for _, child := range node.Children {
+		walkPlan(child, fn)
+	}
+}
+
+// planNumber reads a number of a plan. MySQL reports costs as strings.
+func planNumber(v any) (*float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return &v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, false
+		}
+		return &f, true
+	}
+	return nil, false
+}
+
+func formatPlanNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
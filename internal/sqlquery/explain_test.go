@@ -0,0 +1,178 @@
+package sqlquery
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExplainTarget(t *testing.T) {
+	stmt, err := explainTarget("  SELECT * FROM users;  ")
+	if err != nil || stmt != "SELECT * FROM users" {
+		t.Fatalf("explainTarget = %q, %v", stmt, err)
+	}
+
+	for _, query := range []string{
+		"",
+		"SELECT 1; SELECT 2",
+		"EXPLAIN SELECT 1",
+		"vexplain plan select 1",
+		"/* hint */ DESCRIBE users",
+	} {
+		if _, err := explainTarget(query); err == nil {
+			t.Errorf("explainTarget(%q): expected error", query)
+		}
+	}
+}
+
+const mysqlPlanJSON = `{
+  "query_block": {
+    "select_id": 1,
+    "cost_info": {"query_cost": "1203.50"},
+    "ordering_operation": {
+      "using_filesort": true,
+      "nested_loop": [
+        {
+          "table": {
+            "table_name": "users",
+            "access_type": "ALL",
+            "possible_keys": ["idx_email"],
+            "rows_examined_per_scan": 1000,
+            "cost_info": {"prefix_cost": "101.25"},
+            "attached_condition": "(users.name like 'a%')"
+          }
+        },
+        {
+          "table": {
+            "table_name": "orders",
+            "access_type": "ref",
+            "key": "idx_user_id",
+            "rows_examined_per_scan": 3,
+            "cost_info": {"prefix_cost": "1203.50"}
+          }
+        }
+      ]
+    }
+  }
+}`
+
+func TestParseMySQLPlan(t *testing.T) {
+	root, cost, err := parseMySQLPlan([]byte(mysqlPlanJSON))
+	if err != nil {
+		t.Fatalf("parseMySQLPlan: %v", err)
+	}
+	if cost == nil || *cost != 1203.5 {
+		t.Errorf("cost = %v, want 1203.5", cost)
+	}
+	if root.Operation != "Query block #1" || len(root.Children) != 1 {
+		t.Fatalf("root = %+v", root)
+	}
+	sort := root.Children[0]
+	if sort.Operation != "Sort (filesort)" || len(sort.Children) != 1 || sort.Children[0].Operation != "Nested loop" {
+		t.Fatalf("sort = %+v", sort)
+	}
+
+	loop := sort.Children[0].Children
+	if len(loop) != 2 {
+		t.Fatalf("nested loop has %d steps, want 2", len(loop))
+	}
+	users, orders := loop[0], loop[1]
+	if users.Operation != "Full table scan" || users.Table != "users" || !users.FullScan || *users.Rows != 1000 || *users.Cost != 101.25 {
+		t.Errorf("users = %+v", users)
+	}
+	if orders.Operation != "Index lookup" || orders.Index != "idx_user_id" || orders.FullScan {
+		t.Errorf("orders = %+v", orders)
+	}
+
+	warnings := planWarnings(&Plan{Root: root})
+	if len(warnings) != 1 || warnings[0] != "Full table scan on users (about 1000 rows), possible indexes not used: idx_email" {
+		t.Errorf("warnings = %q", warnings)
+	}
+}
+
+func TestParsePostgresPlan(t *testing.T) {
+	raw := `[{
+	  "Plan": {
+	    "Node Type": "Hash Join",
+	    "Join Type": "Left",
+	    "Total Cost": 48.1,
+	    "Plan Rows": 12,
+	    "Actual Rows": 10,
+	    "Actual Total Time": 0.52,
+	    "Actual Loops": 1,
+	    "Hash Cond": "(o.user_id = u.id)",
+	    "Plans": [
+	      {"Node Type": "Seq Scan", "Relation Name": "orders", "Total Cost": 22.7, "Plan Rows": 1270},
+	      {"Node Type": "Index Scan", "Relation Name": "users", "Index Name": "users_pkey", "Total Cost": 8.17, "Plan Rows": 1}
+	    ]
+	  },
+	  "Planning Time": 0.2,
+	  "Execution Time": 0.61
+	}]`
+
+	plan, err := parsePostgresPlan([]byte(raw))
+	if err != nil {
+		t.Fatalf("parsePostgresPlan: %v", err)
+	}
+	if *plan.Cost != 48.1 || *plan.PlanningTimeMs != 0.2 || *plan.ExecutionTimeMs != 0.61 {
+		t.Errorf("plan = %+v", plan)
+	}
+	root := plan.Root
+	if root.Operation != "Hash Join (Left)" || root.Condition != "(o.user_id = u.id)" || *root.ActualRows != 10 || len(root.Children) != 2 {
+		t.Fatalf("root = %+v", root)
+	}
+	if scan := root.Children[0]; !scan.FullScan || scan.Table != "orders" {
+		t.Errorf("seq scan = %+v", scan)
+	}
+	if scan := root.Children[1]; scan.FullScan || scan.Index != "users_pkey" {
+		t.Errorf("index scan = %+v", scan)
+	}
+
+	warnings := planWarnings(plan)
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "Full table scan on orders") {
+		t.Errorf("warnings = %q", warnings)
+	}
+}
+
+func TestParseVitessPlan(t *testing.T) {
+	raw := `{
+	  "OperatorType": "Aggregate",
+	  "Variant": "Scalar",
+	  "Inputs": [
+	    {
+	      "OperatorType": "Route",
+	      "Variant": "Scatter",
+	      "Keyspace": {"Name": "commerce", "Sharded": true},
+	      "Table": "orders"
+	    }
+	  ]
+	}`
+
+	routing, err := parseVitessPlan([]byte(raw))
+	if err != nil {
+		t.Fatalf("parseVitessPlan: %v", err)
+	}
+	if routing.Operation != "Aggregate (Scalar)" || len(routing.Children) != 1 {
+		t.Fatalf("routing = %+v", routing)
+	}
+	route := routing.Children[0]
+	if route.Operation != "Route (Scatter)" || route.Keyspace != "commerce" || route.Table != "orders" || !route.FullScan {
+		t.Errorf("route = %+v", route)
+	}
+
+	warnings := planWarnings(&Plan{Routing: routing})
+	if len(warnings) != 1 || warnings[0] != "Scatter query sent to every shard of keyspace commerce for orders" {
+		t.Errorf("warnings = %q", warnings)
+	}
+}
+
+func TestParsePlanErrors(t *testing.T) {
+	if _, _, err := parseMySQLPlan([]byte(`{"steps": []}`)); err == nil {
+		t.Error("parseMySQLPlan: expected error without query_block")
+	}
+	if _, err := parsePostgresPlan([]byte(`[]`)); err == nil {
+		t.Error("parsePostgresPlan: expected error for an empty plan")
+	}
+	if _, err := parseVitessPlan([]byte(`not json`)); err == nil {
+		t.Error("parseVitessPlan: expected error for invalid JSON")
+	}
+}
@@ -0,0 +1,123 @@
+package sqlquery
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ParseParams parses name=value pairs as given to --param.
+func ParseParams(pairs []string) (map[string]string, error) {
+	params := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.TrimPrefix(strings.TrimSpace(name), ":")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid parameter %q, expected name=value", pair)
+		}
+		if !isPlaceholderName(name) {
+			return nil, fmt.Errorf("invalid parameter name %q, names may only contain letters, digits, and underscores", name)
+		}
+		if _, dup := params[name]; dup {
+			return nil, fmt.Errorf("parameter %q is given more than once", name)
+		}
+		params[name] = value
+	}
+	return params, nil
+}
+
+// bindParams returns the query and the arguments to run it with. Positional
+// args are passed through as is, using the placeholders of the database (? for
+// MySQL, $1 for PostgreSQL). Named params are referenced as :name in the query
+// and rewritten to the placeholders of the database, so both are sent to the
+// server as prepared statement parameters rather than spliced into the SQL.
+func bindParams(query, engine string, params map[string]string, args []string) (string, []any, error) {
+	if len(params) > 0 && len(args) > 0 {
+		return "", nil, fmt.Errorf("named and positional parameters cannot be combined")
+	}
+	if len(params) == 0 {
+		bound := make([]any, len(args))
+		for i, arg := range args {
+			bound[i] = arg
+		}
+		return query, bound, nil
+	}
+
+	// Comments and quoted text are blanked without changing offsets, so
+	// placeholders found in the stripped query apply to the original one.
+	stripped := stripSQLGuardIgnoredText(query)
+
+	var (
+		out     strings.Builder
+		bound   []any
+		indexes = map[string]int{}
+		used    = map[string]bool{}
+		last    = 0
+	)
+	for i := 0; i < len(stripped); i++ {
+		if stripped[i] != ':' {
+			continue
+		}
+		// Skip PostgreSQL casts (::type) and assignments (:=).
+		if i+1 < len(stripped) && (stripped[i+1] == ':' || stripped[i+1] == '=') {
+			i++
+			continue
+		}
+		if i > 0 && (stripped[i-1] == ':' || isIdentifierChar(stripped[i-1])) {
+			continue
+		}
+		end := i + 1
+		for end < len(stripped) && isIdentifierChar(stripped[end]) {
+			end++
+		}
+		if end == i+1 || (stripped[i+1] >= '0' && stripped[i+1] <= '9') {
+			continue
+		}
+
+		name := stripped[i+1 : end]
+		value, ok := params[name]
+		if !ok {
+			return "", nil, fmt.Errorf("no value given for parameter :%s (use --param %s=value)", name, name)
+		}
+		used[name] = true
+
+		out.WriteString(query[last:i])
+		if engine == "mysql" {
+			out.WriteString("?")
+			bound = append(bound, value)
+		} else {
+			idx, seen := indexes[name]
+			if !seen {
+				bound = append(bound, value)
+				idx = len(bound)
+				indexes[name] = idx
+			}
+			out.WriteString("$" + strconv.Itoa(idx))
+		}
+		last = end
+		i = end - 1
+	}
+	out.WriteString(query[last:])
+
+	var unused []string
+	for name := range params {
+		if !used[name] {
+			unused = append(unused, name)
+		}
+	}
+	if len(unused) > 0 {
+		slices.Sort(unused)
+		return "", nil, fmt.Errorf("parameter(s) not used in the query: %s", strings.Join(unused, ", "))
+	}
+	return out.String(), bound, nil
+}
+
+func isPlaceholderName(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if !isIdentifierChar(name[i]) {
+			return false
+		}
+	}
+	return name[0] < '0' || name[0] > '9'
+}
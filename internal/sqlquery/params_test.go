@@ -0,0 +1,119 @@
+package sqlquery
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestParseParams(t *testing.T) {
+	got, err := ParseParams([]string{"email=jane@example.com", ":id=42", "note=a=b", "empty="})
+	if err != nil {
+		t.Fatalf("ParseParams: %v", err)
+	}
+	want := map[string]string{"email": "jane@example.com", "id": "42", "note": "a=b", "empty": ""}
+	if !maps.Equal(got, want) {
+		t.Fatalf("params = %v, want %v", got, want)
+	}
+
+	for _, pairs := range [][]string{
+		{"novalue"},
+		{"=1"},
+		{"bad-name=1"},
+		{"1st=1"},
+		{"id=1", "id=2"},
+	} {
+		if _, err := ParseParams(pairs); err == nil {
+			t.Fatalf("ParseParams(%q): expected error", pairs)
+		}
+	}
+}
+
+func TestBindParams(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		engine    string
+		params    map[string]string
+		args      []string
+		wantQuery string
+		wantArgs  []any
+		wantErr   string
+	}{
+		{
+			name:      "positional",
+			query:     "SELECT * FROM t WHERE id = ?",
+			engine:    "mysql",
+			args:      []string{"1"},
+			wantQuery: "SELECT * FROM t WHERE id = ?",
+			wantArgs:  []any{"1"},
+		},
+		{
+			name:      "named mysql",
+			query:     "SELECT * FROM t WHERE a = :a AND b = :b OR a = :a",
+			engine:    "mysql",
+			params:    map[string]string{"a": "1", "b": "2"},
+			wantQuery: "SELECT * FROM t WHERE a = ? AND b = ? OR a = ?",
+			wantArgs:  []any{"1", "2", "1"},
+		},
+		{
+			name:      "named postgres reuses index",
+			query:     "SELECT * FROM t WHERE a = :a AND b = :b OR a = :a",
+			engine:    "postgresql",
+			params:    map[string]string{"a": "1", "b": "2"},
+			wantQuery: "SELECT * FROM t WHERE a = $1 AND b = $2 OR a = $1",
+			wantArgs:  []any{"1", "2"},
+		},
+		{
+			name:      "ignores strings, comments and casts",
+			query:     "SELECT ':a', id::text, @x:=1 -- :a\nFROM t WHERE a = :a",
+			engine:    "postgresql",
+			params:    map[string]string{"a": "1"},
+			wantQuery: "SELECT ':a', id::text, @x:=1 -- :a\nFROM t WHERE a = $1",
+			wantArgs:  []any{"1"},
+		},
+		{
+			name:    "missing value",
+			query:   "SELECT :a, :b",
+			engine:  "mysql",
+			params:  map[string]string{"a": "1"},
+			wantErr: "no value given for parameter :b (use --param b=value)",
+		},
+		{
+			name:    "unused param",
+			query:   "SELECT :a",
+			engine:  "mysql",
+			params:  map[string]string{"a": "1", "z": "2", "c": "3"},
+			wantErr: "parameter(s) not used in the query: c, z",
+		},
+		{
+			name:    "mixed",
+			query:   "SELECT :a, ?",
+			engine:  "mysql",
+			params:  map[string]string{"a": "1"},
+			args:    []string{"2"},
+			wantErr: "named and positional parameters cannot be combined",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args, err := bindParams(tt.query, tt.engine, tt.params, tt.args)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("bindParams: %v", err)
+			}
+			if query != tt.wantQuery {
+				t.Fatalf("query = %q, want %q", query, tt.wantQuery)
+			}
+			if !slices.Equal(args, tt.wantArgs) {
+				t.Fatalf("args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
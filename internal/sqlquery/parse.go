@@ -0,0 +1,622 @@
+package sqlquery
+
+import (
+	"slices"
+	"strings"
+)
+
+// sqlDialect selects the lexical rules parseStatement applies.
+type sqlDialect struct {
+	// mysql enables backquoted identifiers, # comments, backslash escapes in
+	// strings and executable /*! */ comments.
+	mysql bool
+	// postgres enables dollar-quoted strings, E'' strings and nested block
+	// comments.
+	postgres bool
+}
+
+var (
+	dialectMySQL    = sqlDialect{mysql: true}
+	dialectPostgres = sqlDialect{postgres: true}
+	// dialectAny is used before the engine of the database is known.
+	dialectAny = sqlDialect{mysql: true, postgres: true}
+)
+
+func dialectForEngine(engine string) sqlDialect {
+	switch engine {
+	case "mysql":
+		return dialectMySQL
+	case "postgresql":
+		return dialectPostgres
+	default:
+		return dialectAny
+	}
+}
+
+type sqlTokenKind int
+
+const (
+	// sqlWord is a keyword or an unquoted identifier.
+	sqlWord sqlTokenKind = iota
+	sqlQuotedIdent
+	sqlString
+	sqlNumber
+	sqlPunct
+)
+
+// sqlToken is a token of a statement. Comments and whitespace are dropped.
+type sqlToken struct {
+	kind sqlTokenKind
+	// text is the token as written.
+	text string
+	// pos is the offset of the token in the statement.
+	pos int
+	// depth is the number of parentheses the token is nested in. Both
+	// parentheses of a pair have the depth of the text around them.
+	depth int
+}
+
+func (t sqlToken) is(word string) bool {
+	return t.kind == sqlWord && strings.EqualFold(t.text, word)
+}
+
+func (t sqlToken) isPunct(p string) bool {
+	return t.kind == sqlPunct && t.text == p
+}
+
+func (t sqlToken) isName() bool {
+	return t.kind == sqlWord || t.kind == sqlQuotedIdent
+}
+
+// lexSQL splits a statement into tokens. Unterminated strings, identifiers
+// and comments run to the end of the statement.
+func lexSQL(stmt string, d sqlDialect) []sqlToken {
+	var toks []sqlToken
+	depth := 0
+	execComment := false
+	for i := 0; i < len(stmt); {
+		c := stmt[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+		case c == '-' && strings.HasPrefix(stmt[i:], "--"), c == '#' && d.mysql:
+			i = skipLine(stmt, i)
+		case c == '/' && strings.HasPrefix(stmt[i:], "/*!") && d.mysql:
+			// MySQL executable comments are live SQL; only the delimiters
+			// and the optional version are skipped.
+			i += 3
+			for i < len(stmt) && stmt[i] >= '0' && stmt[i] <= '9' {
+				i++
+			}
+			execComment = true
+		case c == '*' && execComment && strings.HasPrefix(stmt[i:], "*/"):
+			i += 2
+			execComment = false
+		case c == '/' && strings.HasPrefix(stmt[i:], "/*"):
+			i = skipBlockComment(stmt, i, d.postgres)
+		case c == '\'':
+			end := skipQuoted(stmt, i, '\'', d.mysql)
+			toks = append(toks, sqlToken{kind: sqlString, text: stmt[i:end], pos: i, depth: depth})
+			i = end
+		case c == '"' || (c == '`' && d.mysql):
+			end := skipQuoted(stmt, i, c, false)
+			toks = append(toks, sqlToken{kind: sqlQuotedIdent, text: stmt[i:end], pos: i, depth: depth})
+			i = end
+		case c == '$' && d.postgres && dollarTag(stmt[i:]) != "":
+			tag := dollarTag(stmt[i:])
+			end := len(stmt)
+			if j := strings.Index(stmt[i+len(tag):], tag); j >= 0 {
+				end = i + len(tag) + j + len(tag)
+			}
+			toks = append(toks, sqlToken{kind: sqlString, text: stmt[i:end], pos: i, depth: depth})
+			i = end
+		case isIdentifierChar(c) && (c < '0' || c > '9'), c >= 0x80:
+			start := i
+			for i < len(stmt) && (isIdentifierChar(stmt[i]) || stmt[i] == '$' || stmt[i] >= 0x80) {
+				i++
+			}
+			if d.postgres && i-start == 1 && (c == 'E' || c == 'e') && i < len(stmt) && stmt[i] == '\'' {
+				end := skipQuoted(stmt, i, '\'', true)
+				toks = append(toks, sqlToken{kind: sqlString, text: stmt[start:end], pos: start, depth: depth})
+				i = end
+				continue
+			}
+			toks = append(toks, sqlToken{kind: sqlWord, text: stmt[start:i], pos: start, depth: depth})
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(stmt) && (isIdentifierChar(stmt[i]) || stmt[i] == '.') {
+				i++
+			}
+			toks = append(toks, sqlToken{kind: sqlNumber, text: stmt[start:i], pos: start, depth: depth})
+		case c == '(':
+			toks = append(toks, sqlToken{kind: sqlPunct, text: "(", pos: i, depth: depth})
+			depth++
+			i++
+		case c == ')':
+			depth = max(depth-1, 0)
+			toks = append(toks, sqlToken{kind: sqlPunct, text: ")", pos: i, depth: depth})
+			i++
+		default:
+			toks = append(toks, sqlToken{kind: sqlPunct, text: stmt[i : i+1], pos: i, depth: depth})
+			i++
+		}
+	}
+	return toks
+}
+
+func skipLine(stmt string, i int) int {
+	if j := strings.IndexByte(stmt[i:], '\n'); j >= 0 {
+		return i + j + 1
+	}
+	return len(stmt)
+}
+
+// skipBlockComment returns the offset after the comment starting at i.
+// PostgreSQL block comments nest.
+func skipBlockComment(stmt string, i int, nested bool) int {
+	level := 0
+	for i < len(stmt) {
+		switch {
+		case strings.HasPrefix(stmt[i:], "/*"):
+			if level == 0 || nested {
+				level++
+			}
+			i += 2
+		case strings.HasPrefix(stmt[i:], "*/"):
+			i += 2
+			if level--; level == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return len(stmt)
+}
+
+// skipQuoted returns the offset after the quoted text starting at i. A
+// doubled quote is part of the text, as is a quote after a backslash if
+// backslash escapes are enabled.
+func skipQuoted(stmt string, i int, quote byte, backslash bool) int {
+	for i++; i < len(stmt); i++ {
+		switch stmt[i] {
+		case '\\':
+			if backslash {
+				i++
+			}
+		case quote:
+			if i+1 < len(stmt) && stmt[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(stmt)
+}
+
+// dollarTag returns the $tag$ that opens a PostgreSQL dollar-quoted string
+// at the start of s, or "" if there is none. $1 is a parameter.
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '$':
+			return s[:i+1]
+		case c == '_' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || c >= 0x80 || (i > 1 && c >= '0' && c <= '9'):
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+// topLevelTokens returns the tokens outside of parentheses. The parentheses
+// themselves are kept, so a parenthesized expression shows as ( ).
+func topLevelTokens(toks []sqlToken) []sqlToken {
+	return slices.DeleteFunc(slices.Clone(toks), func(t sqlToken) bool { return t.depth > 0 })
+}
+
+// statementParser reads the clauses of a statement that matter to its risk
+// from its top-level tokens: the tables it changes, and whether an UPDATE
+// or DELETE has a WHERE or LIMIT clause. Subqueries, CTE bodies and quoted
+// text are never mistaken for clauses of the statement.
+type statementParser struct {
+	stmt    string
+	dialect sqlDialect
+	toks    []sqlToken
+	top     []sqlToken
+	i       int
+}
+
+// parseStatement classifies a statement without the Vitess parser: it is
+// used for PostgreSQL statements and for MySQL statements Vitess cannot
+// parse.
+func parseStatement(stmt string, d sqlDialect) StatementRisk {
+	toks := lexSQL(stmt, d)
+	p := &statementParser{stmt: stmt, dialect: d, toks: toks, top: topLevelTokens(toks)}
+	return p.parse()
+}
+
+func (p *statementParser) peek() sqlToken {
+	if p.i < len(p.top) {
+		return p.top[p.i]
+	}
+	return sqlToken{kind: sqlPunct}
+}
+
+// skip advances past the words given, in any order, and reports whether it
+// skipped any.
+func (p *statementParser) skip(words ...string) bool {
+	skipped := false
+	for p.i < len(p.top) && slices.ContainsFunc(words, p.top[p.i].is) {
+		p.i++
+		skipped = true
+	}
+	return skipped
+}
+
+// skipSequence advances past the words if they come next, in order.
+func (p *statementParser) skipSequence(words ...string) bool {
+	if p.i+len(words) > len(p.top) {
+		return false
+	}
+	for j, word := range words {
+		if !p.top[p.i+j].is(word) {
+			return false
+		}
+	}
+	p.i += len(words)
+	return true
+}
+
+// name reads a possibly qualified name such as app.users and returns it,
+// or "" if no name comes next. Quotes are kept only where the name needs
+// them, as the Vitess parser prints names.
+func (p *statementParser) name() string {
+	if !p.peek().isName() {
+		return ""
+	}
+	parts := []string{unquoteName(p.top[p.i])}
+	p.i++
+	for p.i+1 < len(p.top) && p.top[p.i].isPunct(".") && p.top[p.i+1].isName() {
+		parts = append(parts, unquoteName(p.top[p.i+1]))
+		p.i += 2
+	}
+	// PostgreSQL's table * includes the descendant tables.
+	if p.peek().isPunct("*") {
+		p.i++
+	}
+	return strings.Join(parts, ".")
+}
+
+// unquoteName returns a quoted identifier without its quotes if they don't
+// change its meaning: it is lower case, made of identifier characters and
+// doesn't start with a digit.
+func unquoteName(t sqlToken) string {
+	if t.kind != sqlQuotedIdent || len(t.text) < 3 || t.text[len(t.text)-1] != t.text[0] {
+		return t.text
+	}
+	inner := t.text[1 : len(t.text)-1]
+	if inner[0] >= '0' && inner[0] <= '9' {
+		return t.text
+	}
+	for i := 0; i < len(inner); i++ {
+		if !isIdentifierChar(inner[i]) || (inner[i] >= 'A' && inner[i] <= 'Z') {
+			return t.text
+		}
+	}
+	return inner
+}
+
+// names reads a comma-separated list of names.
+func (p *statementParser) names() []string {
+	var out []string
+	for {
+		p.skip("ONLY")
+		name := p.name()
+		if name == "" {
+			return out
+		}
+		out = append(out, name)
+		if !p.peek().isPunct(",") {
+			return out
+		}
+		p.i++
+	}
+}
+
+// tableRefs reads the tables of a FROM-like list up to one of the given
+// top-level words, including joined tables and skipping aliases, ON
+// conditions and parenthesized subqueries.
+func (p *statementParser) tableRefs(until ...string) []string {
+	var out []string
+	expectTable := true
+	for p.i < len(p.top) {
+		tok := p.top[p.i]
+		switch {
+		case slices.ContainsFunc(until, tok.is):
+			return out
+		case tok.isPunct(",") || tok.is("JOIN"):
+			expectTable = true
+			p.i++
+		case expectTable && tok.isName() && !tok.is("ONLY") && !tok.is("LATERAL"):
+			if name := p.name(); name != "" {
+				out = append(out, name)
+			}
+			expectTable = false
+		default:
+			p.i++
+		}
+	}
+	return out
+}
+
+// hasClause reports whether one of the words is a top-level clause after
+// the current token.
+func (p *statementParser) hasClause(words ...string) bool {
+	return slices.ContainsFunc(p.top[p.i:], func(t sqlToken) bool {
+		return slices.ContainsFunc(words, t.is)
+	})
+}
+
+func (p *statementParser) kind() string {
+	var words []string
+	for _, t := range p.top {
+		if len(words) == 12 || t.kind != sqlWord {
+			break
+		}
+		words = append(words, strings.ToUpper(t.text))
+	}
+	return kindFromWords(words)
+}
+
+func (p *statementParser) parse() StatementRisk {
+	risk := StatementRisk{Kind: p.kind()}
+	if risk.Kind == "" {
+		return risk
+	}
+	word, _, _ := strings.Cut(risk.Kind, " ")
+	p.i = 1
+	switch word {
+	case "WITH":
+		if main := p.afterCTEs(); main != nil {
+			if inner := main.parse(); slices.Contains([]string{"INSERT", "UPDATE", "DELETE", "MERGE", "REPLACE"}, inner.Kind) {
+				return inner
+			}
+		}
+	case "SELECT":
+	case "UPDATE":
+		p.skip("LOW_PRIORITY", "IGNORE", "ONLY")
+		risk.Objects = p.tableRefs("SET")
+		if !p.hasClause("WHERE", "LIMIT") {
+			risk.UnboundedWrite = true
+			risk.Destructive = true
+			risk.Reason = "UPDATE without WHERE changes every row"
+		}
+	case "DELETE":
+		p.skip("LOW_PRIORITY", "QUICK", "IGNORE")
+		risk.Destructive = true
+		risk.Reason = "DELETE removes rows"
+		if p.skip("FROM") {
+			risk.Objects = p.tableRefs("USING", "WHERE", "ORDER", "LIMIT", "RETURNING", "PARTITION")
+		} else {
+			// MySQL's DELETE t1, t2 FROM ... deletes from the targets only.
+			risk.Objects = p.names()
+		}
+		if !p.hasClause("WHERE", "LIMIT") {
+			risk.UnboundedWrite = true
+			risk.Reason = "DELETE without WHERE removes every row"
+		}
+	case "INSERT", "REPLACE":
+		p.skip("LOW_PRIORITY", "DELAYED", "HIGH_PRIORITY", "IGNORE", "INTO")
+		if name := p.name(); name != "" {
+			risk.Objects = []string{name}
+		}
+		if word == "REPLACE" {
+			risk.Destructive = true
+			risk.Reason = "REPLACE deletes the rows that conflict with the new ones"
+		}
+	case "MERGE":
+		p.skip("INTO", "ONLY")
+		if name := p.name(); name != "" {
+			risk.Objects = []string{name}
+		}
+	case "TRUNCATE":
+		p.skip("TABLE")
+		risk.Objects = p.names()
+		risk.Destructive = true
+		risk.Reason = "TRUNCATE removes every row"
+	case "CALL":
+		if name := p.name(); name != "" {
+			risk.Objects = []string{name}
+		}
+	case "EXPLAIN":
+		if inner, analyze := p.explained(); analyze && inner != "" {
+			risk = parseStatement(inner, p.dialect)
+			risk.Kind = "EXPLAIN ANALYZE " + risk.Kind
+		}
+	case "CREATE", "ALTER", "DROP", "RENAME":
+		p.ddl(&risk)
+	}
+	return risk
+}
+
+// afterCTEs returns a parser for the statement that follows the common
+// table expressions of a WITH statement, or nil if they can't be read.
+func (p *statementParser) afterCTEs() *statementParser {
+	p.skip("RECURSIVE")
+	for {
+		if p.name() == "" {
+			return nil
+		}
+		if p.peek().isPunct("(") {
+			p.i += 2
+		}
+		if !p.skip("AS") {
+			return nil
+		}
+		p.skip("NOT", "MATERIALIZED")
+		if !p.peek().isPunct("(") {
+			return nil
+		}
+		p.i += 2
+		// PostgreSQL's SEARCH and CYCLE clauses follow the body.
+		for p.i < len(p.top) && !p.peek().isPunct(",") && !slices.ContainsFunc([]string{"SELECT", "INSERT", "UPDATE", "DELETE", "MERGE", "VALUES", "TABLE"}, p.peek().is) {
+			p.i++
+		}
+		if !p.peek().isPunct(",") {
+			break
+		}
+		p.i++
+	}
+	if p.i >= len(p.top) {
+		return nil
+	}
+	rest := p.stmt[p.top[p.i].pos:]
+	toks := lexSQL(rest, p.dialect)
+	return &statementParser{stmt: rest, dialect: p.dialect, toks: toks, top: topLevelTokens(toks)}
+}
+
+// explained returns the statement an EXPLAIN explains, and whether it is
+// run because of the ANALYZE option.
+func (p *statementParser) explained() (inner string, analyze bool) {
+	if p.peek().isPunct("(") {
+		// EXPLAIN (ANALYZE, FORMAT JSON) or EXPLAIN (ANALYZE false).
+		open := slices.IndexFunc(p.toks, func(t sqlToken) bool { return t.pos == p.peek().pos })
+		for j := open + 1; j < len(p.toks) && p.toks[j].depth > 0; j++ {
+			t := p.toks[j]
+			if t.depth != 1 || !(t.is("ANALYZE") || t.is("ANALYSE")) {
+				continue
+			}
+			analyze = true
+			if j+1 < len(p.toks) && (p.toks[j+1].is("FALSE") || p.toks[j+1].is("OFF") || p.toks[j+1].text == "0") {
+				analyze = false
+			}
+		}
+		p.i += 2
+	} else {
+		for {
+			switch {
+			case p.skip("ANALYZE", "ANALYSE"):
+				analyze = true
+			case p.skip("VERBOSE"):
+			default:
+				if p.i >= len(p.top) {
+					return "", analyze
+				}
+				return p.stmt[p.top[p.i].pos:], analyze
+			}
+		}
+	}
+	if p.i >= len(p.top) {
+		return "", analyze
+	}
+	return p.stmt[p.top[p.i].pos:], analyze
+}
+
+// ddl reads the objects of a CREATE, ALTER, DROP or RENAME statement and
+// whether it is destructive.
+func (p *statementParser) ddl(risk *StatementRisk) {
+	// Skip the words of the kind and the modifiers between them.
+	if _, object, ok := strings.Cut(risk.Kind, " "); ok {
+		last := object[strings.LastIndex(object, " ")+1:]
+		for p.i < len(p.top) && !p.top[p.i].is(last) {
+			p.i++
+		}
+		p.i++
+	}
+	p.skip("CONCURRENTLY")
+	if !p.skipSequence("IF", "NOT", "EXISTS") {
+		p.skipSequence("IF", "EXISTS")
+	}
+
+	switch risk.Kind {
+	case "CREATE INDEX", "DROP INDEX":
+		// The index belongs to the table after ON, in PostgreSQL's CREATE
+		// INDEX and in MySQL.
+		names := p.names()
+		if p.skip("ON") {
+			p.skip("ONLY")
+			if name := p.name(); name != "" {
+				names = []string{name}
+			}
+		}
+		risk.Objects = names
+	case "ALTER TABLE":
+		p.skip("ONLY")
+		if name := p.name(); name != "" {
+			risk.Objects = []string{name}
+		}
+		risk.Reason = p.alterTableRisk()
+		risk.Destructive = risk.Reason != ""
+	case "RENAME TABLE":
+		// RENAME TABLE a TO b, c TO d
+		for {
+			if name := p.name(); name != "" {
+				risk.Objects = append(risk.Objects, name)
+			}
+			for p.i < len(p.top) && !p.peek().isPunct(",") {
+				p.i++
+			}
+			if p.i >= len(p.top) {
+				break
+			}
+			p.i++
+		}
+		risk.Destructive = true
+		risk.Reason = "RENAME TABLE breaks queries that use the old name"
+	default:
+		if strings.HasPrefix(risk.Kind, "DROP ") {
+			risk.Objects = p.names()
+		} else if name := p.name(); name != "" {
+			risk.Objects = []string{name}
+		}
+	}
+
+	switch risk.Kind {
+	case "DROP TABLE":
+		risk.Destructive, risk.Reason = true, "DROP TABLE removes tables and their rows"
+	case "DROP VIEW", "DROP MATERIALIZED VIEW":
+		risk.Destructive, risk.Reason = true, risk.Kind+" removes views"
+	case "DROP DATABASE", "DROP SCHEMA":
+		risk.Destructive, risk.Reason = true, risk.Kind+" removes a database and all of its tables"
+	}
+}
+
+// alterTableRisk returns why the actions of an ALTER TABLE statement are
+// destructive, or "" if they are not.
+func (p *statementParser) alterTableRisk() string {
+	for ; p.i < len(p.top); p.i++ {
+		switch {
+		case p.skipSequence("DROP", "PARTITION"):
+			return "ALTER TABLE drops partitions and their rows"
+		case p.skipSequence("TRUNCATE", "PARTITION"):
+			return "ALTER TABLE truncates partitions"
+		case p.skipSequence("DISCARD", "TABLESPACE"), p.skipSequence("DISCARD", "PARTITION"):
+			return "ALTER TABLE discards tablespaces"
+		case p.skipSequence("SET", "TABLESPACE"), p.skipSequence("IMPORT", "TABLESPACE"):
+			return "ALTER TABLE changes the tablespace"
+		case p.skipSequence("RENAME", "TO"), p.skipSequence("RENAME", "AS"):
+			return "ALTER TABLE renames the table, breaking queries that use the old name"
+		case p.skipSequence("RENAME", "INDEX"), p.skipSequence("RENAME", "KEY"), p.skipSequence("RENAME", "CONSTRAINT"):
+			continue
+		case p.peek().is("RENAME"):
+			return "ALTER TABLE renames a column, breaking queries that use the old name"
+		case p.peek().is("DROP"):
+			next := sqlToken{kind: sqlPunct}
+			if p.i+1 < len(p.top) {
+				next = p.top[p.i+1]
+			}
+			switch {
+			case next.is("DEFAULT"), next.is("NOT"), next.is("EXPRESSION"), next.is("IDENTITY"):
+				continue
+			case next.is("INDEX"), next.is("KEY"), next.is("CONSTRAINT"), next.is("PRIMARY"), next.is("FOREIGN"), next.is("CHECK"):
+				return "ALTER TABLE drops an index or constraint"
+			default:
+				return "ALTER TABLE drops a column and its data"
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,298 @@
+package sqlquery
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/planetscale/cli/internal/cmdutil"
+	"github.com/planetscale/cli/internal/config"
+)
+
+// PolicyConfigKey is the key of the SQL policy in a project .pscale.yml.
+const PolicyConfigKey = "sql-policy"
+
+// Policy restricts the statements pscale sql and pscale inspect may run. It
+// only ever takes permissions away: destructive statements still need
+// --force, and --force does not override the policy.
+//
+// Entries of the allow and deny lists are statement categories (read, write,
+// ddl, other), statement kinds as reported by ClassifyQuery (e.g. SELECT,
+// DROP TABLE; DROP matches every kind starting with DROP), or * for every
+// statement.
+type Policy struct {
+	// Deny lists the statements denied on every branch and for every role.
+	Deny []string `yaml:"deny" json:"deny,omitempty"`
+	// Rules are evaluated in order; the first rule matching the branch and
+	// role applies. Statements on branches no rule matches are allowed.
+	Rules []PolicyRule `yaml:"rules" json:"rules,omitempty"`
+
+	// overlay is a policy given with --policy that is enforced on top of the
+	// project policy: a statement either of them denies is denied.
+	overlay *Policy
+}
+
+// PolicyRule restricts the statements allowed on matching branches.
+type PolicyRule struct {
+	// Branches are path.Match patterns such as main or dev-*. An empty list
+	// matches every branch.
+	Branches []string `yaml:"branches" json:"branches,omitempty"`
+	// Roles are the access roles (reader, writer, readwriter, admin) the rule
+	// applies to. An empty list matches every role.
+	Roles []string `yaml:"roles" json:"roles,omitempty"`
+	// Allow lists the only statements allowed. An empty list allows every
+	// statement not denied.
+	Allow []string `yaml:"allow" json:"allow,omitempty"`
+	Deny  []string `yaml:"deny" json:"deny,omitempty"`
+}
+
+// PolicyViolationError is returned when the SQL policy denies a statement.
+type PolicyViolationError struct {
+	Statement string `json:"statement"`
+	Kind      string `json:"kind"`
+	Category  string `json:"category"`
+	Branch    string `json:"branch"`
+	Role      string `json:"role"`
+	Reason    string `json:"reason"`
+}
+
+func (e *PolicyViolationError) Error() string {
+	return fmt.Sprintf("%s statement denied by the SQL policy: %s", e.Kind, e.Reason)
+}
+
+// LoadPolicy reads a SQL policy from a YAML file. The policy is either the
+// whole file or, as in a project .pscale.yml, the value of its sql-policy
+// key. A file without a policy is an error, so a policy file given by
+// mistake does not silently allow every statement. The policy is decoded
+// strictly, so a misspelled key is an error rather than an empty rule.
+func LoadPolicy(file string) (*Policy, error) {
+	policy, err := readPolicy(file)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, fmt.Errorf("%s contains no SQL policy: expected a %s, deny or rules key", file, PolicyConfigKey)
+	}
+	return policy, nil
+}
+
+// readPolicy reads a SQL policy like LoadPolicy, but returns a nil policy if
+// the file has none.
+func readPolicy(file string) (*Policy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading SQL policy: %w", err)
+	}
+
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse SQL policy %s: %w", file, err)
+	}
+	// Only the policy is decoded strictly: the rest of a project
+	// .pscale.yml holds other settings.
+	if project, ok := doc[PolicyConfigKey]; ok {
+		if project == nil {
+			return nil, nil
+		}
+		if data, err = yaml.Marshal(project); err != nil {
+			return nil, fmt.Errorf("parse SQL policy %s: %w", file, err)
+		}
+	} else if _, ok := doc["deny"]; !ok {
+		if _, ok := doc["rules"]; !ok {
+			return nil, nil
+		}
+	}
+
+	policy := &Policy{}
+	if err := yaml.UnmarshalStrict(data, policy); err != nil {
+		return nil, fmt.Errorf("parse SQL policy %s: %w", file, err)
+	}
+	if err := policy.validate(); err != nil {
+		return nil, fmt.Errorf("invalid SQL policy %s: %w", file, err)
+	}
+	return policy, nil
+}
+
+// ResolvePolicy loads the SQL policy of the project .pscale.yml, if any,
+// and the policy in file, as given to --policy. The policy in file can only
+// add restrictions: the project policy is always enforced as well.
+func ResolvePolicy(file string) (*Policy, error) {
+	project, err := projectPolicy()
+	if err != nil {
+		return nil, err
+	}
+	if file == "" {
+		return project, nil
+	}
+	policy, err := LoadPolicy(file)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return policy, nil
+	}
+	project.overlay = policy
+	return project, nil
+}
+
+// projectPolicy loads the sql-policy key of the project .pscale.yml, or
+// returns nil if there is none.
+func projectPolicy() (*Policy, error) {
+	dir := config.ProjectDir()
+	if dir == "" {
+		return nil, nil
+	}
+	file := filepath.Join(dir, config.ProjectConfigFile())
+	if _, err := os.Stat(file); errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return readPolicy(file)
+}
+
+func (p *Policy) validate() error {
+	if err := validatePolicyList("deny", p.Deny); err != nil {
+		return err
+	}
+	for i, rule := range p.Rules {
+		for _, pattern := range rule.Branches {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("rule %d: invalid branch pattern %q", i+1, pattern)
+			}
+		}
+		for _, role := range rule.Roles {
+			if !slices.Contains([]string{"reader", "writer", "readwriter", "admin"}, strings.ToLower(role)) {
+				return fmt.Errorf("rule %d: invalid role %q, allowed values are: reader, writer, readwriter, admin", i+1, role)
+			}
+		}
+		if err := validatePolicyList("allow", rule.Allow); err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		if err := validatePolicyList("deny", rule.Deny); err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// validatePolicyList rejects the entries of an allow or deny list that match
+// no statement, such as a misspelled category.
+func validatePolicyList(name string, list []string) error {
+	for _, entry := range list {
+		entry = strings.TrimSpace(entry)
+		if entry == "*" || slices.Contains([]string{"read", "write", "ddl", "other"}, strings.ToLower(entry)) || isStatementKind(entry) {
+			continue
+		}
+		return fmt.Errorf("invalid %s entry %q, allowed values are: read, write, ddl, other, *, or a statement kind such as SELECT or DROP TABLE", name, entry)
+	}
+	return nil
+}
+
+// Check returns a *PolicyViolationError for the first statement of query the
+// policy denies on branch for role. Statements are classified for engine; see
+// ClassifyQuery.
+func (p *Policy) Check(query, engine, branch, role string) error {
+	if p == nil {
+		return nil
+	}
+	for _, risk := range ClassifyQuery(query, engine) {
+		if reason := p.deny(risk, branch, role); reason != "" {
+			return &PolicyViolationError{
+				Statement: risk.Statement,
+				Kind:      risk.Kind,
+				Category:  risk.Category,
+				Branch:    branch,
+				Role:      role,
+				Reason:    reason,
+			}
+		}
+	}
+	return nil
+}
+
+// guardQuery enforces opts.Policy on opts.Query for the branch and access
+// role of opts and, unless opts.Force is set, the destructive SQL guard.
+// Statements are classified for engine; it is run with opts.Engine before
+// the database is looked up, and again with the database's engine after.
+func guardQuery(opts Options, engine string) error {
+	if opts.Policy != nil {
+		role, err := cmdutil.ResolveAccessRole(opts.Role, opts.Replica, cmdutil.ReaderRole)
+		if err != nil {
+			return err
+		}
+		if err := opts.Policy.Check(opts.Query, engine, opts.Branch, role.ToString()); err != nil {
+			return err
+		}
+	}
+	if !opts.Force {
+		return checkDestructive(opts.Query, engine)
+	}
+	return nil
+}
+
+// deny returns why the policy, or the policy enforced on top of it, denies
+// the statement, or "" if it is allowed.
+func (p *Policy) deny(risk StatementRisk, branch, role string) string {
+	if reason := p.denyOwn(risk, branch, role); reason != "" {
+		return reason
+	}
+	if p.overlay != nil {
+		return p.overlay.deny(risk, branch, role)
+	}
+	return ""
+}
+
+func (p *Policy) denyOwn(risk StatementRisk, branch, role string) string {
+	if policyListMatches(p.Deny, risk) {
+		return fmt.Sprintf("%s statements are denied on every branch", risk.Kind)
+	}
+
+	for i, rule := range p.Rules {
+		if !rule.matches(branch, role) {
+			continue
+		}
+		switch {
+		case policyListMatches(rule.Deny, risk):
+			return fmt.Sprintf("%s statements are denied on branch %s for role %s (rule %d)", risk.Kind, branch, role, i+1)
+		case len(rule.Allow) > 0 && !policyListMatches(rule.Allow, risk):
+			return fmt.Sprintf("only %s statements are allowed on branch %s for role %s (rule %d)", strings.Join(rule.Allow, ", "), branch, role, i+1)
+		}
+		return ""
+	}
+	return ""
+}
+
+func (r PolicyRule) matches(branch, role string) bool {
+	if len(r.Roles) > 0 && !slices.ContainsFunc(r.Roles, func(r string) bool { return strings.EqualFold(r, role) }) {
+		return false
+	}
+	if len(r.Branches) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(r.Branches, func(pattern string) bool {
+		ok, _ := path.Match(pattern, branch)
+		return ok
+	})
+}
+
+func policyListMatches(list []string, risk StatementRisk) bool {
+	return slices.ContainsFunc(list, func(entry string) bool {
+		entry = strings.ToUpper(strings.TrimSpace(entry))
+		switch {
+		case entry == "*":
+			return true
+		case entry == strings.ToUpper(risk.Category):
+			return true
+		case entry == risk.Kind:
+			return true
+		default:
+			return strings.HasPrefix(risk.Kind, entry+" ")
+		}
+	})
+}
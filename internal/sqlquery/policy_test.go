@@ -0,0 +1,195 @@
+package sqlquery
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writePolicy(t *testing.T, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "policy.yml")
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestLoadPolicy(t *testing.T) {
+	inline := writePolicy(t, `
+deny: [ddl]
+rules:
+  - branches: [main]
+    allow: [read]
+`)
+	policy, err := LoadPolicy(inline)
+	if err != nil {
+		t.Fatalf("LoadPolicy: %v", err)
+	}
+	if len(policy.Deny) != 1 || len(policy.Rules) != 1 || policy.Rules[0].Branches[0] != "main" {
+		t.Fatalf("policy = %+v", policy)
+	}
+
+	project := writePolicy(t, `
+org: acme
+sql-policy:
+  rules:
+    - branches: ["dev-*"]
+      roles: [admin]
+      deny: [DROP]
+`)
+	policy, err = LoadPolicy(project)
+	if err != nil {
+		t.Fatalf("LoadPolicy: %v", err)
+	}
+	if len(policy.Rules) != 1 || policy.Rules[0].Roles[0] != "admin" {
+		t.Fatalf("policy = %+v", policy)
+	}
+
+	policy, err = readPolicy(writePolicy(t, "org: acme\n"))
+	if err != nil || policy != nil {
+		t.Fatalf("readPolicy without a policy = %+v, %v; want nil, nil", policy, err)
+	}
+	for _, file := range []string{writePolicy(t, "org: acme\n"), writePolicy(t, ""), os.DevNull} {
+		if _, err := LoadPolicy(file); err == nil || !strings.Contains(err.Error(), "contains no SQL policy") {
+			t.Fatalf("LoadPolicy(%s) = %v, want no SQL policy error", file, err)
+		}
+	}
+
+	for _, content := range []string{
+		"rules:\n  - branches: [\"[\"]\n",
+		"rules:\n  - roles: [owner]\n",
+		"deny: ddl: x\n",
+		"deny: [dll]\n",
+		"rules:\n  - branches: [main]\n    allow: [reads]\n",
+		"rules:\n  - deny: [DROP TABLES]\n",
+		"rules:\n  - branch: [main]\n    deny: [write]\n",
+		"sql-policy:\n  rules:\n    - branches: [main]\n      alow: [read]\n",
+	} {
+		if _, err := LoadPolicy(writePolicy(t, content)); err == nil {
+			t.Fatalf("LoadPolicy(%q): expected error", content)
+		}
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	policy := &Policy{
+		Deny: []string{"ddl"},
+		Rules: []PolicyRule{
+			{Branches: []string{"main"}, Allow: []string{"read"}},
+			{Branches: []string{"dev-*"}, Roles: []string{"reader"}, Allow: []string{"SELECT", "SHOW"}},
+			{Branches: []string{"dev-*"}, Deny: []string{"DELETE"}},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		branch string
+		role   string
+		denied string
+	}{
+		{name: "select on main", query: "SELECT 1", branch: "main", role: "reader"},
+		{name: "insert on main", query: "INSERT INTO t VALUES (1)", branch: "main", role: "admin", denied: "INSERT"},
+		{name: "ddl anywhere", query: "CREATE TABLE t (id int)", branch: "feature", role: "admin", denied: "CREATE TABLE"},
+		{name: "grant anywhere", query: "GRANT SELECT ON t TO reporting", branch: "feature", role: "admin", denied: "GRANT"},
+		{name: "call on main", query: "CALL archive_orders(30)", branch: "main", role: "admin", denied: "CALL"},
+		{name: "second statement", query: "SELECT 1; DROP TABLE t", branch: "feature", role: "admin", denied: "DROP TABLE"},
+		{name: "dev reader select", query: "SELECT 1", branch: "dev-1", role: "reader"},
+		{name: "dev reader write", query: "UPDATE t SET x = 1 WHERE id = 1", branch: "dev-1", role: "reader", denied: "UPDATE"},
+		{name: "dev admin update", query: "UPDATE t SET x = 1 WHERE id = 1", branch: "dev-1", role: "admin"},
+		{name: "dev admin delete", query: "DELETE FROM t WHERE id = 1", branch: "dev-1", role: "admin", denied: "DELETE"},
+		{name: "unmatched branch", query: "DELETE FROM t WHERE id = 1", branch: "feature", role: "admin"},
+	}
+	for _, tt := range tests {
+		for _, engine := range []string{"mysql", "postgresql"} {
+			t.Run(tt.name+"/"+engine, func(t *testing.T) {
+				err := policy.Check(tt.query, engine, tt.branch, tt.role)
+				if tt.denied == "" {
+					if err != nil {
+						t.Fatalf("Check: %v", err)
+					}
+					return
+				}
+				var violation *PolicyViolationError
+				if !errors.As(err, &violation) {
+					t.Fatalf("Check = %v, want *PolicyViolationError", err)
+				}
+				if violation.Kind != tt.denied || violation.Branch != tt.branch || violation.Role != tt.role {
+					t.Fatalf("violation = %+v, want kind %s", violation, tt.denied)
+				}
+			})
+		}
+	}
+
+	var nilPolicy *Policy
+	if err := nilPolicy.Check("DROP TABLE t", "mysql", "main", "admin"); err != nil {
+		t.Fatalf("nil policy Check: %v", err)
+	}
+}
+
+func TestPolicyCheckDeniesProcedureCallsAsWrites(t *testing.T) {
+	policy := &Policy{Deny: []string{"write"}}
+
+	for _, engine := range []string{"mysql", "postgresql"} {
+		err := policy.Check("CALL archive_orders(30)", engine, "main", "admin")
+		var violation *PolicyViolationError
+		if !errors.As(err, &violation) || violation.Kind != "CALL" || violation.Category != "write" {
+			t.Fatalf("Check(CALL, %s) = %v, want a write violation", engine, err)
+		}
+		if err := policy.Check("REVOKE ALL ON users FROM reporting", engine, "main", "admin"); err != nil {
+			t.Fatalf("Check(REVOKE, %s) = %v, want allowed: REVOKE is ddl", engine, err)
+		}
+	}
+}
+
+func TestLoadPolicyReportsInvalidEntries(t *testing.T) {
+	_, err := LoadPolicy(writePolicy(t, "rules:\n  - branches: [main]\n  - branches: [dev]\n    deny: [dll]\n"))
+	if err == nil || !strings.Contains(err.Error(), `rule 2: invalid deny entry "dll"`) {
+		t.Fatalf("LoadPolicy = %v, want rule 2 invalid deny entry", err)
+	}
+
+	policy, err := LoadPolicy(writePolicy(t, "deny: [DROP, drop table, EXPLAIN ANALYZE DELETE, \"*\"]\n"))
+	if err != nil || len(policy.Deny) != 4 {
+		t.Fatalf("LoadPolicy = %+v, %v; want the statement kinds accepted", policy, err)
+	}
+}
+
+func TestResolvePolicyEnforcesProjectPolicy(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	project := t.TempDir()
+	if err := os.WriteFile(filepath.Join(project, ".pscale.yml"), []byte("sql-policy:\n  deny: [ddl]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(project)
+
+	// A --policy file without a policy can't replace the project policy.
+	if _, err := ResolvePolicy(os.DevNull); err == nil {
+		t.Fatal("ResolvePolicy(/dev/null): expected error")
+	}
+	if _, err := ResolvePolicy(writePolicy(t, "org: acme\n")); err == nil {
+		t.Fatal("ResolvePolicy without a policy: expected error")
+	}
+
+	policy, err := ResolvePolicy(writePolicy(t, "rules:\n  - branches: [main]\n    allow: [read, ddl]\n"))
+	if err != nil {
+		t.Fatalf("ResolvePolicy: %v", err)
+	}
+	for _, tt := range []struct {
+		query  string
+		denied bool
+	}{
+		{query: "SELECT 1"},
+		// The project policy denies ddl even though --policy allows it.
+		{query: "DROP TABLE t", denied: true},
+		// --policy adds its own restrictions.
+		{query: "DELETE FROM t WHERE id = 1", denied: true},
+	} {
+		err := policy.Check(tt.query, "mysql", "main", "admin")
+		if denied := err != nil; denied != tt.denied {
+			t.Errorf("Check(%q) = %v, want denied %v", tt.query, err, tt.denied)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package sqlquery
+
+import (
+	"slices"
+	"strings"
+
+	"vitess.io/vitess/go/vt/sqlparser"
+)
+
+// StatementRisk is the destructive SQL guard's report on a single statement.
+type StatementRisk struct {
+	Statement string `json:"statement"`
+	// Kind is the kind of statement, e.g. DELETE, DROP TABLE or ALTER TABLE.
+	Kind string `json:"kind"`
+	// Category groups the kind into read, write, ddl, or other.
+	Category string `json:"category"`
+	// Objects are the tables, views or databases the statement changes, when
+	// the statement could be parsed.
+	Objects     []string `json:"objects,omitempty"`
+	Destructive bool     `json:"destructive"`
+	// UnboundedWrite is set for an UPDATE or DELETE with neither a WHERE nor
+	// a LIMIT clause, which changes every row of the table.
+	UnboundedWrite bool   `json:"unbounded_write,omitempty"`
+	Reason         string `json:"reason,omitempty"`
+}
+
+// ClassifyQuery reports the risk of each statement of query for engine,
+// mysql or postgresql.
+//
+// MySQL statements are parsed with the Vitess SQL parser. PostgreSQL
+// statements, MySQL statements Vitess cannot parse, and statements for an
+// empty engine, used before the database has been looked up, are parsed by
+// parseStatement, which reads the structure of a statement (its kind, the
+// tables it changes and its top-level clauses) rather than a full grammar.
+// The keyword check runs on every statement too, so a statement is never
+// reported as less destructive than its keywords suggest.
+func ClassifyQuery(query, engine string) []StatementRisk {
+	var parser *sqlparser.Parser
+	if engine == "mysql" {
+		// New only fails for an invalid MySQL version. Without the Vitess
+		// parser, every statement goes through parseStatement.
+		parser, _ = sqlparser.New(sqlparser.Options{})
+	}
+
+	dialect := dialectForEngine(engine)
+	var risks []StatementRisk
+	for _, stmt := range SplitStatements(query) {
+		risks = append(risks, classifyStatement(parser, dialect, stmt))
+	}
+	return risks
+}
+
+func classifyStatement(parser *sqlparser.Parser, dialect sqlDialect, stmt string) StatementRisk {
+	stripped := strings.TrimSpace(stripSQLGuardIgnoredText(stmt))
+
+	var risk StatementRisk
+	parsed := false
+	if parser != nil {
+		if ast, err := parser.Parse(stmt); err == nil {
+			risk = classifyAST(ast, stripped)
+			parsed = true
+		}
+	}
+	if !parsed {
+		risk = parseStatement(stmt, dialect)
+	}
+	risk.Statement = stmt
+
+	if !risk.Destructive && isDestructiveStatement(stripped) {
+		risk.Destructive = true
+		risk.Reason = "contains DELETE, DROP, or TRUNCATE"
+	}
+	risk.Category = statementCategory(risk, stripped)
+	return risk
+}
+
+// statementCategories maps the first keyword of a statement kind to its
+// category. Keywords not listed are in category other.
+var statementCategories = map[string]string{}
+
+func init() {
+	for category, keywords := range map[string][]string{
+		"read":  {"SELECT", "SHOW", "DESCRIBE", "DESC", "EXPLAIN", "TABLE", "VALUES"},
+		"write": {"INSERT", "UPDATE", "DELETE", "REPLACE", "MERGE", "COPY", "CALL", "DO", "LOAD", "VACUUM", "ANALYZE", "OPTIMIZE", "REINDEX", "CLUSTER", "REFRESH", "WITH"},
+		"ddl":   {"CREATE", "ALTER", "DROP", "TRUNCATE", "RENAME", "COMMENT", "GRANT", "REVOKE"},
+		"other": {"SET", "USE", "BEGIN", "START", "COMMIT", "ROLLBACK", "SAVEPOINT", "RELEASE", "LOCK", "UNLOCK", "FLUSH", "KILL", "PREPARE", "EXECUTE", "DEALLOCATE", "LISTEN", "NOTIFY", "UNLISTEN", "DISCARD", "RESET", "CHECKPOINT"},
+	} {
+		for _, keyword := range keywords {
+			statementCategories[keyword] = category
+		}
+	}
+}
+
+// statementCategory groups the kind of a statement into read, write, ddl, or
+// other.
+func statementCategory(risk StatementRisk, stripped string) string {
+	word, _, _ := strings.Cut(strings.TrimPrefix(risk.Kind, "EXPLAIN ANALYZE "), " ")
+	category, ok := statementCategories[word]
+	if !ok {
+		category = "other"
+	}
+	if word == "WITH" && isReadQuery(stripped) {
+		category = "read"
+	}
+	// Statements that only look like reads, such as EXPLAIN ANALYZE of a
+	// DELETE the parser could not handle, must not pass as reads.
+	if category == "read" && risk.Destructive {
+		category = "write"
+	}
+	return category
+}
+
+func classifyAST(ast sqlparser.Statement, stripped string) StatementRisk {
+	switch stmt := ast.(type) {
+	case *sqlparser.Delete:
+		risk := StatementRisk{
+			Kind:        "DELETE",
+			Objects:     tableExprNames(stmt.TableExprs),
+			Destructive: true,
+			Reason:      "DELETE removes rows",
+		}
+		if len(stmt.Targets) > 0 {
+			risk.Objects = tableNames(stmt.Targets)
+		}
+		if stmt.Where == nil && stmt.Limit == nil {
+			risk.UnboundedWrite = true
+			risk.Reason = "DELETE without WHERE removes every row"
+		}
+		return risk
+	case *sqlparser.Update:
+		risk := StatementRisk{Kind: "UPDATE", Objects: tableExprNames(stmt.TableExprs)}
+		if stmt.Where == nil && stmt.Limit == nil {
+			risk.UnboundedWrite = true
+			risk.Destructive = true
+			risk.Reason = "UPDATE without WHERE changes every row"
+		}
+		return risk
+	case *sqlparser.Insert:
+		risk := StatementRisk{Kind: "INSERT"}
+		if stmt.Table != nil {
+			risk.Objects = tableExprNames([]sqlparser.TableExpr{stmt.Table})
+		}
+		if stmt.Action == sqlparser.ReplaceAct {
+			risk.Kind = "REPLACE"
+			risk.Destructive = true
+			risk.Reason = "REPLACE deletes the rows that conflict with the new ones"
+		}
+		return risk
+	case *sqlparser.DropTable:
+		return StatementRisk{Kind: "DROP TABLE", Objects: tableNames(stmt.FromTables), Destructive: true, Reason: "DROP TABLE removes tables and their rows"}
+	case *sqlparser.DropView:
+		return StatementRisk{Kind: "DROP VIEW", Objects: tableNames(stmt.FromTables), Destructive: true, Reason: "DROP VIEW removes views"}
+	case *sqlparser.DropDatabase:
+		return StatementRisk{Kind: "DROP DATABASE", Objects: []string{stmt.DBName.String()}, Destructive: true, Reason: "DROP DATABASE removes a database and all of its tables"}
+	case *sqlparser.TruncateTable:
+		return StatementRisk{Kind: "TRUNCATE", Objects: tableNames(sqlparser.TableNames{stmt.Table}), Destructive: true, Reason: "TRUNCATE removes every row"}
+	case *sqlparser.RenameTable:
+		risk := StatementRisk{Kind: "RENAME TABLE", Destructive: true, Reason: "RENAME TABLE breaks queries that use the old name"}
+		for _, pair := range stmt.TablePairs {
+			risk.Objects = append(risk.Objects, sqlparser.String(pair.FromTable))
+		}
+		return risk
+	case *sqlparser.AlterTable:
+		// CREATE INDEX and DROP INDEX parse as ALTER TABLE; the kind keeps
+		// the statement's own words.
+		risk := StatementRisk{Kind: statementKind(stripped), Objects: tableNames(sqlparser.TableNames{stmt.Table})}
+		risk.Reason = alterTableRisk(stmt)
+		risk.Destructive = risk.Reason != ""
+		return risk
+	case *sqlparser.CreateTable:
+		return StatementRisk{Kind: "CREATE TABLE", Objects: tableNames(sqlparser.TableNames{stmt.Table})}
+	case *sqlparser.CreateView:
+		return StatementRisk{Kind: "CREATE VIEW", Objects: tableNames(sqlparser.TableNames{stmt.ViewName})}
+	case *sqlparser.AlterView:
+		return StatementRisk{Kind: "ALTER VIEW", Objects: tableNames(sqlparser.TableNames{stmt.ViewName})}
+	case *sqlparser.CreateDatabase:
+		return StatementRisk{Kind: "CREATE DATABASE", Objects: []string{stmt.DBName.String()}}
+	case *sqlparser.AlterDatabase:
+		return StatementRisk{Kind: "ALTER DATABASE", Objects: []string{stmt.DBName.String()}}
+	case *sqlparser.CallProc:
+		return StatementRisk{Kind: "CALL", Objects: []string{sqlparser.String(stmt.Name)}}
+	case *sqlparser.ExplainStmt:
+		// EXPLAIN ANALYZE runs the statement it explains.
+		if stmt.Type == sqlparser.AnalyzeType {
+			risk := classifyAST(stmt.Statement, sqlparser.String(stmt.Statement))
+			risk.Kind = "EXPLAIN ANALYZE " + risk.Kind
+			return risk
+		}
+		return StatementRisk{Kind: "EXPLAIN"}
+	default:
+		// Vitess names statement types after its own AST (CALL_PROC,
+		// DDL); the keywords give the kind unparsed statements get.
+		return StatementRisk{Kind: statementKind(stripped)}
+	}
+}
+
+// objectKeywords are the object types that complete the kind of a CREATE,
+// ALTER, DROP or RENAME statement, as in DROP TABLE.
+var objectKeywords = []string{
+	"DATABASE", "DOMAIN", "EVENT", "EXTENSION", "FUNCTION", "INDEX", "POLICY",
+	"PROCEDURE", "PUBLICATION", "ROLE", "SCHEMA", "SEQUENCE", "SUBSCRIPTION",
+	"TABLE", "TABLESPACE", "TRIGGER", "TYPE", "USER", "VIEW",
+}
+
+// kindModifiers are the words that may come between CREATE and the object
+// type, as in CREATE OR REPLACE VIEW or CREATE UNIQUE INDEX.
+var kindModifiers = []string{
+	"OR", "REPLACE", "TEMP", "TEMPORARY", "UNLOGGED", "GLOBAL", "LOCAL",
+	"UNIQUE", "FULLTEXT", "SPATIAL", "ONLINE", "OFFLINE", "ALGORITHM",
+	"MERGE", "TEMPTABLE", "UNDEFINED", "DEFINER", "CURRENT_USER", "SQL",
+	"SECURITY", "INVOKER", "AGGREGATE", "RECURSIVE",
+}
+
+// statementKind returns the kind of a statement from its keywords: its first
+// keyword, followed by the object type for CREATE, ALTER, DROP and RENAME
+// (CREATE TABLE, DROP MATERIALIZED VIEW).
+func statementKind(stripped string) string {
+	return kindFromWords(statementWords(stripped, 12))
+}
+
+// kindFromWords returns the kind of a statement from its leading keywords,
+// in upper case.
+func kindFromWords(words []string) string {
+	if len(words) == 0 {
+		return ""
+	}
+	switch words[0] {
+	case "CREATE", "ALTER", "DROP", "RENAME":
+	default:
+		return words[0]
+	}
+	for i := 1; i < len(words); i++ {
+		word := words[i]
+		switch {
+		case word == "MATERIALIZED" && i+1 < len(words) && words[i+1] == "VIEW":
+			return words[0] + " MATERIALIZED VIEW"
+		case slices.Contains(objectKeywords, word):
+			return words[0] + " " + word
+		case !slices.Contains(kindModifiers, word):
+			return words[0]
+		}
+	}
+	return words[0]
+}
+
+// alterTableRisk returns why an ALTER TABLE statement is destructive, or ""
+// if it is not.
+func alterTableRisk(stmt *sqlparser.AlterTable) string {
+	if spec := stmt.PartitionSpec; spec != nil {
+		switch spec.Action {
+		case sqlparser.DropAction:
+			return "ALTER TABLE drops partitions and their rows"
+		case sqlparser.TruncateAction:
+			return "ALTER TABLE truncates partitions"
+		case sqlparser.DiscardAction:
+			return "ALTER TABLE discards tablespaces"
+		}
+	}
+	for _, opt := range stmt.AlterOptions {
+		switch opt.(type) {
+		case *sqlparser.DropColumn:
+			return "ALTER TABLE drops a column and its data"
+		case *sqlparser.DropKey:
+			return "ALTER TABLE drops an index or constraint"
+		case *sqlparser.RenameTableName:
+			return "ALTER TABLE renames the table, breaking queries that use the old name"
+		case *sqlparser.RenameColumn:
+			return "ALTER TABLE renames a column, breaking queries that use the old name"
+		case *sqlparser.TablespaceOperation:
+			return "ALTER TABLE changes the tablespace"
+		}
+	}
+	return ""
+}
+
+func tableNames(names sqlparser.TableNames) []string {
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		out = append(out, sqlparser.String(name))
+	}
+	return out
+}
+
+func tableExprNames(exprs []sqlparser.TableExpr) []string {
+	var out []string
+	for _, expr := range exprs {
+		_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+			if aliased, ok := node.(*sqlparser.AliasedTableExpr); ok {
+				if name, ok := aliased.Expr.(sqlparser.TableName); ok {
+					out = append(out, sqlparser.String(name))
+				}
+				return false, nil
+			}
+			return true, nil
+		}, expr)
+	}
+	return out
+}
+
+// isStatementKind reports whether kind is a statement kind ClassifyQuery
+// reports, such as SELECT, DROP TABLE or EXPLAIN ANALYZE DELETE, or the
+// first keyword of one, such as DROP.
+func isStatementKind(kind string) bool {
+	words := strings.Fields(strings.ToUpper(kind))
+	if len(words) > 2 && words[0] == "EXPLAIN" && words[1] == "ANALYZE" {
+		words = words[2:]
+	}
+	if len(words) == 0 {
+		return false
+	}
+	if _, ok := statementCategories[words[0]]; !ok {
+		return false
+	}
+	switch object := strings.Join(words[1:], " "); {
+	case object == "":
+		return true
+	case !slices.Contains([]string{"CREATE", "ALTER", "DROP", "RENAME"}, words[0]):
+		return words[0] == "EXPLAIN" && object == "ANALYZE"
+	default:
+		return object == "MATERIALIZED VIEW" || slices.Contains(objectKeywords, object)
+	}
+}
@@ -0,0 +1,335 @@
+package sqlquery
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestClassifyQuery(t *testing.T) {
+	tests := []struct {
+		name   string
+		engine string
+		query  string
+		want   []StatementRisk
+	}{
+		{
+			name:   "select",
+			engine: "mysql",
+			query:  "SELECT * FROM users",
+			want:   []StatementRisk{{Statement: "SELECT * FROM users", Kind: "SELECT", Category: "read"}},
+		},
+		{
+			name:   "bounded delete",
+			engine: "mysql",
+			query:  "DELETE FROM app.users WHERE id = 1",
+			want: []StatementRisk{{
+				Statement:   "DELETE FROM app.users WHERE id = 1",
+				Kind:        "DELETE",
+				Category:    "write",
+				Objects:     []string{"app.users"},
+				Destructive: true,
+				Reason:      "DELETE removes rows",
+			}},
+		},
+		{
+			name:   "unbounded update",
+			engine: "mysql",
+			query:  "UPDATE users u JOIN orgs o ON u.org_id = o.id SET u.active = 0",
+			want: []StatementRisk{{
+				Statement:      "UPDATE users u JOIN orgs o ON u.org_id = o.id SET u.active = 0",
+				Kind:           "UPDATE",
+				Category:       "write",
+				Objects:        []string{"users", "orgs"},
+				Destructive:    true,
+				UnboundedWrite: true,
+				Reason:         "UPDATE without WHERE changes every row",
+			}},
+		},
+		{
+			name:   "several statements",
+			engine: "mysql",
+			query:  "INSERT INTO t VALUES (1); DROP TABLE a, b",
+			want: []StatementRisk{
+				{Statement: "INSERT INTO t VALUES (1)", Kind: "INSERT", Category: "write", Objects: []string{"t"}},
+				{
+					Statement:   "DROP TABLE a, b",
+					Kind:        "DROP TABLE",
+					Category:    "ddl",
+					Objects:     []string{"a", "b"},
+					Destructive: true,
+					Reason:      "DROP TABLE removes tables and their rows",
+				},
+			},
+		},
+		{
+			name:   "alter table drop column",
+			engine: "mysql",
+			query:  "ALTER TABLE users DROP COLUMN email",
+			want: []StatementRisk{{
+				Statement:   "ALTER TABLE users DROP COLUMN email",
+				Kind:        "ALTER TABLE",
+				Category:    "ddl",
+				Objects:     []string{"users"},
+				Destructive: true,
+				Reason:      "ALTER TABLE drops a column and its data",
+			}},
+		},
+		{
+			name:   "explain analyze",
+			engine: "mysql",
+			query:  "EXPLAIN ANALYZE DELETE FROM users",
+			want: []StatementRisk{{
+				Statement:      "EXPLAIN ANALYZE DELETE FROM users",
+				Kind:           "EXPLAIN ANALYZE DELETE",
+				Category:       "write",
+				Objects:        []string{"users"},
+				Destructive:    true,
+				UnboundedWrite: true,
+				Reason:         "DELETE without WHERE removes every row",
+			}},
+		},
+		{
+			name:   "postgres explain analyze",
+			engine: "postgresql",
+			query:  "EXPLAIN (ANALYZE, FORMAT JSON) UPDATE users SET name = 'x' WHERE id = 1",
+			want: []StatementRisk{{
+				Statement: "EXPLAIN (ANALYZE, FORMAT JSON) UPDATE users SET name = 'x' WHERE id = 1",
+				Kind:      "EXPLAIN ANALYZE UPDATE",
+				Category:  "write",
+				Objects:   []string{"users"},
+			}},
+		},
+		{
+			name:   "postgres quoted table",
+			engine: "postgresql",
+			query:  `DELETE FROM "users"`,
+			want: []StatementRisk{{
+				Statement:      `DELETE FROM "users"`,
+				Kind:           "DELETE",
+				Category:       "write",
+				Objects:        []string{"users"},
+				Destructive:    true,
+				UnboundedWrite: true,
+				Reason:         "DELETE without WHERE removes every row",
+			}},
+		},
+		{
+			name:   "call",
+			engine: "mysql",
+			query:  "CALL archive_orders(30)",
+			want:   []StatementRisk{{Statement: "CALL archive_orders(30)", Kind: "CALL", Category: "write", Objects: []string{"archive_orders"}}},
+		},
+		{
+			name:   "postgres call",
+			engine: "postgresql",
+			query:  "CALL archive_orders(30)",
+			want:   []StatementRisk{{Statement: "CALL archive_orders(30)", Kind: "CALL", Category: "write", Objects: []string{"archive_orders"}}},
+		},
+		{
+			name:   "grant",
+			engine: "mysql",
+			query:  "GRANT SELECT ON app.* TO 'reporting'@'%'",
+			want:   []StatementRisk{{Statement: "GRANT SELECT ON app.* TO 'reporting'@'%'", Kind: "GRANT", Category: "ddl"}},
+		},
+		{
+			name:   "postgres revoke",
+			engine: "postgresql",
+			query:  "REVOKE ALL ON users FROM reporting",
+			want:   []StatementRisk{{Statement: "REVOKE ALL ON users FROM reporting", Kind: "REVOKE", Category: "ddl"}},
+		},
+		{
+			name:   "create table",
+			engine: "mysql",
+			query:  "CREATE TABLE t (id int)",
+			want:   []StatementRisk{{Statement: "CREATE TABLE t (id int)", Kind: "CREATE TABLE", Category: "ddl", Objects: []string{"t"}}},
+		},
+		{
+			name:   "create index",
+			engine: "mysql",
+			query:  "CREATE UNIQUE INDEX idx ON t (id)",
+			want:   []StatementRisk{{Statement: "CREATE UNIQUE INDEX idx ON t (id)", Kind: "CREATE INDEX", Category: "ddl", Objects: []string{"t"}}},
+		},
+		{
+			name:   "postgres create or replace view",
+			engine: "postgresql",
+			query:  "CREATE OR REPLACE VIEW v AS SELECT 1",
+			want:   []StatementRisk{{Statement: "CREATE OR REPLACE VIEW v AS SELECT 1", Kind: "CREATE VIEW", Category: "ddl", Objects: []string{"v"}}},
+		},
+		{
+			name:   "postgres drop materialized view",
+			engine: "postgresql",
+			query:  "DROP MATERIALIZED VIEW IF EXISTS v",
+			want: []StatementRisk{{
+				Statement:   "DROP MATERIALIZED VIEW IF EXISTS v",
+				Kind:        "DROP MATERIALIZED VIEW",
+				Category:    "ddl",
+				Objects:     []string{"v"},
+				Destructive: true,
+				Reason:      "DROP MATERIALIZED VIEW removes views",
+			}},
+		},
+		{
+			name:   "postgres vacuum",
+			engine: "postgresql",
+			query:  "VACUUM FULL users",
+			want:   []StatementRisk{{Statement: "VACUUM FULL users", Kind: "VACUUM", Category: "write"}},
+		},
+		{
+			name:   "unknown engine",
+			engine: "",
+			query:  "RENAME TABLE a TO b",
+			want: []StatementRisk{{
+				Statement:   "RENAME TABLE a TO b",
+				Kind:        "RENAME TABLE",
+				Category:    "ddl",
+				Objects:     []string{"a"},
+				Destructive: true,
+				Reason:      "RENAME TABLE breaks queries that use the old name",
+			}},
+		},
+		{
+			name:   "postgres delete with where",
+			engine: "postgresql",
+			query:  "DELETE FROM app.users WHERE id = $1 RETURNING id",
+			want: []StatementRisk{{
+				Statement:   "DELETE FROM app.users WHERE id = $1 RETURNING id",
+				Kind:        "DELETE",
+				Category:    "write",
+				Objects:     []string{"app.users"},
+				Destructive: true,
+				Reason:      "DELETE removes rows",
+			}},
+		},
+		{
+			name:   "postgres delete with where in a subquery only",
+			engine: "postgresql",
+			query:  "DELETE FROM orders USING (SELECT id FROM users WHERE banned) b",
+			want: []StatementRisk{{
+				Statement:      "DELETE FROM orders USING (SELECT id FROM users WHERE banned) b",
+				Kind:           "DELETE",
+				Category:       "write",
+				Objects:        []string{"orders"},
+				Destructive:    true,
+				UnboundedWrite: true,
+				Reason:         "DELETE without WHERE removes every row",
+			}},
+		},
+		{
+			name:   "postgres update with where in a subquery only",
+			engine: "postgresql",
+			query:  "UPDATE users SET plan = (SELECT name FROM plans WHERE id = 1)",
+			want: []StatementRisk{{
+				Statement:      "UPDATE users SET plan = (SELECT name FROM plans WHERE id = 1)",
+				Kind:           "UPDATE",
+				Category:       "write",
+				Objects:        []string{"users"},
+				Destructive:    true,
+				UnboundedWrite: true,
+				Reason:         "UPDATE without WHERE changes every row",
+			}},
+		},
+		{
+			name:   "postgres update with where in a literal only",
+			engine: "postgresql",
+			query:  "UPDATE users SET note = $$ WHERE ' LIMIT $$",
+			want: []StatementRisk{{
+				Statement:      "UPDATE users SET note = $$ WHERE ' LIMIT $$",
+				Kind:           "UPDATE",
+				Category:       "write",
+				Objects:        []string{"users"},
+				Destructive:    true,
+				UnboundedWrite: true,
+				Reason:         "UPDATE without WHERE changes every row",
+			}},
+		},
+		{
+			name:   "postgres update with where in a cte only",
+			engine: "postgresql",
+			query:  "WITH old AS (SELECT id FROM users WHERE seen < now()) UPDATE users SET active = false FROM old",
+			want: []StatementRisk{{
+				Statement:      "WITH old AS (SELECT id FROM users WHERE seen < now()) UPDATE users SET active = false FROM old",
+				Kind:           "UPDATE",
+				Category:       "write",
+				Objects:        []string{"users"},
+				Destructive:    true,
+				UnboundedWrite: true,
+				Reason:         "UPDATE without WHERE changes every row",
+			}},
+		},
+		{
+			name:   "postgres alter table drop column",
+			engine: "postgresql",
+			query:  "ALTER TABLE IF EXISTS ONLY users ALTER COLUMN name DROP NOT NULL, DROP COLUMN note",
+			want: []StatementRisk{{
+				Statement:   "ALTER TABLE IF EXISTS ONLY users ALTER COLUMN name DROP NOT NULL, DROP COLUMN note",
+				Kind:        "ALTER TABLE",
+				Category:    "ddl",
+				Objects:     []string{"users"},
+				Destructive: true,
+				Reason:      "ALTER TABLE drops a column and its data",
+			}},
+		},
+		{
+			name:   "postgres create index concurrently",
+			engine: "postgresql",
+			query:  "CREATE INDEX CONCURRENTLY IF NOT EXISTS idx ON ONLY users (email)",
+			want:   []StatementRisk{{Statement: "CREATE INDEX CONCURRENTLY IF NOT EXISTS idx ON ONLY users (email)", Kind: "CREATE INDEX", Category: "ddl", Objects: []string{"users"}}},
+		},
+		{
+			name:   "postgres truncate",
+			engine: "postgresql",
+			query:  "TRUNCATE TABLE ONLY orders, users",
+			want: []StatementRisk{{
+				Statement:   "TRUNCATE TABLE ONLY orders, users",
+				Kind:        "TRUNCATE",
+				Category:    "ddl",
+				Objects:     []string{"orders", "users"},
+				Destructive: true,
+				Reason:      "TRUNCATE removes every row",
+			}},
+		},
+		{
+			name:   "mysql statement vitess cannot parse",
+			engine: "mysql",
+			query:  "UPDATE t SET a = (SELECT b FROM u WHERE c LIMIT 1) RETURNING a",
+			want: []StatementRisk{{
+				Statement:      "UPDATE t SET a = (SELECT b FROM u WHERE c LIMIT 1) RETURNING a",
+				Kind:           "UPDATE",
+				Category:       "write",
+				Objects:        []string{"t"},
+				Destructive:    true,
+				UnboundedWrite: true,
+				Reason:         "UPDATE without WHERE changes every row",
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, ClassifyQuery(tt.query, tt.engine)); diff != "" {
+				t.Fatalf("ClassifyQuery(%q, %q) mismatch (-want +got):\n%s", tt.query, tt.engine, diff)
+			}
+		})
+	}
+}
+
+func TestCheckDestructiveReportsRisks(t *testing.T) {
+	err := checkDestructive("SELECT 1; TRUNCATE TABLE users; UPDATE t SET x = 1", "mysql")
+	destructiveErr, ok := errors.AsType[*DestructiveQueryError](err)
+	if !ok {
+		t.Fatalf("error = %T (%v), want *DestructiveQueryError", err, err)
+	}
+	if len(destructiveErr.Risks) != 2 {
+		t.Fatalf("risks = %+v, want 2", destructiveErr.Risks)
+	}
+	want := "destructive SQL requires explicit user approval (ask the user, then re-run with --force): " +
+		"TRUNCATE removes every row (users); UPDATE without WHERE changes every row (t)"
+	if err.Error() != want {
+		t.Fatalf("error = %q, want %q", err.Error(), want)
+	}
+
+	if err := checkDestructive("SELECT 1; UPDATE t SET x = 1 WHERE id = 2", "mysql"); err != nil {
+		t.Fatalf("error = %v, want nil", err)
+	}
+}
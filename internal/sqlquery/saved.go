@@ -0,0 +1,206 @@
+package sqlquery
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/planetscale/cli/internal/config"
+)
+
+// SavedQueriesConfigKey is the key of the saved queries in a project
+// .pscale.yml and in the user-level queries file.
+const SavedQueriesConfigKey = "queries"
+
+// userQueriesFile is the name of the user-level queries file in the
+// PlanetScale config directory.
+const userQueriesFile = "queries.yml"
+
+// SavedQuery is a named query run with pscale sql --saved. Saved queries are
+// read from the queries key of the project .pscale.yml and of the user-level
+// queries file; project queries take precedence.
+type SavedQuery struct {
+	Name        string `yaml:"-" json:"name"`
+	Description string `yaml:"description" json:"description,omitempty"`
+	Query       string `yaml:"query" json:"query"`
+	// Engine restricts the query to databases of one engine, mysql or
+	// postgresql. Empty runs it on both.
+	Engine string `yaml:"engine" json:"engine,omitempty"`
+	// Params declares the :name placeholders of Query.
+	Params []SavedQueryParam `yaml:"params" json:"params,omitempty"`
+	// Source is the file the query was read from.
+	Source string `yaml:"-" json:"source"`
+}
+
+// SavedQueryParam is a :name placeholder of a saved query. Parameters without
+// a default must be given with --param.
+type SavedQueryParam struct {
+	Name        string  `yaml:"name" json:"name"`
+	Description string  `yaml:"description" json:"description,omitempty"`
+	Default     *string `yaml:"default" json:"default,omitempty"`
+}
+
+// LoadSavedQueries reads the saved queries under the queries key of a YAML
+// file. A missing file has no saved queries.
+func LoadSavedQueries(file string) ([]*SavedQuery, error) {
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading saved queries: %w", err)
+	}
+
+	var doc struct {
+		Queries yaml.MapSlice `yaml:"queries"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse saved queries %s: %w", file, err)
+	}
+
+	var queries []*SavedQuery
+	for _, item := range doc.Queries {
+		name, ok := item.Key.(string)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid saved query name %v in %s", item.Key, file)
+		}
+		// Each entry is decoded on its own, so errors name the query.
+		raw, err := yaml.Marshal(item.Value)
+		if err != nil {
+			return nil, fmt.Errorf("parse saved query %q in %s: %w", name, file, err)
+		}
+		q := &SavedQuery{Name: name, Source: file}
+		if err := yaml.UnmarshalStrict(raw, q); err != nil {
+			return nil, fmt.Errorf("parse saved query %q in %s: %w", name, file, err)
+		}
+		if err := q.validate(); err != nil {
+			return nil, fmt.Errorf("invalid saved query %q in %s: %w", name, file, err)
+		}
+		queries = append(queries, q)
+	}
+	return queries, nil
+}
+
+// ResolveSavedQueries returns the saved queries of the user-level queries
+// file and of the project .pscale.yml, sorted by name. A project query
+// replaces a user-level query of the same name.
+func ResolveSavedQueries() ([]*SavedQuery, error) {
+	var files []string
+	if dir, err := config.ConfigDir(); err == nil {
+		files = append(files, filepath.Join(dir, userQueriesFile))
+	}
+	if dir := config.ProjectDir(); dir != "" {
+		files = append(files, filepath.Join(dir, config.ProjectConfigFile()))
+	}
+
+	byName := make(map[string]*SavedQuery)
+	for _, file := range files {
+		queries, err := LoadSavedQueries(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, q := range queries {
+			byName[q.Name] = q
+		}
+	}
+
+	queries := make([]*SavedQuery, 0, len(byName))
+	for _, q := range byName {
+		queries = append(queries, q)
+	}
+	slices.SortFunc(queries, func(a, b *SavedQuery) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return queries, nil
+}
+
+// FindSavedQuery returns the saved query called name.
+func FindSavedQuery(name string) (*SavedQuery, error) {
+	queries, err := ResolveSavedQueries()
+	if err != nil {
+		return nil, err
+	}
+	for _, q := range queries {
+		if q.Name == name {
+			return q, nil
+		}
+	}
+	return nil, fmt.Errorf("no saved query named %q (list them with pscale sql saved list)", name)
+}
+
+// BindParams returns the parameters to run the query with: params, as given
+// to --param, completed with the defaults of the query. Parameters the query
+// does not declare, and declared parameters without a value, are errors. A
+// query declaring no parameters takes params as they are.
+func (q *SavedQuery) BindParams(params map[string]string) (map[string]string, error) {
+	if len(q.Params) == 0 {
+		return params, nil
+	}
+
+	for name := range params {
+		if !slices.ContainsFunc(q.Params, func(p SavedQueryParam) bool { return p.Name == name }) {
+			return nil, fmt.Errorf("saved query %q has no parameter %q", q.Name, name)
+		}
+	}
+
+	bound := make(map[string]string, len(q.Params))
+	var missing []string
+	for _, p := range q.Params {
+		switch value, ok := params[p.Name]; {
+		case ok:
+			bound[p.Name] = value
+		case p.Default != nil:
+			bound[p.Name] = *p.Default
+		default:
+			missing = append(missing, p.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("saved query %q needs a value for %s (use --param name=value)", q.Name, strings.Join(missing, ", "))
+	}
+	return bound, nil
+}
+
+func (q *SavedQuery) validate() error {
+	if strings.TrimSpace(q.Query) == "" {
+		return errors.New("query is required")
+	}
+	switch q.Engine {
+	case "", "mysql", "postgresql":
+	default:
+		return fmt.Errorf("invalid engine %q, allowed values are: mysql, postgresql", q.Engine)
+	}
+	seen := make(map[string]bool, len(q.Params))
+	for _, p := range q.Params {
+		if !isPlaceholderName(p.Name) {
+			return fmt.Errorf("invalid parameter name %q, names may only contain letters, digits, and underscores", p.Name)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("parameter %q is declared more than once", p.Name)
+		}
+		seen[p.Name] = true
+	}
+	return nil
+}
+
+// checkEngine returns an error if a query restricted to engine cannot run on
+// a database of the given kind.
+func checkEngine(engine, kind string) error {
+	if engine == "" {
+		return nil
+	}
+	actual := "postgresql"
+	if kind == "mysql" {
+		actual = "mysql"
+	}
+	if engine != actual {
+		return fmt.Errorf("the query is for %s databases, but this is a %s database", engine, actual)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package sqlquery
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSavedQueries(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	file := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestLoadSavedQueries(t *testing.T) {
+	file := writeSavedQueries(t, t.TempDir(), ".pscale.yml", `
+org: acme
+queries:
+  slow:
+    description: Slow queries
+    engine: postgresql
+    query: SELECT * FROM pg_stat_statements WHERE mean_exec_time > :ms
+    params:
+      - name: ms
+        default: "100"
+  count:
+    query: SELECT COUNT(*) FROM users
+`)
+	queries, err := LoadSavedQueries(file)
+	if err != nil {
+		t.Fatalf("LoadSavedQueries: %v", err)
+	}
+	if len(queries) != 2 || queries[0].Name != "slow" || queries[1].Name != "count" {
+		t.Fatalf("queries = %+v", queries)
+	}
+	slow := queries[0]
+	if slow.Engine != "postgresql" || slow.Source != file || len(slow.Params) != 1 || *slow.Params[0].Default != "100" {
+		t.Errorf("slow = %+v", slow)
+	}
+
+	missing, err := LoadSavedQueries(filepath.Join(t.TempDir(), "missing.yml"))
+	if err != nil || missing != nil {
+		t.Errorf("missing file = %v, %v, want no queries", missing, err)
+	}
+}
+
+func TestLoadSavedQueriesInvalid(t *testing.T) {
+	for name, content := range map[string]string{
+		"no query":        "queries:\n  q:\n    description: nothing\n",
+		"bad engine":      "queries:\n  q:\n    query: SELECT 1\n    engine: oracle\n",
+		"bad param":       "queries:\n  q:\n    query: SELECT 1\n    params:\n      - name: a-b\n",
+		"duplicate param": "queries:\n  q:\n    query: SELECT 1\n    params:\n      - name: a\n      - name: a\n",
+		"unknown field":   "queries:\n  q:\n    query: SELECT 1\n    sql: SELECT 2\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			file := writeSavedQueries(t, t.TempDir(), "queries.yml", content)
+			if _, err := LoadSavedQueries(file); err == nil || !strings.Contains(err.Error(), `"q"`) {
+				t.Errorf("LoadSavedQueries = %v, want an error naming the query", err)
+			}
+		})
+	}
+}
+
+func TestResolveSavedQueriesPrefersProject(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	writeSavedQueries(t, home, ".config/planetscale/queries.yml", `
+queries:
+  mine:
+    query: SELECT 1
+  shared:
+    query: SELECT 'user'
+`)
+	project := t.TempDir()
+	writeSavedQueries(t, project, ".pscale.yml", `
+queries:
+  shared:
+    query: SELECT 'project'
+`)
+	t.Chdir(project)
+
+	queries, err := ResolveSavedQueries()
+	if err != nil {
+		t.Fatalf("ResolveSavedQueries: %v", err)
+	}
+	if len(queries) != 2 || queries[0].Name != "mine" || queries[1].Name != "shared" {
+		t.Fatalf("queries = %+v", queries)
+	}
+	if queries[1].Query != "SELECT 'project'" {
+		t.Errorf("shared query = %q, want the project one", queries[1].Query)
+	}
+
+	if _, err := FindSavedQuery("nope"); err == nil {
+		t.Error("FindSavedQuery: expected error for an unknown query")
+	}
+}
+
+func TestSavedQueryBindParams(t *testing.T) {
+	limit := "10"
+	q := &SavedQuery{
+		Name:   "q",
+		Query:  "SELECT * FROM t WHERE a = :a LIMIT :limit",
+		Params: []SavedQueryParam{{Name: "a"}, {Name: "limit", Default: &limit}},
+	}
+
+	bound, err := q.BindParams(map[string]string{"a": "x"})
+	if err != nil || bound["a"] != "x" || bound["limit"] != "10" {
+		t.Errorf("BindParams = %v, %v", bound, err)
+	}
+	if _, err := q.BindParams(map[string]string{"limit": "5"}); err == nil || !strings.Contains(err.Error(), "needs a value for a") {
+		t.Errorf("BindParams without a = %v", err)
+	}
+	if _, err := q.BindParams(map[string]string{"a": "x", "b": "y"}); err == nil || !strings.Contains(err.Error(), `no parameter "b"`) {
+		t.Errorf("BindParams with b = %v", err)
+	}
+
+	undeclared := &SavedQuery{Name: "u", Query: "SELECT :b"}
+	if bound, err := undeclared.BindParams(map[string]string{"b": "y"}); err != nil || bound["b"] != "y" {
+		t.Errorf("BindParams of undeclared = %v, %v", bound, err)
+	}
+}
+
+func TestCheckEngine(t *testing.T) {
+	if err := checkEngine("", "mysql"); err != nil {
+		t.Errorf("no engine: %v", err)
+	}
+	if err := checkEngine("postgresql", "horizon"); err != nil {
+		t.Errorf("postgresql on horizon: %v", err)
+	}
+	if err := checkEngine("mysql", "postgresql"); err == nil {
+		t.Error("mysql on postgresql: expected error")
+	}
+}
@@ -0,0 +1,183 @@
+package sqlquery
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/planetscale/cli/internal/cmdutil"
+)
+
+// StatementResult is the outcome of a single statement of a script.
+type StatementResult struct {
+	Index        int              `json:"index"`
+	Statement    string           `json:"statement"`
+	Status       string           `json:"status"`
+	RowCount     int              `json:"row_count"`
+	RowsAffected int64            `json:"rows_affected,omitempty"`
+	Columns      []string         `json:"columns,omitempty"`
+	ColumnTypes  []ColumnType     `json:"column_types,omitempty"`
+	Rows         []map[string]any `json:"rows,omitempty"`
+	Truncated    bool             `json:"truncated,omitempty"`
+	Error        string           `json:"error,omitempty"`
+}
+
+// ScriptError is returned by ExecuteScript alongside the result when one or
+// more statements failed.
+type ScriptError struct {
+	Failed int
+	Total  int
+	// RolledBack is set when the script ran in a transaction. Statement is
+	// then the index of the statement that failed.
+	RolledBack bool
+	Statement  int
+}
+
+func (e *ScriptError) Error() string {
+	if e.RolledBack {
+		return fmt.Sprintf("statement %d of %d failed, the transaction was rolled back", e.Statement, e.Total)
+	}
+	return fmt.Sprintf("%d of %d statement(s) failed", e.Failed, e.Total)
+}
+
+// SplitStatements splits a SQL script into its statements. Semicolons inside
+// quoted strings and comments do not end a statement, and statements made up
+// only of comments are dropped.
+func SplitStatements(script string) []string {
+	// Comments and quoted text are blanked without changing offsets, so the
+	// bounds found in the stripped script apply to the original one.
+	stripped := stripSQLGuardIgnoredText(script)
+
+	var out []string
+	for _, b := range sqlStatementBounds(stripped) {
+		if strings.TrimSpace(stripped[b[0]:b[1]]) == "" {
+			continue
+		}
+		out = append(out, strings.TrimSpace(script[b[0]:b[1]]))
+	}
+	return out
+}
+
+// ExecuteScript splits opts.Query into statements and runs them in order over
+// a single connection. Every statement is checked by the SQL policy and the
+// destructive SQL guard before any of them runs. A failing statement stops the script when
+// opts.StopOnError is set; otherwise the remaining statements still run.
+//
+// With opts.Transaction, the statements run in a single transaction that is
+// rolled back at the first failing statement and committed otherwise.
+//
+// If any statement failed, the result is returned together with a
+// *ScriptError.
+func ExecuteScript(ctx context.Context, ch *cmdutil.Helper, opts Options) (*Result, error) {
+	if len(opts.Params) > 0 || len(opts.Args) > 0 {
+		return nil, fmt.Errorf("parameters cannot be bound to a script")
+	}
+	statements := SplitStatements(opts.Query)
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("script contains no SQL statements")
+	}
+	if err := guardQuery(opts, opts.Engine); err != nil {
+		return nil, err
+	}
+
+	role, err := cmdutil.ResolveAccessRole(opts.Role, opts.Replica, cmdutil.ReaderRole)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := NewSession(ctx, ch, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	// Statements such as SET or USE change the state of the connection they
+	// run on, so the whole script shares one connection from the pool.
+	conn, err := session.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var db queryer = conn
+	var tx *sql.Tx
+	if opts.Transaction {
+		if tx, err = conn.BeginTx(ctx, nil); err != nil {
+			return nil, fmt.Errorf("starting transaction: %w", err)
+		}
+		// Rollback is a no-op once the transaction is committed.
+		defer tx.Rollback() // nolint:errcheck
+		db = tx
+	}
+
+	result := &Result{
+		Status:   "ok",
+		Database: opts.Database,
+		Branch:   opts.Branch,
+		Kind:     session.Kind,
+		Role:     role.ToString(),
+		Replica:  opts.Replica,
+		NextSteps: []string{
+			cmdutil.AgentSQLCmd(opts.Organization, opts.Database, opts.Branch, false),
+		},
+	}
+
+	failed := 0
+	for i, stmt := range statements {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		sr := StatementResult{Index: i + 1, Statement: stmt, Status: "ok"}
+		outcome, err := runQuery(ctx, db, stmt, opts.MaxRows)
+		if err != nil {
+			sr.Status = "error"
+			sr.Error = err.Error()
+			result.Statements = append(result.Statements, sr)
+			failed++
+			if tx != nil {
+				return rollbackScript(result, tx, len(statements), sr.Index)
+			}
+			if opts.StopOnError {
+				break
+			}
+			continue
+		}
+
+		sr.Columns = outcome.columns
+		sr.ColumnTypes = outcome.columnTypes
+		sr.Rows = outcome.rows
+		sr.RowCount = len(outcome.rows)
+		sr.RowsAffected = outcome.rowsAffected
+		sr.Truncated = outcome.truncated
+		result.Statements = append(result.Statements, sr)
+		result.RowCount += sr.RowCount
+		result.RowsAffected += sr.RowsAffected
+	}
+
+	if tx != nil {
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("committing transaction: %w", err)
+		}
+		result.Committed = true
+	}
+	if failed > 0 {
+		result.Status = "error"
+		return result, &ScriptError{Failed: failed, Total: len(statements)}
+	}
+	return result, nil
+}
+
+// rollbackScript rolls back the transaction of a script and reports the
+// statement that failed.
+func rollbackScript(result *Result, tx *sql.Tx, total, statement int) (*Result, error) {
+	if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		return nil, fmt.Errorf("rolling back transaction: %w", err)
+	}
+	result.Status = "error"
+	result.RolledBack = true
+	result.FailedStatement = statement
+	return result, &ScriptError{Failed: 1, Total: total, RolledBack: true, Statement: statement}
+}
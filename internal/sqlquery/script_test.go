@@ -0,0 +1,73 @@
+package sqlquery
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/planetscale/cli/internal/cmdutil"
+	"github.com/planetscale/cli/internal/config"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{name: "single without semicolon", script: "SELECT 1", want: []string{"SELECT 1"}},
+		{name: "multiple", script: "SELECT 1;\nSELECT 2;\n", want: []string{"SELECT 1", "SELECT 2"}},
+		{name: "semicolon in string", script: "INSERT INTO t VALUES ('a;b'); SELECT 1", want: []string{"INSERT INTO t VALUES ('a;b')", "SELECT 1"}},
+		{name: "escaped quote", script: "SELECT 'it''s; fine'; SELECT 2", want: []string{"SELECT 'it''s; fine'", "SELECT 2"}},
+		{name: "semicolon in line comment", script: "-- first; second\nSELECT 1;", want: []string{"-- first; second\nSELECT 1"}},
+		{name: "quote in comment", script: "# don't split\nSELECT 1; SELECT 2", want: []string{"# don't split\nSELECT 1", "SELECT 2"}},
+		{name: "comment only statement", script: "SELECT 1; /* done; */", want: []string{"SELECT 1"}},
+		{name: "empty", script: " ;\n; ", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitStatements(tt.script); !slices.Equal(got, tt.want) {
+				t.Fatalf("SplitStatements(%q) = %q, want %q", tt.script, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteScriptValidation(t *testing.T) {
+	ch := &cmdutil.Helper{
+		Config: &config.Config{Organization: "bb"},
+	}
+
+	_, err := ExecuteScript(t.Context(), ch, Options{
+		Organization: "bb",
+		Database:     "db",
+		Branch:       "main",
+		Query:        "-- nothing to do\n;",
+	})
+	if err == nil || err.Error() != "script contains no SQL statements" {
+		t.Fatalf("error = %v, want script contains no SQL statements", err)
+	}
+
+	_, err = ExecuteScript(t.Context(), ch, Options{
+		Organization: "bb",
+		Database:     "db",
+		Branch:       "main",
+		Query:        "SELECT 1;\nINSERT INTO t VALUES ('DROP');\nDELETE FROM users;",
+	})
+	if _, ok := errors.AsType[*DestructiveQueryError](err); !ok {
+		t.Fatalf("error = %T (%v), want *DestructiveQueryError", err, err)
+	}
+}
+
+func TestScriptErrorMessage(t *testing.T) {
+	err := &ScriptError{Failed: 2, Total: 5}
+	if got, want := err.Error(), "2 of 5 statement(s) failed"; got != want {
+		t.Fatalf("error = %q, want %q", got, want)
+	}
+
+	err = &ScriptError{Failed: 1, Total: 5, RolledBack: true, Statement: 3}
+	if got, want := err.Error(), "statement 3 of 5 failed, the transaction was rolled back"; got != want {
+		t.Fatalf("error = %q, want %q", got, want)
+	}
+}
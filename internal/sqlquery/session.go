@@ -21,6 +21,16 @@ type Session struct {
 
 	db      *sql.DB
 	cleanup func()
+	// conn is set by Pin; queries then run over it instead of the pool.
+	conn *sql.Conn
+
+	// policy is enforced on every query run with Query.
+	policy *Policy
+	branch string
+	role   string
+
+	// replica routes the shard targets of Shards to replicas.
+	replica bool
 }
 
 // NewSession validates the options, looks up the database and branch, and
@@ -52,6 +62,15 @@ func NewSession(ctx context.Context, ch *cmdutil.Helper, opts Options) (*Session
 		return nil, fmt.Errorf("database lookup: %w", err)
 	}
 
+	if err := checkEngine(opts.Engine, string(dbInfo.Kind)); err != nil {
+		return nil, err
+	}
+	if opts.Engine == "" {
+		if err := guardQuery(opts, engineForKind(string(dbInfo.Kind))); err != nil {
+			return nil, err
+		}
+	}
+
 	dbBranch, err := client.DatabaseBranches.Get(ctx, &ps.GetDatabaseBranchRequest{
 		Organization: opts.Organization,
 		Database:     opts.Database,
@@ -83,29 +102,95 @@ func NewSession(ctx context.Context, ch *cmdutil.Helper, opts Options) (*Session
 		return nil, err
 	}
 
-	return &Session{Kind: string(dbInfo.Kind), db: db, cleanup: cleanup}, nil
+	return &Session{
+		Kind:    string(dbInfo.Kind),
+		db:      db,
+		cleanup: cleanup,
+		policy:  opts.Policy,
+		branch:  opts.Branch,
+		role:    role.ToString(),
+		replica: opts.Replica,
+	}, nil
 }
 
 // Engine normalizes Kind to "mysql" or "postgresql".
 func (s *Session) Engine() string {
-	if s.Kind == "mysql" {
+	return engineForKind(s.Kind)
+}
+
+// engineForKind normalizes a database kind as reported by the API to
+// "mysql" or "postgresql".
+func engineForKind(kind string) string {
+	if kind == "mysql" {
 		return "mysql"
 	}
 	return "postgresql"
 }
 
+// Pin makes the session run every following query over a single
+// connection, so statements changing the state of the connection, such as
+// USE or SET, apply to the queries after them.
+func (s *Session) Pin(ctx context.Context) error {
+	if s.conn != nil {
+		return nil
+	}
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	return nil
+}
+
+func (s *Session) queryer() queryer {
+	if s.conn != nil {
+		return s.conn
+	}
+	return s.db
+}
+
 // Query runs a single query over the session's connection and returns the
-// column names (in result order) and rows.
+// column names (in result order) and rows. Queries denied by the SQL policy
+// of the session return a *PolicyViolationError.
 func (s *Session) Query(ctx context.Context, query string) ([]string, []map[string]any, error) {
-	outcome, err := runQuery(ctx, s.db, query)
+	if err := s.policy.Check(query, s.Engine(), s.branch, s.role); err != nil {
+		return nil, nil, err
+	}
+	outcome, err := runQuery(ctx, s.queryer(), query, 0)
 	if err != nil {
 		return nil, nil, err
 	}
 	return outcome.columns, outcome.rows, nil
 }
 
+// Run runs a single statement with the given arguments over the session's
+// connection. Unlike Query, it also reports the column types, and the rows
+// affected by statements that return no rows.
+func (s *Session) Run(ctx context.Context, query string, args ...any) (*StatementResult, error) {
+	if err := s.policy.Check(query, s.Engine(), s.branch, s.role); err != nil {
+		return nil, err
+	}
+	outcome, err := runQuery(ctx, s.queryer(), query, 0, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &StatementResult{
+		Statement:    query,
+		Status:       "ok",
+		RowCount:     len(outcome.rows),
+		RowsAffected: outcome.rowsAffected,
+		Columns:      outcome.columns,
+		ColumnTypes:  outcome.columnTypes,
+		Rows:         outcome.rows,
+	}, nil
+}
+
 // Close releases the connection and cleans up the ephemeral credentials.
 func (s *Session) Close() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
 	if s.cleanup != nil {
 		s.cleanup()
 		s.cleanup = nil
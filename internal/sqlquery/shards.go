@@ -0,0 +1,250 @@
+package sqlquery
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/planetscale/cli/internal/cmdutil"
+)
+
+// ShardColumn is added to every row of a query run on all shards and names
+// the shard the row came from.
+const ShardColumn = "_shard"
+
+// DefaultShardConcurrency is the number of shards queried at once unless
+// Options.Concurrency says otherwise.
+const DefaultShardConcurrency = 8
+
+// ShardResult is the outcome of a query on a single shard.
+type ShardResult struct {
+	Shard        string `json:"shard"`
+	Status       string `json:"status"`
+	RowCount     int    `json:"row_count"`
+	RowsAffected int64  `json:"rows_affected,omitempty"`
+	Truncated    bool   `json:"truncated,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// ShardError is returned alongside the result when the query failed on one
+// or more shards.
+type ShardError struct {
+	Failed int
+	Total  int
+}
+
+func (e *ShardError) Error() string {
+	return fmt.Sprintf("query failed on %d of %d shard(s)", e.Failed, e.Total)
+}
+
+// ShardRows holds the rows a query returned on a single shard. Err is set if
+// the query failed on the shard.
+type ShardRows struct {
+	Shard        string
+	Columns      []string
+	ColumnTypes  []ColumnType
+	Rows         []map[string]any
+	RowsAffected int64
+	Truncated    bool
+	Err          error
+}
+
+// Shards lists the shards of a Vitess keyspace as targets for QueryShards,
+// such as commerce/-80. keyspace is given as to Options.Keyspace; without a
+// keyspace name, the shards of every keyspace are listed. A tablet type in
+// keyspace (commerce@replica) is kept on every target.
+func (s *Session) Shards(ctx context.Context, keyspace string) ([]string, error) {
+	if s.Engine() != "mysql" {
+		return nil, fmt.Errorf("shards are only available on MySQL (Vitess) databases")
+	}
+	name, tabletType, _ := strings.Cut(keyspace, "@")
+	if strings.Contains(name, "/") {
+		return nil, fmt.Errorf("keyspace %q already targets a single shard", keyspace)
+	}
+	if tabletType != "" {
+		tabletType = "@" + tabletType
+	} else if s.replica {
+		tabletType = "@replica"
+	}
+
+	// Listing the shards is not subject to the SQL policy: it is not the
+	// user's query, and some policies only allow SELECT.
+	outcome, err := runQuery(ctx, s.db, "SHOW VITESS_SHARDS", 0)
+	if err != nil {
+		return nil, fmt.Errorf("listing shards: %w", err)
+	}
+	if len(outcome.columns) == 0 {
+		return nil, fmt.Errorf("listing shards: no columns returned")
+	}
+
+	var shards []string
+	for _, row := range outcome.rows {
+		shard := fmt.Sprint(row[outcome.columns[0]])
+		if ks, _, _ := strings.Cut(shard, "/"); name != "" && ks != name {
+			continue
+		}
+		shards = append(shards, shard+tabletType)
+	}
+	if len(shards) == 0 {
+		if name != "" {
+			return nil, fmt.Errorf("no shards found for keyspace %q", name)
+		}
+		return nil, fmt.Errorf("no shards found")
+	}
+	return shards, nil
+}
+
+// QueryShards runs query on every shard, each over its own connection, with
+// at most concurrency shards queried at once. A failure on one shard does not
+// stop the others; it is reported in the ShardRows of that shard. At most
+// maxRows rows are read per shard when it is greater than zero.
+func (s *Session) QueryShards(ctx context.Context, query string, shards []string, concurrency, maxRows int, args ...any) ([]ShardRows, error) {
+	if err := s.policy.Check(query, s.Engine(), s.branch, s.role); err != nil {
+		return nil, err
+	}
+	if concurrency <= 0 {
+		concurrency = DefaultShardConcurrency
+	}
+
+	results := make([]ShardRows, len(shards))
+	var g errgroup.Group
+	g.SetLimit(concurrency)
+	for i, shard := range shards {
+		g.Go(func() error {
+			results[i] = s.queryShard(ctx, shard, query, maxRows, args)
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return results, nil
+}
+
+func (s *Session) queryShard(ctx context.Context, shard, query string, maxRows int, args []any) ShardRows {
+	result := ShardRows{Shard: shard}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer conn.Close()
+	// The connection stays pinned to the shard after USE, so it is discarded
+	// instead of going back to the pool.
+	defer conn.Raw(func(any) error { return driver.ErrBadConn }) // nolint:errcheck
+
+	if _, err := conn.ExecContext(ctx, "USE "+quoteIdentifier(shard)); err != nil {
+		result.Err = fmt.Errorf("targeting shard: %w", err)
+		return result
+	}
+
+	outcome, err := runQuery(ctx, conn, query, maxRows, args...)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Columns = outcome.columns
+	result.ColumnTypes = outcome.columnTypes
+	result.Rows = outcome.rows
+	result.RowsAffected = outcome.rowsAffected
+	result.Truncated = outcome.truncated
+	return result
+}
+
+// ExecuteAllShards runs a query on every shard of opts.Keyspace (every
+// keyspace when it is empty) of a MySQL database. The rows of all shards are
+// merged, each tagged with its shard in ShardColumn, and the outcome on each
+// shard is reported in Result.Shards. opts.MaxRows applies per shard.
+//
+// If the query failed on any shard, the result is returned together with a
+// *ShardError.
+func ExecuteAllShards(ctx context.Context, ch *cmdutil.Helper, opts Options) (*Result, error) {
+	if opts.Query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	if err := guardQuery(opts, opts.Engine); err != nil {
+		return nil, err
+	}
+
+	role, err := cmdutil.ResolveAccessRole(opts.Role, opts.Replica, cmdutil.ReaderRole)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := NewSession(ctx, ch, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	if session.Engine() != "mysql" {
+		return nil, fmt.Errorf("querying all shards is only supported on MySQL (Vitess) databases")
+	}
+	query, args, err := bindParams(opts.Query, session.Engine(), opts.Params, opts.Args)
+	if err != nil {
+		return nil, err
+	}
+	shards, err := session.Shards(ctx, opts.Keyspace)
+	if err != nil {
+		return nil, err
+	}
+	shardRows, err := session.QueryShards(ctx, query, shards, opts.Concurrency, opts.MaxRows, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Status:   "ok",
+		Database: opts.Database,
+		Branch:   opts.Branch,
+		Kind:     session.Kind,
+		Role:     role.ToString(),
+		Replica:  opts.Replica,
+		NextSteps: []string{
+			cmdutil.AgentSQLCmd(opts.Organization, opts.Database, opts.Branch, false),
+		},
+	}
+
+	failed := 0
+	for _, sr := range shardRows {
+		shardResult := ShardResult{Shard: sr.Shard, Status: "ok"}
+		if sr.Err != nil {
+			failed++
+			shardResult.Status = "error"
+			shardResult.Error = sr.Err.Error()
+			result.Shards = append(result.Shards, shardResult)
+			continue
+		}
+
+		if result.Columns == nil && sr.Columns != nil {
+			result.Columns = append([]string{ShardColumn}, sr.Columns...)
+			result.ColumnTypes = sr.ColumnTypes
+		}
+		for _, row := range sr.Rows {
+			row[ShardColumn] = sr.Shard
+			result.Rows = append(result.Rows, row)
+		}
+		shardResult.RowCount = len(sr.Rows)
+		shardResult.RowsAffected = sr.RowsAffected
+		shardResult.Truncated = sr.Truncated
+		result.RowCount += len(sr.Rows)
+		result.RowsAffected += sr.RowsAffected
+		result.Truncated = result.Truncated || sr.Truncated
+		result.Shards = append(result.Shards, shardResult)
+	}
+
+	if failed > 0 {
+		result.Status = "partial"
+		if failed == len(shardRows) {
+			result.Status = "error"
+		}
+		return result, &ShardError{Failed: failed, Total: len(shardRows)}
+	}
+	return result, nil
+}
+
+func quoteIdentifier(identifier string) string {
+	return "`" + strings.ReplaceAll(identifier, "`", "``") + "`"
+}
@@ -0,0 +1,113 @@
+package sqlquery
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/xelabs/go-mysqlstack/driver"
+	querypb "github.com/xelabs/go-mysqlstack/sqlparser/depends/query"
+	"github.com/xelabs/go-mysqlstack/sqlparser/depends/sqltypes"
+	"github.com/xelabs/go-mysqlstack/xlog"
+)
+
+func newShardTestSession(t *testing.T) (*Session, *driver.TestHandler) {
+	t.Helper()
+	log := xlog.NewStdLog(xlog.Level(xlog.ERROR))
+	fakedbs := driver.NewTestHandler(log)
+	server, err := driver.MockMysqlServer(log, fakedbs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	db, err := sql.Open("mysql", "mock:mock@tcp("+server.Addr()+")/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	fakedbs.AddQuery("show vitess_shards", &sqltypes.Result{
+		Fields: []*querypb.Field{{Name: "Shards", Type: querypb.Type_VARCHAR}},
+		Rows: [][]sqltypes.Value{
+			{sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte("commerce/-"))},
+			{sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte("customer/-80"))},
+			{sqltypes.MakeTrusted(querypb.Type_VARCHAR, []byte("customer/80-"))},
+		},
+	})
+	return &Session{Kind: "mysql", db: db}, fakedbs
+}
+
+func TestSessionShards(t *testing.T) {
+	sess, _ := newShardTestSession(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		keyspace string
+		replica  bool
+		want     []string
+	}{
+		{keyspace: "", want: []string{"commerce/-", "customer/-80", "customer/80-"}},
+		{keyspace: "customer", want: []string{"customer/-80", "customer/80-"}},
+		{keyspace: "customer@rdonly", want: []string{"customer/-80@rdonly", "customer/80-@rdonly"}},
+		{keyspace: "commerce", replica: true, want: []string{"commerce/-@replica"}},
+	}
+	for _, tt := range tests {
+		sess.replica = tt.replica
+		got, err := sess.Shards(ctx, tt.keyspace)
+		if err != nil {
+			t.Fatalf("Shards(%q): %v", tt.keyspace, err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Fatalf("Shards(%q) = %q, want %q", tt.keyspace, got, tt.want)
+		}
+	}
+	sess.replica = false
+
+	if _, err := sess.Shards(ctx, "customer/-80"); err == nil {
+		t.Fatal("expected error for a keyspace targeting a shard")
+	}
+	if _, err := sess.Shards(ctx, "missing"); err == nil {
+		t.Fatal("expected error for a keyspace without shards")
+	}
+}
+
+func TestSessionQueryShardsReportsPartialFailure(t *testing.T) {
+	sess, fakedbs := newShardTestSession(t)
+	fakedbs.AddQuery("use `customer/-80`", &sqltypes.Result{})
+	fakedbs.AddQueryError("use `customer/80-`", errors.New("shard unavailable"))
+	fakedbs.AddQuery("select id from t", &sqltypes.Result{
+		Fields: []*querypb.Field{{Name: "id", Type: querypb.Type_INT64}},
+		Rows: [][]sqltypes.Value{
+			{sqltypes.MakeTrusted(querypb.Type_INT64, []byte("1"))},
+			{sqltypes.MakeTrusted(querypb.Type_INT64, []byte("2"))},
+		},
+	})
+
+	results, err := sess.QueryShards(context.Background(), "SELECT id FROM t", []string{"customer/-80", "customer/80-"}, 2, 1)
+	if err != nil {
+		t.Fatalf("QueryShards: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("results = %+v", results)
+	}
+	if ok := results[0]; ok.Err != nil || len(ok.Rows) != 1 || !ok.Truncated || ok.Shard != "customer/-80" {
+		t.Fatalf("results[0] = %+v, want one truncated row", ok)
+	}
+	if failed := results[1]; failed.Err == nil || failed.Shard != "customer/80-" {
+		t.Fatalf("results[1] = %+v, want an error", failed)
+	}
+}
+
+func TestSessionQueryShardsEnforcesPolicy(t *testing.T) {
+	sess, _ := newShardTestSession(t)
+	sess.policy = &Policy{Deny: []string{"write"}}
+
+	_, err := sess.QueryShards(context.Background(), "DELETE FROM t WHERE id = 1", []string{"customer/-80"}, 1, 0)
+	var violation *PolicyViolationError
+	if !errors.As(err, &violation) {
+		t.Fatalf("QueryShards = %v, want *PolicyViolationError", err)
+	}
+}
@@ -36,8 +36,34 @@ type Options struct {
 	Role string
 	// Replica routes reads to replicas when true (same as pscale shell --replica).
 	Replica bool
-	// Force allows destructive SQL (DELETE, DROP, TRUNCATE) after explicit user approval.
+	// Force allows destructive SQL (see ClassifyQuery) after explicit user approval.
 	Force bool
+	// StopOnError stops ExecuteScript at the first failing statement instead
+	// of running the remaining ones.
+	StopOnError bool
+	// Transaction runs the statements of ExecuteScript in a single
+	// transaction, rolled back at the first failing statement.
+	Transaction bool
+	// MaxRows caps the number of rows returned by a query when greater than
+	// zero. Result.Truncated reports whether rows were left out.
+	MaxRows int
+	// Params binds the :name placeholders of Query. Args binds the
+	// positional placeholders (? for MySQL, $1 for PostgreSQL) instead. Both
+	// are sent as prepared statement parameters.
+	Params map[string]string
+	Args   []string
+	// Policy restricts the statements that may run on the branch. It is
+	// enforced even when Force is set.
+	Policy *Policy
+	// Concurrency is the number of shards ExecuteAllShards queries at once.
+	// Defaults to DefaultShardConcurrency.
+	Concurrency int
+	// Engine restricts Query to databases of one engine, mysql or
+	// postgresql, as declared by saved queries. Empty allows both.
+	Engine string
+	// Analyze makes Explain run the statement on PostgreSQL to measure its
+	// plan (EXPLAIN ANALYZE).
+	Analyze bool
 }
 
 // Result is returned for `pscale sql --format json`.
@@ -51,14 +77,45 @@ type Result struct {
 	RowCount     int              `json:"row_count"`
 	RowsAffected int64            `json:"rows_affected,omitempty"`
 	Columns      []string         `json:"columns,omitempty"`
+	ColumnTypes  []ColumnType     `json:"column_types,omitempty"`
 	Rows         []map[string]any `json:"rows,omitempty"`
-	NextSteps    []string         `json:"next_steps,omitempty"`
+	Truncated    bool             `json:"truncated,omitempty"`
+	// Error is set along with Status error when a streamed query fails after
+	// some of its rows were written.
+	Error string `json:"error,omitempty"`
+	// Statements holds the result of each statement when a script is run
+	// with ExecuteScript.
+	Statements []StatementResult `json:"statements,omitempty"`
+	// Shards holds the outcome on each shard when the query is run with
+	// ExecuteAllShards.
+	Shards []ShardResult `json:"shards,omitempty"`
+	// Plan is the query plan reported by Explain.
+	Plan *Plan `json:"plan,omitempty"`
+	// Committed and RolledBack report the outcome of a script run with
+	// Options.Transaction. FailedStatement is the index of the statement
+	// that caused the rollback.
+	Committed       bool     `json:"committed,omitempty"`
+	RolledBack      bool     `json:"rolled_back,omitempty"`
+	FailedStatement int      `json:"failed_statement,omitempty"`
+	NextSteps       []string `json:"next_steps,omitempty"`
+}
+
+// ColumnType describes a result column as reported by the database. Fields
+// the driver cannot report are omitted.
+type ColumnType struct {
+	Name         string `json:"name"`
+	DatabaseType string `json:"database_type"`
+	Nullable     *bool  `json:"nullable,omitempty"`
+	Precision    *int64 `json:"precision,omitempty"`
+	Scale        *int64 `json:"scale,omitempty"`
 }
 
 type queryOutcome struct {
 	columns      []string
+	columnTypes  []ColumnType
 	rows         []map[string]any
 	rowsAffected int64
+	truncated    bool
 }
 
 // Execute runs SQL against MySQL or PostgreSQL using ephemeral credentials.
@@ -66,8 +123,8 @@ func Execute(ctx context.Context, ch *cmdutil.Helper, opts Options) (*Result, er
 	if opts.Query == "" {
 		return nil, fmt.Errorf("query is required")
 	}
-	if !opts.Force && IsDestructiveQuery(opts.Query) {
-		return nil, &DestructiveQueryError{}
+	if err := guardQuery(opts, opts.Engine); err != nil {
+		return nil, err
 	}
 	if opts.Organization == "" {
 		return nil, fmt.Errorf("organization is required (use --org or set org in pscale.yml)")
@@ -94,6 +151,15 @@ func Execute(ctx context.Context, ch *cmdutil.Helper, opts Options) (*Result, er
 		return nil, fmt.Errorf("database lookup: %w", err)
 	}
 
+	if err := checkEngine(opts.Engine, string(dbInfo.Kind)); err != nil {
+		return nil, err
+	}
+	if opts.Engine == "" {
+		if err := guardQuery(opts, engineForKind(string(dbInfo.Kind))); err != nil {
+			return nil, err
+		}
+	}
+
 	dbBranch, err := client.DatabaseBranches.Get(ctx, &ps.GetDatabaseBranchRequest{
 		Organization: opts.Organization,
 		Database:     opts.Database,
@@ -115,17 +181,24 @@ func Execute(ctx context.Context, ch *cmdutil.Helper, opts Options) (*Result, er
 		Replica:  opts.Replica,
 	}
 
+	engine := engineForKind(string(dbInfo.Kind))
+	query, args, err := bindParams(opts.Query, engine, opts.Params, opts.Args)
+	if err != nil {
+		return nil, err
+	}
+	opts.Query = query
+
 	var outcome *queryOutcome
 
 	switch string(dbInfo.Kind) {
 	case "mysql":
-		outcome, err = queryMySQL(ctx, ch, opts, role)
+		outcome, err = queryMySQL(ctx, ch, opts, role, args)
 	case "postgresql", "horizon":
 		pgDB := opts.PostgresDB
 		if pgDB == "" {
 			pgDB = "postgres"
 		}
-		outcome, err = queryPostgres(ctx, ch, opts, pgDB, role)
+		outcome, err = queryPostgres(ctx, ch, opts, pgDB, role, args)
 	default:
 		return nil, fmt.Errorf("unsupported database kind %q", dbInfo.Kind)
 	}
@@ -134,23 +207,25 @@ func Execute(ctx context.Context, ch *cmdutil.Helper, opts Options) (*Result, er
 	}
 
 	result.Columns = outcome.columns
+	result.ColumnTypes = outcome.columnTypes
 	result.Rows = outcome.rows
 	result.RowCount = len(outcome.rows)
 	result.RowsAffected = outcome.rowsAffected
+	result.Truncated = outcome.truncated
 	result.NextSteps = []string{
 		cmdutil.AgentSQLCmd(opts.Organization, opts.Database, opts.Branch, false),
 	}
 	return result, nil
 }
 
-func queryMySQL(ctx context.Context, ch *cmdutil.Helper, opts Options, role cmdutil.PasswordRole) (*queryOutcome, error) {
+func queryMySQL(ctx context.Context, ch *cmdutil.Helper, opts Options, role cmdutil.PasswordRole, args []any) (*queryOutcome, error) {
 	db, cleanup, err := openMySQL(ctx, ch, opts, role)
 	if err != nil {
 		return nil, err
 	}
 	defer cleanup()
 
-	return runQuery(ctx, db, opts.Query)
+	return runQuery(ctx, db, opts.Query, opts.MaxRows, args...)
 }
 
 // openMySQL mints an ephemeral branch password, starts an in-process proxy,
@@ -174,7 +249,12 @@ func openMySQL(ctx context.Context, ch *cmdutil.Helper, opts Options, role cmdut
 	if err != nil {
 		return nil, nil, err
 	}
+	// Sessions can outlive the TTL of the password, so it is renewed until
+	// it is cleaned up.
+	renewCtx, stopRenew := context.WithCancel(context.Background())
+	go pw.Renew(renewCtx) // nolint:errcheck
 	cleanupPassword := func() {
+		stopRenew()
 		cleanupCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 		_ = pw.Cleanup(cleanupCtx)
@@ -243,14 +323,14 @@ func mysqlDSNDatabase(opts Options) string {
 	return "@primary"
 }
 
-func queryPostgres(ctx context.Context, ch *cmdutil.Helper, opts Options, pgDB string, role cmdutil.PasswordRole) (*queryOutcome, error) {
+func queryPostgres(ctx context.Context, ch *cmdutil.Helper, opts Options, pgDB string, role cmdutil.PasswordRole, args []any) (*queryOutcome, error) {
 	db, cleanup, err := openPostgres(ctx, ch, opts, pgDB, role)
 	if err != nil {
 		return nil, err
 	}
 	defer cleanup()
 
-	return runQuery(ctx, db, opts.Query)
+	return runQuery(ctx, db, opts.Query, opts.MaxRows, args...)
 }
 
 // openPostgres mints an ephemeral role and opens a direct connection to the
@@ -275,7 +355,12 @@ func openPostgres(ctx context.Context, ch *cmdutil.Helper, opts Options, pgDB st
 	if err != nil {
 		return nil, nil, err
 	}
+	// Sessions can outlive the TTL of the role, so it is renewed until it is
+	// cleaned up.
+	renewCtx, stopRenew := context.WithCancel(context.Background())
+	go pgRole.Renew(renewCtx) // nolint:errcheck
 	cleanupRole := func() {
+		stopRenew()
 		cleanupCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 		_ = pgRole.Cleanup(cleanupCtx, successor)
@@ -315,7 +400,7 @@ func openPostgres(ctx context.Context, ch *cmdutil.Helper, opts Options, pgDB st
 	return db, cleanup, nil
 }
 
-var readQueryPrefixes = []string{"SELECT", "SHOW", "DESCRIBE", "DESC", "EXPLAIN", "TABLE"}
+var readQueryPrefixes = []string{"SELECT", "SHOW", "DESCRIBE", "DESC", "EXPLAIN", "VEXPLAIN", "TABLE"}
 
 func isReadQuery(query string) bool {
 	q := strings.TrimSpace(stripSQLGuardIgnoredText(query))
@@ -457,22 +542,34 @@ func isIdentifierChar(c byte) bool {
 	return c == '_' || (c >= '0' && c <= '9') || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
 }
 
-func runQuery(ctx context.Context, db *sql.DB, query string) (*queryOutcome, error) {
+// queryer is implemented by both *sql.DB and *sql.Conn.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// runQuery runs a query with the given arguments and returns at most maxRows
+// of its rows when maxRows is greater than zero.
+func runQuery(ctx context.Context, db queryer, query string, maxRows int, args ...any) (*queryOutcome, error) {
 	if isReadQuery(query) || queryReturnsRows(query) {
-		rows, err := db.QueryContext(ctx, query)
+		rows, err := db.QueryContext(ctx, query, args...)
 		if err != nil {
 			return nil, err
 		}
 		defer rows.Close()
 
-		scannedRows, cols, err := scanRows(rows)
+		colTypes, err := columnTypes(rows)
+		if err != nil {
+			return nil, err
+		}
+		scannedRows, cols, truncated, err := scanRows(rows, maxRows)
 		if err != nil {
 			return nil, err
 		}
-		return &queryOutcome{rows: scannedRows, columns: cols}, nil
+		return &queryOutcome{rows: scannedRows, columns: cols, columnTypes: colTypes, truncated: truncated}, nil
 	}
 
-	res, err := db.ExecContext(ctx, query)
+	res, err := db.ExecContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -480,10 +577,10 @@ func runQuery(ctx context.Context, db *sql.DB, query string) (*queryOutcome, err
 	return &queryOutcome{rowsAffected: affected}, nil
 }
 
-func scanRows(rows *sql.Rows) ([]map[string]any, []string, error) {
+func scanRows(rows *sql.Rows, maxRows int) ([]map[string]any, []string, bool, error) {
 	columns, err := rows.Columns()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, false, err
 	}
 
 	values := make([]any, len(columns))
@@ -494,23 +591,49 @@ func scanRows(rows *sql.Rows) ([]map[string]any, []string, error) {
 
 	var out []map[string]any
 	for rows.Next() {
+		if maxRows > 0 && len(out) == maxRows {
+			return out, columns, true, nil
+		}
 		if err := rows.Scan(scanArgs...); err != nil {
-			return nil, nil, err
+			return nil, nil, false, err
 		}
 		rowMap := make(map[string]any, len(columns))
 		for i, col := range columns {
-			val := values[i]
-			switch v := val.(type) {
-			case []byte:
-				rowMap[col] = string(v)
-			default:
-				rowMap[col] = v
-			}
+			rowMap[col] = columnValue(values[i])
 		}
 		out = append(out, rowMap)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, nil, err
+		return nil, nil, false, err
+	}
+	return out, columns, false, nil
+}
+
+func columnTypes(rows *sql.Rows) ([]ColumnType, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]ColumnType, len(types))
+	for i, ct := range types {
+		out[i] = ColumnType{Name: ct.Name(), DatabaseType: ct.DatabaseTypeName()}
+		if nullable, ok := ct.Nullable(); ok {
+			out[i].Nullable = &nullable
+		}
+		if precision, scale, ok := ct.DecimalSize(); ok {
+			out[i].Precision = &precision
+			out[i].Scale = &scale
+		}
+	}
+	return out, nil
+}
+
+// columnValue converts a scanned column to the value reported for it. The
+// drivers return text columns as []byte.
+func columnValue(val any) any {
+	if b, ok := val.([]byte); ok {
+		return string(b)
 	}
-	return out, columns, nil
+	return val
 }
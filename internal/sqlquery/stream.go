@@ -0,0 +1,271 @@
+package sqlquery
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/planetscale/cli/internal/cmdutil"
+)
+
+// StreamFormats are the formats supported by NewRowWriter.
+var StreamFormats = []string{"ndjson", "csv", "tsv"}
+
+// Rows written by a RowWriter are flushed every streamFlushRows rows, or
+// with the first row written streamFlushInterval after the last flush, so a
+// slow query still shows its rows as they arrive.
+const (
+	streamFlushRows     = 1000
+	streamFlushInterval = time.Second
+)
+
+// flushSchedule tells a RowWriter when to flush the rows it buffered.
+type flushSchedule struct {
+	rows int
+	last time.Time
+}
+
+// due counts a written row and reports whether the rows should be flushed.
+func (f *flushSchedule) due() bool {
+	f.rows++
+	if f.rows < streamFlushRows && time.Since(f.last) < streamFlushInterval {
+		return false
+	}
+	f.rows = 0
+	f.last = time.Now()
+	return true
+}
+
+// RowWriter writes the rows of a streamed query as they are read from the
+// database. WriteColumns is called once before any row, and Finish once after
+// the last one.
+type RowWriter interface {
+	WriteColumns(columns []string) error
+	WriteRow(values []any) error
+	// Finish flushes the written rows. Writers that can carry it report the
+	// result envelope as well, including the error of a query that failed
+	// after rows were written.
+	Finish(result *Result) error
+}
+
+// NewRowWriter returns a RowWriter writing rows to w in the given format.
+//
+// The ndjson format writes one JSON object per line: {"columns": [...]}
+// first, then {"row": [...]} for each row and {"result": {...}} last. The csv
+// and tsv formats write a header line with the column names followed by the
+// rows.
+func NewRowWriter(format string, w io.Writer) (RowWriter, error) {
+	switch format {
+	case "ndjson":
+		bw := bufio.NewWriter(w)
+		enc := json.NewEncoder(bw)
+		enc.SetEscapeHTML(false)
+		return &ndjsonRowWriter{w: bw, enc: enc, flush: flushSchedule{last: time.Now()}}, nil
+	case "csv", "tsv":
+		cw := csv.NewWriter(w)
+		if format == "tsv" {
+			cw.Comma = '\t'
+		}
+		return &delimitedRowWriter{w: cw, flush: flushSchedule{last: time.Now()}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported stream format %q, supported formats are: ndjson, csv, tsv", format)
+	}
+}
+
+type ndjsonRowWriter struct {
+	w     *bufio.Writer
+	enc   *json.Encoder
+	flush flushSchedule
+}
+
+func (n *ndjsonRowWriter) WriteColumns(columns []string) error {
+	if err := n.enc.Encode(map[string]any{"columns": columns}); err != nil {
+		return err
+	}
+	return n.w.Flush()
+}
+
+func (n *ndjsonRowWriter) WriteRow(values []any) error {
+	if err := n.enc.Encode(map[string]any{"row": values}); err != nil {
+		return err
+	}
+	if n.flush.due() {
+		return n.w.Flush()
+	}
+	return nil
+}
+
+func (n *ndjsonRowWriter) Finish(result *Result) error {
+	if err := n.enc.Encode(map[string]any{"result": result}); err != nil {
+		return err
+	}
+	return n.w.Flush()
+}
+
+type delimitedRowWriter struct {
+	w      *csv.Writer
+	record []string
+	flush  flushSchedule
+}
+
+func (d *delimitedRowWriter) WriteColumns(columns []string) error {
+	d.record = make([]string, len(columns))
+	if err := d.w.Write(columns); err != nil {
+		return err
+	}
+	d.w.Flush()
+	return d.w.Error()
+}
+
+func (d *delimitedRowWriter) WriteRow(values []any) error {
+	for i, v := range values {
+		if v == nil {
+			d.record[i] = ""
+			continue
+		}
+		d.record[i] = fmt.Sprint(v)
+	}
+	if err := d.w.Write(d.record); err != nil {
+		return err
+	}
+	if d.flush.due() {
+		d.w.Flush()
+		return d.w.Error()
+	}
+	return nil
+}
+
+func (d *delimitedRowWriter) Finish(_ *Result) error {
+	d.w.Flush()
+	return d.w.Error()
+}
+
+// Stream runs a single query and hands its rows to rw as they are read, so
+// memory use does not grow with the size of the result. At most opts.MaxRows
+// rows are written when it is greater than zero. The returned Result has no
+// rows; it only summarizes the query.
+//
+// If the query fails after its columns were written, rw is still finished
+// with the Result, whose Status is error, and the Result is returned along
+// with the error.
+func Stream(ctx context.Context, ch *cmdutil.Helper, opts Options, rw RowWriter) (*Result, error) {
+	if opts.Query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	if err := guardQuery(opts, opts.Engine); err != nil {
+		return nil, err
+	}
+
+	role, err := cmdutil.ResolveAccessRole(opts.Role, opts.Replica, cmdutil.ReaderRole)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := NewSession(ctx, ch, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	result := &Result{
+		Status:   "ok",
+		Database: opts.Database,
+		Branch:   opts.Branch,
+		Kind:     session.Kind,
+		Role:     role.ToString(),
+		Replica:  opts.Replica,
+		NextSteps: []string{
+			cmdutil.AgentSQLCmd(opts.Organization, opts.Database, opts.Branch, false),
+		},
+	}
+
+	query, args, err := bindParams(opts.Query, session.Engine(), opts.Params, opts.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isReadQuery(opts.Query) && !queryReturnsRows(opts.Query) {
+		res, err := session.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+		result.RowsAffected, _ = res.RowsAffected()
+		return result, rw.Finish(result)
+	}
+
+	return streamRows(ctx, session.db, query, args, opts.MaxRows, rw, result)
+}
+
+// streamRows runs a query that returns rows and writes them to rw.
+func streamRows(ctx context.Context, db queryer, query string, args []any, maxRows int, rw RowWriter, result *Result) (*Result, error) {
+	// Canceling the query once maxRows is reached keeps the driver from
+	// reading the rest of the result when the rows are closed.
+	queryCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(queryCtx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result.Columns = columns
+	if result.ColumnTypes, err = columnTypes(rows); err != nil {
+		return nil, err
+	}
+	if err := rw.WriteColumns(columns); err != nil {
+		return nil, err
+	}
+
+	values := make([]any, len(columns))
+	scanArgs := make([]any, len(columns))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+	row := make([]any, len(columns))
+
+	for rows.Next() {
+		if maxRows > 0 && result.RowCount == maxRows {
+			result.Truncated = true
+			cancel()
+			break
+		}
+		if err := rows.Scan(scanArgs...); err != nil {
+			return streamFailed(rw, result, err)
+		}
+		for i, v := range values {
+			row[i] = columnValue(v)
+		}
+		if err := rw.WriteRow(row); err != nil {
+			return streamFailed(rw, result, err)
+		}
+		result.RowCount++
+	}
+	if !result.Truncated {
+		if err := rows.Err(); err != nil {
+			return streamFailed(rw, result, err)
+		}
+	}
+
+	return result, rw.Finish(result)
+}
+
+// streamFailed finishes rw after a query failed part way through its rows,
+// so the rows written so far are flushed and the result envelope reports
+// the error.
+func streamFailed(rw RowWriter, result *Result, err error) (*Result, error) {
+	result.Status = "error"
+	result.Error = err.Error()
+	// The query's error is the one reported; when writing the rows failed,
+	// finishing fails the same way.
+	_ = rw.Finish(result)
+	return result, err
+}
@@ -0,0 +1,156 @@
+package sqlquery
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	querypb "github.com/xelabs/go-mysqlstack/sqlparser/depends/query"
+	"github.com/xelabs/go-mysqlstack/sqlparser/depends/sqltypes"
+)
+
+func TestRowWriters(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: "ndjson",
+			want: `{"columns":["id","name"]}` + "\n" +
+				`{"row":[1,"a,b"]}` + "\n" +
+				`{"row":[2,null]}` + "\n" +
+				`{"result":{"status":"ok","database":"db","branch":"main","kind":"mysql","row_count":2,"columns":["id","name"],"truncated":true}}` + "\n",
+		},
+		{
+			format: "csv",
+			want:   "id,name\n1,\"a,b\"\n2,\n",
+		},
+		{
+			format: "tsv",
+			want:   "id\tname\n1\ta,b\n2\t\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			rw, err := NewRowWriter(tt.format, &buf)
+			if err != nil {
+				t.Fatalf("NewRowWriter: %v", err)
+			}
+
+			columns := []string{"id", "name"}
+			if err := rw.WriteColumns(columns); err != nil {
+				t.Fatalf("WriteColumns: %v", err)
+			}
+			for _, row := range [][]any{{int64(1), "a,b"}, {int64(2), nil}} {
+				if err := rw.WriteRow(row); err != nil {
+					t.Fatalf("WriteRow: %v", err)
+				}
+			}
+			if err := rw.Finish(&Result{
+				Status:    "ok",
+				Database:  "db",
+				Branch:    "main",
+				Kind:      "mysql",
+				RowCount:  2,
+				Columns:   columns,
+				Truncated: true,
+			}); err != nil {
+				t.Fatalf("Finish: %v", err)
+			}
+
+			if got := buf.String(); got != tt.want {
+				t.Fatalf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRowWriterRejectsUnknownFormat(t *testing.T) {
+	if _, err := NewRowWriter("xml", &bytes.Buffer{}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestRowWritersFlushPeriodically(t *testing.T) {
+	for _, format := range StreamFormats {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			rw, err := NewRowWriter(format, &buf)
+			if err != nil {
+				t.Fatalf("NewRowWriter: %v", err)
+			}
+
+			if err := rw.WriteColumns([]string{"id"}); err != nil {
+				t.Fatalf("WriteColumns: %v", err)
+			}
+			if buf.Len() == 0 {
+				t.Fatal("columns were not flushed")
+			}
+
+			header := buf.Len()
+			for i := 1; i <= streamFlushRows; i++ {
+				if err := rw.WriteRow([]any{int64(i)}); err != nil {
+					t.Fatalf("WriteRow: %v", err)
+				}
+				// A few rows stay buffered.
+				if i == 10 && buf.Len() != header {
+					t.Fatal("rows were flushed after 10 rows")
+				}
+			}
+			if got := strings.Count(buf.String(), "\n"); got != streamFlushRows+1 {
+				t.Fatalf("%d lines were flushed, want %d", got, streamFlushRows+1)
+			}
+		})
+	}
+}
+
+// failingRowWriter fails to write the row after the first failAfter ones.
+type failingRowWriter struct {
+	RowWriter
+	failAfter int
+	written   int
+}
+
+func (f *failingRowWriter) WriteRow(values []any) error {
+	if f.written == f.failAfter {
+		return errors.New("broken pipe")
+	}
+	f.written++
+	return f.RowWriter.WriteRow(values)
+}
+
+func TestStreamRowsReportsErrorAfterRows(t *testing.T) {
+	sess, fakedbs := newShardTestSession(t)
+	fakedbs.AddQuery("select id from t", &sqltypes.Result{
+		Fields: []*querypb.Field{{Name: "id", Type: querypb.Type_INT64}},
+		Rows: [][]sqltypes.Value{
+			{sqltypes.MakeTrusted(querypb.Type_INT64, []byte("1"))},
+			{sqltypes.MakeTrusted(querypb.Type_INT64, []byte("2"))},
+			{sqltypes.MakeTrusted(querypb.Type_INT64, []byte("3"))},
+		},
+	})
+
+	var buf bytes.Buffer
+	ndjson, err := NewRowWriter("ndjson", &buf)
+	if err != nil {
+		t.Fatalf("NewRowWriter: %v", err)
+	}
+	rw := &failingRowWriter{RowWriter: ndjson, failAfter: 1}
+
+	result, err := streamRows(context.Background(), sess.db, "SELECT id FROM t", nil, 0, rw, &Result{Status: "ok", Kind: "mysql"})
+	if err == nil || err.Error() != "broken pipe" {
+		t.Fatalf("streamRows error = %v, want broken pipe", err)
+	}
+	if result == nil || result.Status != "error" || result.Error != "broken pipe" || result.RowCount != 1 {
+		t.Fatalf("result = %+v, want an error after one row", result)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 || lines[1] != `{"row":[1]}` || !strings.HasPrefix(lines[2], `{"result":{"status":"error",`) || !strings.Contains(lines[2], `"error":"broken pipe"`) {
+		t.Fatalf("output = %q, want the columns, the first row and an error result", buf.String())
+	}
+}
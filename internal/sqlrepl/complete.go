@@ -0,0 +1,140 @@
+package sqlrepl
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// maxCompletionNames bounds the table and column names loaded for tab
+// completion, so databases with huge schemas stay responsive.
+const maxCompletionNames = 10000
+
+// keywords are the SQL keywords offered by tab completion.
+var keywords = []string{
+	"ALTER", "AND", "AS", "ASC", "BETWEEN", "BY", "CASE", "COUNT", "CREATE", "DELETE",
+	"DESC", "DESCRIBE", "DISTINCT", "DROP", "ELSE", "END", "EXISTS", "EXPLAIN", "FROM",
+	"GROUP", "HAVING", "IN", "INDEX", "INNER", "INSERT", "INTO", "IS", "JOIN", "LEFT",
+	"LIKE", "LIMIT", "NOT", "NULL", "OFFSET", "ON", "OR", "ORDER", "OUTER", "RIGHT",
+	"SELECT", "SET", "SHOW", "TABLE", "TABLES", "THEN", "UNION", "UPDATE", "USE",
+	"VALUES", "WHEN", "WHERE", "WITH",
+}
+
+// Completer completes SQL keywords and the table and column names of the
+// current database on tab.
+type Completer struct {
+	mu    sync.Mutex
+	names []string
+}
+
+// SetNames replaces the table and column names offered for completion.
+func (c *Completer) SetNames(names []string) {
+	names = slices.Clone(names)
+	slices.Sort(names)
+	names = slices.Compact(names)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.names = names
+}
+
+// Complete implements the AutoCompleteCallback of golang.org/x/term. On tab,
+// the word before the cursor is completed to the longest prefix shared by
+// every keyword and name it matches.
+func (c *Completer) Complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+
+	start := pos
+	for start > 0 && isWordChar(line[start-1]) {
+		start--
+	}
+	prefix := line[start:pos]
+	if prefix == "" {
+		return "", 0, false
+	}
+
+	var matches []string
+	lowerPrefix := strings.ToLower(prefix)
+	for _, kw := range keywords {
+		if strings.HasPrefix(strings.ToLower(kw), lowerPrefix) {
+			// Keywords follow the case the user types them in.
+			if prefix == lowerPrefix {
+				kw = strings.ToLower(kw)
+			}
+			matches = append(matches, kw)
+		}
+	}
+	c.mu.Lock()
+	for _, name := range c.names {
+		if strings.HasPrefix(strings.ToLower(name), lowerPrefix) {
+			matches = append(matches, name)
+		}
+	}
+	c.mu.Unlock()
+
+	completion := commonPrefix(matches)
+	if len(completion) <= len(prefix) {
+		return "", 0, false
+	}
+	return line[:start] + completion + line[pos:], start + len(completion), true
+}
+
+// commonPrefix returns the longest prefix shared by words, compared case
+// insensitively and spelled as in the first word.
+func commonPrefix(words []string) string {
+	if len(words) == 0 {
+		return ""
+	}
+	prefix := words[0]
+	for _, word := range words[1:] {
+		n := 0
+		for n < len(prefix) && n < len(word) && strings.EqualFold(prefix[n:n+1], word[n:n+1]) {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	return prefix
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '$' || (c >= '0' && c <= '9') || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
+
+// loadCompletions reads the table and column names of the current database
+// (MySQL) or of the schemas on the search path (PostgreSQL).
+func loadCompletions(ctx context.Context, session Session) ([]string, error) {
+	query := fmt.Sprintf(`SELECT table_name, column_name FROM information_schema.columns
+		WHERE table_schema = DATABASE() LIMIT %d`, maxCompletionNames)
+	if session.Engine() != "mysql" {
+		query = fmt.Sprintf(`SELECT c.relname AS table_name, a.attname AS column_name
+			FROM pg_catalog.pg_attribute a
+			JOIN pg_catalog.pg_class c ON c.oid = a.attrelid
+			JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+			WHERE n.nspname = ANY (current_schemas(false))
+				AND c.relkind IN ('r', 'v', 'm', 'p', 'f')
+				AND a.attnum > 0 AND NOT a.attisdropped
+			LIMIT %d`, maxCompletionNames)
+	}
+
+	result, err := session.Run(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Columns) < 2 {
+		return nil, nil
+	}
+
+	var names []string
+	for _, row := range result.Rows {
+		for _, col := range result.Columns[:2] {
+			if name, ok := row[col].(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,89 @@
+package sqlrepl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompleterComplete(t *testing.T) {
+	c := &Completer{}
+	c.SetNames([]string{"users", "user_id", "customers", "users"})
+
+	tests := []struct {
+		line    string
+		pos     int
+		want    string
+		wantPos int
+		ok      bool
+	}{
+		{line: "sel", pos: 3, want: "select", wantPos: 6, ok: true},
+		{line: "SEL", pos: 3, want: "SELECT", wantPos: 6, ok: true},
+		{line: "select * from cu", pos: 16, want: "select * from customers", wantPos: 23, ok: true},
+		{line: "select * from us where", pos: 16, want: "select * from use where", wantPos: 17, ok: true},
+		{line: "select * from users", pos: 19, ok: false},
+		{line: "select ", pos: 7, ok: false},
+		{line: "select * from zz", pos: 16, ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, pos, ok := c.Complete(tt.line, tt.pos, '\t')
+			if ok != tt.ok || got != tt.want || pos != tt.wantPos {
+				t.Errorf("Complete(%q, %d) = %q, %d, %v, want %q, %d, %v", tt.line, tt.pos, got, pos, ok, tt.want, tt.wantPos, tt.ok)
+			}
+		})
+	}
+
+	if _, _, ok := c.Complete("sel", 3, 'x'); ok {
+		t.Error("Complete handled a key other than tab")
+	}
+}
+
+func TestHistory(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "history", "org.db.main")
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte("_HiStOrY_V2_\nselect\\0401;\n\nshow tables;\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	h := LoadHistory(file)
+	if h.Len() != 2 || h.At(0) != "show tables;" || h.At(1) != "select 1;" {
+		t.Fatalf("loaded history = %q", h.entries)
+	}
+
+	h.Add("show tables;")
+	h.Add("  ")
+	h.Add("select 2;")
+	if h.Len() != 3 || h.At(0) != "select 2;" {
+		t.Fatalf("history = %q", h.entries)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(data), "show tables;\nselect 2;\n") {
+		t.Errorf("history file = %q", data)
+	}
+}
+
+func TestHistoryCreatesFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "pscale", "history", "org.db.main")
+
+	h := LoadHistory(file)
+	if h.Len() != 0 {
+		t.Fatalf("history = %q, want empty", h.entries)
+	}
+	h.Add("select 1;")
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "select 1;\n" {
+		t.Errorf("history file = %q", data)
+	}
+}
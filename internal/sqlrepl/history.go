@@ -0,0 +1,100 @@
+package sqlrepl
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxHistory is the number of lines kept in memory for the up and down keys.
+const maxHistory = 1000
+
+// libeditHistoryHeader starts history files written by the mysql and psql
+// clients when they are built with libedit, which also encode spaces as
+// \040.
+const libeditHistoryHeader = "_HiStOrY_V2_"
+
+// History is the input history of the REPL, persisted to a file shared with
+// the mysql and psql clients. It implements the History of golang.org/x/term.
+type History struct {
+	file    string
+	entries []string
+}
+
+// LoadHistory reads the history in file. A missing or unreadable file starts
+// an empty history; new lines are still appended to file when possible.
+func LoadHistory(file string) *History {
+	h := &History{file: file}
+	if file == "" {
+		return h
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return h
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == libeditHistoryHeader {
+			continue
+		}
+		line = strings.ReplaceAll(line, `\040`, " ")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		h.entries = append(h.entries, line)
+	}
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+	}
+	return h
+}
+
+// Add records a line of input, skipping blank lines and repeats of the
+// previous line.
+func (h *History) Add(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	if n := len(h.entries); n > 0 && h.entries[n-1] == line {
+		return
+	}
+	h.entries = append(h.entries, line)
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[1:]
+	}
+	h.persist(line)
+}
+
+// Len returns the number of lines in the history.
+func (h *History) Len() int {
+	return len(h.entries)
+}
+
+// At returns the line idx lines back in the history; At(0) is the most
+// recent one.
+func (h *History) At(idx int) string {
+	return h.entries[len(h.entries)-1-idx]
+}
+
+// persist appends line to the history file. History is a convenience, so
+// errors are ignored.
+func (h *History) persist(line string) {
+	if h.file == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(h.file), 0o700); err != nil {
+		return
+	}
+	f, err := os.OpenFile(h.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	_, _ = f.WriteString(line + "\n")
+}
@@ -0,0 +1,92 @@
+package sqlrepl
+
+import "strings"
+
+// statement is a complete statement read by the REPL.
+type statement struct {
+	text string
+	// vertical is set for statements ending with \G.
+	vertical bool
+}
+
+// splitInput splits the complete statements off input. A statement ends with
+// ; or \G outside of quotes and comments. The text after the last complete
+// statement is returned as rest. On MySQL, backslashes escape characters in
+// quoted strings; on PostgreSQL, dollar-quoted strings ($$...$$) are skipped.
+func splitInput(input, engine string) (statements []statement, rest string) {
+	mysql := engine == "mysql"
+
+	var (
+		start   = 0
+		quote   string
+		comment string
+	)
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+		switch {
+		case comment == "--":
+			if c == '\n' {
+				comment = ""
+			}
+		case comment == "/*":
+			if strings.HasPrefix(input[i:], "*/") {
+				comment = ""
+				i++
+			}
+		case quote != "":
+			switch {
+			case mysql && c == '\\' && quote != "`":
+				i++
+			case strings.HasPrefix(input[i:], quote):
+				i += len(quote) - 1
+				quote = ""
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = string(c)
+		case !mysql && c == '$':
+			if tag := dollarQuoteTag(input[i:]); tag != "" {
+				quote = tag
+				i += len(tag) - 1
+			}
+		case c == '#' && mysql:
+			comment = "--"
+		case strings.HasPrefix(input[i:], "-- "), strings.HasPrefix(input[i:], "--\n"), strings.HasPrefix(input[i:], "--\t"):
+			comment = "--"
+		case strings.HasPrefix(input[i:], "/*"):
+			comment = "/*"
+			i++
+		case c == ';':
+			statements = appendStatement(statements, input[start:i], false)
+			start = i + 1
+		case c == '\\' && i+1 < len(input) && (input[i+1] == 'G' || input[i+1] == 'g'):
+			statements = appendStatement(statements, input[start:i], input[i+1] == 'G')
+			start = i + 2
+			i++
+		}
+	}
+	return statements, input[start:]
+}
+
+// dollarQuoteTag returns the opening tag of a PostgreSQL dollar-quoted string
+// ($$ or $tag$) at the start of s, or "" if there is none.
+func dollarQuoteTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '$':
+			return s[:i+1]
+		case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 1 && c >= '0' && c <= '9'):
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+func appendStatement(statements []statement, text string, vertical bool) []statement {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return statements
+	}
+	return append(statements, statement{text: text, vertical: vertical})
+}
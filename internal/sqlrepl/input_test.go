@@ -0,0 +1,85 @@
+package sqlrepl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitInput(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		engine     string
+		statements []statement
+		rest       string
+	}{
+		{
+			name:       "single statement",
+			input:      "select 1;\n",
+			engine:     "mysql",
+			statements: []statement{{text: "select 1"}},
+			rest:       "\n",
+		},
+		{
+			name:   "unterminated",
+			input:  "select *\nfrom t\n",
+			engine: "mysql",
+			rest:   "select *\nfrom t\n",
+		},
+		{
+			name:       "several statements and vertical",
+			input:      "select 1; select 2\\G select",
+			engine:     "mysql",
+			statements: []statement{{text: "select 1"}, {text: "select 2", vertical: true}},
+			rest:       " select",
+		},
+		{
+			name:       "semicolons in quotes and comments",
+			input:      "select 'a;b', \"c;d\", `e;f` -- g;h\n/* i; */ from t;",
+			engine:     "mysql",
+			statements: []statement{{text: "select 'a;b', \"c;d\", `e;f` -- g;h\n/* i; */ from t"}},
+		},
+		{
+			name:       "mysql backslash escape",
+			input:      `select 'it\'s;'; `,
+			engine:     "mysql",
+			statements: []statement{{text: `select 'it\'s;'`}},
+			rest:       " ",
+		},
+		{
+			name:       "mysql hash comment",
+			input:      "select 1 # a;\n;",
+			engine:     "mysql",
+			statements: []statement{{text: "select 1 # a;"}},
+		},
+		{
+			name:       "postgres dollar quotes",
+			input:      "do $body$ begin perform 1; end $body$;",
+			engine:     "postgresql",
+			statements: []statement{{text: "do $body$ begin perform 1; end $body$"}},
+		},
+		{
+			name:       "postgres positional parameter",
+			input:      "select $1;",
+			engine:     "postgresql",
+			statements: []statement{{text: "select $1"}},
+		},
+		{
+			name:   "empty statements are dropped",
+			input:  " ; ;",
+			engine: "mysql",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statements, rest := splitInput(tt.input, tt.engine)
+			if !reflect.DeepEqual(statements, tt.statements) {
+				t.Errorf("statements = %#v, want %#v", statements, tt.statements)
+			}
+			if rest != tt.rest {
+				t.Errorf("rest = %q, want %q", rest, tt.rest)
+			}
+		})
+	}
+}
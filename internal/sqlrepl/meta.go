@@ -0,0 +1,155 @@
+package sqlrepl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const helpText = `Statements end with ; or, to show the result vertically, \G.
+
+Meta-commands:
+  \d [table]        List the tables, or describe the columns of a table
+  \use <name>       Switch to a database (MySQL) or schema (PostgreSQL)
+  \x                Toggle between table and vertical output
+  \format <name>    Set the output format: table, vertical, or json
+  \timing [on|off]  Toggle showing how long each statement took
+  \? or help        Show this help
+  \q, quit, exit    Quit
+`
+
+// isMetaCommand reports whether line is a meta-command rather than the start
+// of a SQL statement.
+func isMetaCommand(line string) bool {
+	if strings.HasPrefix(line, `\`) {
+		return true
+	}
+	switch strings.ToLower(strings.TrimSuffix(line, ";")) {
+	case "quit", "exit", "help":
+		return true
+	}
+	return false
+}
+
+// meta runs a meta-command and reports whether the REPL should quit.
+func (r *REPL) meta(ctx context.Context, line string) (quit bool) {
+	name, arg, _ := strings.Cut(strings.TrimSuffix(line, ";"), " ")
+	arg = strings.TrimSpace(arg)
+
+	switch strings.ToLower(name) {
+	case `\q`, "quit", "exit":
+		return true
+	case `\?`, `\h`, "help":
+		fmt.Fprint(r.out, helpText)
+	case `\x`:
+		if r.format == FormatVertical {
+			r.format = FormatTable
+		} else {
+			r.format = FormatVertical
+		}
+		fmt.Fprintf(r.out, "Output format is %s.\n", r.format)
+	case `\format`:
+		switch arg {
+		case FormatTable, FormatVertical, FormatJSON:
+			r.format = arg
+			fmt.Fprintf(r.out, "Output format is %s.\n", r.format)
+		default:
+			fmt.Fprintf(r.out, "ERROR: unknown format %q, supported formats are: table, vertical, json\n", arg)
+		}
+	case `\timing`:
+		switch strings.ToLower(arg) {
+		case "":
+			r.timing = !r.timing
+		case "on":
+			r.timing = true
+		case "off":
+			r.timing = false
+		default:
+			fmt.Fprintf(r.out, "ERROR: \\timing takes on or off, got %q\n", arg)
+			return false
+		}
+		if r.timing {
+			fmt.Fprintln(r.out, "Timing is on.")
+		} else {
+			fmt.Fprintln(r.out, "Timing is off.")
+		}
+	case `\d`:
+		r.describe(ctx, arg)
+	case `\use`:
+		r.use(ctx, arg)
+	default:
+		fmt.Fprintf(r.out, "ERROR: unknown command %s, type \\? for help\n", name)
+	}
+	return false
+}
+
+// describe lists the tables of the current database, or the columns of
+// table.
+func (r *REPL) describe(ctx context.Context, table string) {
+	var (
+		query string
+		args  []any
+	)
+	switch {
+	case r.session.Engine() == "mysql" && table == "":
+		query = "SHOW TABLES"
+	case r.session.Engine() == "mysql":
+		query = "SHOW COLUMNS FROM " + quoteMySQL(table)
+	case table == "":
+		query = `SELECT table_schema AS "schema", table_name AS "name", table_type AS "type"
+			FROM information_schema.tables
+			WHERE table_schema = ANY (current_schemas(false))
+			ORDER BY table_schema, table_name`
+	default:
+		query = `SELECT column_name AS "column", data_type AS "type", is_nullable AS "nullable", column_default AS "default"
+			FROM information_schema.columns
+			WHERE table_schema = ANY (current_schemas(false)) AND table_name = $1
+			ORDER BY ordinal_position`
+		args = []any{table}
+	}
+
+	result, err := r.session.Run(ctx, query, args...)
+	if err != nil {
+		fmt.Fprintf(r.out, "ERROR: %s\n", err)
+		return
+	}
+	if table != "" && result.RowCount == 0 {
+		fmt.Fprintf(r.out, "ERROR: table %s not found\n", table)
+		return
+	}
+	if err := render(r.out, result, r.format); err != nil {
+		fmt.Fprintf(r.out, "ERROR: %s\n", err)
+	}
+}
+
+// use switches to a database on MySQL. PostgreSQL cannot switch databases on
+// an open connection, so it switches the schema instead.
+func (r *REPL) use(ctx context.Context, name string) {
+	if name == "" {
+		fmt.Fprintln(r.out, `ERROR: \use needs a database or schema name`)
+		return
+	}
+
+	query := "USE " + quoteMySQL(name)
+	if r.session.Engine() != "mysql" {
+		query = "SET search_path TO " + quotePostgres(name)
+	}
+	if _, err := r.session.Run(ctx, query); err != nil {
+		fmt.Fprintf(r.out, "ERROR: %s\n", err)
+		return
+	}
+	if r.session.Engine() == "mysql" {
+		fmt.Fprintf(r.out, "Database changed to %s.\n", name)
+	} else {
+		fmt.Fprintf(r.out, "Schema changed to %s.\n", name)
+	}
+	r.refreshCompletions(ctx)
+}
+
+func quoteMySQL(identifier string) string {
+	return "`" + strings.ReplaceAll(identifier, "`", "``") + "`"
+}
+
+func quotePostgres(identifier string) string {
+	return `"` + strings.ReplaceAll(identifier, `"`, `""`) + `"`
+}
@@ -0,0 +1,148 @@
+package sqlrepl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+
+	"github.com/planetscale/cli/internal/sqlquery"
+)
+
+// render writes the result of a statement to w in the given format.
+func render(w io.Writer, result *sqlquery.StatementResult, format string) error {
+	if len(result.Columns) == 0 {
+		fmt.Fprintf(w, "Query OK, %s affected\n", pluralRows(result.RowsAffected))
+		return nil
+	}
+
+	switch format {
+	case FormatVertical:
+		renderVertical(w, result)
+	case FormatJSON:
+		if err := renderJSON(w, result); err != nil {
+			return err
+		}
+	default:
+		renderTable(w, result)
+	}
+	if result.RowCount == 0 {
+		fmt.Fprintln(w, "Empty set")
+		return nil
+	}
+	fmt.Fprintf(w, "%s in set\n", pluralRows(int64(result.RowCount)))
+	return nil
+}
+
+func pluralRows(n int64) string {
+	if n == 1 {
+		return "1 row"
+	}
+	return fmt.Sprintf("%d rows", n)
+}
+
+// formatCell formats a column value for table and vertical output.
+func formatCell(v any) string {
+	if v == nil {
+		return "NULL"
+	}
+	return fmt.Sprint(v)
+}
+
+func renderTable(w io.Writer, result *sqlquery.StatementResult) {
+	if result.RowCount == 0 {
+		return
+	}
+
+	widths := make([]int, len(result.Columns))
+	cells := make([][]string, len(result.Rows))
+	for i, col := range result.Columns {
+		widths[i] = runewidth.StringWidth(col)
+	}
+	for r, row := range result.Rows {
+		cells[r] = make([]string, len(result.Columns))
+		for i, col := range result.Columns {
+			cell := formatCell(row[col])
+			cells[r][i] = cell
+			widths[i] = max(widths[i], runewidth.StringWidth(cell))
+		}
+	}
+
+	var sep strings.Builder
+	sep.WriteString("+")
+	for _, width := range widths {
+		sep.WriteString(strings.Repeat("-", width+2))
+		sep.WriteString("+")
+	}
+
+	writeRow := func(values []string) {
+		var line strings.Builder
+		line.WriteString("|")
+		for i, value := range values {
+			line.WriteString(" ")
+			line.WriteString(runewidth.FillRight(value, widths[i]))
+			line.WriteString(" |")
+		}
+		fmt.Fprintln(w, line.String())
+	}
+
+	fmt.Fprintln(w, sep.String())
+	writeRow(result.Columns)
+	fmt.Fprintln(w, sep.String())
+	for _, row := range cells {
+		writeRow(row)
+	}
+	fmt.Fprintln(w, sep.String())
+}
+
+func renderVertical(w io.Writer, result *sqlquery.StatementResult) {
+	width := 0
+	for _, col := range result.Columns {
+		width = max(width, runewidth.StringWidth(col))
+	}
+	for i, row := range result.Rows {
+		fmt.Fprintf(w, "*************************** %d. row ***************************\n", i+1)
+		for _, col := range result.Columns {
+			fmt.Fprintf(w, "%s: %s\n", runewidth.FillLeft(col, width), formatCell(row[col]))
+		}
+	}
+}
+
+// renderJSON writes the rows as a JSON array of objects, keeping the columns
+// in result order.
+func renderJSON(w io.Writer, result *sqlquery.StatementResult) error {
+	var buf bytes.Buffer
+	buf.WriteString("[")
+	for i, row := range result.Rows {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n  {")
+		for j, col := range result.Columns {
+			if j > 0 {
+				buf.WriteString(", ")
+			}
+			key, err := json.Marshal(col)
+			if err != nil {
+				return err
+			}
+			value, err := json.Marshal(row[col])
+			if err != nil {
+				return err
+			}
+			buf.Write(key)
+			buf.WriteString(": ")
+			buf.Write(value)
+		}
+		buf.WriteString("}")
+	}
+	if len(result.Rows) > 0 {
+		buf.WriteString("\n")
+	}
+	buf.WriteString("]\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
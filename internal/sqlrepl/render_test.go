@@ -0,0 +1,88 @@
+package sqlrepl
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/planetscale/cli/internal/sqlquery"
+)
+
+func testResult() *sqlquery.StatementResult {
+	return &sqlquery.StatementResult{
+		Columns:  []string{"id", "name"},
+		RowCount: 2,
+		Rows: []map[string]any{
+			{"id": int64(1), "name": "ada"},
+			{"id": int64(22), "name": nil},
+		},
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: FormatTable,
+			want: `+----+------+
+| id | name |
++----+------+
+| 1  | ada  |
+| 22 | NULL |
++----+------+
+2 rows in set
+`,
+		},
+		{
+			format: FormatVertical,
+			want: `*************************** 1. row ***************************
+  id: 1
+name: ada
+*************************** 2. row ***************************
+  id: 22
+name: NULL
+2 rows in set
+`,
+		},
+		{
+			format: FormatJSON,
+			want: `[
+  {"id": 1, "name": "ada"},
+  {"id": 22, "name": null}
+]
+2 rows in set
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := render(&buf, testResult(), tt.format); err != nil {
+				t.Fatalf("render: %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("render =\n%s\nwant\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderWithoutRows(t *testing.T) {
+	var buf bytes.Buffer
+	if err := render(&buf, &sqlquery.StatementResult{Columns: []string{"id"}}, FormatTable); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if got, want := buf.String(), "Empty set\n"; got != want {
+		t.Errorf("render = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	if err := render(&buf, &sqlquery.StatementResult{RowsAffected: 3}, FormatTable); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if got, want := buf.String(), "Query OK, 3 rows affected\n"; got != want {
+		t.Errorf("render = %q, want %q", got, want)
+	}
+}
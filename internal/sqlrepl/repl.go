@@ -0,0 +1,193 @@
+// Package sqlrepl is an interactive SQL shell built on sqlquery.Session. It
+// backs pscale shell on machines without the mysql or psql command-line
+// client.
+package sqlrepl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/planetscale/cli/internal/sqlquery"
+)
+
+// Session is the connection the REPL runs statements on. It is implemented
+// by *sqlquery.Session.
+type Session interface {
+	Run(ctx context.Context, query string, args ...any) (*sqlquery.StatementResult, error)
+	// Engine is "mysql" or "postgresql".
+	Engine() string
+}
+
+// LineReader reads the input of the REPL one line at a time.
+type LineReader interface {
+	ReadLine() (string, error)
+	SetPrompt(prompt string)
+}
+
+// Output formats of the REPL.
+const (
+	FormatTable    = "table"
+	FormatVertical = "vertical"
+	FormatJSON     = "json"
+)
+
+// REPL reads statements and meta-commands, runs the statements on a Session,
+// and renders their results.
+type REPL struct {
+	session Session
+	in      LineReader
+	out     io.Writer
+	prompt  string
+
+	format string
+	timing bool
+
+	completer *Completer
+	// interrupt, if set, returns a context canceled when the user
+	// interrupts the running statement, and a function releasing it.
+	interrupt func(ctx context.Context) (context.Context, func())
+}
+
+// New returns a REPL running statements on session, reading from in and
+// writing to out. prompt is shown before each statement; continuation lines
+// of a statement get a prompt of the same width.
+func New(session Session, in LineReader, out io.Writer, prompt string) *REPL {
+	return &REPL{
+		session:   session,
+		in:        in,
+		out:       out,
+		prompt:    prompt,
+		format:    FormatTable,
+		completer: &Completer{},
+	}
+}
+
+// Run reads and runs statements until the input ends or the user quits.
+// Statements end with ; or, to render their result vertically, \G.
+func (r *REPL) Run(ctx context.Context) error {
+	r.refreshCompletions(ctx)
+
+	var buf strings.Builder
+	for {
+		if buf.Len() == 0 {
+			r.in.SetPrompt(r.prompt)
+		} else {
+			r.in.SetPrompt(continuationPrompt(r.prompt))
+		}
+
+		line, err := r.in.ReadLine()
+		if errors.Is(err, io.EOF) {
+			// Like the mysql client, a statement without a terminator at
+			// the end of the input still runs.
+			if stmt := strings.TrimSpace(buf.String()); stmt != "" {
+				r.execute(ctx, statement{text: stmt})
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if buf.Len() == 0 {
+			trimmed := strings.TrimSpace(line)
+			if trimmed == "" {
+				continue
+			}
+			if isMetaCommand(trimmed) {
+				if quit := r.meta(ctx, trimmed); quit {
+					return nil
+				}
+				continue
+			}
+		}
+
+		buf.WriteString(line)
+		buf.WriteString("\n")
+
+		statements, rest := splitInput(buf.String(), r.session.Engine())
+		for _, stmt := range statements {
+			r.execute(ctx, stmt)
+		}
+		buf.Reset()
+		if strings.TrimSpace(rest) != "" {
+			buf.WriteString(rest)
+		}
+	}
+}
+
+// execute runs a single statement and renders its result or error.
+func (r *REPL) execute(ctx context.Context, stmt statement) {
+	if r.interrupt != nil {
+		var release func()
+		ctx, release = r.interrupt(ctx)
+		defer release()
+	}
+
+	start := time.Now()
+	result, err := r.session.Run(ctx, stmt.text)
+	elapsed := time.Since(start)
+	if err != nil {
+		if ctx.Err() != nil {
+			err = errors.New("query interrupted")
+		}
+		fmt.Fprintf(r.out, "ERROR: %s\n", err)
+		return
+	}
+
+	format := r.format
+	if stmt.vertical {
+		format = FormatVertical
+	}
+	if err := render(r.out, result, format); err != nil {
+		fmt.Fprintf(r.out, "ERROR: %s\n", err)
+		return
+	}
+	if r.timing {
+		fmt.Fprintf(r.out, "Time: %.3f sec\n", elapsed.Seconds())
+	}
+
+	if isSchemaChange(stmt.text) {
+		r.refreshCompletions(ctx)
+	}
+}
+
+// refreshCompletions reloads the table and column names offered by tab
+// completion. Completion is best effort, so errors are ignored.
+func (r *REPL) refreshCompletions(ctx context.Context) {
+	names, err := loadCompletions(ctx, r.session)
+	if err != nil {
+		return
+	}
+	r.completer.SetNames(names)
+}
+
+// Completer returns the tab completer of the REPL, for use as the
+// AutoCompleteCallback of a terminal.
+func (r *REPL) Completer() *Completer {
+	return r.completer
+}
+
+// continuationPrompt returns the prompt for the continuation lines of a
+// statement, right-aligned with prompt.
+func continuationPrompt(prompt string) string {
+	width := len([]rune(prompt))
+	if width <= 3 {
+		return "-> "
+	}
+	return strings.Repeat(" ", width-3) + "-> "
+}
+
+// isSchemaChange reports whether stmt may change the names offered by tab
+// completion.
+func isSchemaChange(stmt string) bool {
+	word, _, _ := strings.Cut(strings.TrimSpace(stmt), " ")
+	switch strings.ToUpper(word) {
+	case "CREATE", "ALTER", "DROP", "RENAME", "USE", "SET":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package sqlrepl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/planetscale/cli/internal/sqlquery"
+)
+
+type fakeSession struct {
+	engine  string
+	queries []string
+	results map[string]*sqlquery.StatementResult
+}
+
+func (s *fakeSession) Engine() string { return s.engine }
+
+func (s *fakeSession) Run(_ context.Context, query string, args ...any) (*sqlquery.StatementResult, error) {
+	s.queries = append(s.queries, query)
+	if strings.Contains(query, "information_schema.columns") && len(args) == 0 {
+		return &sqlquery.StatementResult{
+			Columns:  []string{"TABLE_NAME", "COLUMN_NAME"},
+			RowCount: 1,
+			Rows:     []map[string]any{{"TABLE_NAME": "users", "COLUMN_NAME": "email"}},
+		}, nil
+	}
+	if result, ok := s.results[query]; ok {
+		return result, nil
+	}
+	return nil, errors.New("unknown statement")
+}
+
+type scriptReader struct {
+	lines   []string
+	prompts []string
+}
+
+func (r *scriptReader) ReadLine() (string, error) {
+	if len(r.lines) == 0 {
+		return "", io.EOF
+	}
+	line := r.lines[0]
+	r.lines = r.lines[1:]
+	return line, nil
+}
+
+func (r *scriptReader) SetPrompt(prompt string) {
+	r.prompts = append(r.prompts, prompt)
+}
+
+func TestREPLRun(t *testing.T) {
+	session := &fakeSession{
+		engine: "mysql",
+		results: map[string]*sqlquery.StatementResult{
+			"select *\nfrom users": testResult(),
+			"SHOW TABLES": {
+				Columns:  []string{"Tables_in_db"},
+				RowCount: 1,
+				Rows:     []map[string]any{{"Tables_in_db": "users"}},
+			},
+			"USE `other`":           {},
+			"delete from users":     {RowsAffected: 2},
+			"select 1 as n":         {Columns: []string{"n"}, RowCount: 1, Rows: []map[string]any{{"n": int64(1)}}},
+			"select 2 as n":         {Columns: []string{"n"}, RowCount: 1, Rows: []map[string]any{{"n": int64(2)}}},
+			"select 'unterminated'": {Columns: []string{"u"}},
+		},
+	}
+	in := &scriptReader{lines: []string{
+		"select *",
+		"from users;",
+		`\d`,
+		`\use other`,
+		`\timing on`,
+		"delete from users;",
+		`\timing off`,
+		"select 1 as n\\G",
+		`\format json`,
+		"select 2 as n; select 'unterminated'",
+	}}
+
+	var out bytes.Buffer
+	r := New(session, in, &out, "db/main> ")
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		"| users        |\n",
+		"Database changed to other.\n",
+		"Timing is on.\n",
+		"Query OK, 2 rows affected\nTime: ",
+		"Timing is off.\n",
+		"*************************** 1. row ***************************\nn: 1\n",
+		"Output format is json.\n",
+		"[\n  {\"n\": 2}\n]\n",
+		"Empty set\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output is missing %q:\n%s", want, got)
+		}
+	}
+	if in.prompts[1] != "      -> " {
+		t.Errorf("continuation prompt = %q", in.prompts[1])
+	}
+
+	// Completions are loaded at start and reloaded after \use.
+	if got := r.Completer().names; len(got) != 2 || got[0] != "email" || got[1] != "users" {
+		t.Errorf("completion names = %q", got)
+	}
+}
+
+func TestREPLMetaCommands(t *testing.T) {
+	session := &fakeSession{engine: "postgresql"}
+	in := &scriptReader{lines: []string{
+		`\?`,
+		`\x`,
+		`\format xml`,
+		`\use`,
+		`\nope`,
+		"select nothing;",
+		"quit",
+		"select 1;",
+	}}
+
+	var out bytes.Buffer
+	if err := New(session, in, &out, "> ").Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		"Meta-commands:",
+		"Output format is vertical.\n",
+		`ERROR: unknown format "xml"`,
+		`ERROR: \use needs a database or schema name`,
+		`ERROR: unknown command \nope`,
+		"ERROR: unknown statement\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output is missing %q:\n%s", want, got)
+		}
+	}
+	if len(in.lines) != 1 {
+		t.Errorf("quit left %d lines unread, want 1", len(in.lines))
+	}
+	for _, q := range session.queries {
+		if q == "select 1" {
+			t.Error("statement after quit was run")
+		}
+	}
+}
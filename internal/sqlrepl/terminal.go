@@ -0,0 +1,106 @@
+package sqlrepl
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"golang.org/x/term"
+)
+
+// RunTerminal runs a REPL on session reading from stdin and writing to
+// stdout. On a terminal it supports line editing, history persisted to
+// historyFile, and tab completion; otherwise it reads statements line by line.
+//
+// sigc is the channel the CLI uses to cancel its context on os.Interrupt.
+// While a statement runs it is stopped, so Ctrl+C interrupts the statement
+// rather than the whole shell.
+func RunTerminal(ctx context.Context, session Session, prompt, historyFile string, sigc chan os.Signal, signals []os.Signal) error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		r := New(session, &plainReader{scanner: scanner}, os.Stdout, prompt)
+		return r.Run(ctx)
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, state) // nolint:errcheck
+
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, prompt)
+	if width, height, err := term.GetSize(fd); err == nil {
+		_ = t.SetSize(width, height)
+	}
+	t.History = LoadHistory(historyFile)
+
+	r := New(session, &terminalReader{t: t}, t, prompt)
+	t.AutoCompleteCallback = r.Completer().Complete
+	r.interrupt = func(ctx context.Context) (context.Context, func()) {
+		// Leave raw mode so Ctrl+C raises os.Interrupt, and take the
+		// signal over from the CLI for the duration of the statement.
+		_ = term.Restore(fd, state)
+		if sigc != nil {
+			signal.Stop(sigc)
+		}
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+		return ctx, func() {
+			stop()
+			if sigc != nil {
+				signal.Notify(sigc, signals...)
+			}
+			_, _ = term.MakeRaw(fd)
+			if width, height, err := term.GetSize(fd); err == nil {
+				_ = t.SetSize(width, height)
+			}
+		}
+	}
+
+	fmt.Fprintln(t, `Type \? for help, \q to quit.`)
+	return r.Run(ctx)
+}
+
+// terminalReader reads lines from a terminal with line editing.
+type terminalReader struct {
+	t *term.Terminal
+}
+
+func (r *terminalReader) ReadLine() (string, error) {
+	line, err := r.t.ReadLine()
+	if errors.Is(err, term.ErrPasteIndicator) {
+		// The line was pasted; it is complete and can be used as is.
+		err = nil
+	}
+	return line, err
+}
+
+func (r *terminalReader) SetPrompt(prompt string) {
+	r.t.SetPrompt(prompt)
+}
+
+// plainReader reads lines from input that is not a terminal, such as a pipe.
+// It shows no prompt.
+type plainReader struct {
+	scanner *bufio.Scanner
+}
+
+func (r *plainReader) ReadLine() (string, error) {
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.scanner.Text(), nil
+}
+
+func (r *plainReader) SetPrompt(string) {}